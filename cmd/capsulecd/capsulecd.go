@@ -9,6 +9,7 @@ import (
 	"github.com/analogj/capsulecd/pkg"
 	"github.com/analogj/capsulecd/pkg/config"
 	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/server"
 	"github.com/analogj/capsulecd/pkg/utils"
 	"github.com/analogj/capsulecd/pkg/version"
 	"github.com/urfave/cli"
@@ -59,6 +60,9 @@ func main() {
 					configuration, _ := config.Create()
 					configuration.Set("scm", c.String("scm"))
 					configuration.Set("package_type", c.String("package_type"))
+					if c.String("payload_file") != "" {
+						configuration.Set("scm_payload_file", c.String("payload_file"))
+					}
 					//config.Set("dry_run", c.String("dry_run"))
 
 					//override system configuration file (default: ~/capsule.yaml)
@@ -121,6 +125,73 @@ func main() {
 						Name:    "config_file",
 						Usage:   "Specifies the location of the system config file",
 					},
+
+					&cli.StringFlag{
+						Name:  "payload_file",
+						Usage: "Specifies the location of a Github/Bitbucket webhook event payload (or `-` for stdin), used instead of retrieving the pull request from the scm api",
+					},
+				},
+			},
+			{
+				Name:  "serve",
+				Usage: "Start a webhook server, which runs a CapsuleCD package pipeline for each matching Github/Bitbucket event",
+				Action: func(c *cli.Context) error {
+
+					// every job uses a new configuration, so that values set by the pipeline (or repository capsule.yml) are isolated.
+					jobConfig := func() (config.Interface, error) {
+						configuration, err := config.Create()
+						if err != nil {
+							return nil, err
+						}
+						configuration.Set("package_type", c.String("package_type"))
+
+						if c.String("config_file") != "" {
+							absConfigPath, err := filepath.Abs(c.String("config_file"))
+							if err != nil {
+								return nil, err
+							}
+							err = configuration.ReadConfig(absConfigPath)
+							if err != nil {
+								return nil, errors.EngineUnspecifiedError("Could not load repository configuration file. Check syntax.")
+							}
+						}
+						return configuration, nil
+					}
+
+					serverConfig, err := jobConfig()
+					if err != nil {
+						return err
+					}
+					if c.String("address") != "" {
+						serverConfig.Set("server_address", c.String("address"))
+					}
+
+					fmt.Println("package type:", serverConfig.GetString("package_type"))
+					fmt.Println("address:", serverConfig.GetString("server_address"))
+
+					webhookServer, err := server.New(serverConfig, jobConfig)
+					if err != nil {
+						return err
+					}
+					return webhookServer.ListenAndServe()
+				},
+
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "package_type",
+						Value: "default",
+						Usage: "The type of package being built.",
+					},
+
+					&cli.StringFlag{
+						Name:  "config_file",
+						Usage: "Specifies the location of the system config file",
+					},
+
+					&cli.StringFlag{
+						Name:  "address",
+						Usage: "The address the webhook server should listen on (default: server_address)",
+					},
 				},
 			},
 		},
@@ -0,0 +1,193 @@
+package changelog
+
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/conventional"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	FormatMarkdown       = "markdown"
+	FormatKeepAChangelog = "keepachangelog"
+	FormatJSON           = "json"
+)
+
+const (
+	GroupByType   = "type"
+	GroupByLabels = "labels"
+)
+
+// Github/Bitbucket pull request merge commits, eg. `Merge pull request #12 from AnalogJ/feature` or
+// `Merged in feature/branch (pull request #12)`
+var mergePullRequestPattern = regexp.MustCompile(`^(?:Merge pull request #(\d+) from \S+|Merged in \S+ \(pull request #(\d+)\))`)
+
+// squash merge commits, eg. `Add feature (#12)`
+var squashPullRequestPattern = regexp.MustCompile(`^(.*\S)\s+\(#(\d+)\)$`)
+
+// Github noreply emails, eg. `12345+AnalogJ@users.noreply.github.com`
+var githubNoreplyEmailPattern = regexp.MustCompile(`^(?:\d+\+)?([^@]+)@users\.noreply\.github\.com$`)
+
+type Entry struct {
+	Sha               string    `json:"sha"`
+	Subject           string    `json:"subject"`
+	Type              string    `json:"type,omitempty"`
+	Scope             string    `json:"scope,omitempty"`
+	Breaking          bool      `json:"breaking"`
+	AuthorName        string    `json:"author_name"`
+	AuthorLogin       string    `json:"author_login,omitempty"`
+	Date              time.Time `json:"date"`
+	PullRequestNumber string    `json:"pull_request_number,omitempty"`
+	Labels            []string  `json:"labels,omitempty"`
+
+	CommitUrl      string `json:"commit_url,omitempty"`
+	PullRequestUrl string `json:"pull_request_url,omitempty"`
+	AuthorUrl      string `json:"author_url,omitempty"`
+}
+
+func (e *Entry) ShortSha() string {
+	return fmt.Sprintf("%.8s", e.Sha)
+}
+
+type Section struct {
+	Title   string   `json:"title"`
+	Entries []*Entry `json:"entries"`
+}
+
+type Changelog struct {
+	Version     string     `json:"version"`
+	Tag         string     `json:"tag"`
+	PreviousTag string     `json:"previous_tag,omitempty"`
+	Date        time.Time  `json:"date"`
+	CompareUrl  string     `json:"compare_url,omitempty"`
+	Sections    []*Section `json:"sections"`
+}
+
+// a section containing pull requests with any of the specified labels (scm_changelog_label_sections)
+type LabelSection struct {
+	Title  string   `mapstructure:"title"`
+	Labels []string `mapstructure:"labels"`
+}
+
+type Options struct {
+	Format         string // markdown, keepachangelog or json
+	Template       string // Go text/template used to render the changelog (markdown & keepachangelog formats only)
+	GroupBy        string // type (conventional commit type) or labels (pull request labels)
+	LabelSections  []LabelSection
+	ExcludeAuthors []string // commits by these authors (eg. bots) are excluded
+	Links          Links
+
+	// commits that should not be included in the changelog (eg. CapsuleCD version bump commits)
+	ExcludeCommit func(commit *pipeline.GitCommit) bool
+	// retrieves the labels of a pull request, required to group entries by label.
+	PullRequestLabels func(number string) ([]string, error)
+}
+
+// New generates the changelog for a release from its commits (newest first).
+func New(version string, tag string, previousTag string, commits []*pipeline.GitCommit, options Options) (*Changelog, error) {
+	changelog := &Changelog{
+		Version:     version,
+		Tag:         tag,
+		PreviousTag: previousTag,
+		Date:        time.Now().UTC(),
+		CompareUrl:  options.Links.compareUrl(previousTag, tag),
+	}
+
+	entries := []*Entry{}
+	for _, commit := range commits {
+		if entry := newEntry(commit, options); entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	if options.GroupBy == GroupByLabels {
+		if err := populateLabels(entries, options); err != nil {
+			return nil, err
+		}
+		changelog.Sections = groupByLabels(entries, options.LabelSections)
+	} else {
+		changelog.Sections = groupByType(entries, options.Format)
+	}
+	return changelog, nil
+}
+
+// convert a commit into a changelog entry. Returns nil if the commit should be excluded.
+// Pull request merge commits are converted to an entry for the pull request, other merge commits are excluded.
+func newEntry(commit *pipeline.GitCommit, options Options) *Entry {
+	if options.ExcludeCommit != nil && options.ExcludeCommit(commit) {
+		return nil
+	}
+	for _, author := range options.ExcludeAuthors {
+		if strings.EqualFold(author, commit.AuthorName) || strings.EqualFold(author, commit.AuthorEmail) {
+			return nil
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(commit.Message), "\n")
+	entry := &Entry{
+		Sha:        commit.Sha,
+		Subject:    strings.TrimSpace(lines[0]),
+		AuthorName: commit.AuthorName,
+		Date:       commit.Date,
+	}
+
+	if matches := mergePullRequestPattern.FindStringSubmatch(entry.Subject); matches != nil {
+		entry.PullRequestNumber = matches[1] + matches[2]
+		// the pull request title follows the merge commit subject
+		for _, line := range lines[1:] {
+			if strings.TrimSpace(line) != "" {
+				entry.Subject = strings.TrimSpace(line)
+				break
+			}
+		}
+	} else if commit.IsMerge() {
+		return nil
+	} else if matches := squashPullRequestPattern.FindStringSubmatch(entry.Subject); matches != nil {
+		entry.Subject = matches[1]
+		entry.PullRequestNumber = matches[2]
+	}
+
+	message := entry.Subject
+	if len(lines) > 1 {
+		message = entry.Subject + "\n" + strings.Join(lines[1:], "\n")
+	}
+	if parsed, err := conventional.Parse(message); err == nil {
+		entry.Type = parsed.Type
+		entry.Scope = parsed.Scope
+		entry.Breaking = parsed.Breaking
+		entry.Subject = parsed.Description
+	}
+
+	if matches := githubNoreplyEmailPattern.FindStringSubmatch(commit.AuthorEmail); matches != nil {
+		entry.AuthorLogin = matches[1]
+	}
+	entry.CommitUrl = options.Links.commitUrl(entry.Sha)
+	entry.PullRequestUrl = options.Links.pullRequestUrl(entry.PullRequestNumber)
+	entry.AuthorUrl = options.Links.authorUrl(entry.AuthorLogin)
+	return entry
+}
+
+func populateLabels(entries []*Entry, options Options) error {
+	if options.PullRequestLabels == nil {
+		return nil
+	}
+	labelCache := map[string][]string{}
+	for _, entry := range entries {
+		if entry.PullRequestNumber == "" {
+			continue
+		}
+		labels, found := labelCache[entry.PullRequestNumber]
+		if !found {
+			var err error
+			labels, err = options.PullRequestLabels(entry.PullRequestNumber)
+			if err != nil {
+				return err
+			}
+			labelCache[entry.PullRequestNumber] = labels
+		}
+		entry.Labels = labels
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package changelog
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// header written to new changelog files.
+const fileHeader = `# Changelog
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/).
+`
+
+// `## [Unreleased]` or `## Unreleased`
+var unreleasedHeadingPattern = regexp.MustCompile(`(?i)^##\s+\[?unreleased\]?\s*$`)
+
+// link reference definitions, eg. `[1.2.3]: https://github.com/AnalogJ/capsulecd/compare/v1.2.2...v1.2.3`
+var linkReferencePattern = regexp.MustCompile(`^\[[^\]]+\]:\s+\S+`)
+
+// the `Unreleased` compare link, eg. `[Unreleased]: https://github.com/AnalogJ/capsulecd/compare/v1.2.3...HEAD`
+var unreleasedLinkPattern = regexp.MustCompile(`(?i)^(\[unreleased\]:\s+\S*/compare/)\S+(\.\.\.HEAD)\s*$`)
+
+// a `### Title` subsection of a release, and its lines.
+type fileSubsection struct {
+	Title string
+	Lines []string
+}
+
+// UpdateFile prepends the release section (in Keep a Changelog format) to the changelog file, creating it if it does
+// not exist. The release is added below the `Unreleased` section, and its compare link is added to the link references.
+// If moveUnreleased is true, the changes listed in the `Unreleased` section are moved into the release section.
+func UpdateFile(filePath string, changelog *Changelog, moveUnreleased bool) error {
+	content := fileHeader
+	if fileContent, err := ioutil.ReadFile(filePath); err == nil {
+		content = string(fileContent)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	section, err := Render(changelog, FormatKeepAChangelog, "")
+	if err != nil {
+		return err
+	}
+	releaseLines, releaseLinks := splitLinkReferences(strings.Split(strings.TrimSpace(section), "\n"))
+
+	// split the existing file into the preamble, the unreleased section, previous releases & link references.
+	lines, links := splitLinkReferences(strings.Split(strings.TrimRight(content, "\n"), "\n"))
+	preamble, unreleased, releases := lines, []string{}, []string{}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		preamble, releases = lines[:i], lines[i:]
+		if unreleasedHeadingPattern.MatchString(line) {
+			unreleased, releases = releases, []string{}
+			for j := 1; j < len(unreleased); j++ {
+				if strings.HasPrefix(unreleased[j], "## ") {
+					unreleased, releases = unreleased[:j], unreleased[j:]
+					break
+				}
+			}
+		}
+		break
+	}
+
+	if moveUnreleased && len(unreleased) > 0 {
+		releaseLines = mergeUnreleased(releaseLines, unreleased[1:])
+		unreleased = []string{unreleased[0], ""}
+	}
+
+	// the unreleased compare link should now start at the new release.
+	for i, link := range links {
+		if unreleasedLinkPattern.MatchString(link) {
+			links[i] = unreleasedLinkPattern.ReplaceAllString(link, "${1}"+changelog.Tag+"${2}")
+		}
+	}
+	if len(links) > 0 && unreleasedLinkPattern.MatchString(links[0]) {
+		links = append(links[:1], append(releaseLinks, links[1:]...)...)
+	} else {
+		links = append(releaseLinks, links...)
+	}
+
+	updated := []string{}
+	updated = append(updated, trimBlankLines(preamble)...)
+	updated = append(updated, "")
+	if len(unreleased) > 0 {
+		updated = append(updated, trimBlankLines(unreleased)...)
+		updated = append(updated, "")
+	}
+	updated = append(updated, releaseLines...)
+	if len(releases) > 0 {
+		updated = append(updated, "")
+		updated = append(updated, trimBlankLines(releases)...)
+	}
+	if len(links) > 0 {
+		updated = append(updated, "")
+		updated = append(updated, links...)
+	}
+	return ioutil.WriteFile(filePath, []byte(strings.Join(updated, "\n")+"\n"), 0644)
+}
+
+// separate the trailing link reference definitions from the rest of the content.
+func splitLinkReferences(lines []string) ([]string, []string) {
+	end := len(lines)
+	for end > 0 && (linkReferencePattern.MatchString(lines[end-1]) || strings.TrimSpace(lines[end-1]) == "") {
+		end--
+	}
+	links := []string{}
+	for _, line := range lines[end:] {
+		if strings.TrimSpace(line) != "" {
+			links = append(links, line)
+		}
+	}
+	return trimBlankLines(lines[:end]), links
+}
+
+// merge the changes listed in the unreleased section into the release section. Changes are added to the matching
+// `### Title` subsection of the release (before the generated entries), or as a new subsection.
+func mergeUnreleased(releaseLines []string, unreleasedLines []string) []string {
+	releaseIntro, releaseSubsections := parseSubsections(releaseLines[1:])
+	unreleasedIntro, unreleasedSubsections := parseSubsections(unreleasedLines)
+
+	for _, unreleasedSubsection := range unreleasedSubsections {
+		merged := false
+		for _, releaseSubsection := range releaseSubsections {
+			if strings.EqualFold(releaseSubsection.Title, unreleasedSubsection.Title) {
+				releaseSubsection.Lines = append(trimBlankLines(unreleasedSubsection.Lines), releaseSubsection.Lines...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			releaseSubsections = append(releaseSubsections, unreleasedSubsection)
+		}
+	}
+
+	merged := []string{releaseLines[0]}
+	if intro := trimBlankLines(append(releaseIntro, unreleasedIntro...)); len(intro) > 0 {
+		merged = append(merged, "")
+		merged = append(merged, intro...)
+	}
+	for _, subsection := range releaseSubsections {
+		merged = append(merged, "", subsection.Title)
+		merged = append(merged, trimBlankLines(subsection.Lines)...)
+	}
+	return merged
+}
+
+func parseSubsections(lines []string) ([]string, []*fileSubsection) {
+	intro := []string{}
+	subsections := []*fileSubsection{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "### ") {
+			subsections = append(subsections, &fileSubsection{Title: strings.TrimSpace(line)})
+		} else if len(subsections) > 0 {
+			subsections[len(subsections)-1].Lines = append(subsections[len(subsections)-1].Lines, line)
+		} else {
+			intro = append(intro, line)
+		}
+	}
+	return intro, subsections
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
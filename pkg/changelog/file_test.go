@@ -0,0 +1,150 @@
+package changelog_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/changelog"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+const existingChangelog = `# Changelog
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+### Added
+- hand written feature
+
+### Security
+- upgraded dependencies
+
+## [1.2.3] - 2018-01-01
+### Fixed
+- previous fix
+
+[Unreleased]: https://github.com/AnalogJ/capsulecd/compare/v1.2.3...HEAD
+[1.2.3]: https://github.com/AnalogJ/capsulecd/compare/v1.2.2...v1.2.3
+`
+
+func testFileChangelog() *changelog.Changelog {
+	return &changelog.Changelog{
+		Version:    "1.3.0",
+		Tag:        "v1.3.0",
+		Date:       time.Date(2018, 2, 15, 0, 0, 0, 0, time.UTC),
+		CompareUrl: "https://github.com/AnalogJ/capsulecd/compare/v1.2.3...v1.3.0",
+		Sections: []*changelog.Section{
+			{Title: "Added", Entries: []*changelog.Entry{{Sha: "2222222222222222222222222222222222222222", Subject: "generated feature", AuthorName: "Jason Kulatunga"}}},
+		},
+	}
+}
+
+func writeChangelogFile(t *testing.T, content string) (string, string) {
+	dirPath, err := ioutil.TempDir("", "changelog")
+	require.NoError(t, err)
+	filePath := path.Join(dirPath, "CHANGELOG.md")
+	if content != "" {
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	}
+	return dirPath, filePath
+}
+
+func TestUpdateFile_Create(t *testing.T) {
+	//setup
+	dirPath, filePath := writeChangelogFile(t, "")
+	defer os.RemoveAll(dirPath)
+
+	//test
+	err := changelog.UpdateFile(filePath, testFileChangelog(), false)
+	require.NoError(t, err)
+
+	//assert
+	content, rerr := ioutil.ReadFile(filePath)
+	require.NoError(t, rerr)
+	require.Equal(t, `# Changelog
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/).
+
+## [1.3.0] - 2018-02-15
+
+### Added
+- generated feature (22222222) - Jason Kulatunga
+
+[1.3.0]: https://github.com/AnalogJ/capsulecd/compare/v1.2.3...v1.3.0
+`, string(content))
+}
+
+func TestUpdateFile_KeepUnreleased(t *testing.T) {
+	//setup
+	dirPath, filePath := writeChangelogFile(t, existingChangelog)
+	defer os.RemoveAll(dirPath)
+
+	//test
+	err := changelog.UpdateFile(filePath, testFileChangelog(), false)
+	require.NoError(t, err)
+
+	//assert
+	content, rerr := ioutil.ReadFile(filePath)
+	require.NoError(t, rerr)
+	require.Equal(t, `# Changelog
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+### Added
+- hand written feature
+
+### Security
+- upgraded dependencies
+
+## [1.3.0] - 2018-02-15
+
+### Added
+- generated feature (22222222) - Jason Kulatunga
+
+## [1.2.3] - 2018-01-01
+### Fixed
+- previous fix
+
+[Unreleased]: https://github.com/AnalogJ/capsulecd/compare/v1.3.0...HEAD
+[1.3.0]: https://github.com/AnalogJ/capsulecd/compare/v1.2.3...v1.3.0
+[1.2.3]: https://github.com/AnalogJ/capsulecd/compare/v1.2.2...v1.2.3
+`, string(content))
+}
+
+func TestUpdateFile_MoveUnreleased(t *testing.T) {
+	//setup
+	dirPath, filePath := writeChangelogFile(t, existingChangelog)
+	defer os.RemoveAll(dirPath)
+
+	//test
+	err := changelog.UpdateFile(filePath, testFileChangelog(), true)
+	require.NoError(t, err)
+
+	//assert
+	content, rerr := ioutil.ReadFile(filePath)
+	require.NoError(t, rerr)
+	require.Equal(t, `# Changelog
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+## [1.3.0] - 2018-02-15
+
+### Added
+- hand written feature
+- generated feature (22222222) - Jason Kulatunga
+
+### Security
+- upgraded dependencies
+
+## [1.2.3] - 2018-01-01
+### Fixed
+- previous fix
+
+[Unreleased]: https://github.com/AnalogJ/capsulecd/compare/v1.3.0...HEAD
+[1.3.0]: https://github.com/AnalogJ/capsulecd/compare/v1.2.3...v1.3.0
+[1.2.3]: https://github.com/AnalogJ/capsulecd/compare/v1.2.2...v1.2.3
+`, string(content))
+}
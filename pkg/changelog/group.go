@@ -0,0 +1,107 @@
+package changelog
+
+import "strings"
+
+const otherChangesTitle = "Other Changes"
+
+// section titles for each conventional commit type (and breaking changes), in the order they are rendered.
+type typeSection struct {
+	Key   string
+	Title string
+}
+
+var typeSections = map[string][]typeSection{
+	FormatMarkdown: {
+		{"breaking", "Breaking Changes"},
+		{"feat", "Features"},
+		{"fix", "Bug Fixes"},
+		{"perf", "Performance Improvements"},
+		{"other", otherChangesTitle},
+	},
+	// https://keepachangelog.com/en/1.0.0/#how
+	FormatKeepAChangelog: {
+		{"feat", "Added"},
+		{"breaking", "Changed"},
+		{"perf", "Changed"},
+		{"other", "Changed"},
+		{"fix", "Fixed"},
+	},
+}
+
+func typeSectionKey(entry *Entry) string {
+	if entry.Breaking {
+		return "breaking"
+	}
+	switch entry.Type {
+	case "feat", "fix", "perf":
+		return entry.Type
+	default:
+		return "other"
+	}
+}
+
+// group entries by conventional commit type. Sections without entries are omitted.
+func groupByType(entries []*Entry, format string) []*Section {
+	sectionTitles, found := typeSections[format]
+	if !found {
+		sectionTitles = typeSections[FormatMarkdown]
+	}
+
+	sections := []*Section{}
+	sectionsByTitle := map[string]*Section{}
+	for _, sectionTitle := range sectionTitles {
+		for _, entry := range entries {
+			if typeSectionKey(entry) != sectionTitle.Key {
+				continue
+			}
+			section, found := sectionsByTitle[sectionTitle.Title]
+			if !found {
+				section = &Section{Title: sectionTitle.Title}
+				sectionsByTitle[sectionTitle.Title] = section
+				sections = append(sections, section)
+			}
+			section.Entries = append(section.Entries, entry)
+		}
+	}
+	return sections
+}
+
+// group entries by pull request label. Each entry is added to the first matching label section, entries without a
+// matching label are added to an "Other Changes" section. Sections without entries are omitted.
+func groupByLabels(entries []*Entry, labelSections []LabelSection) []*Section {
+	sections := make([]*Section, len(labelSections))
+	for ndx := range labelSections {
+		sections[ndx] = &Section{Title: labelSections[ndx].Title}
+	}
+	other := &Section{Title: otherChangesTitle}
+
+	for _, entry := range entries {
+		section := other
+		for ndx := range labelSections {
+			if hasAnyLabel(entry.Labels, labelSections[ndx].Labels) {
+				section = sections[ndx]
+				break
+			}
+		}
+		section.Entries = append(section.Entries, entry)
+	}
+
+	nonEmpty := []*Section{}
+	for _, section := range append(sections, other) {
+		if len(section.Entries) > 0 {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+	return nonEmpty
+}
+
+func hasAnyLabel(labels []string, wanted []string) bool {
+	for _, label := range labels {
+		for _, wantedLabel := range wanted {
+			if strings.EqualFold(label, wantedLabel) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package changelog
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// url formats used to link commits, pull requests, authors and the release diff. Empty formats are not linked.
+type Links struct {
+	Commit      string // eg. https://github.com/AnalogJ/capsulecd/commit/%s
+	PullRequest string // eg. https://github.com/AnalogJ/capsulecd/pull/%s
+	Author      string // eg. https://github.com/%s
+	Compare     string // eg. https://github.com/AnalogJ/capsulecd/compare/%s...%s
+}
+
+// generate the link formats for the scm, using the repository clone url (eg. https://github.com/AnalogJ/capsulecd.git)
+func ScmLinks(scmName string, cloneUrl string) Links {
+	webUrl, err := repositoryWebUrl(cloneUrl)
+	if err != nil || webUrl == "" {
+		return Links{}
+	}
+
+	switch scmName {
+	case "github":
+		parsed, _ := url.Parse(webUrl)
+		return Links{
+			Commit:      webUrl + "/commit/%s",
+			PullRequest: webUrl + "/pull/%s",
+			Author:      fmt.Sprintf("%s://%s/", parsed.Scheme, parsed.Host) + "%s",
+			Compare:     webUrl + "/compare/%s...%s",
+		}
+	case "bitbucket":
+		return Links{
+			Commit:      webUrl + "/commits/%s",
+			PullRequest: webUrl + "/pull-requests/%s",
+			Compare:     webUrl + "/branches/compare/%[2]s%%0D%[1]s",
+		}
+	default:
+		return Links{}
+	}
+}
+
+// strip credentials and the `.git` suffix from a clone url.
+func repositoryWebUrl(cloneUrl string) (string, error) {
+	parsed, err := url.Parse(cloneUrl)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", nil
+	}
+	parsed.User = nil
+	return strings.TrimSuffix(strings.TrimSuffix(parsed.String(), "/"), ".git"), nil
+}
+
+func (l Links) commitUrl(sha string) string {
+	if l.Commit == "" || sha == "" {
+		return ""
+	}
+	return fmt.Sprintf(l.Commit, sha)
+}
+
+func (l Links) pullRequestUrl(number string) string {
+	if l.PullRequest == "" || number == "" {
+		return ""
+	}
+	return fmt.Sprintf(l.PullRequest, number)
+}
+
+func (l Links) authorUrl(login string) string {
+	if l.Author == "" || login == "" {
+		return ""
+	}
+	return fmt.Sprintf(l.Author, login)
+}
+
+func (l Links) compareUrl(previousTag string, tag string) string {
+	if l.Compare == "" || previousTag == "" || tag == "" {
+		return ""
+	}
+	return fmt.Sprintf(l.Compare, previousTag, tag)
+}
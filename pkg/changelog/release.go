@@ -0,0 +1,79 @@
+package changelog
+
+import (
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/utils"
+)
+
+// OptionsFromConfig reads the changelog options (scm_changelog_*) from the config.
+// CapsuleCD version bump commits are always excluded.
+func OptionsFromConfig(config config.Interface) (Options, error) {
+	options := Options{
+		Format:         config.GetString("scm_changelog_format"),
+		Template:       config.GetString("scm_changelog_template"),
+		GroupBy:        config.GetString("scm_changelog_group_by"),
+		ExcludeAuthors: config.GetStringSlice("scm_changelog_exclude_authors"),
+		ExcludeCommit: func(commit *pipeline.GitCommit) bool {
+			return utils.IsVersionBumpCommit(commit.Message, config.GetString("engine_version_bump_msg"))
+		},
+	}
+	if err := config.UnmarshalKey("scm_changelog_label_sections", &options.LabelSections); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// ReleaseCommits returns the commits included in the release, and the tag they are compared against (if any).
+// Commits are read from the nearest tag to HEAD (unless scm_disable_nearest_tag_changelog is set). Pull requests fall
+// back to the commits between the pull request base and head.
+func ReleaseCommits(config config.Interface, pipelineData *pipeline.Data) ([]*pipeline.GitCommit, string, error) {
+	if pipelineData.GitNearestTag != nil && !config.GetBool("scm_disable_nearest_tag_changelog") {
+		commits, err := utils.GitCommitsBetween(pipelineData.GitLocalPath, pipelineData.GitNearestTag.TagShortName, "HEAD")
+		if err == nil {
+			return commits, pipelineData.GitNearestTag.TagShortName, nil
+		} else if !pipelineData.IsPullRequest {
+			return nil, "", err
+		}
+	}
+
+	if pipelineData.IsPullRequest {
+		commits, err := utils.GitCommitsBetween(pipelineData.GitLocalPath, pipelineData.GitBaseInfo.Sha, pipelineData.GitHeadInfo.Sha)
+		return commits, "", err
+	}
+	return []*pipeline.GitCommit{}, "", nil
+}
+
+// RepositoryLinks returns the links for the repository being released (the pull request base, or the pushed repository).
+func RepositoryLinks(scmName string, pipelineData *pipeline.Data) Links {
+	releaseRepo := pipelineData.GitHeadInfo
+	if pipelineData.GitBaseInfo != nil {
+		releaseRepo = pipelineData.GitBaseInfo
+	}
+	if releaseRepo == nil || releaseRepo.Repo == nil {
+		return Links{}
+	}
+	return ScmLinks(scmName, releaseRepo.Repo.CloneUrl)
+}
+
+// ReleaseChangelog groups the commits included in the specified version.
+func ReleaseChangelog(config config.Interface, pipelineData *pipeline.Data, version string, options Options) (*Changelog, error) {
+	commits, previousTag, err := ReleaseCommits(config, pipelineData)
+	if err != nil {
+		return nil, err
+	}
+	tagName, err := utils.ReleaseTagName(config.GetString("engine_tag_template"), pipelineData, version)
+	if err != nil {
+		return nil, err
+	}
+	return New(version, tagName, previousTag, commits, options)
+}
+
+// Release generates the changelog for the version being released (pipelineData.ReleaseVersion).
+func Release(config config.Interface, pipelineData *pipeline.Data, options Options) (string, error) {
+	releaseChangelog, err := ReleaseChangelog(config, pipelineData, pipelineData.ReleaseVersion, options)
+	if err != nil {
+		return "", err
+	}
+	return Render(releaseChangelog, options.Format, options.Template)
+}
@@ -0,0 +1,71 @@
+package changelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+)
+
+// templates shared by all formats, and available to custom templates (eg. `{{template "entry" .}}`)
+const entryTemplate = `{{define "entry"}}{{if .Scope}}**{{.Scope}}:** {{end}}{{.Subject}} ({{link .ShortSha .CommitUrl}}{{if .PullRequestNumber}}, {{link (printf "#%s" .PullRequestNumber) .PullRequestUrl}}{{end}}) - {{if .AuthorLogin}}{{link (printf "@%s" .AuthorLogin) .AuthorUrl}}{{else}}{{.AuthorName}}{{end}}{{end}}`
+
+var formatTemplates = map[string]string{
+	FormatMarkdown: `{{range .Sections}}### {{.Title}}
+
+{{range .Entries}}- {{template "entry" .}}
+{{end}}
+{{end}}{{if .CompareUrl}}**Full Changelog**: {{.CompareUrl}}
+{{end}}`,
+
+	FormatKeepAChangelog: `## [{{.Version}}] - {{.Date.Format "2006-01-02"}}
+{{range .Sections}}
+### {{.Title}}
+{{range .Entries}}- {{if .Breaking}}**BREAKING:** {{end}}{{template "entry" .}}
+{{end}}{{end}}{{if .CompareUrl}}
+[{{.Version}}]: {{.CompareUrl}}
+{{end}}`,
+}
+
+var templateFuncs = template.FuncMap{
+	// markdown link, or plain text if the url is empty.
+	"link": func(text string, url string) string {
+		if url == "" {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", text, url)
+	},
+}
+
+// Render the changelog using the specified format, or the custom template (markdown & keepachangelog formats only).
+func Render(changelog *Changelog, format string, customTemplate string) (string, error) {
+	if format == FormatJSON {
+		content, err := json.MarshalIndent(changelog, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+
+	tmplString, found := formatTemplates[format]
+	if !found {
+		return "", fmt.Errorf("Unknown changelog format: %s", format)
+	}
+	if customTemplate != "" {
+		tmplString = customTemplate
+	}
+
+	tmpl, err := template.New("changelog").Funcs(templateFuncs).Parse(entryTemplate)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmpl.Parse(tmplString); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, changelog); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -34,16 +34,49 @@ func (c *configuration) Init() error {
 	c.SetDefault("scm", "default")
 	c.SetDefault("runner", "default")
 	c.SetDefault("engine_version_bump_type", "patch")
+	c.SetDefault("engine_version_prerelease_id", "rc")
+	c.SetDefault("engine_version_scheme", "semver")
+	c.SetDefault("engine_version_override", "")
+	c.SetDefault("engine_version_source", "metadata")
+	c.SetDefault("engine_version_initial", "0.0.0")
+	c.SetDefault("engine_calver_format", "YYYY.MM.MICRO")
 	c.SetDefault("engine_version_bump_msg", "Automated packaging of release by CapsuleCD")
+	c.SetDefault("engine_tag_template", "v{{.Version}}")
 	c.SetDefault("mgr_keep_lock_file", "false") //delete *.lock files by default.
 
 	c.SetDefault("scm_notify_source", "CapsuleCD")
 	c.SetDefault("scm_notify_target_url", "https://github.com/AnalogJ/capsulecd")
+	c.SetDefault("scm_merge_strategy", "merge")
+	c.SetDefault("scm_release_pull_request_branch", "capsulecd/release")
+	c.SetDefault("scm_require_signed_commits", false)
+	c.SetDefault("scm_git_backend", "") //libgit2 if available, otherwise go-git
+	c.SetDefault("scm_git_transport", "https")
+	c.SetDefault("scm_git_ssh_username", "git")
+	c.SetDefault("scm_git_ssh_known_hosts_policy", "strict")
 
 	c.SetDefault("engine_git_author_name", "CapsuleCD")
 	c.SetDefault("engine_git_author_email", "CapsuleCD@users.noreply.github.com")
+	c.SetDefault("engine_git_signing_format", "gpg")
 
 	c.SetDefault("engine_repo_config_path",  "capsule.yml")
+	c.SetDefault("engine_release_lock_expiry", 3600)
+	c.SetDefault("engine_conventional_commits_strict", false)
+	c.SetDefault("engine_changelog_path", "")
+	c.SetDefault("engine_changelog_move_unreleased", false)
+
+	c.SetDefault("scm_bump_type_sources", []string{"labels", "title", "body"})
+	c.SetDefault("scm_bump_type_label_prefixes", []string{"release:", "semver:"})
+
+	c.SetDefault("scm_changelog_format", "markdown")
+	c.SetDefault("scm_changelog_group_by", "type")
+	c.SetDefault("scm_changelog_template", "")
+	c.SetDefault("scm_changelog_exclude_authors", []string{})
+
+	c.SetDefault("server_address", ":8080")
+	c.SetDefault("server_queue_size", 100)
+	c.SetDefault("server_job_max_attempts", 3)
+	c.SetDefault("server_job_retry_delay", 30)
+	c.SetDefault("server_job_history", 100)
 
 	//set the default system config file search path.
 	//if you want to load a non-standard location system config file (~/capsule.yml), use ReadConfig
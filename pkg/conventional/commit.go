@@ -0,0 +1,64 @@
+package conventional
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Conventional Commit header, eg. `feat(parser)!: add ability to parse arrays`
+// https://www.conventionalcommits.org/en/v1.0.0/
+var headerPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()]*)\))?(!)?: (\S.*)$`)
+
+// footer tokens that mark a breaking change.
+var breakingChangeFooters = []string{"BREAKING CHANGE:", "BREAKING-CHANGE:"}
+
+type Commit struct {
+	Type        string
+	Scope       string
+	Description string
+	Body        string
+	Breaking    bool
+}
+
+// Parse a commit message as a Conventional Commit.
+func Parse(message string) (*Commit, error) {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	header := strings.TrimSpace(lines[0])
+
+	matches := headerPattern.FindStringSubmatch(header)
+	if matches == nil {
+		return nil, fmt.Errorf("commit header does not match `<type>[(scope)][!]: <description>`: %q", header)
+	}
+
+	commit := &Commit{
+		Type:        strings.ToLower(matches[1]),
+		Scope:       matches[2],
+		Breaking:    matches[3] == "!",
+		Description: matches[4],
+		Body:        strings.TrimSpace(strings.Join(lines[1:], "\n")),
+	}
+	for _, line := range lines[1:] {
+		for _, footer := range breakingChangeFooters {
+			if strings.HasPrefix(strings.TrimSpace(line), footer) {
+				commit.Breaking = true
+			}
+		}
+	}
+	return commit, nil
+}
+
+// Determine the version bump required by this commit: breaking changes are `major`, `feat` is `minor`, and `fix`/`perf`
+// are `patch`. Other types (eg. `docs`, `chore`) do not require a release, and return an empty bump type.
+func (c *Commit) BumpType() string {
+	switch {
+	case c.Breaking:
+		return "major"
+	case c.Type == "feat":
+		return "minor"
+	case c.Type == "fix" || c.Type == "perf":
+		return "patch"
+	default:
+		return ""
+	}
+}
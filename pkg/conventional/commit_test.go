@@ -0,0 +1,80 @@
+package conventional_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/conventional"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	//test
+	commit, err := conventional.Parse("feat(parser): add ability to parse arrays\n\nArrays are now supported.\n")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "feat", commit.Type)
+	require.Equal(t, "parser", commit.Scope)
+	require.Equal(t, "add ability to parse arrays", commit.Description)
+	require.Equal(t, "Arrays are now supported.", commit.Body)
+	require.False(t, commit.Breaking)
+	require.Equal(t, "minor", commit.BumpType())
+}
+
+func TestParse_BreakingChangeMarker(t *testing.T) {
+	t.Parallel()
+
+	//test
+	commit, err := conventional.Parse("refactor!: drop support for Node 6")
+
+	//assert
+	require.NoError(t, err)
+	require.True(t, commit.Breaking)
+	require.Equal(t, "major", commit.BumpType())
+}
+
+func TestParse_BreakingChangeFooter(t *testing.T) {
+	t.Parallel()
+
+	//test
+	commit, err := conventional.Parse("fix: use the new config format\n\nBREAKING CHANGE: the `extends` key is no longer supported")
+
+	//assert
+	require.NoError(t, err)
+	require.True(t, commit.Breaking)
+	require.Equal(t, "major", commit.BumpType())
+}
+
+func TestParse_BumpTypes(t *testing.T) {
+	t.Parallel()
+
+	//test & assert
+	for message, bumpType := range map[string]string{
+		"fix: handle empty payloads":     "patch",
+		"perf: cache the nearest tag":    "patch",
+		"Feat: case insensitive types":   "minor",
+		"docs: correct spelling":         "",
+		"chore(deps): bump go-github":    "",
+		"feat(api)!: remove v1 endpoint": "major",
+	} {
+		commit, err := conventional.Parse(message)
+		require.NoError(t, err, message)
+		require.Equal(t, bumpType, commit.BumpType(), message)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	//test & assert
+	for _, message := range []string{
+		"Update README.md",
+		"feat:missing space",
+		"feat(: unbalanced scope",
+		"",
+	} {
+		_, err := conventional.Parse(message)
+		require.Error(t, err, message)
+	}
+}
@@ -1,13 +1,20 @@
 package engine
 
 import (
+	"github.com/analogj/capsulecd/pkg/changelog"
 	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/conventional"
 	"github.com/analogj/capsulecd/pkg/errors"
 	"github.com/analogj/capsulecd/pkg/pipeline"
 	"github.com/analogj/capsulecd/pkg/utils"
+	"github.com/analogj/capsulecd/pkg/versionfile"
+	"github.com/analogj/capsulecd/pkg/versioning"
 	stderrors "errors"
 	"fmt"
 	"github.com/Masterminds/semver"
+	"log"
+	"path"
+	"strings"
 )
 
 type engineBase struct {
@@ -78,25 +85,302 @@ func (g *engineBase) TestStep() error {
 
 //Helper functions
 
+// determine the version that will be released.
+// if the version bump was already committed (via a merged release pull request), the current version is released as-is.
+// an explicit version (`release-as:` pull request marker/label, or engine_version_override) is used instead of bumping.
+func (e *engineBase) NextVersion(currentVersion string) (string, error) {
+	if e.PipelineData.ReleaseVersionCommitted {
+		return currentVersion, nil
+	}
+
+	// the version in the metadata file is ignored when the version is read from the nearest tag.
+	if e.VersionFromTag() {
+		tagVersion, terr := e.currentTagVersion()
+		if terr != nil {
+			return "", terr
+		}
+		currentVersion = tagVersion
+	}
+
+	// the version specified by the pull request takes precedence over the config.
+	overrideVersion, overrideReason := e.PipelineData.ReleaseVersionOverride, e.PipelineData.ReleaseVersionOverrideReason
+	if overrideVersion == "" {
+		overrideVersion, overrideReason = e.Config.GetString("engine_version_override"), "`engine_version_override` setting"
+	}
+	if overrideVersion != "" {
+		return e.OverrideVersion(currentVersion, overrideVersion, overrideReason)
+	}
+	return e.BumpVersion(currentVersion)
+}
+
+// check if the current version is read from the nearest tag (engine_version_source), instead of the metadata file.
+// No version bump commit is created, the release commit is tagged as-is.
+func (e *engineBase) VersionFromTag() bool {
+	return e.Config.GetString("engine_version_source") == "tag"
+}
+
+// determine the current version from the nearest tag, parsed with the tag template (engine_tag_template).
+// engine_version_initial is used if there are no release tags yet.
+func (e *engineBase) currentTagVersion() (string, error) {
+	if e.PipelineData.GitNearestTag == nil {
+		log.Printf("No release tag found, using the initial version: %s", e.Config.GetString("engine_version_initial"))
+		return e.Config.GetString("engine_version_initial"), nil
+	}
+	return utils.ReleaseTagVersion(e.Config.GetString("engine_tag_template"), e.PipelineData, e.PipelineData.GitNearestTag.TagShortName)
+}
+
+// validate the explicit version, it must be greater than the current version and the nearest tag (if any).
+func (e *engineBase) OverrideVersion(currentVersion string, overrideVersion string, overrideReason string) (string, error) {
+	overrideVersion = strings.TrimPrefix(overrideVersion, "v")
+	scheme, serr := versioning.Create(e.Config.GetString("engine_version_scheme"), e.Config)
+	if serr != nil {
+		return "", serr
+	}
+
+	if e.PipelineData.ReleaseBranch != nil && !e.PipelineData.ReleaseBranch.AllowsBumpType("manual") {
+		return "", errors.EngineBumpTypeNotAllowed(fmt.Sprintf("Version bump type (manual) is not allowed for releases from %s, must be one of: %s", e.PipelineData.ReleaseBranch.Branch, strings.Join(e.PipelineData.ReleaseBranch.BumpTypes, ", ")))
+	}
+
+	cmp, cerr := scheme.Compare(overrideVersion, currentVersion)
+	if cerr != nil {
+		return "", errors.EngineVersionOverrideInvalid(fmt.Sprintf("Version %s (from %s) is invalid: %s", overrideVersion, overrideReason, cerr))
+	} else if cmp <= 0 {
+		return "", errors.EngineVersionOverrideInvalid(fmt.Sprintf("Version %s (from %s) must be greater than the current version %s", overrideVersion, overrideReason, currentVersion))
+	}
+
+	if e.PipelineData.GitNearestTag != nil {
+		tagName := e.PipelineData.GitNearestTag.TagShortName
+		tagVersion, terr := utils.ReleaseTagVersion(e.Config.GetString("engine_tag_template"), e.PipelineData, tagName)
+		if terr == nil {
+			cmp, cerr = scheme.Compare(overrideVersion, tagVersion)
+		}
+		if terr != nil || cerr != nil {
+			log.Printf("Could not determine the version of the nearest tag (%s), ignoring it", tagName)
+		} else if cmp <= 0 {
+			return "", errors.EngineVersionOverrideInvalid(fmt.Sprintf("Version %s (from %s) must be greater than the nearest tag %s", overrideVersion, overrideReason, tagName))
+		}
+	}
+
+	log.Printf("Overriding version %s -> %s (from %s)", currentVersion, overrideVersion, overrideReason)
+	e.PipelineData.ReleaseBumpType = "manual"
+	e.PipelineData.ReleaseBumpReason = overrideReason
+	return overrideVersion, nil
+}
+
+// update the version in additional files (engine_version_files), eg. READMEs, Dockerfile labels or Helm charts.
+// called by each engine AssembleStep after the metadata file is written, the updated files are included in the release commit.
+// not supported when the version is read from the nearest tag (engine_version_source), as there is no release commit.
+func (e *engineBase) UpdateVersionFiles(version string) error {
+	if !e.Config.IsSet("engine_version_files") {
+		return nil
+	}
+	rules := []versionfile.Rule{}
+	if err := e.Config.UnmarshalKey("engine_version_files", &rules); err != nil {
+		return err
+	} else if len(rules) == 0 {
+		return nil
+	}
+	if e.VersionFromTag() {
+		return errors.EngineBuildPackageInvalid("engine_version_files is not supported when the version is read from the nearest tag (engine_version_source), the updated files would not be committed")
+	}
+
+	tagName, nerr := utils.ReleaseTagName(e.Config.GetString("engine_tag_template"), e.PipelineData, version)
+	if nerr != nil {
+		return nerr
+	}
+	updatedPaths, uerr := versionfile.Update(e.PipelineData.GitLocalPath, rules, versionfile.NewTemplateData(utils.ReleaseRepoName(e.PipelineData), version, tagName))
+	if uerr != nil {
+		return uerr
+	}
+	log.Printf("Updated version in: %s", strings.Join(updatedPaths, ", "))
+	return nil
+}
+
+// commit the version bump (and any other local changes), and tag the release commit.
+// if the version bump was already committed (via a merged release pull request), only the tag is created. If the tag
+// already exists, there is nothing to release.
+// if the version is read from the nearest tag (engine_version_source), only the tag is created, local changes (eg. the
+// updated metadata file) are used for packaging but not committed.
+func (e *engineBase) CommitAndTagRelease(version string) error {
+	signature := utils.GitSignature(e.Config.GetString("engine_git_author_name"), e.Config.GetString("engine_git_author_email"))
+	tagName, nerr := utils.ReleaseTagName(e.Config.GetString("engine_tag_template"), e.PipelineData, version)
+	if nerr != nil {
+		return nerr
+	}
+	signer, serr := e.gitSigner()
+	if serr != nil {
+		return serr
+	}
+
+	if e.VersionFromTag() {
+		// the tag is the source of truth, the release commit is tagged without a version bump commit.
+		if e.PipelineData.ReleasePullRequest {
+			return errors.EngineBuildPackageInvalid("Release pull requests require the version to be read from the metadata file (engine_version_source)")
+		}
+	} else if e.PipelineData.ReleaseVersionCommitted {
+		tagExists, terr := utils.GitTagExists(e.PipelineData.GitLocalPath, tagName)
+		if terr != nil {
+			return terr
+		} else if tagExists {
+			return errors.ReleaseSkipped(fmt.Sprintf("%s has already been released", tagName))
+		}
+	} else {
+		if cerr := e.updateChangelogFile(version); cerr != nil {
+			return cerr
+		}
+		if cerr := utils.GitSignedCommit(e.PipelineData.GitLocalPath, utils.VersionBumpCommitMessage(tagName, e.Config.GetString("engine_version_bump_msg")), signature, signer); cerr != nil {
+			return cerr
+		}
+	}
+
+	tagCommit, terr := utils.GitSignedTag(e.PipelineData.GitLocalPath, tagName, e.Config.GetString("engine_version_bump_msg"), signature, signer)
+	if terr != nil {
+		return terr
+	}
+
+	e.PipelineData.ReleaseCommit = tagCommit
+	e.PipelineData.ReleaseVersion = version
+	e.PipelineData.ReleaseTag = tagName
+	if v, verr := semver.NewVersion(version); verr == nil {
+		e.PipelineData.ReleasePrerelease = v.Prerelease() != ""
+	}
+	return nil
+}
+
+// check that the version scheme (engine_version_scheme) only generates semver versions, for engines whose package
+// managers reject other versions (eg. zero padded calver versions are rejected by npm).
+func (e *engineBase) validateSemverVersionScheme(packageManager string) error {
+	if err := versioning.ValidateSemverCompatible(e.Config.GetString("engine_version_scheme"), e.Config); err != nil {
+		return errors.EngineValidateToolError(fmt.Sprintf("%s requires semver versions, engine_version_scheme is not supported: %s", packageManager, err))
+	}
+	return nil
+}
+
 func (e *engineBase) BumpVersion(currentVersion string) (string, error) {
-	v, nerr := semver.NewVersion(currentVersion)
+	scheme, serr := versioning.Create(e.Config.GetString("engine_version_scheme"), e.Config)
+	if serr != nil {
+		return "", serr
+	}
+
+	// the bump type determined from the pull request (labels, title, body) takes precedence over the config.
+	bumpType, bumpReason := "", ""
+	if e.PipelineData != nil && e.PipelineData.ReleaseBumpType != "" {
+		bumpType, bumpReason = e.PipelineData.ReleaseBumpType, e.PipelineData.ReleaseBumpReason
+	} else {
+		bumpType, bumpReason = e.Config.GetString("engine_version_bump_type"), "`engine_version_bump_type` setting"
+	}
+
+	if bumpType == "conventional" {
+		conventionalBumpType, conventionalReason, cerr := e.conventionalBumpType()
+		if cerr != nil {
+			return "", cerr
+		}
+		bumpType, bumpReason = conventionalBumpType, conventionalReason
+	}
+
+	if e.PipelineData != nil && e.PipelineData.ReleaseBranch != nil && !e.PipelineData.ReleaseBranch.AllowsBumpType(bumpType) {
+		return "", errors.EngineBumpTypeNotAllowed(fmt.Sprintf("Version bump type (%s) is not allowed for releases from %s, must be one of: %s", bumpType, e.PipelineData.ReleaseBranch.Branch, strings.Join(e.PipelineData.ReleaseBranch.BumpTypes, ", ")))
+	}
+
+	nextVersion, nerr := scheme.NextVersion(currentVersion, bumpType)
 	if nerr != nil {
 		return "", nerr
 	}
 
-	switch bumpType := e.Config.GetString("engine_version_bump_type"); bumpType {
-	case "major":
-		return fmt.Sprintf("%d.%d.%d", v.Major()+1, 0, 0), nil
-	case "minor":
-		return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor()+1, 0), nil
-	case "patch":
-		return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch()+1), nil
-	default:
-		return "", stderrors.New("Unknown version bump interval")
+	log.Printf("Bumping version %s -> %s (%s bump, from %s)", currentVersion, nextVersion, bumpType, bumpReason)
+	if e.PipelineData != nil {
+		e.PipelineData.ReleaseBumpType = bumpType
+		e.PipelineData.ReleaseBumpReason = bumpReason
 	}
+	return nextVersion, nil
+}
 
+// the signer used to sign the release commit & tag, or nil if engine_git_signing_key is not set (unsigned).
+func (e *engineBase) gitSigner() (utils.GitSigner, error) {
+	if !e.Config.IsSet("engine_git_signing_key") {
+		return nil, nil
+	}
+	signingKey, berr := e.Config.GetBase64Decoded("engine_git_signing_key")
+	if berr != nil {
+		return nil, berr
+	} else if len(signingKey) == 0 {
+		return nil, nil
+	}
+	return utils.NewGitSigner(e.Config.GetString("engine_git_signing_format"), []byte(signingKey), e.Config.GetString("engine_git_signing_key_passphrase"))
 }
 
+// prepend the release section to the changelog file (engine_changelog_path), so that it's included in the version bump
+// commit. The file always uses the Keep a Changelog format, grouped by commit type.
+func (e *engineBase) updateChangelogFile(version string) error {
+	changelogPath := e.Config.GetString("engine_changelog_path")
+	if changelogPath == "" {
+		return nil
+	}
+
+	options, err := changelog.OptionsFromConfig(e.Config)
+	if err != nil {
+		return err
+	}
+	options.Format = changelog.FormatKeepAChangelog
+	options.GroupBy = changelog.GroupByType
+	options.Links = changelog.RepositoryLinks(e.Config.GetString("scm"), e.PipelineData)
+
+	releaseChangelog, err := changelog.ReleaseChangelog(e.Config, e.PipelineData, version, options)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Updating changelog file: %s", changelogPath)
+	return changelog.UpdateFile(path.Join(e.PipelineData.GitLocalPath, changelogPath), releaseChangelog, e.Config.GetBool("engine_changelog_move_unreleased"))
+}
+
+// determine the version bump type from the Conventional Commits since the nearest tag (the same range used by the
+// changelog). Merge commits and CapsuleCD version bump commits are ignored. Commits that are not Conventional Commits
+// are logged, or fail the release if engine_conventional_commits_strict is true.
+// If no commit requires a release (eg. only `docs` or `chore` commits), the release is skipped.
+func (e *engineBase) conventionalBumpType() (string, string, error) {
+	if e.PipelineData == nil || e.PipelineData.GitLocalPath == "" {
+		return "", "", stderrors.New("Conventional commit version bumps require a local repository")
+	}
+
+	baseRef := ""
+	if e.PipelineData.GitNearestTag != nil {
+		baseRef = e.PipelineData.GitNearestTag.TagShortName
+	}
+	commits, err := utils.GitCommitsBetween(e.PipelineData.GitLocalPath, baseRef, "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+
+	bumpType, bumpSha := "", ""
+	invalidShas := []string{}
+	for _, commit := range commits {
+		if commit.IsMerge() || utils.IsVersionBumpCommit(commit.Message, e.Config.GetString("engine_version_bump_msg")) {
+			continue
+		}
+		parsed, perr := conventional.Parse(commit.Message)
+		if perr != nil {
+			log.Printf("Commit %.8s is not a Conventional Commit: %s", commit.Sha, perr)
+			invalidShas = append(invalidShas, fmt.Sprintf("%.8s", commit.Sha))
+			continue
+		}
+		if bumpTypeRank[parsed.BumpType()] > bumpTypeRank[bumpType] {
+			bumpType, bumpSha = parsed.BumpType(), commit.Sha
+		}
+	}
+
+	if len(invalidShas) > 0 && e.Config.GetBool("engine_conventional_commits_strict") {
+		return "", "", errors.EngineConventionalCommitError(fmt.Sprintf("The following commits are not Conventional Commits: %s", strings.Join(invalidShas, ", ")))
+	}
+	if bumpType == "" {
+		return "", "", errors.ReleaseSkipped("no commits since the nearest tag require a release (feat, fix, perf or breaking changes)")
+	}
+	return bumpType, fmt.Sprintf("conventional commit %.8s", bumpSha), nil
+}
+
+// used to find the highest bump type required by a list of commits.
+var bumpTypeRank = map[string]int{"": 0, "patch": 1, "minor": 2, "major": 3}
+
 func (e *engineBase) ExecuteCmdList(configKey string, workingDir string, environ []string, logPrefix string, errorTemplate string) error {
 	cmd := e.Config.GetString(configKey)
 
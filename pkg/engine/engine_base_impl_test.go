@@ -1,10 +1,20 @@
 package engine
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/errors"
 	"github.com/golang/mock/gomock"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/versionfile"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
 )
 
 func TestEngineBase_BumpVersion_Patch(t *testing.T) {
@@ -13,6 +23,7 @@ func TestEngineBase_BumpVersion_Patch(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	fakeConfig := mock_config.NewMockInterface(mockCtrl)
 	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("patch")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
 	eng := engineBase{
 		Config: fakeConfig,
 	}
@@ -35,6 +46,7 @@ func TestEngineBase_BumpVersion_Minor(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	fakeConfig := mock_config.NewMockInterface(mockCtrl)
 	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("minor")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
 	eng := engineBase{
 		Config: fakeConfig,
 	}
@@ -54,6 +66,7 @@ func TestEngineBase_BumpVersion_Major(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	fakeConfig := mock_config.NewMockInterface(mockCtrl)
 	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("major")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
 	eng := engineBase{
 		Config: fakeConfig,
 	}
@@ -72,6 +85,7 @@ func TestEngineBase_BumpVersion_InvalidCurrentVersion(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	fakeConfig := mock_config.NewMockInterface(mockCtrl)
 	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("patch")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
 	eng := engineBase{
 		Config: fakeConfig,
 	}
@@ -90,6 +104,7 @@ func TestEngineBase_BumpVersion_WithVPrefix(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	fakeConfig := mock_config.NewMockInterface(mockCtrl)
 	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("patch")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
 	eng := engineBase{
 		Config: fakeConfig,
 	}
@@ -101,3 +116,690 @@ func TestEngineBase_BumpVersion_WithVPrefix(t *testing.T) {
 	//assert
 	require.Equal(t, nextV, "1.2.4", "should correctly do a patch bump")
 }
+
+func TestEngineBase_BumpVersion_Prerelease(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("prerelease")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_prerelease_id").MinTimes(1).Return("rc")
+	eng := engineBase{
+		Config: fakeConfig,
+	}
+
+	//test
+	ver, err := eng.BumpVersion("1.2.3")
+	require.Nil(t, err)
+	ver2, err := eng.BumpVersion("1.2.0-rc.1")
+	require.Nil(t, err)
+	ver3, err := eng.BumpVersion("1.2.0-beta.3+build.5")
+	require.Nil(t, err)
+	ver4, err := eng.BumpVersion("1.2.0-rc")
+	require.Nil(t, err)
+
+	//assert
+	require.Equal(t, "1.2.4-rc.1", ver, "should start a pre-release of the next patch version")
+	require.Equal(t, "1.2.0-rc.2", ver2, "should increment the pre-release number")
+	require.Equal(t, "1.2.0-rc.1", ver3, "should switch to a higher precedence identifier, and drop build metadata")
+	require.Equal(t, "1.2.0-rc.1", ver4, "should add a pre-release number")
+}
+
+func TestEngineBase_BumpVersion_Prerelease_LowerPrecedence(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("prerelease")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_prerelease_id").MinTimes(1).Return("alpha")
+	eng := engineBase{
+		Config: fakeConfig,
+	}
+
+	//test
+	nextV, err := eng.BumpVersion("1.2.0-rc.1")
+
+	//assert
+	require.Error(t, err, "should not allow a lower precedence pre-release")
+	require.Empty(t, nextV)
+}
+
+func TestEngineBase_BumpVersion_Prerelease_InvalidId(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("prerelease")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_prerelease_id").MinTimes(1).Return("rc.1")
+	eng := engineBase{
+		Config: fakeConfig,
+	}
+
+	//test
+	nextV, err := eng.BumpVersion("1.2.3")
+
+	//assert
+	require.Error(t, err, "should validate the pre-release identifier")
+	require.Empty(t, nextV)
+}
+
+func TestEngineBase_BumpVersion_PromotePrerelease(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{},
+	}
+	promote := func(bumpType string, currentVersion string) (string, error) {
+		eng.PipelineData.ReleaseBumpType = bumpType
+		return eng.BumpVersion(currentVersion)
+	}
+
+	//test & assert
+	ver, err := promote("release", "1.2.0-rc.2")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.0", ver, "should promote the pre-release")
+
+	ver, err = promote("patch", "1.2.1-rc.1")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.1", ver, "patch bump should promote a patch pre-release")
+
+	ver, err = promote("minor", "1.3.0-rc.1")
+	require.NoError(t, err)
+	require.Equal(t, "1.3.0", ver, "minor bump should promote a minor pre-release")
+
+	ver, err = promote("minor", "1.3.1-rc.1")
+	require.NoError(t, err)
+	require.Equal(t, "1.4.0", ver, "minor bump should not promote a patch pre-release")
+
+	ver, err = promote("major", "2.0.0-rc.1")
+	require.NoError(t, err)
+	require.Equal(t, "2.0.0", ver, "major bump should promote a major pre-release")
+
+	_, err = promote("release", "1.2.0")
+	require.Error(t, err, "should not promote a release version")
+}
+
+func TestEngineBase_BumpVersion_Calver(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("patch")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("calver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("YYYY.MM.MICRO").MinTimes(1)
+	eng := engineBase{
+		Config: fakeConfig,
+	}
+	now := time.Now().UTC()
+
+	//test
+	nextV, err := eng.BumpVersion("2000.1.5")
+	require.Nil(t, err)
+
+	//assert
+	require.Equal(t, fmt.Sprintf("%d.%d.0", now.Year(), int(now.Month())), nextV, "should roll over to the current month")
+}
+
+func TestEngineBase_BumpVersion_InvalidVersionScheme(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("romver").MinTimes(1)
+	eng := engineBase{
+		Config: fakeConfig,
+	}
+
+	//test
+	nextV, err := eng.BumpVersion("1.2.3")
+
+	//assert
+	require.Error(t, err, "should return an error for unknown version schemes")
+	require.Empty(t, nextV)
+}
+
+func TestEngineBase_NextVersion(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("patch")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_override").Return("")
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{},
+	}
+
+	//test
+	nextV, err := eng.NextVersion("1.2.3")
+	require.Nil(t, err)
+
+	//assert
+	require.Equal(t, "1.2.4", nextV, "should bump the version")
+}
+
+func TestEngineBase_NextVersion_ReleaseVersionCommitted(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{ReleaseVersionCommitted: true},
+	}
+
+	//test
+	nextV, err := eng.NextVersion("1.2.3")
+	require.Nil(t, err)
+
+	//assert
+	require.Equal(t, "1.2.3", nextV, "should not bump a version that was already committed")
+}
+
+func TestEngineBase_NextVersion_ConfigOverride(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_override").Return("v2.0.0")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	pipelineData := &pipeline.Data{}
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: pipelineData,
+	}
+
+	//test
+	nextV, err := eng.NextVersion("1.2.3")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "2.0.0", nextV, "should use the override instead of bumping the version")
+	require.Equal(t, "manual", pipelineData.ReleaseBumpType)
+	require.Equal(t, "`engine_version_override` setting", pipelineData.ReleaseBumpReason)
+}
+
+func TestEngineBase_NextVersion_PullRequestOverride(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	pipelineData := &pipeline.Data{
+		ReleaseVersionOverride:       "1.5.0",
+		ReleaseVersionOverrideReason: "label `release-as: 1.5.0`",
+		GitNearestTag:                &pipeline.GitTagDetails{TagShortName: "v1.4.0"},
+	}
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: pipelineData,
+	}
+
+	//test
+	nextV, err := eng.NextVersion("1.2.3")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "1.5.0", nextV, "pull request override should take precedence over the config")
+	require.Equal(t, "label `release-as: 1.5.0`", pipelineData.ReleaseBumpReason)
+}
+
+func TestEngineBase_NextVersion_OverrideNotGreater(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{ReleaseVersionOverride: "1.5.0", ReleaseVersionOverrideReason: "title marker `release-as: 1.5.0`"},
+	}
+
+	//test
+	_, cerr := eng.NextVersion("1.5.0")
+	eng.PipelineData.GitNearestTag = &pipeline.GitTagDetails{TagShortName: "v1.6.0"}
+	_, terr := eng.NextVersion("1.2.3")
+	eng.PipelineData.ReleaseVersionOverride = "latest"
+	_, verr := eng.NextVersion("1.2.3")
+
+	//assert
+	require.Error(t, cerr, "should be greater than the current version")
+	require.Error(t, terr, "should be greater than the nearest tag")
+	require.Error(t, verr, "should be a valid version")
+}
+
+func TestEngineBase_NextVersion_FromTag(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("tag").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_tag_template").Return("{{.Name}}@{{.Version}}").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_override").Return("")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").Return("minor")
+	eng := engineBase{
+		Config: fakeConfig,
+		PipelineData: &pipeline.Data{
+			GitHeadInfo:   &pipeline.ScmCommitInfo{Repo: &pipeline.ScmRepoInfo{Name: "capsulecd"}},
+			GitNearestTag: &pipeline.GitTagDetails{TagShortName: "capsulecd@1.4.2"},
+		},
+	}
+
+	//test
+	nextV, err := eng.NextVersion("0.0.0")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "1.5.0", nextV, "should bump the version of the nearest tag, ignoring the metadata file")
+}
+
+func TestEngineBase_NextVersion_FromTag_Initial(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("tag").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_initial").Return("0.0.0").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_override").Return("")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").Return("patch")
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{},
+	}
+
+	//test
+	nextV, err := eng.NextVersion("")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "0.0.1", nextV, "should bump the initial version if there are no release tags")
+}
+
+func TestEngineBase_NextVersion_FromTag_Mismatch(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("tag").MinTimes(1)
+	fakeConfig.EXPECT().GetString("engine_tag_template").Return("release-{{.Version}}").MinTimes(1)
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{GitNearestTag: &pipeline.GitTagDetails{TagShortName: "deploy-prod"}},
+	}
+
+	//test
+	nextV, err := eng.NextVersion("1.2.3")
+
+	//assert
+	require.Error(t, err, "should fail if the nearest tag was not created from the tag template")
+	require.Empty(t, nextV)
+}
+
+func TestEngineBase_BumpVersion_ReleaseBranchNotAllowed(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").MinTimes(1).Return("minor")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	eng := engineBase{
+		Config: fakeConfig,
+		PipelineData: &pipeline.Data{
+			ReleaseBranch: &pipeline.ReleaseBranch{Branch: "release/*", BumpTypes: []string{"patch"}},
+		},
+	}
+
+	//test
+	nextV, err := eng.BumpVersion("1.2.3")
+
+	//assert
+	require.Error(t, err, "should not allow minor bumps on release branches")
+	require.Empty(t, nextV)
+}
+
+func TestEngineBase_BumpVersion_PullRequestBumpType(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	pipelineData := &pipeline.Data{
+		ReleaseBumpType:   "minor",
+		ReleaseBumpReason: "label `release:minor`",
+	}
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: pipelineData,
+	}
+
+	//test
+	nextV, err := eng.BumpVersion("1.2.3")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "1.3.0", nextV, "should use the pull request bump type instead of engine_version_bump_type")
+	require.Equal(t, "label `release:minor`", pipelineData.ReleaseBumpReason)
+}
+
+func TestEngineBase_BumpVersion_RecordsConfigBumpType(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").Return("patch")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	pipelineData := new(pipeline.Data)
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: pipelineData,
+	}
+
+	//test
+	nextV, err := eng.BumpVersion("1.2.3")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "1.2.4", nextV)
+	require.Equal(t, "patch", pipelineData.ReleaseBumpType)
+	require.Equal(t, "`engine_version_bump_type` setting", pipelineData.ReleaseBumpReason)
+}
+
+func TestEngineBase_BumpVersion_ConventionalWithoutRepository(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").Return("conventional")
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: new(pipeline.Data),
+	}
+
+	//test
+	nextV, err := eng.BumpVersion("1.2.3")
+
+	//assert
+	require.Error(t, err, "should require a local repository to read commits from")
+	require.Empty(t, nextV)
+}
+
+// run a git command in the test repository, returning the trimmed output.
+func conventionalTestGit(t *testing.T, repoPath string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=CapsuleCD", "GIT_AUTHOR_EMAIL=CapsuleCD@users.noreply.github.com",
+		"GIT_COMMITTER_NAME=CapsuleCD", "GIT_COMMITTER_EMAIL=CapsuleCD@users.noreply.github.com",
+	)
+	output, cerr := cmd.CombinedOutput()
+	require.NoError(t, cerr, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+// create a local repository with a tagged (v1.0.0) initial commit, followed by a commit for each message.
+// returns an engine that bumps versions using the commits since the tag.
+func conventionalTestEngine(t *testing.T, mockCtrl *gomock.Controller, strict bool, messages ...string) (*engineBase, string) {
+	repoPath, err := ioutil.TempDir("", "engine")
+	require.NoError(t, err)
+	conventionalTestGit(t, repoPath, "init", "-q")
+	conventionalTestGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", "feat: initial release")
+	conventionalTestGit(t, repoPath, "tag", "v1.0.0")
+	for _, message := range messages {
+		conventionalTestGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", message)
+	}
+
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_bump_type").Return("conventional").AnyTimes()
+	fakeConfig.EXPECT().GetString("engine_version_scheme").Return("semver").AnyTimes()
+	fakeConfig.EXPECT().GetString("engine_version_bump_msg").Return("Automated packaging of release by CapsuleCD").AnyTimes()
+	fakeConfig.EXPECT().GetBool("engine_conventional_commits_strict").Return(strict).AnyTimes()
+	return &engineBase{
+		Config: fakeConfig,
+		PipelineData: &pipeline.Data{
+			GitLocalPath:  repoPath,
+			GitNearestTag: &pipeline.GitTagDetails{TagShortName: "v1.0.0"},
+		},
+	}, repoPath
+}
+
+func TestEngineBase_BumpVersion_Conventional_HighestBumpWins(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		messages []string
+		expected string
+	}{
+		{"fix", []string{"fix: handle empty files", "docs: update readme"}, "1.0.1"},
+		{"feat", []string{"fix: handle empty files", "feat: add a new flag", "fix: another fix"}, "1.1.0"},
+		{"breaking bang", []string{"fix: handle empty files", "feat!: remove the old flag", "feat: add a new flag"}, "2.0.0"},
+		{"breaking footer", []string{"feat: add a new flag", "fix: rename the config file\n\nBREAKING CHANGE: the config file was renamed", "fix: handle empty files"}, "2.0.0"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			//setup
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			eng, repoPath := conventionalTestEngine(t, mockCtrl, false, tc.messages...)
+			defer os.RemoveAll(repoPath)
+
+			//test
+			nextV, err := eng.BumpVersion("1.0.0")
+
+			//assert
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, nextV)
+		})
+	}
+}
+
+func TestEngineBase_BumpVersion_Conventional_SkipsMergeAndBumpCommits(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	eng, repoPath := conventionalTestEngine(t, mockCtrl, true, "fix: handle empty files")
+	defer os.RemoveAll(repoPath)
+	fixSha := conventionalTestGit(t, repoPath, "rev-parse", "HEAD")
+	conventionalTestGit(t, repoPath, "checkout", "-q", "-b", "feature")
+	conventionalTestGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", "docs: document the feature")
+	conventionalTestGit(t, repoPath, "checkout", "-q", "-")
+	conventionalTestGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", "chore: tidy up")
+	// neither the merge commit nor the version bump commit are conventional commits (or valid bumps), and would fail strict mode.
+	conventionalTestGit(t, repoPath, "merge", "-q", "--no-ff", "-m", "Merge branch 'feature' (feat!: not a breaking change)", "feature")
+	conventionalTestGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", "(v1.0.1) Automated packaging of release by CapsuleCD")
+
+	//test
+	nextV, err := eng.BumpVersion("1.0.0")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "1.0.1", nextV)
+	require.Equal(t, "patch", eng.PipelineData.ReleaseBumpType)
+	require.Equal(t, "conventional commit "+fixSha[:8], eng.PipelineData.ReleaseBumpReason)
+}
+
+func TestEngineBase_BumpVersion_Conventional_NoReleasableCommits(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	eng, repoPath := conventionalTestEngine(t, mockCtrl, false, "chore: tidy up", "docs: update readme")
+	defer os.RemoveAll(repoPath)
+
+	//test
+	nextV, err := eng.BumpVersion("1.0.0")
+
+	//assert
+	require.Error(t, err)
+	require.IsType(t, errors.ReleaseSkipped(""), err)
+	require.Empty(t, nextV)
+}
+
+func TestEngineBase_BumpVersion_Conventional_Strict(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	eng, repoPath := conventionalTestEngine(t, mockCtrl, true, "fix: handle empty files", "updated some things", "feat: add a new flag", "WIP")
+	defer os.RemoveAll(repoPath)
+	invalidFirst := conventionalTestGit(t, repoPath, "rev-parse", "HEAD~2")
+	invalidSecond := conventionalTestGit(t, repoPath, "rev-parse", "HEAD")
+
+	//test
+	nextV, err := eng.BumpVersion("1.0.0")
+
+	//assert
+	require.Error(t, err)
+	require.IsType(t, errors.EngineConventionalCommitError(""), err)
+	require.Contains(t, err.Error(), invalidFirst[:8])
+	require.Contains(t, err.Error(), invalidSecond[:8])
+	require.Empty(t, nextV)
+}
+
+func TestEngineBase_BumpVersion_Conventional_NonStrictIgnoresInvalidCommits(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	eng, repoPath := conventionalTestEngine(t, mockCtrl, false, "fix: handle empty files", "updated some things", "WIP")
+	defer os.RemoveAll(repoPath)
+
+	//test
+	nextV, err := eng.BumpVersion("1.0.0")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "1.0.1", nextV)
+}
+
+func TestEngineBase_UpdateChangelogFile_Disabled(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_changelog_path").Return("")
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{},
+	}
+
+	//test
+	err := eng.updateChangelogFile("1.2.3")
+
+	//assert
+	require.NoError(t, err, "should not generate a changelog when engine_changelog_path is empty")
+}
+
+func TestEngineBase_GitSigner_Unsigned(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().IsSet("engine_git_signing_key").Return(false)
+	eng := engineBase{
+		Config: fakeConfig,
+	}
+
+	//test
+	signer, err := eng.gitSigner()
+
+	//assert
+	require.NoError(t, err)
+	require.Nil(t, signer, "should not sign commits & tags when engine_git_signing_key is not set")
+}
+
+func TestEngineBase_GitSigner_InvalidKey(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().IsSet("engine_git_signing_key").Return(true)
+	fakeConfig.EXPECT().GetBase64Decoded("engine_git_signing_key").Return("not a key", nil)
+	fakeConfig.EXPECT().GetString("engine_git_signing_format").Return("gpg")
+	fakeConfig.EXPECT().GetString("engine_git_signing_key_passphrase").Return("")
+	eng := engineBase{
+		Config: fakeConfig,
+	}
+
+	//test
+	_, err := eng.gitSigner()
+
+	//assert
+	require.Error(t, err)
+}
+
+func TestEngineBase_UpdateVersionFiles(t *testing.T) {
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	require.NoError(t, ioutil.WriteFile(path.Join(parentPath, "Dockerfile"), []byte("FROM alpine\nLABEL version=\"1.2.3\"\n"), 0644))
+
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().IsSet("engine_version_files").Return(true)
+	fakeConfig.EXPECT().UnmarshalKey("engine_version_files", gomock.Any()).DoAndReturn(func(key string, rawVal interface{}) error {
+		*(rawVal.(*[]versionfile.Rule)) = []versionfile.Rule{{Path: "Dockerfile", Regex: `LABEL version="([^"]+)"`, Template: "{{.Tag}}"}}
+		return nil
+	})
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("metadata")
+	fakeConfig.EXPECT().GetString("engine_tag_template").Return("release-{{.Version}}")
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{GitLocalPath: parentPath},
+	}
+
+	//test
+	uerr := eng.UpdateVersionFiles("1.2.4")
+
+	//assert
+	require.NoError(t, uerr)
+	content, rerr := ioutil.ReadFile(path.Join(parentPath, "Dockerfile"))
+	require.NoError(t, rerr)
+	require.Equal(t, "FROM alpine\nLABEL version=\"release-1.2.4\"\n", string(content))
+}
+
+func TestEngineBase_UpdateVersionFiles_VersionFromTag(t *testing.T) {
+
+	//setup
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().IsSet("engine_version_files").Return(true)
+	fakeConfig.EXPECT().UnmarshalKey("engine_version_files", gomock.Any()).DoAndReturn(func(key string, rawVal interface{}) error {
+		*(rawVal.(*[]versionfile.Rule)) = []versionfile.Rule{{Path: "Dockerfile", Regex: `LABEL version="([^"]+)"`}}
+		return nil
+	})
+	fakeConfig.EXPECT().GetString("engine_version_source").Return("tag")
+	eng := engineBase{
+		Config:       fakeConfig,
+		PipelineData: &pipeline.Data{GitLocalPath: "/tmp/does-not-exist"},
+	}
+
+	//test
+	uerr := eng.UpdateVersionFiles("1.2.4")
+
+	//assert
+	require.Error(t, uerr, "should reject version files, as they would not be committed")
+	require.IsType(t, errors.EngineBuildPackageInvalid(""), uerr)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/analogj/capsulecd/pkg/utils"
 	"encoding/json"
 	"fmt"
+	"github.com/Masterminds/semver"
 	"io/ioutil"
 	"log"
 	"os"
@@ -49,6 +50,10 @@ func (g *engineChef) GetNextMetadata() interface{} {
 }
 
 func (g *engineChef) ValidateTools() error {
+	if verr := g.validateSemverVersionScheme("Chef"); verr != nil {
+		return verr
+	}
+
 	if _, berr := exec.LookPath("foodcritic"); berr != nil && !g.Config.GetBool("engine_disable_lint") {
 		return errors.EngineValidateToolError("foodcritic binary is missing")
 	}
@@ -59,21 +64,31 @@ func (g *engineChef) ValidateTools() error {
 func (g *engineChef) AssembleStep() error {
 	//validate that the chef metadata.rb file exists
 
-	if !utils.FileExists(path.Join(g.PipelineData.GitLocalPath, "metadata.rb")) {
+	// the metadata file is optional when the version is read from the nearest tag, it's updated (but not committed) if it exists.
+	metadataFileExists := utils.FileExists(path.Join(g.PipelineData.GitLocalPath, "metadata.rb"))
+	if !metadataFileExists && !g.VersionFromTag() {
 		return errors.EngineBuildPackageInvalid("metadata.rb file is required to process Chef cookbook")
 	}
 
 	// bump up the chef cookbook version
-	if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
-		return merr
+	if metadataFileExists {
+		if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
+			return merr
+		}
 	}
 
 	if perr := g.populateNextMetadata(); perr != nil {
 		return perr
 	}
 
-	if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
-		return nerr
+	if metadataFileExists {
+		if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
+			return nerr
+		}
+	}
+
+	if verr := g.UpdateVersionFiles(g.NextMetadata.Version); verr != nil {
+		return verr
 	}
 
 	// TODO: check if this cookbook name and version already exist.
@@ -107,26 +122,7 @@ func (g *engineChef) AssembleStep() error {
 // func (g *engineChef) TestStep() error {}
 
 func (g *engineChef) PackageStep() error {
-
-	signature := utils.GitSignature(g.Config.GetString("engine_git_author_name"), g.Config.GetString("engine_git_author_email"))
-
-	if cerr := utils.GitCommit(
-		g.PipelineData.GitLocalPath,
-		fmt.Sprintf("(v%s) %s", g.NextMetadata.Version,
-		g.Config.GetString("engine_version_bump_msg")),
-		signature); cerr != nil {
-		return cerr
-	}
-	tagCommit, terr := utils.GitTag(g.PipelineData.GitLocalPath,
-		fmt.Sprintf("v%s", g.NextMetadata.Version), g.Config.GetString("engine_version_bump_msg"),
-		signature)
-	if terr != nil {
-		return terr
-	}
-
-	g.PipelineData.ReleaseCommit = tagCommit
-	g.PipelineData.ReleaseVersion = g.NextMetadata.Version
-	return nil
+	return g.CommitAndTagRelease(g.NextMetadata.Version)
 }
 
 //private Helpers
@@ -154,7 +150,7 @@ func (g *engineChef) retrieveCurrentMetadata(gitLocalPath string) error {
 
 func (g *engineChef) populateNextMetadata() error {
 
-	nextVersion, err := g.BumpVersion(g.CurrentMetadata.Version)
+	nextVersion, err := g.NextVersion(g.CurrentMetadata.Version)
 	if err != nil {
 		return err
 	}
@@ -166,5 +162,11 @@ func (g *engineChef) populateNextMetadata() error {
 }
 
 func (g *engineChef) writeNextMetadata(gitLocalPath string) error {
+	// chef cookbook versions are limited to `major.minor.patch`
+	if v, verr := semver.NewVersion(g.NextMetadata.Version); verr != nil {
+		return verr
+	} else if v.Prerelease() != "" {
+		return errors.EngineBuildPackageInvalid(fmt.Sprintf("Chef cookbooks do not support pre-release versions (%s)", g.NextMetadata.Version))
+	}
 	return utils.BashCmdExec(fmt.Sprintf("knife spork bump %s manual %s -o ../", path.Base(gitLocalPath), g.NextMetadata.Version), gitLocalPath, nil, "")
 }
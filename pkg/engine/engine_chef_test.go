@@ -77,6 +77,7 @@ func TestEngineChef_TestSuite(t *testing.T) {
 func (suite *EngineChefTestSuite) TestEngineChef_ValidateTools() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver")
 	chefEngine, err := engine.Create("chef", suite.PipelineData, suite.Config, suite.Scm)
 	require.NoError(suite.T(), err)
 
@@ -87,10 +88,29 @@ func (suite *EngineChefTestSuite) TestEngineChef_ValidateTools() {
 	require.NoError(suite.T(), berr)
 }
 
+func (suite *EngineChefTestSuite) TestEngineChef_ValidateTools_CalverNotSemver() {
+	//setup
+	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("calver")
+	suite.Config.EXPECT().GetString("engine_calver_format").Return("YYYY.0M.MICRO")
+	chefEngine, err := engine.Create("chef", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+
+	//test
+	berr := chefEngine.ValidateTools()
+
+	//assert
+	require.EqualError(suite.T(), berr, "EngineValidateToolError: \"Chef requires semver versions, engine_version_scheme is not supported: Calver format (YYYY.0M.MICRO) must not have zero padded segments (0M) to generate semver versions\"", "zero padded versions are not valid semver")
+}
+
 func (suite *EngineChefTestSuite) TestEngineChef_AssembleStep() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
 	parentPath, err := ioutil.TempDir("", "")
@@ -118,6 +138,10 @@ func (suite *EngineChefTestSuite) TestEngineChef_AssembleStep_WithMinimalCookboo
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
 	parentPath, err := ioutil.TempDir("", "")
@@ -140,9 +164,43 @@ func (suite *EngineChefTestSuite) TestEngineChef_AssembleStep_WithMinimalCookboo
 	require.True(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, ".gitignore")))
 }
 
+func (suite *EngineChefTestSuite) TestEngineChef_AssembleStep_VersionFromTagWithoutMetadata() {
+	//setup
+	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("tag").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
+
+	//copy cookbook fixture into a temp directory.
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(parentPath)
+	suite.PipelineData.GitParentPath = parentPath
+	suite.PipelineData.GitLocalPath = path.Join(parentPath, "cookbook_analogj_test")
+	suite.PipelineData.GitNearestTag = &pipeline.GitTagDetails{TagShortName: "v0.1.3"}
+	cerr := utils.CopyDir(path.Join("testdata", "chef", "cookbook_analogj_test"), suite.PipelineData.GitLocalPath)
+	require.NoError(suite.T(), cerr)
+	os.Remove(path.Join(suite.PipelineData.GitLocalPath, "metadata.rb"))
+
+	chefEngine, err := engine.Create("chef", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+
+	//test
+	berr := chefEngine.AssembleStep()
+
+	//assert
+	require.NoError(suite.T(), berr, "metadata.rb is optional when the version is read from the nearest tag")
+	require.Equal(suite.T(), "0.1.4", suite.PipelineData.ReleaseVersion)
+	require.False(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, "metadata.rb")))
+}
+
 func (suite *EngineChefTestSuite) TestEngineChef_AssembleStep_WithoutMetadata() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
 	parentPath, err := ioutil.TempDir("", "")
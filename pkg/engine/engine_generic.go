@@ -11,6 +11,7 @@ import (
 	"github.com/Masterminds/semver"
 	"io/ioutil"
 	"path"
+	"regexp"
 	"strings"
 )
 
@@ -54,41 +55,38 @@ func (g *engineGeneric) ValidateTools() error {
 func (g *engineGeneric) AssembleStep() error {
 	//validate that the chef metadata.rb file exists
 
-	if !utils.FileExists(path.Join(g.PipelineData.GitLocalPath, g.Config.GetString("engine_version_metadata_path"))) {
+	// the version file is optional when the version is read from the nearest tag, it's updated (but not committed) if it exists.
+	versionFileExists := utils.FileExists(path.Join(g.PipelineData.GitLocalPath, g.Config.GetString("engine_version_metadata_path")))
+	if !versionFileExists && !g.VersionFromTag() {
 		return errors.EngineBuildPackageInvalid(fmt.Sprintf("version file (%s) is required for metadata storage via generic engine", g.Config.GetString("engine_version_metadata_path")))
 	}
 
 	// bump up the go package version
-	if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
-		return merr
+	if versionFileExists {
+		if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
+			return merr
+		}
 	}
 
 	if perr := g.populateNextMetadata(); perr != nil {
 		return perr
 	}
 
-	if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
-		return nerr
+	if versionFileExists {
+		if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
+			return nerr
+		}
+	}
+
+	if verr := g.UpdateVersionFiles(g.NextMetadata.Version); verr != nil {
+		return verr
 	}
 
 	return nil
 }
 
 func (g *engineGeneric) PackageStep() error {
-
-	signature := utils.GitSignature(g.Config.GetString("engine_git_author_name"), g.Config.GetString("engine_git_author_email"))
-
-	if cerr := utils.GitCommit(g.PipelineData.GitLocalPath, fmt.Sprintf("(v%s) %s", g.NextMetadata.Version, g.Config.GetString("engine_version_bump_msg")), signature); cerr != nil {
-		return cerr
-	}
-	tagCommit, terr := utils.GitTag(g.PipelineData.GitLocalPath, fmt.Sprintf("v%s", g.NextMetadata.Version), g.Config.GetString("engine_version_bump_msg"), signature)
-	if terr != nil {
-		return terr
-	}
-
-	g.PipelineData.ReleaseCommit = tagCommit
-	g.PipelineData.ReleaseVersion = g.NextMetadata.Version
-	return nil
+	return g.CommitAndTagRelease(g.NextMetadata.Version)
 }
 
 //Helpers
@@ -99,19 +97,34 @@ func (g *engineGeneric) retrieveCurrentMetadata(gitLocalPath string) error {
 		return rerr
 	}
 
+	template := g.Config.GetString("engine_generic_version_template")
+	if g.Config.GetString("engine_version_scheme") == "calver" {
+		version, verr := readGenericCalverVersion(template, string(versionContent))
+		if verr != nil {
+			return verr
+		}
+		g.CurrentMetadata.Version = version
+		return nil
+	}
+
 	major := 0
 	minor := 0
 	patch := 0
-	template := g.Config.GetString("engine_generic_version_template")
 	fmt.Sscanf(strings.TrimSpace(string(versionContent)), template, &major, &minor, &patch)
 
 	g.CurrentMetadata.Version = fmt.Sprintf("%d.%d.%d", major, minor, patch)
+
+	// the template only includes the major, minor & patch, the pre-release (if any) directly follows the patch.
+	prereleaseRe := regexp.MustCompile(regexp.QuoteMeta(g.CurrentMetadata.Version) + `-([0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)`)
+	if matches := prereleaseRe.FindStringSubmatch(string(versionContent)); matches != nil {
+		g.CurrentMetadata.Version = fmt.Sprintf("%s-%s", g.CurrentMetadata.Version, matches[1])
+	}
 	return nil
 }
 
 func (g *engineGeneric) populateNextMetadata() error {
 
-	nextVersion, err := g.BumpVersion(g.CurrentMetadata.Version)
+	nextVersion, err := g.NextVersion(g.CurrentMetadata.Version)
 	if err != nil {
 		return err
 	}
@@ -122,14 +135,61 @@ func (g *engineGeneric) populateNextMetadata() error {
 }
 
 func (g *engineGeneric) writeNextMetadata(gitLocalPath string) error {
+	template := g.Config.GetString("engine_generic_version_template")
+	if g.Config.GetString("engine_version_scheme") == "calver" {
+		versionContent, verr := writeGenericCalverVersion(template, g.NextMetadata.Version)
+		if verr != nil {
+			return verr
+		}
+		return ioutil.WriteFile(path.Join(gitLocalPath, g.Config.GetString("engine_version_metadata_path")), []byte(versionContent), 0644)
+	}
 
 	v, nerr := semver.NewVersion(g.NextMetadata.Version)
 	if nerr != nil {
 		return nerr
 	}
 
-	template := g.Config.GetString("engine_generic_version_template")
 	versionContent := fmt.Sprintf(template, v.Major(), v.Minor(), v.Patch())
 
+	// append the pre-release to the patch, eg. `version := "1.2.0-rc.1"`
+	if v.Prerelease() != "" {
+		versionCore := fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+		if !strings.Contains(versionContent, versionCore) {
+			return errors.EngineBuildPackageInvalid(fmt.Sprintf("Pre-release versions require the version template (%s) to contain `%%d.%%d.%%d`", template))
+		}
+		versionContent = strings.Replace(versionContent, versionCore, fmt.Sprintf("%s-%s", versionCore, v.Prerelease()), 1)
+	}
+
 	return ioutil.WriteFile(path.Join(gitLocalPath, g.Config.GetString("engine_version_metadata_path")), []byte(versionContent), 0644)
 }
+
+// calver versions are read & written verbatim in place of the `%d.%d.%d` in the version template, as they may have
+// zero padded segments (eg. `2024.03.1`) or more than 3 segments, which would be lost when parsed with the template.
+func genericCalverTemplate(template string) (string, string, error) {
+	parts := strings.SplitN(template, "%d.%d.%d", 2)
+	if len(parts) != 2 {
+		return "", "", errors.EngineBuildPackageInvalid(fmt.Sprintf("Calver versions require the version template (%s) to contain `%%d.%%d.%%d`", template))
+	}
+	return parts[0], parts[1], nil
+}
+
+func readGenericCalverVersion(template string, versionContent string) (string, error) {
+	prefix, suffix, terr := genericCalverTemplate(template)
+	if terr != nil {
+		return "", terr
+	}
+	versionRe := regexp.MustCompile(regexp.QuoteMeta(prefix) + `([0-9]+(\.[0-9]+)*)` + regexp.QuoteMeta(suffix))
+	matches := versionRe.FindStringSubmatch(versionContent)
+	if matches == nil {
+		return "", errors.EngineBuildPackageInvalid(fmt.Sprintf("Could not find a calver version matching the version template (%s)", template))
+	}
+	return matches[1], nil
+}
+
+func writeGenericCalverVersion(template string, version string) (string, error) {
+	prefix, suffix, terr := genericCalverTemplate(template)
+	if terr != nil {
+		return "", terr
+	}
+	return prefix + version + suffix, nil
+}
@@ -0,0 +1,159 @@
+package engine_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/engine"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/scm/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// run a git command in the test repository, returning the trimmed output.
+func genericTestGit(t *testing.T, repoPath string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=CapsuleCD", "GIT_AUTHOR_EMAIL=CapsuleCD@users.noreply.github.com",
+		"GIT_COMMITTER_NAME=CapsuleCD", "GIT_COMMITTER_EMAIL=CapsuleCD@users.noreply.github.com",
+	)
+	output, cerr := cmd.CombinedOutput()
+	require.NoError(t, cerr, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+func TestEngineGeneric_AssembleStep_VersionFiles(t *testing.T) {
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	repoPath := path.Join(parentPath, "capsulecd")
+	require.NoError(t, os.MkdirAll(path.Join(repoPath, "chart"), 0755))
+	require.NoError(t, ioutil.WriteFile(path.Join(repoPath, "VERSION"), []byte(`version := "1.2.3"`), 0644))
+	require.NoError(t, ioutil.WriteFile(path.Join(repoPath, "README.md"), []byte("# Install\n\n    go get capsulecd@v1.2.3\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(path.Join(repoPath, "chart", "Chart.yaml"), []byte("name: capsulecd\n# the released version\nappVersion: 1.2.3\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(path.Join(repoPath, "Dockerfile"), []byte("FROM alpine\nLABEL version=\"1.2.3\"\n"), 0644))
+	genericTestGit(t, repoPath, "init", "-q")
+	genericTestGit(t, repoPath, "add", "-A")
+	genericTestGit(t, repoPath, "commit", "-q", "-m", "feat: initial release")
+	genericTestGit(t, repoPath, "tag", "v1.2.3")
+	baseSha := genericTestGit(t, repoPath, "rev-parse", "HEAD")
+
+	testConfig, err := config.Create()
+	require.NoError(t, err)
+	testConfig.Set("engine_version_files", []map[string]interface{}{
+		{"path": "README.md", "regex": `capsulecd@(v[0-9.]+)`, "template": "{{.Tag}}"},
+		{"path": "chart/Chart.yaml", "yamlpath": "$.appVersion"},
+	})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	pipelineData := &pipeline.Data{
+		GitParentPath:  parentPath,
+		GitLocalPath:   repoPath,
+		GitLocalBranch: "master",
+		GitHeadInfo:    &pipeline.ScmCommitInfo{Sha: baseSha, Ref: "master", Repo: &pipeline.ScmRepoInfo{Name: "capsulecd"}},
+		GitNearestTag:  &pipeline.GitTagDetails{TagShortName: "v1.2.3", CommitSha: baseSha},
+	}
+	genericEngine, err := engine.Create("generic", pipelineData, testConfig, mock_scm.NewMockInterface(mockCtrl))
+	require.NoError(t, err)
+
+	//test
+	aerr := genericEngine.AssembleStep()
+	require.NoError(t, aerr)
+	perr := genericEngine.PackageStep()
+	require.NoError(t, perr)
+
+	//assert
+	require.Equal(t, "v1.2.4", pipelineData.ReleaseTag)
+	require.Equal(t, genericTestGit(t, repoPath, "rev-parse", "v1.2.4^{commit}"), genericTestGit(t, repoPath, "rev-parse", "HEAD"), "should tag the release commit")
+	require.Equal(t, baseSha, genericTestGit(t, repoPath, "rev-parse", "HEAD~1"), "should create a single release commit")
+	require.Equal(t, "(v1.2.4) Automated packaging of release by CapsuleCD", genericTestGit(t, repoPath, "log", "-1", "--format=%s"))
+	require.Equal(t, "README.md\nVERSION\nchart/Chart.yaml", genericTestGit(t, repoPath, "diff", "--name-only", "HEAD~1", "HEAD"), "the release commit should contain the metadata file and the version files")
+	require.Empty(t, genericTestGit(t, repoPath, "status", "--porcelain"), "all updated files should be committed")
+
+	require.Equal(t, `version := "1.2.4"`, genericTestGit(t, repoPath, "show", "HEAD:VERSION"))
+	require.Equal(t, "# Install\n\n    go get capsulecd@v1.2.4", genericTestGit(t, repoPath, "show", "HEAD:README.md"))
+	require.Equal(t, "name: capsulecd\n# the released version\nappVersion: 1.2.4", genericTestGit(t, repoPath, "show", "HEAD:chart/Chart.yaml"), "should preserve the yaml comments")
+	require.Equal(t, "FROM alpine\nLABEL version=\"1.2.3\"", genericTestGit(t, repoPath, "show", "HEAD:Dockerfile"), "files without a rule should be unchanged")
+}
+
+// calver versions are written verbatim, so zero padded & 4 segment versions can be read again for the next release.
+func TestEngineGeneric_AssembleStep_CalverRoundTrip(t *testing.T) {
+	now := time.Now().UTC()
+	var calverTests = []struct {
+		format         string
+		currentVersion string
+		nextPeriod     string
+	}{
+		{"YYYY.MM.MICRO", "2020.1.3", now.Format("2006.1")},
+		{"YYYY.0M.MICRO", "2020.01.3", now.Format("2006.01")},
+		{"0Y.0M.0D.MICRO", "20.01.02.3", now.Format("06.01.02")},
+	}
+	for _, tt := range calverTests {
+		t.Run(tt.format, func(t *testing.T) {
+			//setup
+			parentPath, err := ioutil.TempDir("", "")
+			require.NoError(t, err)
+			defer os.RemoveAll(parentPath)
+			repoPath := path.Join(parentPath, "capsulecd")
+			require.NoError(t, os.MkdirAll(repoPath, 0755))
+			require.NoError(t, ioutil.WriteFile(path.Join(repoPath, "VERSION"), []byte(`version := "`+tt.currentVersion+`"`), 0644))
+
+			testConfig, err := config.Create()
+			require.NoError(t, err)
+			testConfig.Set("engine_version_scheme", "calver")
+			testConfig.Set("engine_calver_format", tt.format)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			assemble := func() string {
+				pipelineData := &pipeline.Data{GitParentPath: parentPath, GitLocalPath: repoPath}
+				genericEngine, err := engine.Create("generic", pipelineData, testConfig, mock_scm.NewMockInterface(mockCtrl))
+				require.NoError(t, err)
+				require.NoError(t, genericEngine.AssembleStep())
+				return pipelineData.ReleaseVersion
+			}
+
+			//test
+			releaseVersion := assemble()
+			releaseContent, rerr := ioutil.ReadFile(path.Join(repoPath, "VERSION"))
+			require.NoError(t, rerr)
+			nextReleaseVersion := assemble()
+
+			//assert
+			require.Equal(t, tt.nextPeriod+".0", releaseVersion)
+			require.Equal(t, `version := "`+tt.nextPeriod+`.0"`, string(releaseContent), "should write the version verbatim")
+			require.Equal(t, tt.nextPeriod+".1", nextReleaseVersion, "should read the written version for the next release")
+		})
+	}
+}
+
+func TestEngineGeneric_AssembleStep_CalverInvalidTemplate(t *testing.T) {
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	require.NoError(t, ioutil.WriteFile(path.Join(parentPath, "VERSION"), []byte(`version := "2020.1.3"`), 0644))
+	testConfig, err := config.Create()
+	require.NoError(t, err)
+	testConfig.Set("engine_version_scheme", "calver")
+	testConfig.Set("engine_generic_version_template", `version := "%d-%d-%d"`)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	genericEngine, err := engine.Create("generic", &pipeline.Data{GitLocalPath: parentPath}, testConfig, mock_scm.NewMockInterface(mockCtrl))
+	require.NoError(t, err)
+
+	//test
+	aerr := genericEngine.AssembleStep()
+
+	//assert
+	require.IsType(t, errors.EngineBuildPackageInvalid(""), aerr)
+	require.Contains(t, aerr.Error(), "Calver versions require the version template")
+}
@@ -102,21 +102,31 @@ func (g *engineGolang) ValidateTools() error {
 func (g *engineGolang) AssembleStep() error {
 	//validate that the chef metadata.rb file exists
 
-	if !utils.FileExists(path.Join(g.PipelineData.GitLocalPath, g.Config.GetString("engine_version_metadata_path"))) {
+	// the version file is optional when the version is read from the nearest tag, it's updated (but not committed) if it exists.
+	versionFileExists := utils.FileExists(path.Join(g.PipelineData.GitLocalPath, g.Config.GetString("engine_version_metadata_path")))
+	if !versionFileExists && !g.VersionFromTag() {
 		return errors.EngineBuildPackageInvalid(fmt.Sprintf("%s file is required to process Go library", g.Config.GetString("engine_version_metadata_path")))
 	}
 
 	// bump up the go package version
-	if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
-		return merr
+	if versionFileExists {
+		if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
+			return merr
+		}
 	}
 
 	if perr := g.populateNextMetadata(); perr != nil {
 		return perr
 	}
 
-	if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
-		return nerr
+	if versionFileExists {
+		if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
+			return nerr
+		}
+	}
+
+	if verr := g.UpdateVersionFiles(g.NextMetadata.Version); verr != nil {
+		return verr
 	}
 
 	gitignorePath := path.Join(g.PipelineData.GitLocalPath, ".gitignore")
@@ -207,19 +217,7 @@ func (g *engineGolang) TestStep() error {
 }
 
 func (g *engineGolang) PackageStep() error {
-	signature := utils.GitSignature(g.Config.GetString("engine_git_author_name"), g.Config.GetString("engine_git_author_email"))
-
-	if cerr := utils.GitCommit(g.PipelineData.GitLocalPath, fmt.Sprintf("(v%s) %s", g.NextMetadata.Version, g.Config.GetString("engine_version_bump_msg")), signature); cerr != nil {
-		return cerr
-	}
-	tagCommit, terr := utils.GitTag(g.PipelineData.GitLocalPath, fmt.Sprintf("v%s", g.NextMetadata.Version), g.Config.GetString("engine_version_bump_msg"), signature)
-	if terr != nil {
-		return terr
-	}
-
-	g.PipelineData.ReleaseCommit = tagCommit
-	g.PipelineData.ReleaseVersion = g.NextMetadata.Version
-	return nil
+	return g.CommitAndTagRelease(g.NextMetadata.Version)
 }
 
 //private Helpers
@@ -270,7 +268,7 @@ func (g *engineGolang) retrieveCurrentMetadata(gitLocalPath string) error {
 
 func (g *engineGolang) populateNextMetadata() error {
 
-	nextVersion, err := g.BumpVersion(g.CurrentMetadata.Version)
+	nextVersion, err := g.NextVersion(g.CurrentMetadata.Version)
 	if err != nil {
 		return err
 	}
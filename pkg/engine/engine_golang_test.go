@@ -95,6 +95,10 @@ func (suite *EngineGolangTestSuite) TestEngineGolang_AssembleStep() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 	suite.Config.EXPECT().GetString("scm").Return("github").MinTimes(1)
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/golang_analogj_test").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_golang_package_path").Return("github.com/analogj/golang_analogj_test").MinTimes(1)
@@ -124,6 +128,10 @@ func (suite *EngineGolangTestSuite) TestEngineGolang_AssembleStep_WithMinimalCoo
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 	suite.Config.EXPECT().GetString("scm").Return("github").MinTimes(1)
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/golang_analogj_test").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_golang_package_path").Return("github.com/analogj/golang_analogj_test").MinTimes(1)
@@ -299,6 +307,8 @@ func (suite *EngineGolangTestSuite) TestEngineGolang_PackageStep_WithoutLockFile
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/golang_analogj_test").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_golang_package_path").Return("github.com/analogj/golang_analogj_test").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_msg").Return("Automated packaging of release by CapsuleCD").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_git_author_name").Return("CapsuleCD").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_git_author_email").Return("CapsuleCD@users.noreply.github.com").MinTimes(1)
 
@@ -47,6 +47,9 @@ func (g *engineNode) GetNextMetadata() interface{} {
 }
 
 func (g *engineNode) ValidateTools() error {
+	if verr := g.validateSemverVersionScheme("npm"); verr != nil {
+		return verr
+	}
 
 	if _, kerr := exec.LookPath("node"); kerr != nil {
 		return errors.EngineValidateToolError("node binary is missing")
@@ -65,17 +68,31 @@ func (g *engineNode) ValidateTools() error {
 
 func (g *engineNode) AssembleStep() error {
 
+	// the package.json file is optional when the version is read from the nearest tag, it's updated (but not committed) if it exists.
+	packageFileExists := utils.FileExists(path.Join(g.PipelineData.GitLocalPath, "package.json"))
+	if !packageFileExists && !g.VersionFromTag() {
+		return errors.EngineBuildPackageInvalid("package.json file is required to process Node package")
+	}
+
 	// bump up the package version
-	if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
-		return merr
+	if packageFileExists {
+		if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
+			return merr
+		}
 	}
 
 	if perr := g.populateNextMetadata(); perr != nil {
 		return perr
 	}
 
-	if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
-		return nerr
+	if packageFileExists {
+		if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
+			return nerr
+		}
+	}
+
+	if verr := g.UpdateVersionFiles(g.NextMetadata.Version); verr != nil {
+		return verr
 	}
 
 	// check for/create any required missing folders/files
@@ -99,20 +116,7 @@ func (g *engineNode) AssembleStep() error {
 //func (g *engineNode) TestStep() error { }
 
 func (g *engineNode) PackageStep() error {
-	signature := utils.GitSignature(g.Config.GetString("engine_git_author_name"), g.Config.GetString("engine_git_author_email"))
-
-	if cerr := utils.GitCommit(g.PipelineData.GitLocalPath, fmt.Sprintf("(v%s) %s", g.NextMetadata.Version, g.Config.GetString("engine_version_bump_msg")), signature); cerr != nil {
-		return cerr
-	}
-
-	tagCommit, terr := utils.GitTag(g.PipelineData.GitLocalPath, fmt.Sprintf("v%s", g.NextMetadata.Version), g.Config.GetString("engine_version_bump_msg"), signature)
-	if terr != nil {
-		return terr
-	}
-
-	g.PipelineData.ReleaseCommit = tagCommit
-	g.PipelineData.ReleaseVersion = g.NextMetadata.Version
-	return nil
+	return g.CommitAndTagRelease(g.NextMetadata.Version)
 }
 
 //private Helpers
@@ -133,7 +137,7 @@ func (g *engineNode) retrieveCurrentMetadata(gitLocalPath string) error {
 
 func (g *engineNode) populateNextMetadata() error {
 
-	nextVersion, err := g.BumpVersion(g.CurrentMetadata.Version)
+	nextVersion, err := g.NextVersion(g.CurrentMetadata.Version)
 	if err != nil {
 		return err
 	}
@@ -145,6 +149,11 @@ func (g *engineNode) populateNextMetadata() error {
 }
 
 func (g *engineNode) writeNextMetadata(gitLocalPath string) error {
+	// npm version will fail if the version is unchanged (ie. the version bump was already committed)
+	if g.NextMetadata.Version == g.CurrentMetadata.Version {
+		return nil
+	}
+
 	// The version will be bumped up via the npm version command.
 	// --no-git-tag-version ensures that we dont create a git commit (which npm will do by default).
 	versionCmd := fmt.Sprintf("npm --no-git-tag-version version %s",
@@ -76,6 +76,7 @@ func TestEngineNode_TestSuite(t *testing.T) {
 func (suite *EngineNodeTestSuite) TestEngineNode_ValidateTools() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver")
 	//suite.Config.EXPECT().GetBool("engine_disable_security_check").Return(true).MinTimes(1)
 	nodeEngine, err := engine.Create("node", suite.PipelineData, suite.Config, suite.Scm)
 	require.NoError(suite.T(), err)
@@ -87,10 +88,29 @@ func (suite *EngineNodeTestSuite) TestEngineNode_ValidateTools() {
 	require.NoError(suite.T(), berr)
 }
 
+func (suite *EngineNodeTestSuite) TestEngineNode_ValidateTools_CalverNotSemver() {
+	//setup
+	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("calver")
+	suite.Config.EXPECT().GetString("engine_calver_format").Return("YYYY.0M.MICRO")
+	nodeEngine, err := engine.Create("node", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+
+	//test
+	berr := nodeEngine.ValidateTools()
+
+	//assert
+	require.EqualError(suite.T(), berr, "EngineValidateToolError: \"npm requires semver versions, engine_version_scheme is not supported: Calver format (YYYY.0M.MICRO) must not have zero padded segments (0M) to generate semver versions\"", "zero padded versions are not valid semver")
+}
+
 func (suite *EngineNodeTestSuite) TestEngineNode_AssembleStep() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
 	parentPath, err := ioutil.TempDir("", "")
@@ -114,6 +134,7 @@ func (suite *EngineNodeTestSuite) TestEngineNode_AssembleStep() {
 func (suite *EngineNodeTestSuite) TestEngineNode_AssembleStep_WithoutPackageJson() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
 	parentPath, err := ioutil.TempDir("", "")
@@ -135,6 +156,40 @@ func (suite *EngineNodeTestSuite) TestEngineNode_AssembleStep_WithoutPackageJson
 	require.Error(suite.T(), berr, "should return an error")
 }
 
+func (suite *EngineNodeTestSuite) TestEngineNode_AssembleStep_VersionFromTagWithoutPackageJson() {
+	//setup
+	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("tag").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
+
+	//copy cookbook fixture into a temp directory.
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(parentPath)
+	suite.PipelineData.GitParentPath = parentPath
+	suite.PipelineData.GitLocalPath = path.Join(parentPath, "npm_analogj_test")
+	suite.PipelineData.GitNearestTag = &pipeline.GitTagDetails{TagShortName: "v0.1.3"}
+	cerr := utils.CopyDir(path.Join("testdata", "node", "npm_analogj_test"), suite.PipelineData.GitLocalPath)
+	require.NoError(suite.T(), cerr)
+	os.Remove(path.Join(suite.PipelineData.GitLocalPath, "package.json"))
+	ioutil.WriteFile(path.Join(suite.PipelineData.GitLocalPath, ".gitignore"), []byte("node_modules\n"), 0644)
+
+	nodeEngine, err := engine.Create("node", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+
+	//test
+	berr := nodeEngine.AssembleStep()
+
+	//assert
+	require.NoError(suite.T(), berr, "package.json is optional when the version is read from the nearest tag")
+	require.Equal(suite.T(), "0.1.4", suite.PipelineData.ReleaseVersion)
+	require.False(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, "package.json")))
+}
+
 func (suite *EngineNodeTestSuite) TestEngineNode_TestStep_AllDisabled() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
@@ -245,6 +300,8 @@ func (suite *EngineNodeTestSuite) TestEngineNode_PackageStep_WithoutLockFiles()
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	//suite.Config.EXPECT().GetBool("mgr_keep_lock_file").Return(false).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_msg").Return("Automated packaging of release by CapsuleCD").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_git_author_name").Return("CapsuleCD").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_git_author_email").Return("CapsuleCD@users.noreply.github.com").MinTimes(1)
 
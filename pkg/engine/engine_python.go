@@ -7,7 +7,6 @@ import (
 	"github.com/analogj/capsulecd/pkg/pipeline"
 	"github.com/analogj/capsulecd/pkg/scm"
 	"github.com/analogj/capsulecd/pkg/utils"
-	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -75,11 +74,14 @@ func (g *enginePython) AssembleStep() error {
 	}
 
 	// check for/create required VERSION file
-	if !utils.FileExists(path.Join(g.PipelineData.GitLocalPath, g.Config.GetString("engine_version_metadata_path"))) {
+	// the VERSION file is optional when the version is read from the nearest tag, it's updated (but not committed) if it exists.
+	versionFileExists := utils.FileExists(path.Join(g.PipelineData.GitLocalPath, g.Config.GetString("engine_version_metadata_path")))
+	if !versionFileExists && !g.VersionFromTag() {
 		ioutil.WriteFile(path.Join(g.PipelineData.GitLocalPath, g.Config.GetString("engine_version_metadata_path")),
 			[]byte("0.0.0"),
 			0644,
 		)
+		versionFileExists = true
 	}
 
 	// bump up the version here.
@@ -91,16 +93,24 @@ func (g *enginePython) AssembleStep() error {
 	// additional packaging structures, like those listed below, may also be supported in the future.
 	// http://stackoverflow.com/a/7071358/1157633
 
-	if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
-		return merr
+	if versionFileExists {
+		if merr := g.retrieveCurrentMetadata(g.PipelineData.GitLocalPath); merr != nil {
+			return merr
+		}
 	}
 
 	if perr := g.populateNextMetadata(); perr != nil {
 		return perr
 	}
 
-	if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
-		return nerr
+	if versionFileExists {
+		if nerr := g.writeNextMetadata(g.PipelineData.GitLocalPath); nerr != nil {
+			return nerr
+		}
+	}
+
+	if verr := g.UpdateVersionFiles(g.NextMetadata.Version); verr != nil {
+		return verr
 	}
 
 	// make sure the package testing manager is available.
@@ -156,19 +166,7 @@ func (g *enginePython) AssembleStep() error {
 func (g *enginePython) PackageStep() error {
 	os.RemoveAll(path.Join(g.PipelineData.GitLocalPath, ".tox")) //remove .tox folder.
 
-	signature := utils.GitSignature(g.Config.GetString("engine_git_author_name"), g.Config.GetString("engine_git_author_email"))
-
-	if cerr := utils.GitCommit(g.PipelineData.GitLocalPath, fmt.Sprintf("(v%s) %s", g.NextMetadata.Version, g.Config.GetString("engine_version_bump_msg")), signature); cerr != nil {
-		return cerr
-	}
-	tagCommit, terr := utils.GitTag(g.PipelineData.GitLocalPath, fmt.Sprintf("v%s", g.NextMetadata.Version), g.Config.GetString("engine_version_bump_msg"), signature)
-	if terr != nil {
-		return terr
-	}
-
-	g.PipelineData.ReleaseCommit = tagCommit
-	g.PipelineData.ReleaseVersion = g.NextMetadata.Version
-	return nil
+	return g.CommitAndTagRelease(g.NextMetadata.Version)
 }
 
 //private Helpers
@@ -185,7 +183,7 @@ func (g *enginePython) retrieveCurrentMetadata(gitLocalPath string) error {
 
 func (g *enginePython) populateNextMetadata() error {
 
-	nextVersion, err := g.BumpVersion(g.CurrentMetadata.Version)
+	nextVersion, err := g.NextVersion(g.CurrentMetadata.Version)
 	if err != nil {
 		return err
 	}
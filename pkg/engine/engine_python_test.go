@@ -18,6 +18,7 @@ import (
 	"github.com/analogj/capsulecd/pkg/scm/mock"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestEnginePython_Create(t *testing.T) {
@@ -94,6 +95,10 @@ func (suite *EnginePythonTestSuite) TestEnginePython_AssembleStep() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_metadata_path").Return("VERSION").MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
@@ -121,6 +126,10 @@ func (suite *EnginePythonTestSuite) TestEnginePython_AssembleStep_WithMinimalCoo
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_metadata_path").Return("VERSION").MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
@@ -146,6 +155,80 @@ func (suite *EnginePythonTestSuite) TestEnginePython_AssembleStep_WithMinimalCoo
 	require.True(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, ".gitignore")))
 }
 
+func (suite *EnginePythonTestSuite) TestEnginePython_AssembleStep_VersionFromTagWithoutVersionFile() {
+	//setup
+	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("tag").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_metadata_path").Return("VERSION").MinTimes(1)
+
+	//copy cookbook fixture into a temp directory.
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(parentPath)
+	suite.PipelineData.GitParentPath = parentPath
+	suite.PipelineData.GitLocalPath = path.Join(parentPath, "pip_analogj_test")
+	suite.PipelineData.GitNearestTag = &pipeline.GitTagDetails{TagShortName: "v0.1.3"}
+	cerr := utils.CopyDir(path.Join("testdata", "python", "pip_analogj_test"), suite.PipelineData.GitLocalPath)
+	require.NoError(suite.T(), cerr)
+	os.Remove(path.Join(suite.PipelineData.GitLocalPath, "VERSION"))
+
+	pythonEngine, err := engine.Create("python", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+
+	//test
+	berr := pythonEngine.AssembleStep()
+
+	//assert
+	require.NoError(suite.T(), berr, "the VERSION file is optional when the version is read from the nearest tag")
+	require.Equal(suite.T(), "0.1.4", suite.PipelineData.ReleaseVersion)
+	require.False(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, "VERSION")), "should not create the VERSION file")
+}
+
+// calver versions are written verbatim to the VERSION file, so zero padded & 4 segment versions can be read again.
+func (suite *EnginePythonTestSuite) TestEnginePython_AssembleStep_CalverRoundTrip() {
+	//setup
+	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("calver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_calver_format").Return("YYYY.0M.0D.MICRO").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_metadata_path").Return("VERSION").MinTimes(1)
+
+	//copy cookbook fixture into a temp directory.
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(parentPath)
+	suite.PipelineData.GitParentPath = parentPath
+	suite.PipelineData.GitLocalPath = path.Join(parentPath, "pip_analogj_test")
+	cerr := utils.CopyDir(path.Join("testdata", "python", "pip_analogj_test"), suite.PipelineData.GitLocalPath)
+	require.NoError(suite.T(), cerr)
+	require.NoError(suite.T(), ioutil.WriteFile(path.Join(suite.PipelineData.GitLocalPath, "VERSION"), []byte("2020.01.02.3"), 0644))
+	nextPeriod := time.Now().UTC().Format("2006.01.02")
+
+	//test
+	pythonEngine, err := engine.Create("python", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), pythonEngine.AssembleStep())
+	releaseVersion := suite.PipelineData.ReleaseVersion
+	versionContent, rerr := ioutil.ReadFile(path.Join(suite.PipelineData.GitLocalPath, "VERSION"))
+	require.NoError(suite.T(), rerr)
+	nextPythonEngine, err := engine.Create("python", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), nextPythonEngine.AssembleStep())
+
+	//assert
+	require.Equal(suite.T(), nextPeriod+".0", releaseVersion)
+	require.Equal(suite.T(), nextPeriod+".0", string(versionContent), "should write the version verbatim")
+	require.Equal(suite.T(), nextPeriod+".1", suite.PipelineData.ReleaseVersion, "should read the written version for the next release")
+}
+
 func (suite *EnginePythonTestSuite) TestEnginePython_AssembleStep_WithoutSetupPy() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
@@ -278,6 +361,8 @@ func (suite *EnginePythonTestSuite) TestEnginePython_PackageStep() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_msg").Return("Automated packaging of release by CapsuleCD").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_git_author_name").Return("CapsuleCD").MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_git_author_email").Return("CapsuleCD@users.noreply.github.com").MinTimes(1)
 
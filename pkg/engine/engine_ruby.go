@@ -15,6 +15,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
 )
 
 type rubyGemspec struct {
@@ -98,6 +99,10 @@ func (g *engineRuby) AssembleStep() error {
 		return nerr
 	}
 
+	if verr := g.UpdateVersionFiles(g.NextMetadata.Version); verr != nil {
+		return verr
+	}
+
 	if !utils.FileExists(path.Join(g.PipelineData.GitLocalPath, "Rakefile")) {
 		ioutil.WriteFile(path.Join(g.PipelineData.GitLocalPath, "Rakefile"),
 			[]byte("task :default => :spec"),
@@ -134,19 +139,7 @@ func (g *engineRuby) AssembleStep() error {
 //func (g *engineRuby) TestStep() error { }
 
 func (g *engineRuby) PackageStep() error {
-	signature := utils.GitSignature(g.Config.GetString("engine_git_author_name"), g.Config.GetString("engine_git_author_email"))
-
-	if cerr := utils.GitCommit(g.PipelineData.GitLocalPath, fmt.Sprintf("(v%s) %s", g.NextMetadata.Version, g.Config.GetString("engine_version_bump_msg")), signature); cerr != nil {
-		return cerr
-	}
-	tagCommit, terr := utils.GitTag(g.PipelineData.GitLocalPath, fmt.Sprintf("v%s", g.NextMetadata.Version), g.Config.GetString("engine_version_bump_msg"), signature)
-	if terr != nil {
-		return terr
-	}
-
-	g.PipelineData.ReleaseCommit = tagCommit
-	g.PipelineData.ReleaseVersion = g.NextMetadata.Version
-	return nil
+	return g.CommitAndTagRelease(g.NextMetadata.Version)
 }
 
 //private Helpers
@@ -187,11 +180,12 @@ func (g *engineRuby) retrieveCurrentMetadata(gitLocalPath string) error {
 	}
 
 	g.CurrentMetadata.Name = gemspecObj.Name
-	g.CurrentMetadata.Version = gemspecObj.Version.Version
+	g.CurrentMetadata.Version = rubyGemVersionToSemver(gemspecObj.Version.Version)
 
 	//ensure that there is a lib/GEMNAME/version.rb file.
+	// the version.rb file is optional when the version is read from the nearest tag, it's updated (but not committed) if it exists.
 	versionrbPath := path.Join("lib", gemspecObj.Name, "version.rb")
-	if !utils.FileExists(path.Join(g.PipelineData.GitLocalPath, versionrbPath)) {
+	if !utils.FileExists(path.Join(g.PipelineData.GitLocalPath, versionrbPath)) && !g.VersionFromTag() {
 		return errors.EngineBuildPackageInvalid(
 			fmt.Sprintf("version.rb file (%s) is required to process Ruby gem", versionrbPath))
 	}
@@ -200,7 +194,7 @@ func (g *engineRuby) retrieveCurrentMetadata(gitLocalPath string) error {
 
 func (g *engineRuby) populateNextMetadata() error {
 
-	nextVersion, err := g.BumpVersion(g.CurrentMetadata.Version)
+	nextVersion, err := g.NextVersion(g.CurrentMetadata.Version)
 	if err != nil {
 		return err
 	}
@@ -214,11 +208,25 @@ func (g *engineRuby) populateNextMetadata() error {
 func (g *engineRuby) writeNextMetadata(gitLocalPath string) error {
 
 	versionrbPath := path.Join(g.PipelineData.GitLocalPath, "lib", g.CurrentMetadata.Name, "version.rb")
+	if !utils.FileExists(versionrbPath) {
+		// only possible when the version is read from the nearest tag.
+		return nil
+	}
 	versionrbContent, rerr := ioutil.ReadFile(versionrbPath)
 	if rerr != nil {
 		return rerr
 	}
-	re := regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
-	updatedContent := re.ReplaceAllLiteralString(string(versionrbContent), g.NextMetadata.Version)
+	re := regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)(\.[0-9A-Za-z]+)*`)
+	updatedContent := re.ReplaceAllLiteralString(string(versionrbContent), semverToRubyGemVersion(g.NextMetadata.Version))
 	return ioutil.WriteFile(versionrbPath, []byte(updatedContent), 0644)
 }
+
+// RubyGems pre-release versions are separated by dots instead of a hyphen, eg. `1.2.0.rc.1` instead of `1.2.0-rc.1`
+func semverToRubyGemVersion(version string) string {
+	return strings.Replace(version, "-", ".", 1)
+}
+
+func rubyGemVersionToSemver(version string) string {
+	re := regexp.MustCompile(`^(\d+\.\d+\.\d+)\.(pre\.)?([A-Za-z][0-9A-Za-z.]*)$`)
+	return re.ReplaceAllString(version, "$1-$3")
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/analogj/capsulecd/pkg/config/mock"
 	"github.com/analogj/capsulecd/pkg/scm/mock"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -90,6 +91,10 @@ func (suite *EngineRubyTestSuite) TestEngineRuby_AssembleStep() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
 	parentPath, err := ioutil.TempDir("", "")
@@ -118,6 +123,10 @@ func (suite *EngineRubyTestSuite) TestEngineRuby_AssembleStep_WithMinimalGem() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
 	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("metadata").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
 
 	//copy cookbook fixture into a temp directory.
 	parentPath, err := ioutil.TempDir("", "")
@@ -142,6 +151,46 @@ func (suite *EngineRubyTestSuite) TestEngineRuby_AssembleStep_WithMinimalGem() {
 	require.True(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, "gem_analogj_test-0.1.4.gem")))
 }
 
+func (suite *EngineRubyTestSuite) TestEngineRuby_AssembleStep_VersionFromTagWithoutVersionRb() {
+	//setup
+	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_bump_type").Return("patch").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_scheme").Return("semver").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_override").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_version_source").Return("tag").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
+	suite.Config.EXPECT().IsSet("engine_version_files").Return(false).MinTimes(1)
+
+	//copy cookbook fixture into a temp directory.
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(parentPath)
+	suite.PipelineData.GitParentPath = parentPath
+	suite.PipelineData.GitLocalPath = path.Join(parentPath, "gem_analogj_test")
+	suite.PipelineData.GitNearestTag = &pipeline.GitTagDetails{TagShortName: "v0.1.3"}
+	cerr := utils.CopyDir(path.Join("testdata", "ruby", "gem_analogj_test"), suite.PipelineData.GitLocalPath)
+	require.NoError(suite.T(), cerr)
+	os.Remove(path.Join(suite.PipelineData.GitLocalPath, "lib", "gem_analogj_test", "version.rb"))
+	// the gemspec specifies the (tagged) version directly, instead of loading it from version.rb
+	gemspecPath := path.Join(suite.PipelineData.GitLocalPath, "gem_analogj_test.gemspec")
+	gemspecContent, rerr := ioutil.ReadFile(gemspecPath)
+	require.NoError(suite.T(), rerr)
+	gemspecContent = []byte(strings.Replace(strings.Replace(string(gemspecContent), "require 'gem_analogj_test/version'", "", 1), "GemTest::VERSION", `"0.1.4"`, 1))
+	require.NoError(suite.T(), ioutil.WriteFile(gemspecPath, gemspecContent, 0644))
+
+	rubyEngine, err := engine.Create("ruby", suite.PipelineData, suite.Config, suite.Scm)
+	require.NoError(suite.T(), err)
+
+	//test
+	berr := rubyEngine.AssembleStep()
+
+	//assert
+	require.NoError(suite.T(), berr, "version.rb is optional when the version is read from the nearest tag")
+	require.Equal(suite.T(), "0.1.4", suite.PipelineData.ReleaseVersion)
+	require.False(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, "lib", "gem_analogj_test", "version.rb")))
+	require.True(suite.T(), utils.FileExists(path.Join(suite.PipelineData.GitLocalPath, "gem_analogj_test-0.1.4.gem")))
+}
+
 func (suite *EngineRubyTestSuite) TestEngineRuby_AssembleStep_WithoutGemspec() {
 	//setup
 	suite.Config.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
@@ -72,6 +72,43 @@ func (str ScmMergeAnalysisUnknownError) Error() string {
 	return fmt.Sprintf("ScmMergeAnalysisUnknownError: %q", string(str))
 }
 
+// Raised during a PR merge when the PR cannot be merged using the configured merge strategy (eg. fast-forward-only)
+type ScmMergeStrategyError string
+
+func (str ScmMergeStrategyError) Error() string {
+	return fmt.Sprintf("ScmMergeStrategyError: %q", string(str))
+}
+
+// Raised when a release is requested from a branch that is not the default branch, and does not match any release branch rule (scm_release_branches)
+type ScmReleaseBranchUnsupported string
+
+func (str ScmReleaseBranchUnsupported) Error() string {
+	return fmt.Sprintf("ScmReleaseBranchUnsupported: %q", string(str))
+}
+
+// Raised when pull request commits are unsigned, or not signed by an allowed key (scm_require_signed_commits)
+type ScmUnverifiedCommitsError string
+
+func (str ScmUnverifiedCommitsError) Error() string {
+	return fmt.Sprintf("ScmUnverifiedCommitsError: %q", string(str))
+}
+
+// Raised when the release pull request branch (scm_release_pull_request_branch) contains commits that would be
+// overwritten when the release pull request is updated.
+type ScmReleaseBranchConflict string
+
+func (str ScmReleaseBranchConflict) Error() string {
+	return fmt.Sprintf("ScmReleaseBranchConflict: %q", string(str))
+}
+
+// Raised when there is nothing to release (eg. the current version has already been tagged).
+// This is not a failure, the pipeline will stop without creating a release.
+type ReleaseSkipped string
+
+func (str ReleaseSkipped) Error() string {
+	return fmt.Sprintf("ReleaseSkipped: %q", string(str))
+}
+
 // Raised when the config file specifies a hook/override for a step when the type is :repo
 type EngineTransformUnavailableStep string
 
@@ -79,6 +116,28 @@ func (str EngineTransformUnavailableStep) Error() string {
 	return fmt.Sprintf("EngineTransformUnavailableStep: %q", string(str))
 }
 
+// Raised when the version bump type is not allowed by the release branch rule (scm_release_branches)
+type EngineBumpTypeNotAllowed string
+
+func (str EngineBumpTypeNotAllowed) Error() string {
+	return fmt.Sprintf("EngineBumpTypeNotAllowed: %q", string(str))
+}
+
+// Raised when the explicit version override (engine_version_override or `release-as:`) is not greater than the current
+// version and the nearest tag.
+type EngineVersionOverrideInvalid string
+
+func (str EngineVersionOverrideInvalid) Error() string {
+	return fmt.Sprintf("EngineVersionOverrideInvalid: %q", string(str))
+}
+
+// Raised when a version file rule (engine_version_files) is invalid, or does not match the expected number of values.
+type EngineVersionFileInvalid string
+
+func (str EngineVersionFileInvalid) Error() string {
+	return fmt.Sprintf("EngineVersionFileInvalid: %q", string(str))
+}
+
 // Raised when the environment is missing a required tool/binary
 type EngineValidateToolError string
 
@@ -148,3 +207,34 @@ type MgrDistPackageError string
 func (str MgrDistPackageError) Error() string {
 	return fmt.Sprintf("MgrDistPackageError: %q", string(str))
 }
+
+// Raised when the release lock for a repository is held by another process.
+// The error is temporary, the release should be retried later.
+type ReleaseLocked string
+
+func (str ReleaseLocked) Error() string {
+	return fmt.Sprintf("ReleaseLocked: %q", string(str))
+}
+
+func (str ReleaseLocked) Temporary() bool {
+	return true
+}
+
+// Raised when a scm API request fails with a server error (5xx).
+// The error is temporary, the release should be retried later.
+type ScmServerError string
+
+func (str ScmServerError) Error() string {
+	return fmt.Sprintf("ScmServerError: %q", string(str))
+}
+
+func (str ScmServerError) Temporary() bool {
+	return true
+}
+
+// Raised when a commit is not a valid Conventional Commit, and engine_conventional_commits_strict is enabled.
+type EngineConventionalCommitError string
+
+func (str EngineConventionalCommitError) Error() string {
+	return fmt.Sprintf("EngineConventionalCommitError: %q", string(str))
+}
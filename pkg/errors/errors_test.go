@@ -44,4 +44,13 @@ func TestErrors(t *testing.T) {
 	require.Implements(t, (*error)(nil), errors.ScmUnauthorizedUser("test"), "should implement the error interface")
 	require.Implements(t, (*error)(nil), errors.ScmUnspecifiedError("test"), "should implement the error interface")
 	require.Implements(t, (*error)(nil), errors.ScmCleanupFailed("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ScmMergeStrategyError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ReleaseSkipped("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ScmReleaseBranchUnsupported("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ScmUnverifiedCommitsError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ScmReleaseBranchConflict("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.EngineBumpTypeNotAllowed("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ReleaseLocked("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ScmServerError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.EngineConventionalCommitError("test"), "should implement the error interface")
 }
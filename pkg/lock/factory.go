@@ -0,0 +1,32 @@
+package lock
+
+import (
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"fmt"
+	"time"
+)
+
+func Create(lockType string, pipelineData *pipeline.Data, config config.Interface) (Interface, error) {
+	expiry := time.Duration(config.GetInt("engine_release_lock_expiry")) * time.Second
+
+	switch lockType {
+	case "ref":
+		return &refLock{
+			PipelineData: pipelineData,
+			Config:       config,
+			Expiry:       expiry,
+		}, nil
+	case "file":
+		if config.GetString("engine_release_lock_path") == "" {
+			return nil, errors.EngineUnspecifiedError("engine_release_lock_path must be set when using a file release lock")
+		}
+		return &fileLock{
+			Path:   config.GetString("engine_release_lock_path"),
+			Expiry: expiry,
+		}, nil
+	default:
+		return nil, errors.EngineUnspecifiedError(fmt.Sprintf("Unknown release lock type: %s", lockType))
+	}
+}
@@ -0,0 +1,55 @@
+package lock_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/lock"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCreate_File(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetInt("engine_release_lock_expiry").Return(3600)
+	mockConfig.EXPECT().GetString("engine_release_lock_path").Return("/tmp/capsulecd.lock").Times(2)
+
+	//test
+	releaseLock, err := lock.Create("file", new(pipeline.Data), mockConfig)
+
+	//assert
+	require.NoError(t, err)
+	require.NotNil(t, releaseLock)
+}
+
+func TestCreate_FileWithoutPath(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetInt("engine_release_lock_expiry").Return(3600)
+	mockConfig.EXPECT().GetString("engine_release_lock_path").Return("")
+
+	//test
+	_, err := lock.Create("file", new(pipeline.Data), mockConfig)
+
+	//assert
+	require.Error(t, err)
+}
+
+func TestCreate_Unknown(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetInt("engine_release_lock_expiry").Return(3600)
+
+	//test
+	_, err := lock.Create("unknown", new(pipeline.Data), mockConfig)
+
+	//assert
+	require.Error(t, err)
+}
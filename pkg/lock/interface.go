@@ -0,0 +1,14 @@
+package lock
+
+// Release lock, held from the package_step until the scm_publish_step completes, so that concurrent pipelines for the
+// same repository cannot race on the version bump & tag.
+type Interface interface {
+
+	// Acquire the release lock for the repository.
+	// MUST return errors.ReleaseLocked if the lock is held by another process, and its lease has not expired.
+	Acquire() error
+
+	// Release the lock, if it is still held by this process.
+	// Releasing a lock that was not acquired (or has already been released) is a no-op.
+	Release() error
+}
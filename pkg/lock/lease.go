@@ -0,0 +1,54 @@
+package lock
+
+import (
+	"github.com/analogj/capsulecd/pkg/errors"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// a lease identifies the process holding the release lock, and when the lock expires (so that locks held by crashed
+// processes are eventually released).
+type lease struct {
+	Holder  string
+	Expires time.Time
+}
+
+func newLease(expiry time.Duration) lease {
+	hostname, _ := os.Hostname()
+	randomBytes := make([]byte, 4)
+	rand.Read(randomBytes)
+
+	return lease{
+		Holder:  fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(randomBytes)),
+		Expires: time.Now().Add(expiry).UTC(),
+	}
+}
+
+func (l lease) expired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+func (l lease) String() string {
+	return fmt.Sprintf("capsulecd release lock\n\nHolder: %s\nExpires: %s\n", l.Holder, l.Expires.Format(time.RFC3339))
+}
+
+// parse a lease generated by lease.String(). Unparseable leases are considered expired.
+func parseLease(content string) lease {
+	parsed := lease{}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "Holder: ") {
+			parsed.Holder = strings.TrimSpace(strings.TrimPrefix(line, "Holder: "))
+		} else if strings.HasPrefix(line, "Expires: ") {
+			parsed.Expires, _ = time.Parse(time.RFC3339, strings.TrimSpace(strings.TrimPrefix(line, "Expires: ")))
+		}
+	}
+	return parsed
+}
+
+func lockedError(current lease) error {
+	return errors.ReleaseLocked(fmt.Sprintf("Release lock is held by %s until %s, please retry later", current.Holder, current.Expires.Format(time.RFC3339)))
+}
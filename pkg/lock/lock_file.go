@@ -0,0 +1,86 @@
+package lock
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileLock stores the lease in a lock file, for pipelines running on the same machine (or sharing a disk).
+type fileLock struct {
+	Path   string
+	Expiry time.Duration
+
+	lease *lease
+}
+
+func (l *fileLock) Acquire() error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+
+	newLease := newLease(l.Expiry)
+	err := l.create(newLease)
+	if os.IsExist(err) {
+		content, rerr := ioutil.ReadFile(l.Path)
+		if rerr != nil {
+			return rerr
+		}
+		current := parseLease(string(content))
+		if !current.expired(time.Now()) {
+			return lockedError(current)
+		}
+
+		// the lease expired (the process holding it probably crashed), take over the lock.
+		// NOTE: if two processes take over the same expired lease simultaneously, the second may remove the first's new lease.
+		log.Printf("Release lock held by %s expired at %s, taking over", current.Holder, current.Expires.Format(time.RFC3339))
+		if rerr := os.Remove(l.Path); rerr != nil && !os.IsNotExist(rerr) {
+			return rerr
+		}
+		err = l.create(newLease)
+		if os.IsExist(err) {
+			return lockedError(current)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	l.lease = &newLease
+	log.Printf("Acquired release lock (%s), expires at %s", l.Path, newLease.Expires.Format(time.RFC3339))
+	return nil
+}
+
+func (l *fileLock) Release() error {
+	if l.lease == nil {
+		return nil
+	}
+	defer func() {
+		l.lease = nil
+	}()
+
+	content, err := ioutil.ReadFile(l.Path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if current := parseLease(string(content)); current.Holder != l.lease.Holder {
+		log.Printf("Release lock is no longer held by this process (lease expired and was taken over by %s)", current.Holder)
+		return nil
+	}
+	return os.Remove(l.Path)
+}
+
+// create the lock file, failing if it already exists.
+func (l *fileLock) create(newLease lease) error {
+	lockFile, err := os.OpenFile(l.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer lockFile.Close()
+	_, err = lockFile.WriteString(newLease.String())
+	return err
+}
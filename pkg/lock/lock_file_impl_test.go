@@ -0,0 +1,91 @@
+package lock
+
+import (
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLock_Contention(t *testing.T) {
+	//setup
+	dirPath, err := ioutil.TempDir("", "capsulecd-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirPath)
+	lockPath := filepath.Join(dirPath, "capsulecd.lock")
+	firstLock := &fileLock{Path: lockPath, Expiry: time.Hour}
+	secondLock := &fileLock{Path: lockPath, Expiry: time.Hour}
+
+	//test
+	require.NoError(t, firstLock.Acquire())
+	cerr := secondLock.Acquire()
+	require.NoError(t, firstLock.Release())
+
+	//assert
+	require.IsType(t, errors.ReleaseLocked(""), cerr, "should return a retryable error when the lock is held")
+	require.NoError(t, secondLock.Acquire(), "should acquire the lock once released")
+	require.NoError(t, secondLock.Release())
+	require.NoError(t, secondLock.Release(), "releasing twice should be a no-op")
+	_, serr := os.Stat(lockPath)
+	require.True(t, os.IsNotExist(serr))
+}
+
+func TestFileLock_ExpiredLease(t *testing.T) {
+	//setup
+	dirPath, err := ioutil.TempDir("", "capsulecd-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirPath)
+	lockPath := filepath.Join(dirPath, "capsulecd.lock")
+	expiredLease := lease{Holder: "crashed", Expires: time.Now().Add(-time.Minute)}
+	require.NoError(t, ioutil.WriteFile(lockPath, []byte(expiredLease.String()), 0644))
+	releaseLock := &fileLock{Path: lockPath, Expiry: time.Hour}
+
+	//test
+	aerr := releaseLock.Acquire()
+
+	//assert
+	require.NoError(t, aerr, "should take over an expired lease")
+	content, err := ioutil.ReadFile(lockPath)
+	require.NoError(t, err)
+	require.Equal(t, releaseLock.lease.Holder, parseLease(string(content)).Holder)
+}
+
+func TestFileLock_ReleaseTakenOver(t *testing.T) {
+	//setup
+	dirPath, err := ioutil.TempDir("", "capsulecd-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirPath)
+	lockPath := filepath.Join(dirPath, "capsulecd.lock")
+	releaseLock := &fileLock{Path: lockPath, Expiry: time.Hour}
+	require.NoError(t, releaseLock.Acquire())
+	otherLease := lease{Holder: "other", Expires: time.Now().Add(time.Hour)}
+	require.NoError(t, ioutil.WriteFile(lockPath, []byte(otherLease.String()), 0644))
+
+	//test
+	rerr := releaseLock.Release()
+
+	//assert
+	require.NoError(t, rerr)
+	require.FileExists(t, lockPath, "should not remove a lease held by another process")
+}
+
+func TestParseLease(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	expires := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+	original := lease{Holder: "ci-runner-1234", Expires: expires}
+
+	//test
+	parsed := parseLease(original.String())
+
+	//assert
+	require.Equal(t, "ci-runner-1234", parsed.Holder)
+	require.True(t, expires.Equal(parsed.Expires))
+	require.False(t, parsed.expired(expires.Add(-time.Second)))
+	require.True(t, parsed.expired(expires))
+	require.True(t, parseLease("invalid").expired(time.Now()), "unparseable leases should be considered expired")
+}
@@ -0,0 +1,86 @@
+package lock
+
+import (
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"fmt"
+	"log"
+	"time"
+)
+
+// the lease commit is pushed to this reference in the remote repository.
+const releaseLockRef = "refs/capsulecd/lock"
+
+// local reference used to fetch the current remote lease.
+const remoteReleaseLockRef = "refs/capsulecd/remote-lock"
+
+// refLock stores the lease as a commit message, in a reference pushed to the remote repository, so that it works for
+// pipelines running on different machines.
+// Pushes are never forced: a new lease commit has no parent (so the push is rejected if the lock already exists), and
+// a commit taking over an expired lease uses it as a parent (so the push is rejected if the lease changed in the meantime).
+type refLock struct {
+	PipelineData *pipeline.Data
+	Config       config.Interface
+	Expiry       time.Duration
+
+	leaseSha string
+}
+
+func (l *refLock) Acquire() error {
+	currentSha, currentMessage, err := utils.GitFetchRemoteRef(l.PipelineData.GitLocalPath, releaseLockRef, remoteReleaseLockRef)
+	if err != nil {
+		return err
+	}
+	if currentSha != "" {
+		current := parseLease(currentMessage)
+		if !current.expired(time.Now()) {
+			return lockedError(current)
+		}
+		log.Printf("Release lock held by %s expired at %s, taking over", current.Holder, current.Expires.Format(time.RFC3339))
+	}
+
+	newLease := newLease(l.Expiry)
+	leaseSha, err := utils.GitCreateMetadataCommit(
+		l.PipelineData.GitLocalPath,
+		releaseLockRef,
+		currentSha,
+		newLease.String(),
+		utils.GitSignature(l.Config.GetString("engine_git_author_name"), l.Config.GetString("engine_git_author_email")),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.GitPushRef(l.PipelineData.GitLocalPath, releaseLockRef, releaseLockRef, false); err != nil {
+		return errors.ReleaseLocked(fmt.Sprintf("Release lock was acquired by another process, please retry later (%s)", err))
+	}
+
+	l.leaseSha = leaseSha
+	log.Printf("Acquired release lock (%s), expires at %s", releaseLockRef, newLease.Expires.Format(time.RFC3339))
+	return nil
+}
+
+func (l *refLock) Release() error {
+	if l.leaseSha == "" {
+		return nil
+	}
+	defer func() {
+		l.leaseSha = ""
+	}()
+
+	currentSha, currentMessage, err := utils.GitFetchRemoteRef(l.PipelineData.GitLocalPath, releaseLockRef, remoteReleaseLockRef)
+	if err != nil {
+		return err
+	}
+	if currentSha == "" {
+		return nil
+	} else if currentSha != l.leaseSha {
+		log.Printf("Release lock is no longer held by this process (lease expired and was taken over by %s)", parseLease(currentMessage).Holder)
+		return nil
+	}
+
+	// delete the remote lock reference
+	return utils.GitPushRef(l.PipelineData.GitLocalPath, "", releaseLockRef, false)
+}
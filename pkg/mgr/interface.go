@@ -50,5 +50,6 @@ type Interface interface {
 	// USES pypi_username
 	// USES pypi_password
 	// USES rubygems_api_key
+	// SHOULD set pipelineData.ReleaseRegistry
 	MgrDistStep(currentMetadata interface{}, nextMetadata interface{}) error
 }
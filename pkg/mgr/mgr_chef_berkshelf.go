@@ -152,5 +152,6 @@ func (m *mgrChefBerkshelf) MgrDistStep(currentMetadata interface{}, nextMetadata
 	if derr := utils.BashCmdExec(cookbookDistCmd, "", nil, ""); derr != nil {
 		return errors.MgrDistPackageError("knife cookbook upload to supermarket failed")
 	}
+	m.PipelineData.ReleaseRegistry = "https://supermarket.chef.io"
 	return nil
 }
@@ -100,5 +100,6 @@ func (m *mgrNodeNpm) MgrDistStep(currentMetadata interface{}, nextMetadata inter
 	if derr != nil {
 		return errors.MgrDistPackageError("npm publish failed. Check log for exact error")
 	}
+	m.PipelineData.ReleaseRegistry = "https://registry.npmjs.org"
 	return nil
 }
@@ -97,6 +97,7 @@ func (m *mgrNodeYarn) MgrDistStep(currentMetadata interface{}, nextMetadata inte
 	if derr != nil {
 		return errors.MgrDistPackageError("npm publish failed. Check log for exact error")
 	}
+	m.PipelineData.ReleaseRegistry = "https://registry.npmjs.org"
 	return nil
 }
 
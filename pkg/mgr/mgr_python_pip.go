@@ -112,5 +112,6 @@ func (m *mgrPythonPip) MgrDistStep(currentMetadata interface{}, nextMetadata int
 	if uerr := utils.BashCmdExec(pypiUploadCmd, m.PipelineData.GitLocalPath, nil, ""); uerr != nil {
 		return errors.MgrDistPackageError("twine package upload failed. Check log for exact error")
 	}
+	m.PipelineData.ReleaseRegistry = m.Config.GetString("pypi_repository")
 	return nil
 }
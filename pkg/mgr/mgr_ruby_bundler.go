@@ -118,5 +118,6 @@ func (m *mgrRubyBundler) MgrDistStep(currentMetadata interface{}, nextMetadata i
 		return errors.MgrDistPackageError("Pushing gem to RubyGems.org using `gem push` failed. Check log for exact error")
 	}
 
+	m.PipelineData.ReleaseRegistry = "https://rubygems.org"
 	return nil
 }
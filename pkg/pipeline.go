@@ -3,10 +3,12 @@ package pkg
 import (
 	"github.com/analogj/capsulecd/pkg/config"
 	"github.com/analogj/capsulecd/pkg/engine"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/lock"
 	"github.com/analogj/capsulecd/pkg/pipeline"
 	"github.com/analogj/capsulecd/pkg/scm"
 	"github.com/analogj/capsulecd/pkg/utils"
-	"errors"
+	stderrors "errors"
 	"fmt"
 	"log"
 	"os"
@@ -20,12 +22,16 @@ type Pipeline struct {
 	Scm    scm.Interface
 	Engine engine.Interface
 	PackageManager mgr.Interface
+	ReleaseLock lock.Interface
 }
 
 func (p *Pipeline) Start(config config.Interface) error {
 	// Initialize Pipeline.
 	p.Config = config
 	p.Data = new(pipeline.Data)
+	// the command output tail is process-global, output from a previous pipeline (eg. the previous queued job) must not
+	// be included in this pipeline's failure notifications.
+	utils.ResetCmdOutputTail()
 
 	defer p.Cleanup()
 	if err := p.PipelineInitStep(); err != nil {
@@ -51,6 +57,16 @@ func (p *Pipeline) Start(config config.Interface) error {
 		return err
 	}
 
+	if err := p.ReleaseModeStep(); err != nil {
+		return p.skipRelease(err)
+	}
+
+	if p.Data.IsPullRequest {
+		// resolved after the repo config is parsed, so that capsule.yml can specify the bump type sources.
+		p.Data.ReleaseBumpType, p.Data.ReleaseBumpReason = scm.ResolveBumpType(p.Config, payload)
+		p.Data.ReleaseVersionOverride, p.Data.ReleaseVersionOverrideReason = scm.ResolveVersionOverride(p.Config, payload)
+	}
+
 	if err := p.StepExecNotify("mgr_init_step", p.MgrInitStep); err != nil {
 		return err
 	}
@@ -65,7 +81,7 @@ func (p *Pipeline) Start(config config.Interface) error {
 	}
 
 	if err := p.StepExecNotify("assemble_step", p.AssembleStep); err != nil { //this step includes Mgr work.
-		return err
+		return p.skipRelease(err)
 	}
 
 	if err := p.StepExecNotify("mgr_dependencies_step", p.MgrDependenciesStep); err != nil {
@@ -80,9 +96,19 @@ func (p *Pipeline) Start(config config.Interface) error {
 		return err
 	}
 
-	if err := p.StepExecNotify("package_step", p.PackageStep); err != nil { //this step includes Mgr work
+	// pull requests are only tested in release pull request mode, the release pull request is created once they are merged.
+	if p.Data.IsPullRequest && p.Config.GetBool("scm_enable_release_pull_request") {
+		return p.skipRelease(errors.ReleaseSkipped("the release pull request will be created (or updated) when this pull request is merged"))
+	}
+
+	if err := p.StepExecNotify("release_lock_step", p.ReleaseLockStep); err != nil {
 		return err
 	}
+	defer p.releaseLock()
+
+	if err := p.StepExecNotify("package_step", p.PackageStep); err != nil { //this step includes Mgr work
+		return p.skipRelease(err)
+	}
 
 	if err := p.StepExecNotify("mgr_dist_step", p.MgrDistStep); err != nil {
 		return err
@@ -91,6 +117,7 @@ func (p *Pipeline) Start(config config.Interface) error {
 	if err := p.StepExecNotify("scm_publish_step", p.ScmPublishStep); err != nil {
 		return err
 	}
+	p.releaseLock()
 
 	if err := p.StepExecNotify("scm_cleanup_step", p.ScmCleanupStep); err != nil {
 		return err
@@ -100,8 +127,9 @@ func (p *Pipeline) Start(config config.Interface) error {
 	p.Scm.Notify(
 		p.Data.GitHeadInfo.Sha,
 		"success",
-		"Pull-request was successfully merged, new release created.",
+		p.successMessage(),
 	)
+	p.Scm.Summarize("", nil)
 
 	return nil
 }
@@ -120,6 +148,12 @@ func (p *Pipeline) PipelineInitStep() error {
 	// start the source, and whatever work needs to be done there.
 	// MUST set options.GitParentPath
 	log.Println("pipeline_init_step")
+	if berr := utils.GitConfigureBackend(p.Config.GetString("scm_git_backend")); berr != nil {
+		return berr
+	}
+	if terr := scm.ConfigureGitTransport(p.Config); terr != nil {
+		return terr
+	}
 	scmImpl, serr := scm.Create(p.Config.GetString("scm"), p.Data, p.Config, nil)
 	if serr != nil {
 		return serr
@@ -220,7 +254,7 @@ func (p *Pipeline) ParseRepoConfig() error {
 	repoConfig := path.Join(p.Data.GitLocalPath, p.Config.GetString("engine_repo_config_path"))
 	if utils.FileExists(repoConfig) {
 		if err := p.Config.ReadConfig(repoConfig); err != nil {
-			return errors.New("An error occured while parsing repository capsule.yml file")
+			return stderrors.New("An error occured while parsing repository capsule.yml file")
 		}
 	} else {
 		log.Println("No repo capsule.yml file found, using existing config.")
@@ -239,6 +273,88 @@ func (p *Pipeline) ParseRepoConfig() error {
 	return nil
 }
 
+// determine how (and if) the release should be created, now that the repository config has been parsed.
+// when scm_enable_release_pull_request is true, the version bump is not pushed to the default branch. Instead:
+// - pull requests are only tested, the release pull request is created (or updated) once they are merged.
+// - pushes to the default branch open (or update) a release pull request containing the version bump commit.
+// - pushes that include the version bump commit (ie. a merged release pull request) tag & release the committed version.
+func (p *Pipeline) ReleaseModeStep() error {
+	if p.Data.IsPullRequest {
+		if p.Config.GetBool("scm_enable_release_pull_request") {
+			log.Println("release_mode: test only, the release pull request will be created when the pull request is merged")
+		}
+		return nil
+	}
+
+	if p.Config.GetBool("scm_enable_release_pull_request") && p.Data.GitLocalBranch == p.Config.GetString("scm_release_pull_request_branch") {
+		return errors.ReleaseSkipped("changes to the release pull request branch are released when the release pull request is merged")
+	}
+	return p.validatePushRelease()
+}
+
+// pushes are only released from the default branch (or a release branch), when there are changes since the nearest tag.
+// CapsuleCD's own version bump commits are never released, otherwise every release would trigger another release.
+func (p *Pipeline) validatePushRelease() error {
+	if p.Data.ReleaseBranch == nil {
+		defaultBranch, derr := p.Scm.DefaultBranch()
+		if derr != nil {
+			return derr
+		} else if p.Data.GitLocalBranch != defaultBranch {
+			return errors.ReleaseSkipped(fmt.Sprintf("pushes to %s are not released, only pushes to the default branch (%s) or a release branch", p.Data.GitLocalBranch, defaultBranch))
+		}
+	}
+
+	if p.Data.GitNearestTag != nil && p.Data.GitNearestTag.CommitSha == p.Data.GitHeadInfo.Sha {
+		return errors.ReleaseSkipped(fmt.Sprintf("no changes since the nearest tag (%s)", p.Data.GitNearestTag.TagShortName))
+	}
+
+	if p.Config.GetBool("scm_enable_release_pull_request") {
+		return p.validateReleasePullRequestPush()
+	}
+
+	headMessage, merr := utils.GitHeadCommitMessage(p.Data.GitLocalPath)
+	if merr != nil {
+		return merr
+	} else if utils.IsVersionBumpCommit(headMessage, p.Config.GetString("engine_version_bump_msg")) {
+		return errors.ReleaseSkipped("the pushed commit is a CapsuleCD version bump commit")
+	}
+	return nil
+}
+
+// in release pull request mode, check the commits since the nearest tag for a version bump commit (ie. a merged release
+// pull request). If the bumped version has not been tagged yet, it will be released as-is. Otherwise the pushed changes
+// will be proposed via the release pull request.
+func (p *Pipeline) validateReleasePullRequestPush() error {
+	nearestTagSha := ""
+	if p.Data.GitNearestTag != nil {
+		nearestTagSha = p.Data.GitNearestTag.CommitSha
+	}
+	commits, cerr := utils.GitCommitsBetween(p.Data.GitLocalPath, nearestTagSha, "HEAD")
+	if cerr != nil {
+		return cerr
+	}
+
+	for _, commit := range commits {
+		tagName, isBump := utils.VersionBumpCommitTag(commit.Message, p.Config.GetString("engine_version_bump_msg"))
+		if !isBump {
+			continue
+		}
+		tagExists, terr := utils.GitTagExists(p.Data.GitLocalPath, tagName)
+		if terr != nil {
+			return terr
+		} else if tagExists {
+			return errors.ReleaseSkipped(fmt.Sprintf("%s has already been released", tagName))
+		}
+		log.Printf("release_mode: release committed version (%s)", tagName)
+		p.Data.ReleaseVersionCommitted = true
+		return nil
+	}
+
+	log.Println("release_mode: release pull request")
+	p.Data.ReleasePullRequest = true
+	return nil
+}
+
 func (p *Pipeline) MgrInitStep() error {
 	log.Println("mgr_init_step")
 	if p.Config.IsSet("mgr_type") {
@@ -381,6 +497,71 @@ func (p *Pipeline) TestStep() error {
 	return nil
 }
 
+// this step acquires the release lock (engine_release_lock), which is held until the scm_publish_step completes, so that
+// concurrent pipelines for the same repository cannot race on the version bump & tag.
+func (p *Pipeline) ReleaseLockStep() error {
+	lockType := p.Config.GetString("engine_release_lock")
+	if lockType == "" {
+		log.Println("skipping release_lock_step, engine_release_lock is not set")
+		return nil
+	}
+
+	releaseLock, err := lock.Create(lockType, p.Data, p.Config)
+	if err != nil {
+		return err
+	}
+	if err := releaseLock.Acquire(); err != nil {
+		return err
+	}
+	p.ReleaseLock = releaseLock
+
+	if err := p.validateReleaseUnchanged(); err != nil {
+		p.releaseLock()
+		return err
+	}
+	return nil
+}
+
+// the repository was checked out (and the next version determined) before the release lock was acquired, so another
+// pipeline may have released in the meantime. The target branch & tags are fetched again, and the release must be
+// retried (errors.ReleaseLocked) if the target branch has new commits, or the nearest tag has changed.
+func (p *Pipeline) validateReleaseUnchanged() error {
+	targetBranch := p.Data.GitLocalBranch
+	if p.Data.GitBaseInfo != nil {
+		targetBranch = p.Data.GitBaseInfo.Ref
+	}
+
+	remoteSha, _, ferr := utils.GitFetchRemoteRef(p.Data.GitLocalPath, fmt.Sprintf("refs/heads/%s", targetBranch), fmt.Sprintf("refs/remotes/origin/%s", targetBranch))
+	if ferr != nil {
+		return ferr
+	} else if remoteSha != "" {
+		commits, cerr := utils.GitCommitsBetween(p.Data.GitLocalPath, "HEAD", remoteSha)
+		if cerr != nil {
+			return cerr
+		} else if len(commits) > 0 {
+			return errors.ReleaseLocked(fmt.Sprintf("%s was updated (%s) while the release was being assembled, please retry", targetBranch, remoteSha))
+		}
+	}
+
+	if terr := utils.GitFetchTags(p.Data.GitLocalPath); terr != nil {
+		return terr
+	}
+	tagPattern, perr := scm.NearestTagPattern(p.Config, p.Data)
+	if perr != nil {
+		return perr
+	}
+	// failures finding the nearest tag are ignored (eg. no tags), like when the repository is checked out.
+	nearestTag, _ := utils.GitFindNearestMatchingTagName(p.Data.GitLocalPath, tagPattern)
+	previousTag := ""
+	if p.Data.GitNearestTag != nil {
+		previousTag = p.Data.GitNearestTag.TagShortName
+	}
+	if nearestTag != previousTag {
+		return errors.ReleaseLocked(fmt.Sprintf("%s was released while the release was being assembled, please retry", nearestTag))
+	}
+	return nil
+}
+
 // this step should commit any local changes and create a git tag. It should also generate the releaser artifacts. Nothing should be pushed to remote repository
 func (p *Pipeline) PackageStep() error {
 	// PRE HOOK
@@ -412,6 +593,9 @@ func (p *Pipeline) MgrDistStep() error {
 	if p.Config.GetBool("mgr_disable_dist") {
 		log.Println("skipping mgr_dist_step.pre, mgr_dist_step, mgr_dist_step.post")
 		return nil
+	} else if p.Data.ReleasePullRequest {
+		log.Println("skipping mgr_dist_step.pre, mgr_dist_step, mgr_dist_step.post, package will be distributed when the release pull request is merged")
+		return nil
 	}
 
 	// PRE HOOK
@@ -470,6 +654,9 @@ func (p *Pipeline) ScmCleanupStep() error {
 	if p.Config.GetBool("scm_disable_cleanup") {
 		log.Println("skipping scm_cleanup_step.pre, scm_cleanup_step, scm_cleanup_step.post")
 		return nil
+	} else if p.Data.ReleasePullRequest {
+		log.Println("skipping scm_cleanup_step.pre, scm_cleanup_step, scm_cleanup_step.post, pull request has not been merged yet")
+		return nil
 	}
 
 	// PRE HOOK
@@ -498,14 +685,62 @@ func (p *Pipeline) ScmCleanupStep() error {
 // Helpers
 
 func (p *Pipeline) StepExecNotify(step string, callback func() error) error {
-	p.Scm.Notify(p.Data.GitHeadInfo.Sha, "pending", fmt.Sprintf("Started '%s' step. Pull request will be merged automatically when complete.", step))
+	// only the output of commands run during this step should be included in the failure notification.
+	utils.ResetCmdOutputTail()
+
+	p.Scm.NotifyStep(p.Data.GitHeadInfo.Sha, step, "pending", fmt.Sprintf("Started '%s' step. Pull request will be merged automatically when complete.", step), "")
 	if cerr := callback(); cerr != nil {
-		p.Scm.Notify(p.Data.GitHeadInfo.Sha, "failure", fmt.Sprintf("Error: '%s'", cerr))
+		if _, skipped := cerr.(errors.ReleaseSkipped); skipped {
+			p.Scm.NotifyStep(p.Data.GitHeadInfo.Sha, step, "success", fmt.Sprintf("Skipped release: '%s'", cerr), "")
+			return cerr
+		}
+		if _, locked := cerr.(errors.ReleaseLocked); locked {
+			// another pipeline is releasing, this is not a failure. The release should be retried later.
+			p.Scm.NotifyStep(p.Data.GitHeadInfo.Sha, step, "pending", fmt.Sprintf("Waiting to release: '%s'", cerr), "")
+			return cerr
+		}
+		p.Scm.NotifyStep(p.Data.GitHeadInfo.Sha, step, "failure", fmt.Sprintf("Error: '%s'", cerr), utils.CmdOutputTail())
+		p.Scm.Summarize(step, cerr)
 		return cerr
 	}
+	p.Scm.NotifyStep(p.Data.GitHeadInfo.Sha, step, "success", fmt.Sprintf("Completed '%s' step.", step), "")
 	return nil
 }
 
+// the final commit status message, including the version bump (if any), eg. `... v1.2.4 (minor, from label `minor`)`.
+// The full bump reason is reported in the summary comment, scms truncate the status message if it's too long.
+func (p *Pipeline) successMessage() string {
+	successMessage := "Pull-request was successfully merged, new release created."
+	if p.Data.ReleasePullRequest {
+		successMessage = "Release pull-request was created, release will be created when it is merged."
+	}
+	if p.Data.ReleaseBumpType != "" && !p.Data.ReleaseVersionCommitted {
+		successMessage = fmt.Sprintf("%s %s (%s, from %s)", successMessage, p.Data.ReleaseTag, p.Data.ReleaseBumpType, p.Data.ReleaseBumpReason)
+	}
+	return successMessage
+}
+
+// ReleaseSkipped errors are not failures, the pipeline should stop without creating a release.
+func (p *Pipeline) skipRelease(err error) error {
+	if _, skipped := err.(errors.ReleaseSkipped); skipped {
+		log.Printf("Skipping release: %s", err)
+		p.Scm.Notify(p.Data.GitHeadInfo.Sha, "success", fmt.Sprintf("Skipped release: %s", err))
+		return nil
+	}
+	return err
+}
+
+// release the lock acquired by the release_lock_step (if any). Failures are logged, as the lock will expire eventually.
+func (p *Pipeline) releaseLock() {
+	if p.ReleaseLock == nil {
+		return
+	}
+	if err := p.ReleaseLock.Release(); err != nil {
+		log.Printf("Could not release the release lock, it will be released when it expires: %s", err)
+	}
+	p.ReleaseLock = nil
+}
+
 func (p *Pipeline) Cleanup() {
 	if p.Config.GetBool("engine_disable_cleanup") {
 		log.Println("Skipping Cleanup...")
@@ -10,9 +10,32 @@ type Data struct {
 	GitRemote      string
 	GitNearestTag  *GitTagDetails
 
-	ReleaseVersion string
-	ReleaseCommit  string
-	ReleaseAssets  []ScmReleaseAsset
+	ReleaseVersion    string
+	ReleaseTag        string // release tag name, generated from the tag template (engine_tag_template), eg. `v1.2.3`
+	ReleaseCommit     string
+	ReleasePrerelease bool // the release version is a pre-release, eg. `1.2.0-rc.1`
+	ReleaseAssets     []ScmReleaseAsset
+
+	//Version bump type (major, minor, patch, prerelease, release), determined from the pull request labels/title/body or engine_version_bump_type
+	ReleaseBumpType   string
+	ReleaseBumpReason string // why the bump type was chosen, eg. "label `release:minor`"
+
+	//Explicit release version, determined from the pull request labels/title/body (eg. `release-as: 2.0.0`)
+	ReleaseVersionOverride       string
+	ReleaseVersionOverrideReason string // where the version was specified, eg. "label `release-as: 2.0.0`"
+
+	//Release summary data, populated as the release is distributed & published.
+	ReleaseUrl             string
+	ReleaseChangelog       string
+	ReleaseRegistry        string
+	ReleasePublishedAssets []ScmPublishedAsset
+
+	//Release pull request mode (scm_enable_release_pull_request)
+	ReleasePullRequest      bool // the version bump will be proposed via a release pull request instead of being released.
+	ReleaseVersionCommitted bool // the version bump was already committed (via a merged release pull request), only tag & release.
+
+	//Release branch rule (scm_release_branches) matching the branch being released, nil when releasing from the default branch.
+	ReleaseBranch *ReleaseBranch
 
 	//Engine specific pipeline data
 	GolangGoPath string
@@ -0,0 +1,17 @@
+package pipeline
+
+import "time"
+
+// a commit in the local repository, used to determine the version bump (conventional commits) and generate changelogs.
+type GitCommit struct {
+	Sha         string
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time
+	ParentCount int
+}
+
+func (c *GitCommit) IsMerge() bool {
+	return c.ParentCount > 1
+}
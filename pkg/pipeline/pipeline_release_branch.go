@@ -0,0 +1,33 @@
+package pipeline
+
+import (
+	"path"
+)
+
+type ReleaseBranch struct { //mapstructure is used to deserialize by Config.
+	Branch     string   `mapstructure:"branch"`      // branch name or glob pattern, eg. `release/*`
+	BumpTypes  []string `mapstructure:"bump_types"`  // allowed version bump types, all bump types are allowed if empty.
+	TagPattern string   `mapstructure:"tag_pattern"` // glob pattern used to limit nearest tag detection (and the changelog) to this release line, eg. `v1.*`
+}
+
+// check if the branch name matches this release branch rule.
+func (r *ReleaseBranch) Matches(branch string) bool {
+	if r.Branch == branch {
+		return true
+	}
+	matched, err := path.Match(r.Branch, branch)
+	return err == nil && matched
+}
+
+// check if the version bump type is allowed for releases from this branch.
+func (r *ReleaseBranch) AllowsBumpType(bumpType string) bool {
+	if len(r.BumpTypes) == 0 {
+		return true
+	}
+	for _, allowedBumpType := range r.BumpTypes {
+		if allowedBumpType == bumpType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package pipeline_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReleaseBranch_Matches(t *testing.T) {
+	//setup
+	releaseBranch := pipeline.ReleaseBranch{Branch: "release/*"}
+
+	//test & assert
+	require.True(t, releaseBranch.Matches("release/1.x"))
+	require.False(t, releaseBranch.Matches("master"))
+	require.False(t, releaseBranch.Matches("release/1.x/hotfix"), "glob should not match nested branches")
+}
+
+func TestReleaseBranch_AllowsBumpType(t *testing.T) {
+	//setup
+	releaseBranch := pipeline.ReleaseBranch{Branch: "release/*", BumpTypes: []string{"patch"}}
+
+	//test & assert
+	require.True(t, releaseBranch.AllowsBumpType("patch"))
+	require.False(t, releaseBranch.AllowsBumpType("minor"))
+}
+
+func TestReleaseBranch_AllowsBumpType_Empty(t *testing.T) {
+	//setup
+	releaseBranch := pipeline.ReleaseBranch{Branch: "release/*"}
+
+	//test & assert
+	require.True(t, releaseBranch.AllowsBumpType("major"), "all bump types should be allowed when none are specified")
+}
@@ -5,3 +5,8 @@ type ScmReleaseAsset struct { //mapstructure is used to deserialize by Config.
 	ArtifactName string `mapstructure:"artifact_name"`
 	ContentType  string `mapstructure:"content_type"`
 }
+
+type ScmPublishedAsset struct {
+	ArtifactName string
+	DownloadUrl  string
+}
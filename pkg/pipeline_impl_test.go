@@ -0,0 +1,346 @@
+package pkg
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/scm/mock"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// run a git command in the test repository, returning the output.
+func pushTestGit(t *testing.T, repoPath string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=CapsuleCD", "GIT_AUTHOR_EMAIL=CapsuleCD@users.noreply.github.com",
+		"GIT_COMMITTER_NAME=CapsuleCD", "GIT_COMMITTER_EMAIL=CapsuleCD@users.noreply.github.com",
+	)
+	output, cerr := cmd.CombinedOutput()
+	require.NoError(t, cerr, string(output))
+	return string(output)
+}
+
+// create a local repository with a commit for each message, returning the repository path & HEAD commit sha.
+func pushTestRepo(t *testing.T, messages ...string) (string, string) {
+	repoPath, err := ioutil.TempDir("", "pipeline")
+	require.NoError(t, err)
+	pushTestGit(t, repoPath, "init", "-q")
+	require.NoError(t, ioutil.WriteFile(repoPath+"/README.md", []byte("# Title\n"), 0644))
+	pushTestGit(t, repoPath, "add", "-A")
+	for _, message := range messages {
+		pushTestGit(t, repoPath, "commit", "-q", "--allow-empty", "-m", message)
+	}
+	sha := pushTestGit(t, repoPath, "rev-parse", "HEAD")
+	return repoPath, sha[:len(sha)-1]
+}
+
+func pushTestPipeline(t *testing.T, mockCtrl *gomock.Controller, releasePullRequest bool, messages ...string) (*Pipeline, *mock_scm.MockInterface) {
+	repoPath, sha := pushTestRepo(t, messages...)
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetString("engine_version_bump_msg").Return("Automated packaging of release by CapsuleCD").AnyTimes()
+	mockConfig.EXPECT().GetBool("scm_enable_release_pull_request").Return(releasePullRequest).AnyTimes()
+	mockScm := mock_scm.NewMockInterface(mockCtrl)
+
+	p := &Pipeline{
+		Config: mockConfig,
+		Scm:    mockScm,
+		Data: &pipeline.Data{
+			GitLocalPath:   repoPath,
+			GitLocalBranch: "master",
+			GitHeadInfo:    &pipeline.ScmCommitInfo{Sha: sha, Ref: "master"},
+			GitNearestTag:  &pipeline.GitTagDetails{TagShortName: "v1.0.0", CommitSha: "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"},
+		},
+	}
+	return p, mockScm
+}
+
+func TestPipeline_validatePushRelease(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, false, "Add a feature")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.NoError(t, err)
+}
+
+func TestPipeline_validatePushRelease_NonDefaultBranch(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, false, "Add a feature")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	p.Data.GitLocalBranch = "feature/key-bindings"
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.IsType(t, errors.ReleaseSkipped(""), err, "pushes to other branches should not be released")
+}
+
+func TestPipeline_validatePushRelease_DefaultBranchError(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, false, "Add a feature")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	mockScm.EXPECT().DefaultBranch().Return("", errors.ScmAuthenticationFailed("Bad credentials"))
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.IsType(t, errors.ScmAuthenticationFailed(""), err)
+}
+
+func TestPipeline_validatePushRelease_ReleaseBranch(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, _ := pushTestPipeline(t, mockCtrl, false, "Fix a bug")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	p.Data.GitLocalBranch = "release/1.x"
+	p.Data.ReleaseBranch = &pipeline.ReleaseBranch{Branch: "release/1.x"}
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.NoError(t, err, "release branches should be released without retrieving the default branch")
+}
+
+func TestPipeline_validatePushRelease_UnchangedHead(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, false, "Add a feature")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	p.Data.GitNearestTag.CommitSha = p.Data.GitHeadInfo.Sha
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.IsType(t, errors.ReleaseSkipped(""), err, "should not release a commit that is already tagged")
+}
+
+func TestPipeline_validatePushRelease_VersionBumpCommit(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, false, "(v1.1.0) Automated packaging of release by CapsuleCD")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.IsType(t, errors.ReleaseSkipped(""), err, "should not release CapsuleCD's own version bump commits")
+}
+
+func TestPipeline_validatePushRelease_ReleasePullRequest(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, true, "Initial commit", "Add a feature")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	p.Data.GitNearestTag = nil
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.NoError(t, err)
+	require.True(t, p.Data.ReleasePullRequest, "pushed changes should be proposed via the release pull request")
+	require.False(t, p.Data.ReleaseVersionCommitted)
+}
+
+func TestPipeline_validatePushRelease_ReleasePullRequestMerged(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, true, "Initial commit", "Add a feature", "(v1.1.0) Automated packaging of release by CapsuleCD", "Merge pull request #13 from capsulecd/release")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	p.Data.GitNearestTag = nil
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.NoError(t, err)
+	require.True(t, p.Data.ReleaseVersionCommitted, "the version bump commit of the merged release pull request should be released")
+	require.False(t, p.Data.ReleasePullRequest)
+}
+
+func TestPipeline_validatePushRelease_ReleasePullRequestReleased(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, true, "Initial commit", "(v1.1.0) Automated packaging of release by CapsuleCD", "Fix a bug")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	pushTestGit(t, p.Data.GitLocalPath, "tag", "v1.1.0", "HEAD~1")
+	p.Data.GitNearestTag = nil
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.IsType(t, errors.ReleaseSkipped(""), err, "should not release a version bump that is already tagged")
+	require.False(t, p.Data.ReleasePullRequest)
+	require.False(t, p.Data.ReleaseVersionCommitted)
+}
+
+func TestPipeline_validatePushRelease_ReleasePullRequestSinceNearestTag(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, mockScm := pushTestPipeline(t, mockCtrl, true, "Initial commit", "(v1.1.0) Automated packaging of release by CapsuleCD", "Fix a bug")
+	defer os.RemoveAll(p.Data.GitLocalPath)
+	tagSha := pushTestGit(t, p.Data.GitLocalPath, "rev-parse", "HEAD~1")
+	p.Data.GitNearestTag = &pipeline.GitTagDetails{TagShortName: "v1.1.0", CommitSha: tagSha[:len(tagSha)-1]}
+	mockScm.EXPECT().DefaultBranch().Return("master", nil)
+
+	//test
+	err := p.validatePushRelease()
+
+	//assert
+	require.NoError(t, err)
+	require.True(t, p.Data.ReleasePullRequest, "version bump commits before the nearest tag should be ignored")
+}
+
+// clone a test repository (the origin) with a v1.0.0 tag, returning a pipeline for the clone (within the origin directory).
+func releaseLockTestPipeline(t *testing.T, mockCtrl *gomock.Controller) (*Pipeline, string) {
+	originPath, _ := pushTestRepo(t, "Initial commit")
+	pushTestGit(t, originPath, "tag", "v1.0.0")
+	pushTestGit(t, originPath, "commit", "-q", "--allow-empty", "-m", "Add a feature")
+	clonePath, cerr := utils.GitClone(originPath, "clone", originPath)
+	require.NoError(t, cerr)
+	sha := pushTestGit(t, clonePath, "rev-parse", "HEAD")
+	branch := pushTestGit(t, clonePath, "rev-parse", "--abbrev-ref", "HEAD")
+
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").AnyTimes()
+	p := &Pipeline{
+		Config: mockConfig,
+		Data: &pipeline.Data{
+			GitLocalPath:   clonePath,
+			GitLocalBranch: branch[:len(branch)-1],
+			GitHeadInfo:    &pipeline.ScmCommitInfo{Sha: sha[:len(sha)-1]},
+			GitNearestTag:  &pipeline.GitTagDetails{TagShortName: "v1.0.0"},
+		},
+	}
+	return p, originPath
+}
+
+func TestPipeline_validateReleaseUnchanged(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, originPath := releaseLockTestPipeline(t, mockCtrl)
+	defer os.RemoveAll(originPath)
+
+	//test
+	err := p.validateReleaseUnchanged()
+
+	//assert
+	require.NoError(t, err)
+}
+
+func TestPipeline_validateReleaseUnchanged_BranchUpdated(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, originPath := releaseLockTestPipeline(t, mockCtrl)
+	defer os.RemoveAll(originPath)
+	pushTestGit(t, originPath, "commit", "-q", "--allow-empty", "-m", "(v1.1.0) Automated packaging of release by CapsuleCD")
+
+	//test
+	err := p.validateReleaseUnchanged()
+
+	//assert
+	require.IsType(t, errors.ReleaseLocked(""), err, "should retry the release if the target branch was updated by another release")
+}
+
+func TestPipeline_validateReleaseUnchanged_Tagged(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	p, originPath := releaseLockTestPipeline(t, mockCtrl)
+	defer os.RemoveAll(originPath)
+	pushTestGit(t, originPath, "tag", "v1.1.0")
+
+	//test
+	err := p.validateReleaseUnchanged()
+
+	//assert
+	require.IsType(t, errors.ReleaseLocked(""), err, "should retry the release if the nearest tag was created by another release")
+}
+
+func TestPipeline_StepExecNotify_ReleaseLocked(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockScm := mock_scm.NewMockInterface(mockCtrl)
+	p := &Pipeline{Scm: mockScm, Data: &pipeline.Data{GitHeadInfo: &pipeline.ScmCommitInfo{Sha: "49f5bfbf4610f0c2a54d33945521051ba92b2eac"}}}
+	mockScm.EXPECT().NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "release_lock_step", "pending", gomock.Any(), "").Times(2)
+
+	//test
+	err := p.StepExecNotify("release_lock_step", func() error {
+		return errors.ReleaseLocked("Release lock is held by another process, please retry later")
+	})
+
+	//assert
+	require.IsType(t, errors.ReleaseLocked(""), err, "lock contention should be reported as pending, without a failure summary")
+}
+
+func TestPipeline_successMessage(t *testing.T) {
+	//setup
+	p := &Pipeline{Data: &pipeline.Data{ReleaseTag: "v1.2.4", ReleaseBumpType: "minor", ReleaseBumpReason: "label `minor`"}}
+	releasePullRequest := &Pipeline{Data: &pipeline.Data{ReleaseTag: "v1.2.4", ReleaseBumpType: "patch", ReleaseBumpReason: "`engine_version_bump_type` setting", ReleasePullRequest: true}}
+	committed := &Pipeline{Data: &pipeline.Data{ReleaseTag: "v1.2.4", ReleaseBumpType: "minor", ReleaseBumpReason: "label `minor`", ReleaseVersionCommitted: true}}
+
+	//test & assert
+	require.Equal(t, "Pull-request was successfully merged, new release created. v1.2.4 (minor, from label `minor`)", p.successMessage())
+	require.Equal(t, "Release pull-request was created, release will be created when it is merged. v1.2.4 (patch, from `engine_version_bump_type` setting)", releasePullRequest.successMessage())
+	require.Equal(t, "Pull-request was successfully merged, new release created.", committed.successMessage(), "the version bump was already committed")
+}
+
+func TestPipeline_Start_ResetsCmdOutputTail(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("pipeline_init_step.pre").Return(nil)
+	mockConfig.EXPECT().IsSet("pipeline_init_step.override").Return(false)
+	mockConfig.EXPECT().GetString("scm_git_backend").Return("svn")
+	mockConfig.EXPECT().GetBool("engine_disable_cleanup").Return(false)
+	require.NoError(t, utils.BashCmdExec("echo 'output from the previous pipeline'", "", nil, ""))
+	require.Contains(t, utils.CmdOutputTail(), "output from the previous pipeline")
+	p := Pipeline{}
+
+	//test
+	err := p.Start(mockConfig)
+
+	//assert
+	require.EqualError(t, err, "Unknown git backend: svn")
+	require.Empty(t, utils.CmdOutputTail(), "should not include the output of the previous pipeline")
+}
@@ -0,0 +1,39 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+)
+
+type Job struct {
+	Id           string            `json:"id"`
+	RepoFullName string            `json:"repo_full_name"` // jobs for the same repository are never run concurrently.
+	Status       string            `json:"status"`
+	Attempts     int               `json:"attempts"`
+	Error        string            `json:"error,omitempty"`
+	Data         map[string]string `json:"data,omitempty"` // runner specific job data (eg. scm, event type, workspace path)
+
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // set when a failed job is waiting to be retried.
+}
+
+func (j *Job) finished() bool {
+	return j.Status == JobStatusSuccess || j.Status == JobStatusFailed
+}
+
+func NewJobId() string {
+	randomBytes := make([]byte, 4)
+	rand.Read(randomBytes)
+	return fmt.Sprintf("%d-%s", time.Now().UTC().Unix(), hex.EncodeToString(randomBytes))
+}
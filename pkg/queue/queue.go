@@ -0,0 +1,376 @@
+package queue
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"github.com/google/go-github/github"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Runner is called by a worker to process a job. Transient errors (see IsTransient) will be retried with an exponential
+// backoff.
+type Runner func(job Job) error
+
+type Options struct {
+	Workers     int           // number of jobs that can run concurrently (for different repositories)
+	MaxQueued   int           // maximum number of queued jobs, Enqueue will fail when the queue is full.
+	MaxAttempts int           // maximum number of attempts for jobs failing with a transient error.
+	RetryDelay  time.Duration // delay before the first retry, doubled for every subsequent attempt.
+	History     int           // number of finished jobs to keep.
+	StatePath   string        // file used to persist the queue state, so that jobs survive restarts. Not persisted if empty.
+	Finished    func(job Job) // called when a job has finished (succeeded, or failed after its last attempt), eg. to remove its workspace.
+}
+
+// Queue is a persistent job queue, processed by a bounded pool of workers.
+// Jobs for the same repository are processed strictly in order, one at a time, so that concurrent releases cannot
+// race on the version bump & tag.
+type Queue struct {
+	options Options
+	runner  Runner
+
+	jobs        map[string]*Job
+	order       []string        // job ids, in the order they were enqueued
+	activeRepos map[string]bool // repositories with a running job
+
+	mutex   sync.Mutex
+	wake    chan struct{}
+	stop    chan struct{}
+	workers sync.WaitGroup
+}
+
+// maximum time a worker waits before checking the queue again.
+const pollInterval = 5 * time.Second
+
+func New(options Options, runner Runner) (*Queue, error) {
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = 1
+	}
+
+	q := &Queue{
+		options:     options,
+		runner:      runner,
+		jobs:        map[string]*Job{},
+		activeRepos: map[string]bool{},
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// start the worker pool.
+func (q *Queue) Start() {
+	for i := 0; i < q.options.Workers; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	q.notify()
+}
+
+// stop the worker pool, waiting for running jobs to complete.
+func (q *Queue) Stop() {
+	close(q.stop)
+	q.workers.Wait()
+}
+
+func (q *Queue) Enqueue(job Job) (Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.options.MaxQueued > 0 && q.queuedCount() >= q.options.MaxQueued {
+		return Job{}, fmt.Errorf("Job queue is full (%d jobs), please retry later", q.options.MaxQueued)
+	}
+
+	if job.Id == "" {
+		job.Id = NewJobId()
+	}
+	job.Status = JobStatusQueued
+	job.CreatedAt = time.Now()
+	q.jobs[job.Id] = &job
+	q.order = append(q.order, job.Id)
+
+	if err := q.persist(); err != nil {
+		return Job{}, err
+	}
+	q.notify()
+	return job, nil
+}
+
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, found := q.jobs[id]
+	if !found {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// list all jobs, most recent first.
+func (q *Queue) List() []Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	jobs := []Job{}
+	for i := len(q.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *q.jobs[q.order[i]])
+	}
+	return jobs
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+	for {
+		job, found, wait := q.next()
+		if found {
+			q.run(job)
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// find the oldest queued job that is ready to run, and does not belong to a repository with a running job.
+// If no job is ready, returns how long to wait before the next job retry is due.
+func (q *Queue) next() (Job, bool, time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	wait := pollInterval
+	select {
+	case <-q.stop:
+		return Job{}, false, wait
+	default:
+	}
+
+	now := time.Now()
+	blockedRepos := map[string]bool{}
+	for _, id := range q.order {
+		job := q.jobs[id]
+		if job.Status != JobStatusQueued || q.activeRepos[job.RepoFullName] || blockedRepos[job.RepoFullName] {
+			continue
+		}
+		if job.NextAttemptAt != nil && job.NextAttemptAt.After(now) {
+			// later jobs for this repository must wait for the retry, to keep the repository jobs in order.
+			blockedRepos[job.RepoFullName] = true
+			if retryIn := job.NextAttemptAt.Sub(now); retryIn < wait {
+				wait = retryIn
+			}
+			continue
+		}
+
+		job.Status = JobStatusRunning
+		job.Attempts++
+		job.StartedAt = &now
+		job.NextAttemptAt = nil
+		q.activeRepos[job.RepoFullName] = true
+		q.persistOrLog()
+		return *job, true, 0
+	}
+	return Job{}, false, wait
+}
+
+func (q *Queue) run(job Job) {
+	err := q.safeRun(job)
+
+	finishedJob, finished := q.complete(job, err)
+	if finished && q.options.Finished != nil {
+		q.options.Finished(finishedJob)
+	}
+}
+
+// store the result of a job attempt. Jobs failing with a transient error are queued again, unless they have reached
+// the maximum number of attempts. Returns the stored job, and true if the job has finished.
+func (q *Queue) complete(job Job, err error) (Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	stored := q.jobs[job.Id]
+	delete(q.activeRepos, stored.RepoFullName)
+	finishedAt := time.Now()
+
+	if err == nil {
+		stored.Status = JobStatusSuccess
+		stored.Error = ""
+		stored.FinishedAt = &finishedAt
+	} else if IsTransient(err) && stored.Attempts < q.options.MaxAttempts {
+		nextAttemptAt := finishedAt.Add(q.options.RetryDelay * time.Duration(1<<uint(stored.Attempts-1)))
+		log.Printf("Job %s failed with a transient error, retrying at %s: %s", stored.Id, nextAttemptAt.Format(time.RFC3339), err)
+		stored.Status = JobStatusQueued
+		stored.Error = err.Error()
+		stored.NextAttemptAt = &nextAttemptAt
+	} else {
+		stored.Status = JobStatusFailed
+		stored.Error = err.Error()
+		stored.FinishedAt = &finishedAt
+	}
+
+	completed := *stored
+	q.prune()
+	q.persistOrLog()
+	q.notify()
+	return completed, completed.finished()
+}
+
+// a panicking runner should only fail the job, not the worker.
+func (q *Queue) safeRun(job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Job panic: %v", r)
+		}
+	}()
+	return q.runner(job)
+}
+
+// IsTransient returns true if the error is temporary and the job should be retried:
+// - errors with a `Temporary() bool` method that returns true (eg. lock contention, errors.ScmServerError)
+// - network errors (eg. connection refused, DNS failures & timeouts)
+// - Github API server errors (5xx)
+// Wrapped errors (eg. `*url.Error`, `fmt.Errorf("...: %w", err)`) are unwrapped.
+func IsTransient(err error) bool {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		if temporary, ok := err.(interface {
+			Temporary() bool
+		}); ok && temporary.Temporary() {
+			return true
+		}
+
+		switch typedErr := err.(type) {
+		case *net.OpError, *net.DNSError:
+			return true
+		case *github.ErrorResponse:
+			if typedErr.Response != nil && typedErr.Response.StatusCode >= http.StatusInternalServerError {
+				return true
+			}
+		case net.Error:
+			if typedErr.Timeout() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) queuedCount() int {
+	count := 0
+	for _, job := range q.jobs {
+		if job.Status == JobStatusQueued {
+			count++
+		}
+	}
+	return count
+}
+
+// remove the oldest finished jobs, keeping at most options.History finished jobs.
+func (q *Queue) prune() {
+	if q.options.History <= 0 {
+		return
+	}
+	finished := 0
+	for i := len(q.order) - 1; i >= 0; i-- {
+		if q.jobs[q.order[i]].finished() {
+			finished++
+		}
+	}
+
+	order := []string{}
+	for _, id := range q.order {
+		if finished > q.options.History && q.jobs[id].finished() {
+			delete(q.jobs, id)
+			finished--
+			continue
+		}
+		order = append(order, id)
+	}
+	q.order = order
+}
+
+type queueState struct {
+	Jobs []*Job `json:"jobs"`
+}
+
+// load the persisted queue state. Jobs that were running when the process stopped are queued again.
+func (q *Queue) load() error {
+	if q.options.StatePath == "" {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(q.options.StatePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	state := queueState{}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return fmt.Errorf("Could not parse queue state (%s): %s", q.options.StatePath, err)
+	}
+	for _, job := range state.Jobs {
+		if job.Status == JobStatusRunning {
+			log.Printf("Job %s was interrupted, it will be run again", job.Id)
+			job.Status = JobStatusQueued
+		}
+		q.jobs[job.Id] = job
+		q.order = append(q.order, job.Id)
+	}
+	return nil
+}
+
+// write the queue state to disk atomically (write to a temporary file, then rename).
+func (q *Queue) persist() error {
+	if q.options.StatePath == "" {
+		return nil
+	}
+
+	state := queueState{}
+	for _, id := range q.order {
+		state.Jobs = append(state.Jobs, q.jobs[id])
+	}
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.options.StatePath), 0700); err != nil {
+		return err
+	}
+	tmpPath := q.options.StatePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, q.options.StatePath)
+}
+
+func (q *Queue) persistOrLog() {
+	if err := q.persist(); err != nil {
+		log.Printf("Could not persist queue state: %s", err)
+	}
+}
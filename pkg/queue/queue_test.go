@@ -0,0 +1,343 @@
+package queue_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"github.com/analogj/capsulecd/pkg"
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/queue"
+	"github.com/analogj/capsulecd/pkg/scm"
+	"github.com/analogj/capsulecd/pkg/scm/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "connection reset" }
+func (temporaryError) Temporary() bool { return true }
+
+// wait until the job has finished (success or failed)
+func waitForJob(t *testing.T, jobQueue *queue.Queue, id string) queue.Job {
+	for i := 0; i < 200; i++ {
+		job, found := jobQueue.Get(id)
+		require.True(t, found)
+		if job.Status == queue.JobStatusSuccess || job.Status == queue.JobStatusFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Fail(t, "job did not finish", id)
+	return queue.Job{}
+}
+
+func TestQueue_SerializesJobsPerRepo(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockScm := mock_scm.NewMockInterface(mockCtrl)
+	mockScm.EXPECT().Publish().Return(nil).Times(4)
+
+	var mutex sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	completed := []string{}
+	jobQueue, err := queue.New(queue.Options{Workers: 3}, func(job queue.Job) error {
+		mutex.Lock()
+		running[job.RepoFullName]++
+		if running[job.RepoFullName] > maxRunning[job.RepoFullName] {
+			maxRunning[job.RepoFullName] = running[job.RepoFullName]
+		}
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		err := mockScm.Publish()
+
+		mutex.Lock()
+		running[job.RepoFullName]--
+		completed = append(completed, job.Id)
+		mutex.Unlock()
+		return err
+	})
+	require.NoError(t, err)
+
+	//test
+	ids := []string{}
+	for _, repo := range []string{"AnalogJ/capsulecd", "AnalogJ/capsulecd", "AnalogJ/capsulecd", "AnalogJ/lexicon"} {
+		job, err := jobQueue.Enqueue(queue.Job{RepoFullName: repo})
+		require.NoError(t, err)
+		ids = append(ids, job.Id)
+	}
+	jobQueue.Start()
+	defer jobQueue.Stop()
+	for _, id := range ids {
+		waitForJob(t, jobQueue, id)
+	}
+
+	//assert
+	require.Equal(t, 1, maxRunning["AnalogJ/capsulecd"], "jobs for the same repo should never run concurrently")
+	repoOrder := []string{}
+	for _, id := range completed {
+		if id != ids[3] {
+			repoOrder = append(repoOrder, id)
+		}
+	}
+	require.Equal(t, ids[:3], repoOrder, "jobs for the same repo should run in order")
+}
+
+func TestQueue_RetriesTransientFailures(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockScm := mock_scm.NewMockInterface(mockCtrl)
+	gomock.InOrder(
+		mockScm.EXPECT().Publish().Return(temporaryError{}),
+		mockScm.EXPECT().Publish().Return(nil),
+	)
+	jobQueue, err := queue.New(queue.Options{MaxAttempts: 3, RetryDelay: 10 * time.Millisecond}, func(job queue.Job) error {
+		return mockScm.Publish()
+	})
+	require.NoError(t, err)
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
+	//test
+	job, err := jobQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd"})
+	require.NoError(t, err)
+	job = waitForJob(t, jobQueue, job.Id)
+
+	//assert
+	require.Equal(t, queue.JobStatusSuccess, job.Status)
+	require.Equal(t, 2, job.Attempts)
+}
+
+func TestQueue_DoesNotRetryPermanentFailures(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockScm := mock_scm.NewMockInterface(mockCtrl)
+	mockScm.EXPECT().Publish().Return(stderrors.New("release already exists"))
+	jobQueue, err := queue.New(queue.Options{MaxAttempts: 3, RetryDelay: 10 * time.Millisecond}, func(job queue.Job) error {
+		return mockScm.Publish()
+	})
+	require.NoError(t, err)
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
+	//test
+	job, err := jobQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd"})
+	require.NoError(t, err)
+	job = waitForJob(t, jobQueue, job.Id)
+
+	//assert
+	require.Equal(t, queue.JobStatusFailed, job.Status)
+	require.Equal(t, "release already exists", job.Error)
+	require.Equal(t, 1, job.Attempts)
+}
+
+func TestQueue_Finished(t *testing.T) {
+	//setup
+	attempts := 0
+	finished := make(chan queue.Job, 2)
+	jobQueue, err := queue.New(queue.Options{
+		MaxAttempts: 3,
+		RetryDelay:  10 * time.Millisecond,
+		Finished: func(job queue.Job) {
+			finished <- job
+		},
+	}, func(job queue.Job) error {
+		attempts++
+		if attempts == 1 {
+			return temporaryError{}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
+	//test
+	job, err := jobQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd"})
+	require.NoError(t, err)
+
+	//assert
+	select {
+	case finishedJob := <-finished:
+		require.Equal(t, job.Id, finishedJob.Id)
+		require.Equal(t, queue.JobStatusSuccess, finishedJob.Status, "should only be called once the job has finished, not between retries")
+		require.Equal(t, 2, finishedJob.Attempts)
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "finished was not called")
+	}
+	require.Len(t, finished, 0)
+}
+
+func TestQueue_RecoversPanics(t *testing.T) {
+	//setup
+	jobQueue, err := queue.New(queue.Options{}, func(job queue.Job) error {
+		panic("unexpected")
+	})
+	require.NoError(t, err)
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
+	//test
+	job, err := jobQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd"})
+	require.NoError(t, err)
+	job = waitForJob(t, jobQueue, job.Id)
+
+	//assert
+	require.Equal(t, queue.JobStatusFailed, job.Status)
+	require.Contains(t, job.Error, "unexpected")
+}
+
+func TestQueue_Full(t *testing.T) {
+	//setup
+	jobQueue, err := queue.New(queue.Options{MaxQueued: 1}, nil)
+	require.NoError(t, err)
+
+	//test
+	_, err = jobQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd"})
+	require.NoError(t, err)
+	_, err = jobQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd"})
+
+	//assert
+	require.Error(t, err)
+}
+
+func TestQueue_PersistsJobsAcrossRestarts(t *testing.T) {
+	//setup
+	dirPath, err := ioutil.TempDir("", "capsulecd-queue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirPath)
+	statePath := filepath.Join(dirPath, "queue.json")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockScm := mock_scm.NewMockInterface(mockCtrl)
+	mockScm.EXPECT().Publish().Return(nil)
+
+	stoppedQueue, err := queue.New(queue.Options{StatePath: statePath}, nil)
+	require.NoError(t, err)
+	job, err := stoppedQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd", Data: map[string]string{"scm": "github"}})
+	require.NoError(t, err)
+
+	//test
+	var runData map[string]string
+	restartedQueue, err := queue.New(queue.Options{StatePath: statePath}, func(job queue.Job) error {
+		runData = job.Data
+		return mockScm.Publish()
+	})
+	require.NoError(t, err)
+	restartedQueue.Start()
+	defer restartedQueue.Stop()
+	job = waitForJob(t, restartedQueue, job.Id)
+
+	//assert
+	require.Equal(t, queue.JobStatusSuccess, job.Status)
+	require.Equal(t, "github", runData["scm"])
+}
+
+func TestQueue_RequeuesInterruptedJobs(t *testing.T) {
+	//setup
+	dirPath, err := ioutil.TempDir("", "capsulecd-queue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirPath)
+	statePath := filepath.Join(dirPath, "queue.json")
+	require.NoError(t, ioutil.WriteFile(statePath, []byte(`{"jobs": [{"id": "interrupted", "repo_full_name": "AnalogJ/capsulecd", "status": "running", "attempts": 1}]}`), 0600))
+
+	//test
+	jobQueue, err := queue.New(queue.Options{StatePath: statePath}, nil)
+	require.NoError(t, err)
+
+	//assert
+	job, found := jobQueue.Get("interrupted")
+	require.True(t, found)
+	require.Equal(t, queue.JobStatusQueued, job.Status)
+}
+
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	//test & assert
+	require.True(t, queue.IsTransient(temporaryError{}))
+	require.True(t, queue.IsTransient(errors.ReleaseLocked("locked")))
+	require.True(t, queue.IsTransient(errors.ScmServerError("Bitbucket API request failed (503 Service Unavailable)")))
+	require.True(t, queue.IsTransient(fmt.Errorf("could not publish: %w", temporaryError{})), "should unwrap errors")
+	require.True(t, queue.IsTransient(&url.Error{Op: "Get", URL: "https://api.github.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}), "should retry network errors")
+	require.True(t, queue.IsTransient(&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}), "should retry Github server errors")
+	require.False(t, queue.IsTransient(&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}))
+	require.False(t, queue.IsTransient(errors.ScmAuthenticationFailed("Bad credentials")))
+	require.False(t, queue.IsTransient(stderrors.New("permanent")))
+	require.False(t, queue.IsTransient(nil))
+}
+
+func TestQueue_RetriesPipelineScmServerErrors(t *testing.T) {
+	//setup
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"message": "Service Unavailable"}`))
+			return
+		}
+		w.Write([]byte(`{"full_name": "AnalogJ/capsulecd", "default_branch": "master"}`))
+	}))
+	defer server.Close()
+
+	jobQueue, err := queue.New(queue.Options{MaxAttempts: 3, RetryDelay: 10 * time.Millisecond}, func(job queue.Job) error {
+		jobConfig, cerr := config.Create()
+		if cerr != nil {
+			return cerr
+		}
+		jobConfig.Set("scm_github_access_token", "token")
+		jobConfig.Set("scm_github_api_endpoint", server.URL+"/")
+		jobConfig.Set("scm_repo_full_name", job.RepoFullName)
+
+		pipelineData := &pipeline.Data{
+			GitLocalBranch: "feature/key-bindings",
+			GitHeadInfo:    &pipeline.ScmCommitInfo{Sha: "49f5bfbf4610f0c2a54d33945521051ba92b2eac"},
+		}
+		githubScm, serr := scm.Create("github", pipelineData, jobConfig, nil)
+		if serr != nil {
+			return serr
+		}
+		defer os.RemoveAll(pipelineData.GitParentPath)
+
+		p := pkg.Pipeline{Config: jobConfig, Data: pipelineData, Scm: githubScm}
+		if perr := p.ReleaseModeStep(); perr != nil {
+			if _, skipped := perr.(errors.ReleaseSkipped); !skipped {
+				return perr
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
+	//test
+	job, err := jobQueue.Enqueue(queue.Job{RepoFullName: "AnalogJ/capsulecd"})
+	require.NoError(t, err)
+	job = waitForJob(t, jobQueue, job.Id)
+
+	//assert
+	require.Equal(t, queue.JobStatusSuccess, job.Status, "the Github server error should be retried")
+	require.Equal(t, 2, job.Attempts)
+	require.Equal(t, 2, requests)
+}
@@ -0,0 +1,43 @@
+package scm
+
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/config"
+	"strings"
+)
+
+// determine which user must be authorized to create a release.
+// By default this is the pull request author, but it can be changed to the user that triggered the build (scm_sender)
+func authorizationUser(config config.Interface, payload *Payload) string {
+	if config.GetString("scm_authorization_user") == "sender" {
+		return payload.Sender
+	}
+	return payload.Author
+}
+
+// check if the user is explicitly allowed to create releases (scm_authorization_users)
+func authorizationAllowlisted(config config.Interface, user string) bool {
+	if user == "" {
+		return false
+	}
+	for _, allowedUser := range config.GetStringSlice("scm_authorization_users") {
+		if strings.EqualFold(allowedUser, user) {
+			return true
+		}
+	}
+	return false
+}
+
+// generate the markdown body for the comment posted when an unauthorized user attempts to create a release.
+func unauthorizedComment(user string) string {
+	if user == "" {
+		user = "unknown"
+	}
+	return fmt.Sprintf(
+		"### :no_entry: Release not authorized\n\n"+
+			"CapsuleCD will not create a release for this pull request, `@%s` is not authorized to create releases for this repository.\n\n"+
+			"A release can only be triggered by a user with write access to this repository, a member of an authorized team, "+
+			"or a user included in the `scm_authorization_users` list. Please ask a maintainer to re-run the build.\n",
+		user,
+	)
+}
@@ -0,0 +1,59 @@
+package scm
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAuthorizationUser_Author(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetString("scm_authorization_user").Return("")
+
+	//test
+	user := authorizationUser(mockConfig, &Payload{Author: "author", Sender: "sender"})
+
+	//assert
+	require.Equal(t, "author", user, "should default to the pull request author")
+}
+
+func TestAuthorizationUser_Sender(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetString("scm_authorization_user").Return("sender")
+
+	//test
+	user := authorizationUser(mockConfig, &Payload{Author: "author", Sender: "sender"})
+
+	//assert
+	require.Equal(t, "sender", user)
+}
+
+func TestAuthorizationAllowlisted(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_authorization_users").Return([]string{"AnalogJ", "capsulecd-bot"}).Times(2)
+
+	//test & assert
+	require.True(t, authorizationAllowlisted(mockConfig, "analogj"), "allowlist should be case-insensitive")
+	require.False(t, authorizationAllowlisted(mockConfig, "unknown"))
+	require.False(t, authorizationAllowlisted(mockConfig, ""), "empty user should never be authorized")
+}
+
+func TestUnauthorizedComment(t *testing.T) {
+	t.Parallel()
+
+	//test
+	comment := unauthorizedComment("octocat")
+
+	//assert
+	require.Contains(t, comment, "`@octocat` is not authorized")
+}
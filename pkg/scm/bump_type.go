@@ -0,0 +1,106 @@
+package scm
+
+import (
+	"github.com/analogj/capsulecd/pkg/config"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// supported bump types, ordered from lowest to highest.
+var bumpTypes = []string{"patch", "minor", "major"}
+
+// ResolveBumpType determines the version bump type from the pull request labels (eg. `release:minor`, `semver:major`)
+// and title/body markers (eg. `[major]`).
+// Sources are checked in the order specified by scm_bump_type_sources, the first source specifying a bump type wins.
+// If a source specifies multiple bump types, the highest is used. Returns an empty bump type if none was specified, in
+// which case engine_version_bump_type should be used.
+func ResolveBumpType(config config.Interface, payload *Payload) (string, string) {
+	for _, source := range config.GetStringSlice("scm_bump_type_sources") {
+		switch source {
+		case "labels":
+			if bumpType, label := labelBumpType(config.GetStringSlice("scm_bump_type_label_prefixes"), payload.Labels); bumpType != "" {
+				return bumpType, fmt.Sprintf("label `%s`", label)
+			}
+		case "title":
+			if bumpType := markerBumpType(payload.Title); bumpType != "" {
+				return bumpType, fmt.Sprintf("title marker `[%s]`", bumpType)
+			}
+		case "body":
+			if bumpType := markerBumpType(payload.Body); bumpType != "" {
+				return bumpType, fmt.Sprintf("body marker `[%s]`", bumpType)
+			}
+		}
+	}
+	return "", ""
+}
+
+// the explicit version override label/marker, eg. `release-as: 2.0.0`
+var versionOverridePattern = regexp.MustCompile(`(?i)release-as:\s*v?([0-9][0-9A-Za-z.+-]*[0-9A-Za-z])`)
+
+// ResolveVersionOverride determines the explicit release version from the pull request labels or title/body markers,
+// eg. `release-as: 2.0.0`. Sources are checked in the order specified by scm_bump_type_sources, the first source
+// specifying a version wins. Returns an empty version if none was specified, in which case engine_version_override (or
+// the version bump) should be used.
+func ResolveVersionOverride(config config.Interface, payload *Payload) (string, string) {
+	for _, source := range config.GetStringSlice("scm_bump_type_sources") {
+		switch source {
+		case "labels":
+			for _, label := range payload.Labels {
+				if matches := versionOverridePattern.FindStringSubmatch(label); matches != nil {
+					return matches[1], fmt.Sprintf("label `%s`", label)
+				}
+			}
+		case "title":
+			if matches := versionOverridePattern.FindStringSubmatch(payload.Title); matches != nil {
+				return matches[1], fmt.Sprintf("title marker `release-as: %s`", matches[1])
+			}
+		case "body":
+			if matches := versionOverridePattern.FindStringSubmatch(payload.Body); matches != nil {
+				return matches[1], fmt.Sprintf("body marker `release-as: %s`", matches[1])
+			}
+		}
+	}
+	return "", ""
+}
+
+// find the highest bump type specified by a label, eg. `release:minor`, and return it with the matching label.
+func labelBumpType(prefixes []string, labels []string) (string, string) {
+	highest := -1
+	highestLabel := ""
+	for _, label := range labels {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(strings.ToLower(label), strings.ToLower(prefix)) {
+				continue
+			}
+			if ndx := bumpTypeIndex(strings.TrimSpace(label[len(prefix):])); ndx > highest {
+				highest = ndx
+				highestLabel = label
+			}
+		}
+	}
+	if highest < 0 {
+		return "", ""
+	}
+	return bumpTypes[highest], highestLabel
+}
+
+// find the highest bump type marker in the text, eg. `[major]`
+func markerBumpType(text string) string {
+	text = strings.ToLower(text)
+	for ndx := len(bumpTypes) - 1; ndx >= 0; ndx-- {
+		if strings.Contains(text, fmt.Sprintf("[%s]", bumpTypes[ndx])) {
+			return bumpTypes[ndx]
+		}
+	}
+	return ""
+}
+
+func bumpTypeIndex(bumpType string) int {
+	for ndx := range bumpTypes {
+		if strings.EqualFold(bumpTypes[ndx], bumpType) {
+			return ndx
+		}
+	}
+	return -1
+}
@@ -0,0 +1,146 @@
+package scm
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestResolveBumpType_Label(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_sources").Return([]string{"labels", "title", "body"})
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_label_prefixes").Return([]string{"release:", "semver:"})
+	payload := &Payload{
+		Title:  "Add support for custom key bindings [patch]",
+		Labels: []string{"enhancement", "semver:minor", "Release:Major"},
+	}
+
+	//test
+	bumpType, reason := ResolveBumpType(mockConfig, payload)
+
+	//assert
+	require.Equal(t, "major", bumpType, "should use the highest labeled bump type")
+	require.Equal(t, "label `Release:Major`", reason)
+}
+
+func TestResolveBumpType_TitleMarker(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_sources").Return([]string{"labels", "title", "body"})
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_label_prefixes").Return([]string{"release:", "semver:"})
+	payload := &Payload{
+		Title:  "[Minor] Add support for custom key bindings",
+		Body:   "Breaking change [major]",
+		Labels: []string{"enhancement"},
+	}
+
+	//test
+	bumpType, reason := ResolveBumpType(mockConfig, payload)
+
+	//assert
+	require.Equal(t, "minor", bumpType, "title should take precedence over the body")
+	require.Equal(t, "title marker `[minor]`", reason)
+}
+
+func TestResolveBumpType_Precedence(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_sources").Return([]string{"body", "labels"})
+	payload := &Payload{
+		Body:   "Breaking change [major]",
+		Labels: []string{"release:patch"},
+	}
+
+	//test
+	bumpType, reason := ResolveBumpType(mockConfig, payload)
+
+	//assert
+	require.Equal(t, "major", bumpType)
+	require.Equal(t, "body marker `[major]`", reason)
+}
+
+func TestResolveBumpType_None(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_sources").Return([]string{"labels", "title"})
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_label_prefixes").Return([]string{"release:"})
+	payload := &Payload{
+		Title:  "Add support for custom key bindings",
+		Labels: []string{"release:unknown", "bug"},
+	}
+
+	//test
+	bumpType, reason := ResolveBumpType(mockConfig, payload)
+
+	//assert
+	require.Empty(t, bumpType)
+	require.Empty(t, reason)
+}
+
+func TestResolveVersionOverride_Label(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_sources").Return([]string{"labels", "title", "body"})
+	payload := &Payload{
+		Title:  "Realign versions release-as: 3.0.0",
+		Labels: []string{"enhancement", "Release-As: v2.0.0"},
+	}
+
+	//test
+	version, reason := ResolveVersionOverride(mockConfig, payload)
+
+	//assert
+	require.Equal(t, "2.0.0", version, "labels should take precedence over the title")
+	require.Equal(t, "label `Release-As: v2.0.0`", reason)
+}
+
+func TestResolveVersionOverride_BodyMarker(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_sources").Return([]string{"labels", "title", "body"})
+	payload := &Payload{
+		Title: "Prepare the next major version [minor]",
+		Body:  "This pull request removes the deprecated options.\n\nrelease-as: 2.0.0-rc.1.",
+	}
+
+	//test
+	version, reason := ResolveVersionOverride(mockConfig, payload)
+
+	//assert
+	require.Equal(t, "2.0.0-rc.1", version, "should ignore trailing punctuation")
+	require.Equal(t, "body marker `release-as: 2.0.0-rc.1`", reason)
+}
+
+func TestResolveVersionOverride_None(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_bump_type_sources").Return([]string{"title"})
+	payload := &Payload{
+		Title:  "Add support for custom key bindings",
+		Body:   "release-as: 2.0.0",
+		Labels: []string{"release-as: 2.0.0"},
+	}
+
+	//test
+	version, reason := ResolveVersionOverride(mockConfig, payload)
+
+	//assert
+	require.Empty(t, version, "should only check the configured sources")
+	require.Empty(t, reason)
+}
@@ -46,6 +46,12 @@ type Interface interface {
 	// REQUIRES pipelineData.GitParentPath
 	CheckoutPullRequestPayload(payload *Payload) error
 
+	// DefaultBranch should return the default branch of the repository (eg. `master`), which pushes are released from.
+	// The default branch should be retrieved from the webhook payload if available, otherwise from the scm API.
+	// CAN NOT override
+	// REQUIRES config.scm_repo_full_name
+	DefaultBranch() (string, error)
+
 	// The repository should now contain code that has been the merged, tested and version bumped.
 	// This method will push these changes to the source code repository
 	// this step should also do any scm specific releases (github release, asset uploading, etc)
@@ -91,4 +97,28 @@ type Interface interface {
 	// In general, if the Notify method returns an error, we'll ignore it, and continue the pipeline.
 	// REQUIRES config.scm_repo_full_name
 	Notify(ref string, state string, message string) error
+
+	// NotifyStep should update the scm with the status of a single pipeline step.
+	// log should contain the tail of the output generated by the step (may be empty).
+	// A started step may report "pending" again (eg. waiting for the release lock), the step should remain in progress.
+	// If the scm does not support per-step notifications, this should fall back to Notify, ignoring step "success"
+	// states (the commit status should only be successful once the whole pipeline completes)
+	// In general, if the NotifyStep method returns an error, we'll ignore it, and continue the pipeline.
+	// USES scm_github_enable_checks
+	// REQUIRES config.scm_repo_full_name
+	NotifyStep(ref string, step string, state string, message string, log string) error
+
+	// Summarize should post (or update in place) a pull request comment summarizing the outcome of the pipeline.
+	// On success the comment should include the released version, tag, release & asset links, package registry and changelog.
+	// If failedStep is set, the comment should include the failing step and error instead.
+	// If this is not a pull request, or the scm does not support comments, this should be a no-op
+	// In general, if the Summarize method returns an error, we'll ignore it, and continue the pipeline.
+	// USES scm_disable_pull_request_comment
+	// REQUIRES config.scm_repo_full_name
+	// REQUIRES config.scm_pull_request
+	// USES pipelineData.ReleaseUrl
+	// USES pipelineData.ReleaseChangelog
+	// USES pipelineData.ReleaseRegistry
+	// USES pipelineData.ReleasePublishedAssets
+	Summarize(failedStep string, failure error) error
 }
@@ -85,6 +85,19 @@ func (mr *MockInterfaceMockRecorder) CheckoutPullRequestPayload(payload interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckoutPullRequestPayload", reflect.TypeOf((*MockInterface)(nil).CheckoutPullRequestPayload), payload)
 }
 
+// DefaultBranch mocks base method
+func (m *MockInterface) DefaultBranch() (string, error) {
+	ret := m.ctrl.Call(m, "DefaultBranch")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DefaultBranch indicates an expected call of DefaultBranch
+func (mr *MockInterfaceMockRecorder) DefaultBranch() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DefaultBranch", reflect.TypeOf((*MockInterface)(nil).DefaultBranch))
+}
+
 // Publish mocks base method
 func (m *MockInterface) Publish() error {
 	ret := m.ctrl.Call(m, "Publish")
@@ -132,3 +145,27 @@ func (m *MockInterface) Notify(ref, state, message string) error {
 func (mr *MockInterfaceMockRecorder) Notify(ref, state, message interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockInterface)(nil).Notify), ref, state, message)
 }
+
+// NotifyStep mocks base method
+func (m *MockInterface) NotifyStep(ref, step, state, message, log string) error {
+	ret := m.ctrl.Call(m, "NotifyStep", ref, step, state, message, log)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// NotifyStep indicates an expected call of NotifyStep
+func (mr *MockInterfaceMockRecorder) NotifyStep(ref, step, state, message, log interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyStep", reflect.TypeOf((*MockInterface)(nil).NotifyStep), ref, step, state, message, log)
+}
+
+// Summarize mocks base method
+func (m *MockInterface) Summarize(failedStep string, failure error) error {
+	ret := m.ctrl.Call(m, "Summarize", failedStep, failure)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Summarize indicates an expected call of Summarize
+func (mr *MockInterfaceMockRecorder) Summarize(failedStep, failure interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Summarize", reflect.TypeOf((*MockInterface)(nil).Summarize), failedStep, failure)
+}
@@ -1,6 +1,8 @@
 package scm
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/analogj/capsulecd/pkg/config"
 	"github.com/analogj/capsulecd/pkg/errors"
 	"github.com/analogj/capsulecd/pkg/pipeline"
@@ -11,6 +13,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"strconv"
@@ -29,6 +32,10 @@ type scmBitbucketPullrequest struct {
 	PullRequestNumber int       `mapstructure:"id"`
 	State             string    `mapstructure:"state"`
 	Title             string    `mapstructure:"title"`
+	Description       string    `mapstructure:"description"`
+	Author            struct {
+		Username string `mapstructure:"username"`
+	} `mapstructure:"author"`
 	Base              struct {
 		Branch struct {
 			Name string `mapstructure:"name"`
@@ -56,6 +63,40 @@ type scmBitbucketPullrequest struct {
 	} `mapstructure:"source"`
 }
 
+type scmBitbucketWebhook struct {
+	PullRequest map[string]interface{} `mapstructure:"pullrequest"`
+	Actor       struct {
+		Username string `mapstructure:"username"`
+		Nickname string `mapstructure:"nickname"`
+	} `mapstructure:"actor"`
+	Repository struct {
+		FullName string `mapstructure:"full_name"`
+		Name     string `mapstructure:"name"`
+	} `mapstructure:"repository"`
+	Push struct {
+		Changes []struct {
+			New *struct {
+				Type   string `mapstructure:"type"`
+				Name   string `mapstructure:"name"`
+				Target struct {
+					Hash string `mapstructure:"hash"`
+				} `mapstructure:"target"`
+			} `mapstructure:"new"`
+		} `mapstructure:"changes"`
+	} `mapstructure:"push"`
+}
+
+// a page of pull request comments, Next is the url of the next page (empty for the last page).
+type scmBitbucketComments struct {
+	Values []struct {
+		ID      int `json:"id"`
+		Content struct {
+			Raw string `json:"raw"`
+		} `json:"content"`
+	} `json:"values"`
+	Next string `json:"next"`
+}
+
 // configure method will generate an authenticated client that can be used to comunicate with Github
 // MUST set @git_parent_path
 // MUST set @client field
@@ -91,11 +132,12 @@ func (b *scmBitbucket) Init(pipelineData *pipeline.Data, myconfig config.Interfa
 }
 
 func (b *scmBitbucket) RetrievePayload() (*Payload, error) {
-	if !b.Config.IsSet("scm_pull_request") {
-		log.Print("This is not a pull request. No automatic continuous deployment processing required. Continuous Integration testing will continue.")
-		b.PipelineData.IsPullRequest = false
+	if b.Config.IsSet("scm_payload_file") {
+		return b.retrieveWebhookPayload()
+	}
 
-		return &Payload{
+	if !b.Config.IsSet("scm_pull_request") {
+		return b.pushPayload(&Payload{
 			Head: &pipeline.ScmCommitInfo{
 				Sha: b.Config.GetString("scm_sha"),
 				Ref: b.Config.GetString("scm_branch"),
@@ -104,63 +146,145 @@ func (b *scmBitbucket) RetrievePayload() (*Payload, error) {
 					Name:     b.Config.GetString("scm_repo_name"),
 					FullName: b.Config.GetString("scm_repo_full_name"),
 				}},
-		}, nil
+		})
 		//make this as similar to a pull request as possible
 	} else {
-		b.PipelineData.IsPullRequest = true
 		parts := strings.Split(b.Config.GetString("scm_repo_full_name"), "/")
 		prDataMap, err := b.Client.Repositories.PullRequests.Get(&bitbucket.PullRequestsOptions{
 			ID:       b.Config.GetString("scm_pull_request"),
 			Owner:    parts[0],
 			RepoSlug: parts[1],
 		})
-		if err != nil {
+		if err = bitbucketError(err); err != nil {
+			if _, serverError := err.(errors.ScmServerError); serverError {
+				return nil, err
+			}
 			return nil, errors.ScmAuthenticationFailed("Could not retrieve pull request from Bitbucket")
 		}
 
 		prData := new(scmBitbucketPullrequest)
 		mapstructure.Decode(prDataMap, prData)
+		return b.pullRequestPayload(prData)
+	}
+}
 
-		//validate pullrequest
-		if strings.ToLower(prData.State) != "open" {
-			return nil, errors.ScmPayloadUnsupported("Pull request has an invalid action")
-		}
-		//TODO: see if we can determien the "main branch" using the Bitbucket API.
-		//if pr.Base.Repo.GetDefaultBranch() != pr.Base.GetRef() {
-		//	return nil, errors.ScmPayloadUnsupported(fmt.Sprintf("Pull request is not being created against the default branch of this repository (%s vs %s)", pr.Base.Repo.GetDefaultBranch(), pr.Base.GetRef()))
-		//}
-
-		//TODO: figure out how to do optional authenication. possible options, Source USER, token based auth, no auth when used with capsulecd.com.
-		// unless @source_client.collaborator?(payload['base']['repo']['full_name'], payload['user']['login'])
-		//
-		//   @source_client.add_comment(payload['base']['repo']['full_name'], payload['number'], CapsuleCD::BotUtils.pull_request_comment)
-		//   fail CapsuleCD::Error::SourceUnauthorizedUser, 'Pull request was opened by an unauthorized user'
-		// end
-
-		return &Payload{
-			Title:             prData.Title,
-			PullRequestNumber: strconv.Itoa(prData.PullRequestNumber),
-			Head: &pipeline.ScmCommitInfo{
-				Sha: prData.Head.Commit.Hash,
-				Ref: prData.Head.Branch.Name,
-				Repo: &pipeline.ScmRepoInfo{
-					CloneUrl: fmt.Sprintf("https://bitbucket.org/%s.git", prData.Head.Repository.FullName),
-					Name:     prData.Head.Repository.Name,
-					FullName: prData.Head.Repository.FullName,
-				},
+// build the payload from a Bitbucket `pullrequest:*` or `repo:push` webhook event body (scm_payload_file), instead of
+// the Bitbucket API. config values used throughout the pipeline (scm_pull_request, scm_repo_full_name, scm_sender) are
+// populated from the event, unless they are already set.
+func (b *scmBitbucket) retrieveWebhookPayload() (*Payload, error) {
+	body, err := readWebhookPayload(b.Config.GetString("scm_payload_file"))
+	if err != nil {
+		return nil, err
+	}
+
+	eventMap := map[string]interface{}{}
+	if jerr := json.Unmarshal(body, &eventMap); jerr != nil {
+		return nil, errors.ScmPayloadFormatError(fmt.Sprintf("Could not parse Bitbucket webhook payload: %s", jerr))
+	}
+	event := new(scmBitbucketWebhook)
+	mapstructure.Decode(eventMap, event)
+
+	sender := event.Actor.Username
+	if sender == "" {
+		sender = event.Actor.Nickname
+	}
+
+	if event.PullRequest != nil {
+		prData := new(scmBitbucketPullrequest)
+		mapstructure.Decode(event.PullRequest, prData)
+		setDefaultConfig(b.Config, "scm_pull_request", strconv.Itoa(prData.PullRequestNumber))
+		setDefaultConfig(b.Config, "scm_repo_full_name", prData.Base.Repository.FullName)
+		setDefaultConfig(b.Config, "scm_sender", sender)
+		return b.pullRequestPayload(prData)
+	}
+
+	if len(event.Push.Changes) == 0 {
+		return nil, errors.ScmPayloadUnsupported("Bitbucket webhook payload must be a `pullrequest:*` or `repo:push` event")
+	}
+	// only the last change is released, Bitbucket includes a change for each ref updated by the push.
+	change := event.Push.Changes[len(event.Push.Changes)-1]
+	if change.New == nil || change.New.Type != "branch" {
+		return nil, errors.ScmPayloadUnsupported("Bitbucket push event must update a branch")
+	}
+	setDefaultConfig(b.Config, "scm_repo_full_name", event.Repository.FullName)
+	setDefaultConfig(b.Config, "scm_sender", sender)
+
+	return b.pushPayload(&Payload{
+		Sender: sender,
+		Head: &pipeline.ScmCommitInfo{
+			Sha: change.New.Target.Hash,
+			Ref: change.New.Name,
+			Repo: &pipeline.ScmRepoInfo{
+				CloneUrl: fmt.Sprintf("https://bitbucket.org/%s.git", event.Repository.FullName),
+				Name:     event.Repository.Name,
+				FullName: event.Repository.FullName,
+			}},
+	})
+}
+
+func (b *scmBitbucket) pushPayload(payload *Payload) (*Payload, error) {
+	log.Print("This is not a pull request. A release will be created from the pushed commit, if it has changed since the nearest tag.")
+	b.PipelineData.IsPullRequest = false
+
+	releaseBranch, rerr := releaseBranchRule(b.Config, payload.Head.Ref)
+	if rerr != nil {
+		return nil, rerr
+	}
+	b.PipelineData.ReleaseBranch = releaseBranch
+	return payload, nil
+}
+
+func (b *scmBitbucket) pullRequestPayload(prData *scmBitbucketPullrequest) (*Payload, error) {
+	b.PipelineData.IsPullRequest = true
+
+	//validate pullrequest
+	if strings.ToLower(prData.State) != "open" {
+		return nil, errors.ScmPayloadUnsupported("Pull request has an invalid action")
+	}
+	//TODO: see if we can determien the "main branch" using the Bitbucket API.
+	//if pr.Base.Repo.GetDefaultBranch() != pr.Base.GetRef() {
+	//	return nil, errors.ScmPayloadUnsupported(fmt.Sprintf("Pull request is not being created against the default branch of this repository (%s vs %s)", pr.Base.Repo.GetDefaultBranch(), pr.Base.GetRef()))
+	//}
+
+	payload := &Payload{
+		Title:             prData.Title,
+		Body:              prData.Description,
+		PullRequestNumber: strconv.Itoa(prData.PullRequestNumber),
+		Author:            prData.Author.Username,
+		Sender:            b.Config.GetString("scm_sender"),
+		Head: &pipeline.ScmCommitInfo{
+			Sha: prData.Head.Commit.Hash,
+			Ref: prData.Head.Branch.Name,
+			Repo: &pipeline.ScmRepoInfo{
+				CloneUrl: fmt.Sprintf("https://bitbucket.org/%s.git", prData.Head.Repository.FullName),
+				Name:     prData.Head.Repository.Name,
+				FullName: prData.Head.Repository.FullName,
 			},
-			Base: &pipeline.ScmCommitInfo{
-				Sha: prData.Base.Commit.Hash,
-				Ref: prData.Base.Branch.Name,
-				Repo: &pipeline.ScmRepoInfo{
-					CloneUrl: fmt.Sprintf("https://bitbucket.org/%s.git", prData.Base.Repository.FullName),
-					Name:     prData.Base.Repository.Name,
-					FullName: prData.Base.Repository.FullName,
-				},
+		},
+		Base: &pipeline.ScmCommitInfo{
+			Sha: prData.Base.Commit.Hash,
+			Ref: prData.Base.Branch.Name,
+			Repo: &pipeline.ScmRepoInfo{
+				CloneUrl: fmt.Sprintf("https://bitbucket.org/%s.git", prData.Base.Repository.FullName),
+				Name:     prData.Base.Repository.Name,
+				FullName: prData.Base.Repository.FullName,
 			},
-		}, nil
+		},
+	}
+
+	releaseBranch, rerr := releaseBranchRule(b.Config, payload.Base.Ref)
+	if rerr != nil {
+		return nil, rerr
+	}
+	b.PipelineData.ReleaseBranch = releaseBranch
+
+	// check that the pull request user is authorized to create a release.
+	if b.Config.GetBool("scm_enable_authorization") {
+		if aerr := b.authorize(payload); aerr != nil {
+			return nil, aerr
+		}
 	}
-	return nil, nil
+	return payload, nil
 }
 
 func (b *scmBitbucket) CheckoutPushPayload(payload *Payload) error {
@@ -177,7 +301,8 @@ func (b *scmBitbucket) CheckoutPushPayload(payload *Payload) error {
 		cloneCred = b.Config.GetString("scm_bitbucket_access_token")
 	}
 
-	authRemote, aerr := authGitRemote(
+	authRemote, aerr := gitRemoteUrl(
+		b.Config,
 		b.PipelineData.GitHeadInfo.Repo.CloneUrl,
 		b.Config.GetString("scm_bitbucket_username"),
 		cloneCred,
@@ -202,8 +327,17 @@ func (b *scmBitbucket) CheckoutPushPayload(payload *Payload) error {
 		return cerr
 	}
 
+	// release the pushed commit, even if the branch has since been updated (the release push will be rejected).
+	if rerr := utils.GitResetToCommit(b.PipelineData.GitLocalPath, b.PipelineData.GitHeadInfo.Sha); rerr != nil {
+		return rerr
+	}
+
 	//retrieve and store the nearestTag to this commit.
-	nearestTag, err := utils.GitFindNearestTagName(gitLocalPath)
+	tagPattern, perr := NearestTagPattern(b.Config, b.PipelineData)
+	if perr != nil {
+		return perr
+	}
+	nearestTag, err := utils.GitFindNearestMatchingTagName(gitLocalPath, tagPattern)
 	if err != nil {
 		return nil // we dont care about failures finding the nearest tag, we'll just have an empty changelog.
 	}
@@ -237,7 +371,8 @@ func (b *scmBitbucket) CheckoutPullRequestPayload(payload *Payload) error {
 		cloneCred = b.Config.GetString("scm_bitbucket_access_token")
 	}
 
-	authBaseRemoteUrl, aberr := authGitRemote(
+	authBaseRemoteUrl, aberr := gitRemoteUrl(
+		b.Config,
 		b.PipelineData.GitBaseInfo.Repo.CloneUrl,
 		b.Config.GetString("scm_bitbucket_username"),
 		cloneCred,
@@ -247,8 +382,10 @@ func (b *scmBitbucket) CheckoutPullRequestPayload(payload *Payload) error {
 	}
 	b.PipelineData.GitRemote = authBaseRemoteUrl
 
-	authHeadRemoteUrl, aherr := authGitRemote(
-		b.PipelineData.GitBaseInfo.Repo.CloneUrl,
+	// the pull request head may be in a fork of the base repository.
+	authHeadRemoteUrl, aherr := gitRemoteUrl(
+		b.Config,
+		b.PipelineData.GitHeadInfo.Repo.CloneUrl,
 		b.Config.GetString("scm_bitbucket_username"),
 		cloneCred,
 	)
@@ -268,17 +405,52 @@ func (b *scmBitbucket) CheckoutPullRequestPayload(payload *Payload) error {
 	b.PipelineData.GitLocalPath = gitLocalPath
 	b.PipelineData.GitLocalBranch = fmt.Sprintf("pr_%s", payload.PullRequestNumber)
 
+	requireSignedCommits := b.Config.GetBool("scm_require_signed_commits")
+	if requireSignedCommits {
+		// fetch the pull request head commits, so they can be verified before they are merged.
+		if ferr := utils.GitFetchRemoteUrlRef(
+			b.PipelineData.GitLocalPath,
+			authHeadRemoteUrl,
+			fmt.Sprintf("refs/heads/%s", b.PipelineData.GitHeadInfo.Ref),
+			fmt.Sprintf("refs/remotes/origin/pr/%s/head", payload.PullRequestNumber),
+		); ferr != nil {
+			return ferr
+		}
+		if verr := verifyPullRequestSignatures(b.Config, b.PipelineData); verr != nil {
+			return verr
+		}
+	}
+
 	signature := utils.GitSignature(b.Config.GetString("engine_git_author_name"), b.Config.GetString("engine_git_author_email"))
-	ferr := utils.GitMergeRemoteBranch(b.PipelineData.GitLocalPath, b.PipelineData.GitLocalBranch, b.PipelineData.GitBaseInfo.Ref, authHeadRemoteUrl, b.PipelineData.GitHeadInfo.Ref, signature)
+	mergeStrategy := b.Config.GetString("scm_merge_strategy")
+	ferr := utils.GitMergeRemoteBranch(
+		b.PipelineData.GitLocalPath,
+		b.PipelineData.GitLocalBranch,
+		b.PipelineData.GitBaseInfo.Ref,
+		authHeadRemoteUrl,
+		b.PipelineData.GitHeadInfo.Ref,
+		mergeStrategy,
+		mergeCommitMessage(mergeStrategy, payload),
+		signature,
+	)
 	if ferr != nil {
 		return ferr
 	}
+	if requireSignedCommits {
+		if verr := verifyMergedPullRequestHead(b.PipelineData, utils.GitMergeRemoteBranchRef(b.PipelineData.GitHeadInfo.Ref)); verr != nil {
+			return verr
+		}
+	}
 
 	// show a processing message on the github PR.
 	b.Notify(b.PipelineData.GitHeadInfo.Sha, "pending", "Started processing package. Pull request will be merged automatically when complete.")
 
 	//retrieve and store the nearestTag to this commit.
-	nearestTag, err := utils.GitFindNearestTagName(gitLocalPath)
+	tagPattern, perr := NearestTagPattern(b.Config, b.PipelineData)
+	if perr != nil {
+		return perr
+	}
+	nearestTag, err := utils.GitFindNearestMatchingTagName(gitLocalPath, tagPattern)
 	if err != nil {
 		return nil // we dont care about failures finding the nearest tag, we'll just have an empty changelog.
 	}
@@ -292,10 +464,30 @@ func (b *scmBitbucket) CheckoutPullRequestPayload(payload *Payload) error {
 	return nil
 }
 
+func (b *scmBitbucket) DefaultBranch() (string, error) {
+	// Bitbucket webhook payloads do not include the repository main branch.
+	parts := strings.Split(b.Config.GetString("scm_repo_full_name"), "/")
+	repoData := new(struct {
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	})
+	if err := b.apiRequest("GET", fmt.Sprintf("%s/repositories/%s/%s", bitbucket.GetApiBaseURL(), parts[0], parts[1]), nil, repoData); err != nil {
+		return "", err
+	} else if repoData.MainBranch.Name == "" {
+		return "", errors.ScmPayloadFormatError("Could not determine the main branch of the repository")
+	}
+	return repoData.MainBranch.Name, nil
+}
+
 func (b *scmBitbucket) Publish() error {
 
+	if b.PipelineData.ReleasePullRequest {
+		return b.publishReleasePullRequest()
+	}
+
 	// push the version bumped metadata file + newly created files to
-	perr := utils.GitPush(b.PipelineData.GitLocalPath, b.PipelineData.GitLocalBranch, b.PipelineData.GitBaseInfo.Ref, fmt.Sprintf("v%s", b.PipelineData.ReleaseVersion))
+	perr := utils.GitPush(b.PipelineData.GitLocalPath, b.PipelineData.GitLocalBranch, releaseTargetBranch(b.PipelineData), b.PipelineData.ReleaseTag)
 	if perr != nil {
 		return perr
 	}
@@ -303,6 +495,9 @@ func (b *scmBitbucket) Publish() error {
 	time.Sleep(5 * time.Second)
 
 	//TODO: Bitbucket does not seem to support Github style releases.
+	// we'll link to the tagged source instead, and store the changelog for the pull request summary.
+	b.PipelineData.ReleaseUrl = fmt.Sprintf("https://bitbucket.org/%s/src/%s", b.Config.GetString("scm_repo_full_name"), url.PathEscape(b.PipelineData.ReleaseTag))
+	b.PipelineData.ReleaseChangelog = releaseChangelog(b.PipelineData, b.Config, "bitbucket", nil)
 
 	//// calculate the release sha
 	//releaseSha := utils.LeftPad2Len(b.PipelineData.ReleaseCommit, "0", 40)
@@ -377,13 +572,19 @@ func (b *scmBitbucket) PublishAssets(releaseData interface{}) error {
 			return lerr
 		}
 
-		b.publishAsset(
+		perr := b.publishAsset(
 			b.Client,
 			parts[0],
 			parts[1],
 			artifactNamePopulated,
 			path.Join(b.PipelineData.GitLocalPath, localPathPopulated),
 			5)
+		if perr == nil {
+			b.PipelineData.ReleasePublishedAssets = append(b.PipelineData.ReleasePublishedAssets, pipeline.ScmPublishedAsset{
+				ArtifactName: artifactNamePopulated,
+				DownloadUrl:  fmt.Sprintf("https://bitbucket.org/%s/%s/downloads/%s", parts[0], parts[1], url.PathEscape(artifactNamePopulated)),
+			})
+		}
 	}
 	return nil
 }
@@ -448,6 +649,43 @@ func (b *scmBitbucket) Notify(ref string, state /*pending, failure, success*/ st
 	return err
 }
 
+func (b *scmBitbucket) NotifyStep(ref string, step string, state /*pending, failure, success*/ string, message string, log string) error {
+	//Bitbucket build statuses are keyed per commit, so we'll just update the single CapsuleCD status.
+	return notifyStepStatus(b, ref, state, message)
+}
+
+func (b *scmBitbucket) Summarize(failedStep string, failure error) error {
+	if b.Config.GetBool("scm_disable_pull_request_comment") || !b.PipelineData.IsPullRequest {
+		return nil
+	}
+
+	body := summaryComment(b.PipelineData, failedStep, failure)
+
+	parts := strings.Split(b.Config.GetString("scm_repo_full_name"), "/")
+	prNumber := b.Config.GetString("scm_pull_request")
+	commentsUrl := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%s/comments", bitbucket.GetApiBaseURL(), parts[0], parts[1], prNumber)
+	commentData := map[string]interface{}{
+		"content": map[string]string{"raw": body},
+	}
+
+	// find the summary comment posted by a previous run, so that we can update it in place.
+	// comments are paginated, the `next` link is followed until the last page.
+	pageUrl := commentsUrl
+	for pageUrl != "" {
+		comments := new(scmBitbucketComments)
+		if lerr := b.apiRequest("GET", pageUrl, nil, comments); lerr != nil {
+			return lerr
+		}
+		for _, comment := range comments.Values {
+			if strings.Contains(comment.Content.Raw, summaryCommentMarker) {
+				return b.apiRequest("PUT", fmt.Sprintf("%s/%d", commentsUrl, comment.ID), commentData, nil)
+			}
+		}
+		pageUrl = comments.Next
+	}
+	return b.apiRequest("POST", commentsUrl, commentData, nil)
+}
+
 func (b *scmBitbucket) convertNotifyState(state string) string {
 	switch state {
 	case "pending":
@@ -463,6 +701,143 @@ func (b *scmBitbucket) convertNotifyState(state string) string {
 
 //private
 
+// push the default branch (with the version bump commit) to the release branch, and open (or update) the release pull request.
+// the tag, release and dist will be created when the release pull request is merged.
+func (b *scmBitbucket) publishReleasePullRequest() error {
+	releaseBranch := b.Config.GetString("scm_release_pull_request_branch")
+	baseBranch := releaseTargetBranch(b.PipelineData)
+
+	if perr := pushReleaseBranch(b.PipelineData, b.Config, releaseBranch); perr != nil {
+		return perr
+	}
+
+	b.PipelineData.ReleaseChangelog = releaseChangelog(b.PipelineData, b.Config, "bitbucket", nil)
+	parts := strings.Split(b.Config.GetString("scm_repo_full_name"), "/")
+	prOpts := &bitbucket.PullRequestsOptions{
+		Owner:             parts[0],
+		RepoSlug:          parts[1],
+		Title:             fmt.Sprintf("Release %s", b.PipelineData.ReleaseTag),
+		Description:       releasePullRequestBody(b.PipelineData),
+		SourceBranch:      releaseBranch,
+		DestinationBranch: baseBranch,
+	}
+
+	// find the open release pull request (if any)
+	openPrsMap, lerr := b.Client.Repositories.PullRequests.Gets(&bitbucket.PullRequestsOptions{
+		Owner:    parts[0],
+		RepoSlug: parts[1],
+	})
+	if lerr != nil {
+		return bitbucketError(lerr)
+	}
+	openPrs := new(struct {
+		Values []scmBitbucketPullrequest `mapstructure:"values"`
+	})
+	mapstructure.Decode(openPrsMap, openPrs)
+
+	var releasePrMap interface{}
+	var rerr error
+	for _, openPr := range openPrs.Values {
+		if openPr.Head.Branch.Name == releaseBranch && openPr.Base.Branch.Name == baseBranch {
+			prOpts.ID = strconv.Itoa(openPr.PullRequestNumber)
+			break
+		}
+	}
+	if prOpts.ID != "" {
+		log.Printf("Updating release pull request #%s for version: `%s`", prOpts.ID, b.PipelineData.ReleaseVersion)
+		releasePrMap, rerr = b.Client.Repositories.PullRequests.Update(prOpts)
+	} else {
+		log.Printf("Creating release pull request for version: `%s`", b.PipelineData.ReleaseVersion)
+		releasePrMap, rerr = b.Client.Repositories.PullRequests.Create(prOpts)
+	}
+	if rerr != nil {
+		return bitbucketError(rerr)
+	}
+
+	releasePr := new(scmBitbucketPullrequest)
+	mapstructure.Decode(releasePrMap, releasePr)
+	b.PipelineData.ReleaseUrl = fmt.Sprintf("https://bitbucket.org/%s/pull-requests/%d", b.Config.GetString("scm_repo_full_name"), releasePr.PullRequestNumber)
+	return nil
+}
+
+// check the pull request user against the authorization policies.
+// The Bitbucket API does not expose repository permissions or team membership for other users, so only the
+// scm_authorization_users allowlist is supported.
+// if the user is unauthorized, an explanatory comment will be posted to the pull request.
+func (b *scmBitbucket) authorize(payload *Payload) error {
+	user := authorizationUser(b.Config, payload)
+	if authorizationAllowlisted(b.Config, user) {
+		return nil
+	}
+
+	if !b.Config.GetBool("scm_disable_pull_request_comment") {
+		parts := strings.Split(b.Config.GetString("scm_repo_full_name"), "/")
+		commentsUrl := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%s/comments", bitbucket.GetApiBaseURL(), parts[0], parts[1], payload.PullRequestNumber)
+		cerr := b.apiRequest("POST", commentsUrl, map[string]interface{}{
+			"content": map[string]string{"raw": unauthorizedComment(user)},
+		}, nil)
+		if cerr != nil {
+			log.Printf("An error occured while commenting on the pull request: %s", cerr)
+		}
+	}
+	return errors.ScmUnauthorizedUser(fmt.Sprintf("Pull request was opened by an unauthorized user (%s)", user))
+}
+
+// go-bitbucket does not support creating or updating pull request comments (or retrieving the repository main branch),
+// so we'll make the (authenticated) request ourselves. The response is decoded into result, unless it is nil.
+func (b *scmBitbucket) apiRequest(method string, urlStr string, data interface{}, result interface{}) error {
+	var body []byte
+	if data != nil {
+		jsonBody, jerr := json.Marshal(data)
+		if jerr != nil {
+			return jerr
+		}
+		body = jsonBody
+	}
+
+	req, rerr := http.NewRequest(method, urlStr, bytes.NewReader(body))
+	if rerr != nil {
+		return rerr
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if b.Config.IsSet("scm_bitbucket_password") {
+		req.SetBasicAuth(b.Config.GetString("scm_bitbucket_username"), b.Config.GetString("scm_bitbucket_password"))
+	} else {
+		req.Header.Set("Authorization", "Bearer "+b.Config.GetString("scm_bitbucket_access_token"))
+	}
+
+	resp, derr := b.Client.HttpClient.Do(req)
+	if derr != nil {
+		return derr
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return errors.ScmServerError(fmt.Sprintf("Bitbucket API request failed: %s %s (%s)", method, urlStr, resp.Status))
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("Bitbucket API request failed: %s %s (%s)", method, urlStr, resp.Status)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+// go-bitbucket returns the response status (eg. `503 Service Unavailable`) as the error message for unsuccessful
+// requests. Server errors (5xx) are converted to a (temporary) errors.ScmServerError, other errors are returned as-is.
+func bitbucketError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if statusCode, cerr := strconv.Atoi(strings.SplitN(err.Error(), " ", 2)[0]); cerr == nil && statusCode >= http.StatusInternalServerError && statusCode < 600 {
+		return errors.ScmServerError(fmt.Sprintf("Bitbucket API request failed (%s)", err))
+	}
+	return err
+}
+
 func (b *scmBitbucket) publishAsset(client *bitbucket.Client, repoOwner string, repoName string, assetName, filePath string, retries int) error {
 
 	log.Printf("Attempt (%d) to upload release asset %s from %s", retries, assetName, filePath)
@@ -0,0 +1,187 @@
+package scm
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/go-bitbucket"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestBitbucketError(t *testing.T) {
+	t.Parallel()
+
+	//test & assert
+	require.IsType(t, errors.ScmServerError(""), bitbucketError(stderrors.New("503 Service Unavailable")), "server errors should be retried")
+	require.IsType(t, errors.ScmServerError(""), bitbucketError(stderrors.New("500 Internal Server Error")))
+	require.EqualError(t, bitbucketError(stderrors.New("404 Not Found")), "404 Not Found")
+	require.EqualError(t, bitbucketError(stderrors.New("response body is nil")), "response body is nil")
+	require.NoError(t, bitbucketError(nil))
+}
+
+// a Bitbucket API request received by the test server.
+type bitbucketTestRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// create a Bitbucket scm that sends API requests to a test server. The handler returns the response body for each request.
+func bitbucketTestScm(t *testing.T, mockConfig *mock_config.MockInterface, pipelineData *pipeline.Data, handler func(req bitbucketTestRequest, serverUrl string) interface{}) (*scmBitbucket, *[]bitbucketTestRequest, func()) {
+	requests := []bitbucketTestRequest{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := bitbucketTestRequest{Method: r.Method, Path: r.URL.Path}
+		if r.ContentLength > 0 {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req.Body))
+		}
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(handler(req, server.URL)))
+	}))
+
+	apiBaseUrl := bitbucket.GetApiBaseURL()
+	bitbucket.SetApiBaseURL(server.URL)
+	return &scmBitbucket{Config: mockConfig, PipelineData: pipelineData, Client: bitbucket.NewOAuthbearerToken("placeholder")}, &requests, func() {
+		bitbucket.SetApiBaseURL(apiBaseUrl)
+		server.Close()
+	}
+}
+
+func TestScmBitbucket_Summarize_UpdatesExistingComment(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetBool("scm_disable_pull_request_comment").Return(false)
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
+	mockConfig.EXPECT().GetString("scm_pull_request").Return("12")
+	mockConfig.EXPECT().IsSet("scm_bitbucket_password").Return(false).Times(3)
+	mockConfig.EXPECT().GetString("scm_bitbucket_access_token").Return("placeholder").Times(3)
+	bitbucketScm, requests, closeServer := bitbucketTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req bitbucketTestRequest, serverUrl string) interface{} {
+		if req.Method != "GET" {
+			return map[string]interface{}{"id": 8}
+		} else if req.Path == "/repositories/sparktree/gem_analogj_test/pullrequests/12/comments" {
+			// the summary comment is on the second page of comments.
+			return map[string]interface{}{
+				"values": []map[string]interface{}{{"id": 7, "content": map[string]interface{}{"raw": "LGTM"}}},
+				"next":   serverUrl + "/repositories/sparktree/gem_analogj_test/pullrequests/12/comments/page2",
+			}
+		}
+		return map[string]interface{}{
+			"values": []map[string]interface{}{{"id": 8, "content": map[string]interface{}{"raw": summaryCommentMarker + "\n### :x: Release failed\n"}}},
+		}
+	})
+	defer closeServer()
+
+	//test
+	err := bitbucketScm.Summarize("test_step", stderrors.New("tests failed"))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []bitbucketTestRequest{
+		{Method: "GET", Path: "/repositories/sparktree/gem_analogj_test/pullrequests/12/comments"},
+		{Method: "GET", Path: "/repositories/sparktree/gem_analogj_test/pullrequests/12/comments/page2"},
+		{Method: "PUT", Path: "/repositories/sparktree/gem_analogj_test/pullrequests/12/comments/8", Body: map[string]interface{}{
+			"content": map[string]interface{}{"raw": summaryComment(bitbucketScm.PipelineData, "test_step", stderrors.New("tests failed"))},
+		}},
+	}, *requests, "should update the previous summary comment, instead of creating a new comment")
+}
+
+func TestScmBitbucket_Summarize_CreatesComment(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetBool("scm_disable_pull_request_comment").Return(false)
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
+	mockConfig.EXPECT().GetString("scm_pull_request").Return("12")
+	mockConfig.EXPECT().IsSet("scm_bitbucket_password").Return(false).Times(2)
+	mockConfig.EXPECT().GetString("scm_bitbucket_access_token").Return("placeholder").Times(2)
+	bitbucketScm, requests, closeServer := bitbucketTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req bitbucketTestRequest, serverUrl string) interface{} {
+		if req.Method == "GET" {
+			return map[string]interface{}{
+				"values": []map[string]interface{}{{"id": 7, "content": map[string]interface{}{"raw": "LGTM"}}},
+			}
+		}
+		return map[string]interface{}{"id": 9}
+	})
+	defer closeServer()
+
+	//test
+	err := bitbucketScm.Summarize("test_step", stderrors.New("tests failed"))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []bitbucketTestRequest{
+		{Method: "GET", Path: "/repositories/sparktree/gem_analogj_test/pullrequests/12/comments"},
+		{Method: "POST", Path: "/repositories/sparktree/gem_analogj_test/pullrequests/12/comments", Body: map[string]interface{}{
+			"content": map[string]interface{}{"raw": summaryComment(bitbucketScm.PipelineData, "test_step", stderrors.New("tests failed"))},
+		}},
+	}, *requests, "should create a summary comment")
+}
+
+func TestScmBitbucket_CheckoutPullRequestPayload_Fork(t *testing.T) {
+	//setup
+	parentPath, err := ioutil.TempDir("", "bitbucket_fork")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	basePath := path.Join(parentPath, "base")
+	require.NoError(t, os.MkdirAll(basePath, 0755))
+	releaseBranchTestGit(t, basePath, "init", "-q")
+	releaseBranchTestGit(t, basePath, "checkout", "-q", "-b", "master")
+	releaseBranchTestGit(t, basePath, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	baseSha := releaseBranchTestGit(t, basePath, "rev-parse", "HEAD")
+	forkPath := path.Join(parentPath, "fork")
+	releaseBranchTestGit(t, parentPath, "clone", "-q", basePath, forkPath)
+	releaseBranchTestGit(t, forkPath, "checkout", "-q", "-b", "feature")
+	require.NoError(t, ioutil.WriteFile(path.Join(forkPath, "feature.txt"), []byte("from the fork\n"), 0644))
+	releaseBranchTestGit(t, forkPath, "add", "-A")
+	releaseBranchTestGit(t, forkPath, "commit", "-q", "-m", "feat: fork commit")
+	headSha := releaseBranchTestGit(t, forkPath, "rev-parse", "HEAD")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().IsSet("scm_bitbucket_password").Return(false).AnyTimes()
+	mockConfig.EXPECT().GetString("scm_bitbucket_access_token").Return("").AnyTimes()
+	mockConfig.EXPECT().GetString("scm_bitbucket_username").Return("").MinTimes(1)
+	mockConfig.EXPECT().GetString("scm_git_transport").Return("").MinTimes(1)
+	mockConfig.EXPECT().GetBool("scm_require_signed_commits").Return(false)
+	mockConfig.EXPECT().GetString("engine_git_author_name").Return("CapsuleCD")
+	mockConfig.EXPECT().GetString("engine_git_author_email").Return("CapsuleCD@users.noreply.github.com")
+	mockConfig.EXPECT().GetString("scm_merge_strategy").Return("merge")
+	mockConfig.EXPECT().GetString("scm_notify_source").Return("CapsuleCD")
+	mockConfig.EXPECT().GetString("scm_notify_target_url").Return("https://www.capsulecd.com")
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
+	mockConfig.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}")
+	bitbucketScm, _, closeServer := bitbucketTestScm(t, mockConfig, &pipeline.Data{GitParentPath: parentPath, IsPullRequest: true}, func(req bitbucketTestRequest, serverUrl string) interface{} {
+		return map[string]interface{}{}
+	})
+	defer closeServer()
+	payload := &Payload{
+		Title:             "feat: fork commit",
+		PullRequestNumber: "12",
+		Head:              &pipeline.ScmCommitInfo{Sha: headSha, Ref: "feature", Repo: &pipeline.ScmRepoInfo{CloneUrl: forkPath, Name: "fork", FullName: "contributor/gem_analogj_test"}},
+		Base:              &pipeline.ScmCommitInfo{Sha: baseSha, Ref: "master", Repo: &pipeline.ScmRepoInfo{CloneUrl: basePath, Name: "gem_analogj_test", FullName: "sparktree/gem_analogj_test"}},
+	}
+
+	//test
+	err = bitbucketScm.CheckoutPullRequestPayload(payload)
+
+	//assert
+	require.NoError(t, err, "should fetch the pull request head from the fork")
+	content, rerr := ioutil.ReadFile(path.Join(bitbucketScm.PipelineData.GitLocalPath, "feature.txt"))
+	require.NoError(t, rerr)
+	require.Equal(t, "from the fork\n", string(content))
+	require.Equal(t, headSha, releaseBranchTestGit(t, bitbucketScm.PipelineData.GitLocalPath, "rev-parse", "HEAD^2"), "should merge the fork head")
+}
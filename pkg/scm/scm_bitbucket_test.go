@@ -6,12 +6,14 @@ import (
 	"github.com/analogj/capsulecd/pkg/pipeline"
 	"github.com/analogj/capsulecd/pkg/scm"
 	"github.com/analogj/capsulecd/pkg/utils"
+	"github.com/analogj/go-bitbucket"
 	"github.com/golang/mock/gomock"
 	"github.com/seborama/govcr"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"testing"
@@ -168,9 +170,13 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_RetrievePayload_PullRequest
 	suite.Config.EXPECT().GetString("scm_bitbucket_username").Return(suite.Username)
 	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return(suite.Password)
 	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(false)
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
 	suite.Config.EXPECT().GetString("scm_pull_request").Return("1")
 	suite.Config.EXPECT().IsSet("scm_pull_request").Return(true)
+	suite.Config.EXPECT().GetString("scm_sender").Return("")
+	suite.Config.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
+	suite.Config.EXPECT().GetBool("scm_enable_authorization").Return(false)
 
 	//test
 	testScm, err := scm.Create("bitbucket", suite.PipelineData, suite.Config, suite.Client)
@@ -192,6 +198,7 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_RetrievePayload_PullRequest
 	suite.Config.EXPECT().GetString("scm_bitbucket_username").Return(suite.Username)
 	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return(suite.Password)
 	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(false)
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
 	suite.Config.EXPECT().GetString("scm_pull_request").Return("2")
 	suite.Config.EXPECT().IsSet("scm_pull_request").Return(true)
@@ -215,12 +222,14 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_RetrievePayload_Push() {
 	suite.Config.EXPECT().GetString("scm_bitbucket_username").Return(suite.Username)
 	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return(suite.Password)
 	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(false)
 	suite.Config.EXPECT().IsSet("scm_pull_request").Return(false)
 	suite.Config.EXPECT().GetString("scm_sha").Return("0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c")
 	suite.Config.EXPECT().GetString("scm_branch").Return("master")
 	suite.Config.EXPECT().GetString("scm_clone_url").Return("https://bitbucket.org/sparktree/gem_analogj_test.git")
 	suite.Config.EXPECT().GetString("scm_repo_name").Return("gem_analogj_test")
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
+	suite.Config.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
 
 	//test
 	testScm, err := scm.Create("bitbucket", suite.PipelineData, suite.Config, suite.Client)
@@ -238,6 +247,97 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_RetrievePayload_Push() {
 	require.False(suite.T(), suite.PipelineData.IsPullRequest)
 }
 
+func (suite *ScmBitbucketTestSuite) TestScmBitbucket_RetrievePayload_WebhookPullRequest() {
+	//setup
+	suite.Config.EXPECT().IsSet("scm_bitbucket_username").Return(true)
+	suite.Config.EXPECT().IsSet("scm_bitbucket_password").MinTimes(1).Return(true)
+	suite.Config.EXPECT().GetString("scm_bitbucket_username").Return(suite.Username)
+	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return(suite.Password)
+	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(true)
+	suite.Config.EXPECT().GetString("scm_payload_file").Return(path.Join("testdata", "webhooks", "bitbucket_pullrequest.json"))
+	suite.Config.EXPECT().IsSet("scm_pull_request").Return(false)
+	suite.Config.EXPECT().Set("scm_pull_request", "1")
+	suite.Config.EXPECT().IsSet("scm_repo_full_name").Return(false)
+	suite.Config.EXPECT().Set("scm_repo_full_name", "sparktree/gem_analogj_test")
+	suite.Config.EXPECT().IsSet("scm_sender").Return(false)
+	suite.Config.EXPECT().Set("scm_sender", "sparktree")
+	suite.Config.EXPECT().GetString("scm_sender").Return("sparktree")
+	suite.Config.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
+	suite.Config.EXPECT().GetBool("scm_enable_authorization").Return(false)
+
+	//test
+	testScm, err := scm.Create("bitbucket", suite.PipelineData, suite.Config, suite.Client)
+	require.NoError(suite.T(), err)
+	payload, perr := testScm.RetrievePayload()
+	require.NoError(suite.T(), perr)
+
+	//assert
+	require.True(suite.T(), suite.PipelineData.IsPullRequest)
+	require.Equal(suite.T(), "1", payload.PullRequestNumber)
+	require.Equal(suite.T(), "AnalogJ", payload.Author)
+	require.Equal(suite.T(), "sparktree", payload.Sender)
+	require.Equal(suite.T(), "Adds a keybindings config file. [minor]", payload.Body)
+	require.Equal(suite.T(), "feature/key-bindings", payload.Head.Ref)
+	require.Equal(suite.T(), "https://bitbucket.org/AnalogJ/gem_analogj_test.git", payload.Head.Repo.CloneUrl)
+	require.Equal(suite.T(), "master", payload.Base.Ref)
+}
+
+func (suite *ScmBitbucketTestSuite) TestScmBitbucket_RetrievePayload_WebhookPush() {
+	//setup
+	suite.Config.EXPECT().IsSet("scm_bitbucket_username").Return(true)
+	suite.Config.EXPECT().IsSet("scm_bitbucket_password").MinTimes(1).Return(true)
+	suite.Config.EXPECT().GetString("scm_bitbucket_username").Return(suite.Username)
+	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return(suite.Password)
+	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(true)
+	suite.Config.EXPECT().GetString("scm_payload_file").Return(path.Join("testdata", "webhooks", "bitbucket_push.json"))
+	suite.Config.EXPECT().IsSet("scm_repo_full_name").Return(true)
+	suite.Config.EXPECT().IsSet("scm_sender").Return(true)
+	suite.Config.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
+
+	//test
+	testScm, err := scm.Create("bitbucket", suite.PipelineData, suite.Config, suite.Client)
+	require.NoError(suite.T(), err)
+	payload, perr := testScm.RetrievePayload()
+	require.NoError(suite.T(), perr)
+
+	//assert
+	require.False(suite.T(), suite.PipelineData.IsPullRequest)
+	require.Equal(suite.T(), "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c", payload.Head.Sha)
+	require.Equal(suite.T(), "master", payload.Head.Ref)
+	require.Equal(suite.T(), "https://bitbucket.org/sparktree/gem_analogj_test.git", payload.Head.Repo.CloneUrl)
+	require.Equal(suite.T(), "sparktree/gem_analogj_test", payload.Head.Repo.FullName)
+}
+
+func (suite *ScmBitbucketTestSuite) TestScmBitbucket_DefaultBranch() {
+	//setup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(suite.T(), "/repositories/sparktree/gem_analogj_test", r.URL.Path)
+		w.Write([]byte(`{"full_name": "sparktree/gem_analogj_test", "mainbranch": {"type": "branch", "name": "main"}}`))
+	}))
+	defer server.Close()
+	apiBaseUrl := bitbucket.GetApiBaseURL()
+	bitbucket.SetApiBaseURL(server.URL)
+	defer bitbucket.SetApiBaseURL(apiBaseUrl)
+
+	suite.Config.EXPECT().IsSet("scm_bitbucket_username").Return(true)
+	suite.Config.EXPECT().IsSet("scm_bitbucket_password").MinTimes(1).Return(true)
+	suite.Config.EXPECT().GetString("scm_bitbucket_username").MinTimes(1).Return(suite.Username)
+	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return(suite.Password)
+	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
+
+	//test
+	testScm, err := scm.Create("bitbucket", suite.PipelineData, suite.Config, &http.Client{})
+	require.NoError(suite.T(), err)
+	defaultBranch, derr := testScm.DefaultBranch()
+
+	//assert
+	require.NoError(suite.T(), derr)
+	require.Equal(suite.T(), "main", defaultBranch)
+}
+
 func (suite *ScmBitbucketTestSuite) TestScmBitbucket_CheckoutPushPayload() {
 	//setup
 	suite.Config.EXPECT().IsSet("scm_bitbucket_username").Return(true)
@@ -246,12 +346,16 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_CheckoutPushPayload() {
 	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return("")
 	// (so that git doesnt fail on placeholder token)
 	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(false)
+	suite.Config.EXPECT().GetString("scm_git_transport").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
 	suite.Config.EXPECT().IsSet("scm_pull_request").Return(false)
 	suite.Config.EXPECT().GetString("scm_sha").Return("4aa9e889f0beddbc6248f8efa09cecf9a85435a5")
 	suite.Config.EXPECT().GetString("scm_branch").Return("master")
 	suite.Config.EXPECT().GetString("scm_clone_url").Return("https://bitbucket.org/sparktree/gem_analogj_test.git")
 	suite.Config.EXPECT().GetString("scm_repo_name").Return("gem_analogj_test")
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test")
+	suite.Config.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
 
 	//test
 	githubScm, err := scm.Create("bitbucket", suite.PipelineData, suite.Config, suite.Client)
@@ -294,11 +398,19 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_CheckoutPullRequestPayload(
 	suite.Config.EXPECT().GetString("scm_bitbucket_username").MinTimes(1).Return("")
 	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return("")
 	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(false)
+	suite.Config.EXPECT().GetString("scm_git_transport").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test").MinTimes(1)
 	suite.Config.EXPECT().GetString("scm_pull_request").Return("3")
 	suite.Config.EXPECT().IsSet("scm_pull_request").Return(true)
+	suite.Config.EXPECT().GetString("scm_sender").Return("")
+	suite.Config.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
+	suite.Config.EXPECT().GetBool("scm_enable_authorization").Return(false)
 	suite.Config.EXPECT().GetString("engine_git_author_name").Return("CapsuleCD")
 	suite.Config.EXPECT().GetString("engine_git_author_email").Return("CapsuleCD@users.noreply.github.com")
+	suite.Config.EXPECT().GetBool("scm_require_signed_commits").Return(false)
+	suite.Config.EXPECT().GetString("scm_merge_strategy").Return("merge")
 	suite.Config.EXPECT().GetString("scm_notify_source").Return("CapsuleCD")
 	suite.Config.EXPECT().GetString("scm_notify_target_url").Return("https://www.capsulecd.com")
 
@@ -327,9 +439,15 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_Publish() {
 	suite.Config.EXPECT().GetString("scm_bitbucket_username").MinTimes(1).Return("")
 	suite.Config.EXPECT().GetString("scm_bitbucket_password").MinTimes(1).Return("")
 	suite.Config.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	suite.Config.EXPECT().IsSet("scm_payload_file").Return(false)
+	suite.Config.EXPECT().GetString("scm_git_transport").Return("").MinTimes(1)
+	suite.Config.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
 	suite.Config.EXPECT().GetString("scm_repo_full_name").Return("sparktree/gem_analogj_test").MinTimes(1)
 	suite.Config.EXPECT().GetString("scm_pull_request").Return("4")
 	suite.Config.EXPECT().IsSet("scm_pull_request").Return(true)
+	suite.Config.EXPECT().GetString("scm_sender").Return("")
+	suite.Config.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
+	suite.Config.EXPECT().GetBool("scm_enable_authorization").Return(false)
 	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
 
 	//test
@@ -342,6 +460,7 @@ func (suite *ScmBitbucketTestSuite) TestScmBitbucket_Publish() {
 	_, terr := utils.GitTag(suite.PipelineData.GitLocalPath, "v1.0.0", "test git tag message", signature)
 	require.NoError(suite.T(), terr)
 	suite.PipelineData.ReleaseVersion = "1.0.0"
+	suite.PipelineData.ReleaseTag = "v1.0.0"
 	pberr := testScm.Publish()
 	require.NoError(suite.T(), pberr)
 
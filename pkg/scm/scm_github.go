@@ -6,6 +6,7 @@ import (
 	"github.com/analogj/capsulecd/pkg/pipeline"
 	"github.com/analogj/capsulecd/pkg/utils"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/google/go-github/github"
 	"golang.org/x/oauth2"
@@ -24,12 +25,19 @@ type scmGithub struct {
 	Config       config.Interface
 	PipelineData *pipeline.Data
 	Client       *github.Client
+
+	//check runs that have been started for each pipeline step (only used when scm_github_enable_checks is true)
+	checkRuns map[string]*github.CheckRun
+
+	//default branch of the repository, populated from the webhook payload (if available)
+	defaultBranch string
 }
 
 func (g *scmGithub) Init(pipelineData *pipeline.Data, myconfig config.Interface, client *http.Client) error {
 	g.PipelineData = pipelineData
 	g.Config = myconfig
 	g.Config.SetDefault("scm_github_access_token_type", "user")
+	g.Config.SetDefault("scm_authorization_permissions", []string{"admin", "maintain", "write"})
 
 	if !g.Config.IsSet("scm_github_access_token") {
 		return errors.ScmAuthenticationFailed("Missing github access token")
@@ -69,11 +77,12 @@ func (g *scmGithub) Init(pipelineData *pipeline.Data, myconfig config.Interface,
 }
 
 func (g *scmGithub) RetrievePayload() (*Payload, error) {
-	if !g.Config.IsSet("scm_pull_request") {
-		log.Print("This is not a pull request. No automatic continuous deployment processing required. Continuous Integration testing will continue.")
-		g.PipelineData.IsPullRequest = false
+	if g.Config.IsSet("scm_payload_file") {
+		return g.retrieveWebhookPayload()
+	}
 
-		return &Payload{
+	if !g.Config.IsSet("scm_pull_request") {
+		return g.pushPayload(&Payload{
 			Head: &pipeline.ScmCommitInfo{
 				Sha: g.Config.GetString("scm_sha"),
 				Ref: g.Config.GetString("scm_branch"),
@@ -82,57 +91,137 @@ func (g *scmGithub) RetrievePayload() (*Payload, error) {
 					Name:     g.Config.GetString("scm_repo_name"),
 					FullName: g.Config.GetString("scm_repo_full_name"),
 				}},
-		}, nil
+		})
 		//make this as similar to a pull request as possible
 	} else {
-		g.PipelineData.IsPullRequest = true
 		ctx := context.Background()
 		parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
 		pr, _, err := g.Client.PullRequests.Get(ctx, parts[0], parts[1], g.Config.GetInt("scm_pull_request"))
 
-		if err != nil {
+		if errResponse, ok := err.(*github.ErrorResponse); ok && errResponse.Response != nil && errResponse.Response.StatusCode >= http.StatusInternalServerError {
+			return nil, errors.ScmServerError(fmt.Sprintf("Could not retrieve pull request from Github: %s", err))
+		} else if err != nil {
 			return nil, errors.ScmAuthenticationFailed(fmt.Sprintf("Could not retrieve pull request from Github: %s", err))
 		}
+		return g.pullRequestPayload(pr)
+	}
+}
 
-		//validate pullrequest
-		if pr.GetState() != "open" {
-			return nil, errors.ScmPayloadUnsupported("Pull request has an invalid action")
-		}
-		if pr.Base.Repo.GetDefaultBranch() != pr.Base.GetRef() {
-			return nil, errors.ScmPayloadUnsupported(fmt.Sprintf("Pull request is not being created against the default branch of this repository (%s vs %s)", pr.Base.Repo.GetDefaultBranch(), pr.Base.GetRef()))
+// build the payload from a Github `pull_request` or `push` webhook event body (scm_payload_file), instead of the Github API.
+// config values used throughout the pipeline (scm_pull_request, scm_repo_full_name, scm_sender) are populated from
+// the event, unless they are already set.
+func (g *scmGithub) retrieveWebhookPayload() (*Payload, error) {
+	body, err := readWebhookPayload(g.Config.GetString("scm_payload_file"))
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequestEvent := new(github.PullRequestEvent)
+	if jerr := json.Unmarshal(body, pullRequestEvent); jerr != nil {
+		return nil, errors.ScmPayloadFormatError(fmt.Sprintf("Could not parse Github webhook payload: %s", jerr))
+	}
+	if pullRequestEvent.PullRequest != nil {
+		pr := pullRequestEvent.PullRequest
+		setDefaultConfig(g.Config, "scm_pull_request", pr.GetNumber())
+		setDefaultConfig(g.Config, "scm_repo_full_name", pr.Base.Repo.GetFullName())
+		setDefaultConfig(g.Config, "scm_sender", pullRequestEvent.Sender.GetLogin())
+		return g.pullRequestPayload(pr)
+	}
+
+	pushEvent := new(github.PushEvent)
+	if jerr := json.Unmarshal(body, pushEvent); jerr != nil {
+		return nil, errors.ScmPayloadFormatError(fmt.Sprintf("Could not parse Github webhook payload: %s", jerr))
+	}
+	if pushEvent.Ref == nil || pushEvent.Repo == nil {
+		return nil, errors.ScmPayloadUnsupported("Github webhook payload must be a `pull_request` or `push` event")
+	} else if !strings.HasPrefix(pushEvent.GetRef(), "refs/heads/") {
+		return nil, errors.ScmPayloadUnsupported(fmt.Sprintf("Github push event must be for a branch (%s)", pushEvent.GetRef()))
+	}
+	setDefaultConfig(g.Config, "scm_repo_full_name", pushEvent.Repo.GetFullName())
+	setDefaultConfig(g.Config, "scm_sender", pushEvent.Sender.GetLogin())
+	g.defaultBranch = pushEvent.Repo.GetDefaultBranch()
+
+	return g.pushPayload(&Payload{
+		Sender: pushEvent.Sender.GetLogin(),
+		Head: &pipeline.ScmCommitInfo{
+			Sha: pushEvent.GetAfter(),
+			Ref: strings.TrimPrefix(pushEvent.GetRef(), "refs/heads/"),
+			Repo: &pipeline.ScmRepoInfo{
+				CloneUrl: pushEvent.Repo.GetCloneURL(),
+				Name:     pushEvent.Repo.GetName(),
+				FullName: pushEvent.Repo.GetFullName(),
+			}},
+	})
+}
+
+func (g *scmGithub) pushPayload(payload *Payload) (*Payload, error) {
+	log.Print("This is not a pull request. A release will be created from the pushed commit, if it has changed since the nearest tag.")
+	g.PipelineData.IsPullRequest = false
+
+	releaseBranch, rerr := releaseBranchRule(g.Config, payload.Head.Ref)
+	if rerr != nil {
+		return nil, rerr
+	}
+	g.PipelineData.ReleaseBranch = releaseBranch
+	return payload, nil
+}
+
+func (g *scmGithub) pullRequestPayload(pr *github.PullRequest) (*Payload, error) {
+	g.PipelineData.IsPullRequest = true
+	g.defaultBranch = pr.Base.Repo.GetDefaultBranch()
+
+	//validate pullrequest
+	if pr.GetState() != "open" {
+		return nil, errors.ScmPayloadUnsupported("Pull request has an invalid action")
+	}
+	if pr.Base.Repo.GetDefaultBranch() != pr.Base.GetRef() {
+		//pull requests against non-default branches are only supported if they match a release branch rule.
+		releaseBranch, rerr := releaseBranchRule(g.Config, pr.Base.GetRef())
+		if rerr != nil {
+			return nil, rerr
+		} else if releaseBranch == nil {
+			return nil, errors.ScmReleaseBranchUnsupported(fmt.Sprintf("Pull request is not being created against the default branch of this repository, or a release branch (%s vs %s)", pr.Base.Repo.GetDefaultBranch(), pr.Base.GetRef()))
 		}
-		// check the payload push user.
-
-		//TODO: figure out how to do optional authenication. possible options, Source USER, token based auth, no auth when used with capsulecd.com.
-		// unless @source_client.collaborator?(payload['base']['repo']['full_name'], payload['user']['login'])
-		//
-		//   @source_client.add_comment(payload['base']['repo']['full_name'], payload['number'], CapsuleCD::BotUtils.pull_request_comment)
-		//   fail CapsuleCD::Error::SourceUnauthorizedUser, 'Pull request was opened by an unauthorized user'
-		// end
-
-		return &Payload{
-			Title:             pr.GetTitle(),
-			PullRequestNumber: strconv.Itoa(pr.GetNumber()),
-			Head: &pipeline.ScmCommitInfo{
-				Sha: pr.Head.GetSHA(),
-				Ref: pr.Head.GetRef(),
-				Repo: &pipeline.ScmRepoInfo{
-					CloneUrl: pr.Head.Repo.GetCloneURL(),
-					Name:     pr.Head.Repo.GetName(),
-					FullName: pr.Head.Repo.GetFullName(),
-				},
+		g.PipelineData.ReleaseBranch = releaseBranch
+	}
+	labels := []string{}
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
+	}
+	payload := &Payload{
+		Title:             pr.GetTitle(),
+		Body:              pr.GetBody(),
+		Labels:            labels,
+		PullRequestNumber: strconv.Itoa(pr.GetNumber()),
+		Author:            pr.User.GetLogin(),
+		Sender:            g.Config.GetString("scm_sender"),
+		Head: &pipeline.ScmCommitInfo{
+			Sha: pr.Head.GetSHA(),
+			Ref: pr.Head.GetRef(),
+			Repo: &pipeline.ScmRepoInfo{
+				CloneUrl: pr.Head.Repo.GetCloneURL(),
+				Name:     pr.Head.Repo.GetName(),
+				FullName: pr.Head.Repo.GetFullName(),
 			},
-			Base: &pipeline.ScmCommitInfo{
-				Sha: pr.Base.GetSHA(),
-				Ref: pr.Base.GetRef(),
-				Repo: &pipeline.ScmRepoInfo{
-					CloneUrl: pr.Base.Repo.GetCloneURL(),
-					Name:     pr.Base.Repo.GetName(),
-					FullName: pr.Base.Repo.GetFullName(),
-				},
+		},
+		Base: &pipeline.ScmCommitInfo{
+			Sha: pr.Base.GetSHA(),
+			Ref: pr.Base.GetRef(),
+			Repo: &pipeline.ScmRepoInfo{
+				CloneUrl: pr.Base.Repo.GetCloneURL(),
+				Name:     pr.Base.Repo.GetName(),
+				FullName: pr.Base.Repo.GetFullName(),
 			},
-		}, nil
+		},
+	}
+
+	// check that the pull request user is authorized to create a release.
+	if g.Config.GetBool("scm_enable_authorization") {
+		if aerr := g.authorize(payload); aerr != nil {
+			return nil, aerr
+		}
 	}
+	return payload, nil
 }
 
 func (g *scmGithub) CheckoutPushPayload(payload *Payload) error {
@@ -155,7 +244,7 @@ func (g *scmGithub) CheckoutPushPayload(payload *Payload) error {
 	}
 
 
-	authRemote, aerr := authGitRemote(g.PipelineData.GitHeadInfo.Repo.CloneUrl, gitRemoteUsername, gitRemotePassword)
+	authRemote, aerr := gitRemoteUrl(g.Config, g.PipelineData.GitHeadInfo.Repo.CloneUrl, gitRemoteUsername, gitRemotePassword)
 	if aerr != nil {
 		return aerr
 	}
@@ -176,8 +265,17 @@ func (g *scmGithub) CheckoutPushPayload(payload *Payload) error {
 		return cerr
 	}
 
+	// release the pushed commit, even if the branch has since been updated (the release push will be rejected).
+	if rerr := utils.GitResetToCommit(g.PipelineData.GitLocalPath, g.PipelineData.GitHeadInfo.Sha); rerr != nil {
+		return rerr
+	}
+
 	//retrieve and store the nearestTag to this commit.
-	nearestTag, err := utils.GitFindNearestTagName(gitLocalPath)
+	tagPattern, perr := NearestTagPattern(g.Config, g.PipelineData)
+	if perr != nil {
+		return perr
+	}
+	nearestTag, err := utils.GitFindNearestMatchingTagName(gitLocalPath, tagPattern)
 	if err != nil {
 		return nil // we dont care about failures finding the nearest tag, we'll just have an empty changelog.
 	}
@@ -215,7 +313,7 @@ func (g *scmGithub) CheckoutPullRequestPayload(payload *Payload) error {
 		gitRemotePassword = ""
 	}
 
-	authRemote, aerr := authGitRemote(g.PipelineData.GitBaseInfo.Repo.CloneUrl, gitRemoteUsername, gitRemotePassword)
+	authRemote, aerr := gitRemoteUrl(g.Config, g.PipelineData.GitBaseInfo.Repo.CloneUrl, gitRemoteUsername, gitRemotePassword)
 	if aerr != nil {
 		return aerr
 	}
@@ -233,16 +331,70 @@ func (g *scmGithub) CheckoutPullRequestPayload(payload *Payload) error {
 	g.PipelineData.GitLocalPath = gitLocalPath
 	g.PipelineData.GitLocalBranch = fmt.Sprintf("pr_%s", payload.PullRequestNumber)
 
-	ferr := utils.GitFetchPullRequest(g.PipelineData.GitLocalPath, payload.PullRequestNumber, g.PipelineData.GitLocalBranch, "refs/pull/%s/merge", "")
-	if ferr != nil {
-		return ferr
+	requireSignedCommits := g.Config.GetBool("scm_require_signed_commits")
+	if requireSignedCommits {
+		// fetch the pull request head commits, so they can be verified before they are merged.
+		if _, _, ferr := utils.GitFetchRemoteRef(
+			g.PipelineData.GitLocalPath,
+			fmt.Sprintf("refs/pull/%s/head", payload.PullRequestNumber),
+			fmt.Sprintf("refs/remotes/origin/pr/%s/head", payload.PullRequestNumber),
+		); ferr != nil {
+			return ferr
+		}
+		if verr := verifyPullRequestSignatures(g.Config, g.PipelineData); verr != nil {
+			return verr
+		}
+	}
+
+	mergeStrategy := g.Config.GetString("scm_merge_strategy")
+	if mergeStrategy == "" || mergeStrategy == utils.GitMergeStrategyMerge {
+		// Github has already created a merge commit for this pull request.
+		ferr := utils.GitFetchPullRequest(g.PipelineData.GitLocalPath, payload.PullRequestNumber, g.PipelineData.GitLocalBranch, "refs/pull/%s/merge", "")
+		if ferr != nil {
+			return ferr
+		}
+		if requireSignedCommits {
+			// the pull request head is the second parent of the merge commit.
+			if verr := verifyMergedPullRequestHead(g.PipelineData, fmt.Sprintf("refs/remotes/origin/pr/%s/merge^2", payload.PullRequestNumber)); verr != nil {
+				return verr
+			}
+		}
+	} else {
+		authHeadRemote, aherr := gitRemoteUrl(g.Config, g.PipelineData.GitHeadInfo.Repo.CloneUrl, gitRemoteUsername, gitRemotePassword)
+		if aherr != nil {
+			return aherr
+		}
+
+		signature := utils.GitSignature(g.Config.GetString("engine_git_author_name"), g.Config.GetString("engine_git_author_email"))
+		ferr := utils.GitMergeRemoteBranch(
+			g.PipelineData.GitLocalPath,
+			g.PipelineData.GitLocalBranch,
+			g.PipelineData.GitBaseInfo.Ref,
+			authHeadRemote,
+			g.PipelineData.GitHeadInfo.Ref,
+			mergeStrategy,
+			mergeCommitMessage(mergeStrategy, payload),
+			signature,
+		)
+		if ferr != nil {
+			return ferr
+		}
+		if requireSignedCommits {
+			if verr := verifyMergedPullRequestHead(g.PipelineData, utils.GitMergeRemoteBranchRef(g.PipelineData.GitHeadInfo.Ref)); verr != nil {
+				return verr
+			}
+		}
 	}
 
 	// show a processing message on the github PR.
 	g.Notify(g.PipelineData.GitHeadInfo.Sha, "pending", "Started processing package. Pull request will be merged automatically when complete.")
 
 	//retrieve and store the nearestTag to this commit.
-	nearestTag, err := utils.GitFindNearestTagName(gitLocalPath)
+	tagPattern, perr := NearestTagPattern(g.Config, g.PipelineData)
+	if perr != nil {
+		return perr
+	}
+	nearestTag, err := utils.GitFindNearestMatchingTagName(gitLocalPath, tagPattern)
 	if err != nil {
 		return nil // we dont care about failures finding the nearest tag, we'll just have an empty changelog.
 	}
@@ -256,13 +408,36 @@ func (g *scmGithub) CheckoutPullRequestPayload(payload *Payload) error {
 	return nil
 }
 
+func (g *scmGithub) DefaultBranch() (string, error) {
+	if g.defaultBranch != "" {
+		return g.defaultBranch, nil
+	}
+
+	ctx := context.Background()
+	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+	repoData, _, err := g.Client.Repositories.Get(ctx, parts[0], parts[1])
+	if err != nil {
+		return "", err
+	} else if repoData.GetDefaultBranch() == "" {
+		return "", errors.ScmPayloadFormatError("Could not determine the default branch of the repository")
+	}
+	g.defaultBranch = repoData.GetDefaultBranch()
+	return g.defaultBranch, nil
+}
+
 func (g *scmGithub) Publish() error {
+	if g.PipelineData.ReleasePullRequest {
+		return g.publishReleasePullRequest()
+	}
 
 	// push the version bumped metadata file + newly created files to
-	perr := utils.GitPush(g.PipelineData.GitLocalPath, g.PipelineData.GitLocalBranch, g.PipelineData.GitBaseInfo.Ref, fmt.Sprintf("v%s", g.PipelineData.ReleaseVersion))
+	perr := utils.GitPush(g.PipelineData.GitLocalPath, g.PipelineData.GitLocalBranch, releaseTargetBranch(g.PipelineData), g.PipelineData.ReleaseTag)
 	if perr != nil {
 		return perr
 	}
+	if cerr := g.closeMergedPullRequest(); cerr != nil {
+		log.Printf("An error occured while closing the pull request: %s", cerr)
+	}
 	//sleep because github needs time to process the new tag.
 	time.Sleep(5 * time.Second)
 
@@ -270,48 +445,35 @@ func (g *scmGithub) Publish() error {
 	releaseSha := utils.LeftPad2Len(g.PipelineData.ReleaseCommit, "0", 40)
 
 	//get the release changelog
-	// logic is complicated.
-	// If this is a push we can only do a tag-tag Changelog
-	// If this is a pull request we can do either
-	// if disable_nearest_tag_changelog is true, we must attempt
-	var releaseBody string = ""
-	if g.PipelineData.GitNearestTag != nil && !g.Config.GetBool("scm_disable_nearest_tag_changelog") {
-		releaseBody, _ = utils.GitGenerateChangelog(
-			g.PipelineData.GitLocalPath,
-			g.PipelineData.GitNearestTag.TagShortName,
-			g.PipelineData.GitLocalBranch,
-		)
-	}
-	//fallback to using diff if pullrequest.
-	if g.PipelineData.IsPullRequest && releaseBody == "" {
-		releaseBody, _ = utils.GitGenerateChangelog(
-			g.PipelineData.GitLocalPath,
-			g.PipelineData.GitBaseInfo.Sha,
-			g.PipelineData.GitHeadInfo.Sha,
-		)
-	}
+	releaseBody := releaseChangelog(g.PipelineData, g.Config, "github", g.pullRequestLabels)
+	g.PipelineData.ReleaseChangelog = releaseBody
 
 	//create release.
 	ctx := context.Background()
 	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
-	version := fmt.Sprintf("v%s", g.PipelineData.ReleaseVersion)
+	version := g.PipelineData.ReleaseTag
 
 	log.Printf("Creating new release for `%s/%s` with version: `%s` on commit: `%s`. Commit message: `%s`", parts[0], parts[1], version, releaseSha, releaseBody)
 
-	releaseData, _, rerr := g.Client.Repositories.CreateRelease(
-		ctx,
-		parts[0],
-		parts[1],
-		&github.RepositoryRelease{
-			TargetCommitish: &releaseSha,
-			Body:            &releaseBody,
-			TagName:         &version,
-			Name:            &version,
-		},
-	)
+	release := &github.RepositoryRelease{
+		TargetCommitish: &releaseSha,
+		Body:            &releaseBody,
+		TagName:         &version,
+		Name:            &version,
+		Prerelease:      &g.PipelineData.ReleasePrerelease,
+	}
+	var releaseData *github.RepositoryRelease
+	var rerr error
+	if g.PipelineData.ReleaseBranch != nil {
+		// releases from maintenance branches should never be marked as the "latest" release.
+		releaseData, rerr = g.createNonLatestRelease(ctx, parts[0], parts[1], release)
+	} else {
+		releaseData, _, rerr = g.Client.Repositories.CreateRelease(ctx, parts[0], parts[1], release)
+	}
 	if rerr != nil {
 		return rerr
 	}
+	g.PipelineData.ReleaseUrl = releaseData.GetHTMLURL()
 
 	if perr := g.PublishAssets(releaseData.GetID()); perr != nil {
 		log.Print("An error occured while publishing assets:")
@@ -322,6 +484,41 @@ func (g *scmGithub) Publish() error {
 	return nil
 }
 
+// squash & rebase merges create new commits, so Github cannot detect that the pull request was merged when the base
+// branch is pushed. These pull requests are closed explicitly.
+func (g *scmGithub) closeMergedPullRequest() error {
+	mergeStrategy := g.Config.GetString("scm_merge_strategy")
+	if !g.PipelineData.IsPullRequest || (mergeStrategy != utils.GitMergeStrategySquash && mergeStrategy != utils.GitMergeStrategyRebase) {
+		return nil
+	}
+
+	ctx := context.Background()
+	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+	_, _, err := g.Client.PullRequests.Edit(ctx, parts[0], parts[1], g.Config.GetInt("scm_pull_request"), &github.PullRequest{
+		State: github.String("closed"),
+	})
+	return err
+}
+
+// the go-github RepositoryRelease type does not support the `make_latest` attribute, so the request is built manually.
+func (g *scmGithub) createNonLatestRelease(ctx context.Context, owner string, repo string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	body := struct {
+		*github.RepositoryRelease
+		MakeLatest string `json:"make_latest"`
+	}{release, "false"}
+
+	req, err := g.Client.NewRequest("POST", fmt.Sprintf("repos/%s/%s/releases", owner, repo), body)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseData := new(github.RepositoryRelease)
+	if _, err := g.Client.Do(ctx, req, releaseData); err != nil {
+		return nil, err
+	}
+	return releaseData, nil
+}
+
 func (g *scmGithub) PublishAssets(releaseData interface{}) error {
 	//releaseData should be an ID (int)
 	releaseId, ok := releaseData.(int64)
@@ -344,7 +541,7 @@ func (g *scmGithub) PublishAssets(releaseData interface{}) error {
 			return lerr
 		}
 
-		g.publishGithubAsset(
+		asset, perr := g.publishGithubAsset(
 			g.Client,
 			ctx,
 			parts[0],
@@ -353,6 +550,12 @@ func (g *scmGithub) PublishAssets(releaseData interface{}) error {
 			path.Join(g.PipelineData.GitLocalPath, localPathPopulated),
 			releaseId,
 			5)
+		if perr == nil {
+			g.PipelineData.ReleasePublishedAssets = append(g.PipelineData.ReleasePublishedAssets, pipeline.ScmPublishedAsset{
+				ArtifactName: artifactNamePopulated,
+				DownloadUrl:  asset.GetBrowserDownloadURL(),
+			})
+		}
 	}
 	return nil
 }
@@ -394,6 +597,7 @@ func (g *scmGithub) Cleanup() error {
 func (g *scmGithub) Notify(ref string, state /*pending, failure, success*/ string, message string) error {
 	targetURL := g.Config.GetString("scm_notify_target_url")
 	contextApp := g.Config.GetString("scm_notify_source")
+	message = truncateStatusDescription(message)
 
 	ctx := context.Background()
 	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
@@ -406,26 +610,345 @@ func (g *scmGithub) Notify(ref string, state /*pending, failure, success*/ strin
 	return serr
 }
 
+func (g *scmGithub) NotifyStep(ref string, step string, state /*pending, failure, success*/ string, message string, log string) error {
+	if !g.Config.GetBool("scm_github_enable_checks") {
+		return notifyStepStatus(g, ref, state, message)
+	}
+
+	// Checks API is only available when authenticated as a Github App.
+	// see https://developer.github.com/v3/checks/runs/
+	targetURL := g.Config.GetString("scm_notify_target_url")
+	checkName := fmt.Sprintf("%s / %s", g.Config.GetString("scm_notify_source"), step)
+	now := github.Timestamp{Time: time.Now()}
+
+	var headBranch string
+	if g.PipelineData.GitHeadInfo != nil {
+		headBranch = g.PipelineData.GitHeadInfo.Ref
+	}
+
+	ctx := context.Background()
+	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+
+	if g.checkRuns == nil {
+		g.checkRuns = map[string]*github.CheckRun{}
+	}
+	checkRun, started := g.checkRuns[step]
+
+	if state == "pending" && started {
+		//the step is waiting (eg. for the release lock), leave the check run in progress and update the output.
+		_, _, uerr := g.Client.Checks.UpdateCheckRun(ctx, parts[0], parts[1], checkRun.GetID(), github.UpdateCheckRunOptions{
+			Name:   checkName,
+			Output: g.checkRunOutput(step, state, message, log),
+		})
+		return uerr
+	} else if state == "pending" {
+		status := "in_progress"
+		createdRun, _, cerr := g.Client.Checks.CreateCheckRun(ctx, parts[0], parts[1], github.CreateCheckRunOptions{
+			Name:       checkName,
+			HeadBranch: headBranch,
+			HeadSHA:    ref,
+			DetailsURL: &targetURL,
+			Status:     &status,
+			StartedAt:  &now,
+			Output:     g.checkRunOutput(step, state, message, log),
+		})
+		if cerr != nil {
+			return cerr
+		}
+		g.checkRuns[step] = createdRun
+		return nil
+	}
+
+	status := "completed"
+	conclusion := state
+	if !started {
+		//the step never reported that it started, create a completed check run.
+		_, _, cerr := g.Client.Checks.CreateCheckRun(ctx, parts[0], parts[1], github.CreateCheckRunOptions{
+			Name:        checkName,
+			HeadBranch:  headBranch,
+			HeadSHA:     ref,
+			DetailsURL:  &targetURL,
+			Status:      &status,
+			Conclusion:  &conclusion,
+			StartedAt:   &now,
+			CompletedAt: &now,
+			Output:      g.checkRunOutput(step, state, message, log),
+		})
+		return cerr
+	}
+
+	_, _, uerr := g.Client.Checks.UpdateCheckRun(ctx, parts[0], parts[1], checkRun.GetID(), github.UpdateCheckRunOptions{
+		Name:        checkName,
+		DetailsURL:  &targetURL,
+		Status:      &status,
+		Conclusion:  &conclusion,
+		CompletedAt: &now,
+		Output:      g.checkRunOutput(step, state, message, log),
+	})
+	delete(g.checkRuns, step)
+	return uerr
+}
+
+func (g *scmGithub) Summarize(failedStep string, failure error) error {
+	if g.Config.GetBool("scm_disable_pull_request_comment") || !g.PipelineData.IsPullRequest {
+		return nil
+	}
+
+	body := summaryComment(g.PipelineData, failedStep, failure)
+
+	ctx := context.Background()
+	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+	prNumber := g.Config.GetInt("scm_pull_request")
+
+	// find the summary comment posted by a previous run, so that we can update it in place.
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, lerr := g.Client.Issues.ListComments(ctx, parts[0], parts[1], prNumber, opts)
+		if lerr != nil {
+			return lerr
+		}
+		for _, comment := range comments {
+			if strings.Contains(comment.GetBody(), summaryCommentMarker) {
+				_, _, eerr := g.Client.Issues.EditComment(ctx, parts[0], parts[1], comment.GetID(), &github.IssueComment{Body: &body})
+				return eerr
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	_, _, cerr := g.Client.Issues.CreateComment(ctx, parts[0], parts[1], prNumber, &github.IssueComment{Body: &body})
+	return cerr
+}
+
 //private
 
-func (g *scmGithub) publishGithubAsset(client *github.Client, ctx context.Context, repoOwner string, repoName string, assetName, filePath string, releaseID int64, retries int) error {
+// push the default branch (with the version bump commit) to the release branch, and open (or update) the release pull request.
+// the tag, release and dist will be created when the release pull request is merged.
+func (g *scmGithub) publishReleasePullRequest() error {
+	releaseBranch := g.Config.GetString("scm_release_pull_request_branch")
+	baseBranch := releaseTargetBranch(g.PipelineData)
+
+	if perr := pushReleaseBranch(g.PipelineData, g.Config, releaseBranch); perr != nil {
+		return perr
+	}
+
+	g.PipelineData.ReleaseChangelog = releaseChangelog(g.PipelineData, g.Config, "github", g.pullRequestLabels)
+	title := fmt.Sprintf("Release %s", g.PipelineData.ReleaseTag)
+	body := releasePullRequestBody(g.PipelineData)
+
+	ctx := context.Background()
+	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+
+	openPrs, _, lerr := g.Client.PullRequests.List(ctx, parts[0], parts[1], &github.PullRequestListOptions{
+		State: "open",
+		Head:  fmt.Sprintf("%s:%s", parts[0], releaseBranch),
+		Base:  baseBranch,
+	})
+	if lerr != nil {
+		return lerr
+	}
+
+	var releasePr *github.PullRequest
+	var rerr error
+	if len(openPrs) > 0 {
+		log.Printf("Updating release pull request #%d for version: `%s`", openPrs[0].GetNumber(), g.PipelineData.ReleaseVersion)
+		releasePr, _, rerr = g.Client.PullRequests.Edit(ctx, parts[0], parts[1], openPrs[0].GetNumber(), &github.PullRequest{
+			Title: &title,
+			Body:  &body,
+		})
+	} else {
+		log.Printf("Creating release pull request for version: `%s`", g.PipelineData.ReleaseVersion)
+		releasePr, _, rerr = g.Client.PullRequests.Create(ctx, parts[0], parts[1], &github.NewPullRequest{
+			Title: &title,
+			Head:  &releaseBranch,
+			Base:  &baseBranch,
+			Body:  &body,
+		})
+	}
+	if rerr != nil {
+		return rerr
+	}
+	g.PipelineData.ReleaseUrl = releasePr.GetHTMLURL()
+	return nil
+}
+
+// check the pull request user against the authorization policies. The user is authorized if any policy matches:
+// - user is included in scm_authorization_users
+// - user has one of the scm_authorization_permissions on the repository (admin, maintain, write by default)
+// - user is an active member of one of the scm_authorization_teams (`org/team-slug`)
+// if the user is unauthorized, an explanatory comment will be posted to the pull request.
+func (g *scmGithub) authorize(payload *Payload) error {
+	user := authorizationUser(g.Config, payload)
+	authorized, err := g.isAuthorized(user)
+	if err != nil {
+		return err
+	} else if authorized {
+		return nil
+	}
+
+	if !g.Config.GetBool("scm_disable_pull_request_comment") {
+		ctx := context.Background()
+		parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+		prNumber, _ := strconv.Atoi(payload.PullRequestNumber)
+		body := unauthorizedComment(user)
+		if _, _, cerr := g.Client.Issues.CreateComment(ctx, parts[0], parts[1], prNumber, &github.IssueComment{Body: &body}); cerr != nil {
+			log.Printf("An error occured while commenting on the pull request: %s", cerr)
+		}
+	}
+	return errors.ScmUnauthorizedUser(fmt.Sprintf("Pull request was opened by an unauthorized user (%s)", user))
+}
+
+func (g *scmGithub) isAuthorized(user string) (bool, error) {
+	if user == "" {
+		return false, nil
+	} else if authorizationAllowlisted(g.Config, user) {
+		return true, nil
+	}
+
+	ctx := context.Background()
+	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+
+	// non-collaborators may not have a permission level (404), or it may not be visible to the token (403), so these
+	// errors are not fatal, we'll continue checking teams. Other errors are returned, so they can be retried.
+	permissionLevel, _, perr := g.Client.Repositories.GetPermissionLevel(ctx, parts[0], parts[1], user)
+	if perr != nil && !githubErrorStatus(perr, http.StatusNotFound, http.StatusForbidden) {
+		return false, perr
+	} else if perr != nil {
+		log.Printf("Could not retrieve repository permission level for %s: %s", user, perr)
+	} else {
+		for _, permission := range g.Config.GetStringSlice("scm_authorization_permissions") {
+			if permission == permissionLevel.GetPermission() {
+				return true, nil
+			}
+		}
+	}
+
+	for _, team := range g.Config.GetStringSlice("scm_authorization_teams") {
+		isMember, terr := g.isTeamMember(ctx, team, user)
+		if terr != nil {
+			return false, terr
+		} else if isMember {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// team should be specified as `org/team-slug`
+func (g *scmGithub) isTeamMember(ctx context.Context, team string, user string) (bool, error) {
+	teamParts := strings.SplitN(team, "/", 2)
+	if len(teamParts) != 2 {
+		return false, errors.ScmUnauthorizedUser(fmt.Sprintf("Invalid team specified in scm_authorization_teams (%s), should be `org/team-slug`", team))
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		teams, resp, lerr := g.Client.Teams.ListTeams(ctx, teamParts[0], opts)
+		if lerr != nil {
+			return false, lerr
+		}
+		for _, orgTeam := range teams {
+			if orgTeam.GetSlug() != teamParts[1] {
+				continue
+			}
+			membership, _, merr := g.Client.Teams.GetTeamMembership(ctx, orgTeam.GetID(), user)
+			if githubErrorStatus(merr, http.StatusNotFound) {
+				// Github returns a 404 if the user is not a member of the team.
+				return false, nil
+			} else if merr != nil {
+				return false, merr
+			}
+			return membership.GetState() == "active", nil
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return false, nil
+}
+
+// check if the error is a Github API error response with one of the status codes.
+func githubErrorStatus(err error, statusCodes ...int) bool {
+	errResponse, ok := err.(*github.ErrorResponse)
+	if !ok || errResponse.Response == nil {
+		return false
+	}
+	for _, statusCode := range statusCodes {
+		if errResponse.Response.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Github rejects commit statuses with a description longer than 140 characters, so longer messages are truncated.
+// details (eg. the version bump reason) belong in the pull request summary comment instead.
+func truncateStatusDescription(message string) string {
+	const maxLength = 140
+	runes := []rune(message)
+	if len(runes) <= maxLength {
+		return message
+	}
+	return string(runes[:maxLength-3]) + "..."
+}
+
+// generate the markdown output for a check run. The log tail is only included for failed steps.
+func (g *scmGithub) checkRunOutput(step string, state string, message string, log string) *github.CheckRunOutput {
+	title := fmt.Sprintf("%s: %s", step, state)
+	output := &github.CheckRunOutput{
+		Title:   &title,
+		Summary: &message,
+	}
+
+	if state == "failure" && log != "" {
+		text := fmt.Sprintf("### Log output (last lines)\n\n```\n%s\n```\n", escapeCodeFence(log))
+		output.Text = &text
+	}
+	return output
+}
+
+func (g *scmGithub) publishGithubAsset(client *github.Client, ctx context.Context, repoOwner string, repoName string, assetName, filePath string, releaseID int64, retries int) (*github.ReleaseAsset, error) {
 
 	log.Printf("Attempt (%d) to upload release asset %s from %s", retries, assetName, filePath)
 	f, err := os.Open(filePath)
 	if err != nil {
 		log.Print(err)
-		return err
+		return nil, err
 	}
 
-	_, _, err = client.Repositories.UploadReleaseAsset(ctx, repoOwner, repoName, releaseID, &github.UploadOptions{
+	asset, _, err := client.Repositories.UploadReleaseAsset(ctx, repoOwner, repoName, releaseID, &github.UploadOptions{
 		Name: assetName,
 	}, f)
 
 	if err != nil && retries > 0 {
 		fmt.Println("artifact upload errored out, retrying in one second. Err:", err)
 		time.Sleep(time.Second)
-		err = g.publishGithubAsset(client, ctx, repoOwner, repoName, assetName, filePath, releaseID, retries-1)
+		asset, err = g.publishGithubAsset(client, ctx, repoOwner, repoName, assetName, filePath, releaseID, retries-1)
 	}
 
-	return err
+	return asset, err
+}
+
+// retrieve the labels of a pull request, used to group changelog entries by label.
+func (g *scmGithub) pullRequestLabels(number string) ([]string, error) {
+	prNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(g.Config.GetString("scm_repo_full_name"), "/")
+	labels, _, err := g.Client.Issues.ListLabelsByIssue(context.Background(), parts[0], parts[1], prNumber, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, err
+	}
+
+	labelNames := []string{}
+	for _, label := range labels {
+		labelNames = append(labelNames, label.GetName())
+	}
+	return labelNames, nil
 }
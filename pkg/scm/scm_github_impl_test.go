@@ -0,0 +1,457 @@
+package scm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/queue"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// a Github API request received by the test server.
+type githubTestRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// an error response returned by a test server handler, eg. a 404 when the user is not a team member.
+type githubTestError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+}
+
+// create a Github scm that sends API requests to a test server. The handler returns the response body for each request,
+// or a githubTestError.
+func githubTestScm(t *testing.T, mockConfig *mock_config.MockInterface, pipelineData *pipeline.Data, handler func(req githubTestRequest) interface{}) (*scmGithub, *[]githubTestRequest, func()) {
+	requests := []githubTestRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := githubTestRequest{Method: r.Method, Path: r.URL.Path}
+		if r.ContentLength != 0 {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req.Body))
+		}
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		resp := handler(req)
+		if errResp, ok := resp.(githubTestError); ok {
+			w.WriteHeader(errResp.StatusCode)
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+
+	client := github.NewClient(nil)
+	baseUrl, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseUrl
+
+	return &scmGithub{Config: mockConfig, PipelineData: pipelineData, Client: client}, &requests, server.Close
+}
+
+func TestScmGithub_closeMergedPullRequest(t *testing.T) {
+	for _, mergeStrategy := range []string{"squash", "rebase"} {
+		//setup
+		mockCtrl := gomock.NewController(t)
+		mockConfig := mock_config.NewMockInterface(mockCtrl)
+		mockConfig.EXPECT().GetString("scm_merge_strategy").Return(mergeStrategy)
+		mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+		mockConfig.EXPECT().GetInt("scm_pull_request").Return(12)
+		githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req githubTestRequest) interface{} {
+			return map[string]interface{}{"number": 12, "state": "closed"}
+		})
+
+		//test
+		err := githubScm.closeMergedPullRequest()
+
+		//assert
+		require.NoError(t, err)
+		require.Equal(t, []githubTestRequest{
+			{Method: "PATCH", Path: "/repos/AnalogJ/capsulecd/pulls/12", Body: map[string]interface{}{"state": "closed"}},
+		}, *requests, "should close the pull request merged with the %s strategy", mergeStrategy)
+		closeServer()
+		mockCtrl.Finish()
+	}
+}
+
+func TestScmGithub_closeMergedPullRequest_Merge(t *testing.T) {
+	for _, mergeStrategy := range []string{"", "merge", "fast-forward-only"} {
+		//setup
+		mockCtrl := gomock.NewController(t)
+		mockConfig := mock_config.NewMockInterface(mockCtrl)
+		mockConfig.EXPECT().GetString("scm_merge_strategy").Return(mergeStrategy)
+		githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req githubTestRequest) interface{} {
+			return map[string]interface{}{}
+		})
+
+		//test
+		err := githubScm.closeMergedPullRequest()
+
+		//assert
+		require.NoError(t, err)
+		require.Empty(t, *requests, "Github detects pull requests merged with the %s strategy", mergeStrategy)
+		closeServer()
+		mockCtrl.Finish()
+	}
+}
+
+func TestScmGithub_NotifyStep_PendingAfterStarted(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetBool("scm_github_enable_checks").Return(true).AnyTimes()
+	mockConfig.EXPECT().GetString("scm_notify_target_url").Return("https://ci.example.com/builds/1").AnyTimes()
+	mockConfig.EXPECT().GetString("scm_notify_source").Return("CapsuleCD").AnyTimes()
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd").AnyTimes()
+	githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{}, func(req githubTestRequest) interface{} {
+		return map[string]interface{}{"id": 4}
+	})
+	defer closeServer()
+
+	//test
+	serr := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "release_lock_step", "pending", "Started 'release_lock_step' step.", "")
+	werr := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "release_lock_step", "pending", "Waiting to release", "")
+
+	//assert
+	require.NoError(t, serr)
+	require.NoError(t, werr)
+	require.Len(t, *requests, 2)
+	require.Equal(t, "POST", (*requests)[0].Method)
+	require.Equal(t, "/repos/AnalogJ/capsulecd/check-runs", (*requests)[0].Path)
+	require.Equal(t, githubTestRequest{
+		Method: "PATCH",
+		Path:   "/repos/AnalogJ/capsulecd/check-runs/4",
+		Body: map[string]interface{}{
+			"name":   "CapsuleCD / release_lock_step",
+			"output": map[string]interface{}{"title": "release_lock_step: pending", "summary": "Waiting to release"},
+		},
+	}, (*requests)[1], "should update the started check run, leaving it in progress")
+}
+
+// create a Github scm with the Checks API enabled, and a test server that responds with check run 4.
+func githubChecksTestScm(t *testing.T, mockCtrl *gomock.Controller) (*scmGithub, *[]githubTestRequest, func()) {
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetBool("scm_github_enable_checks").Return(true).AnyTimes()
+	mockConfig.EXPECT().GetString("scm_notify_target_url").Return("https://ci.example.com/builds/1").AnyTimes()
+	mockConfig.EXPECT().GetString("scm_notify_source").Return("CapsuleCD").AnyTimes()
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd").AnyTimes()
+	pipelineData := &pipeline.Data{GitHeadInfo: &pipeline.ScmCommitInfo{Sha: "49f5bfbf4610f0c2a54d33945521051ba92b2eac", Ref: "feature"}}
+	return githubTestScm(t, mockConfig, pipelineData, func(req githubTestRequest) interface{} {
+		return map[string]interface{}{"id": 4}
+	})
+}
+
+// remove the (current time) timestamp fields from a check run request body, after ensuring they were sent.
+func withoutCheckRunTimestamps(t *testing.T, req githubTestRequest, fields ...string) githubTestRequest {
+	for _, field := range fields {
+		require.NotEmpty(t, req.Body[field], "should set %s", field)
+		delete(req.Body, field)
+	}
+	return req
+}
+
+func TestScmGithub_NotifyStep_Checks_Success(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	githubScm, requests, closeServer := githubChecksTestScm(t, mockCtrl)
+	defer closeServer()
+
+	//test
+	serr := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "test_step", "pending", "Started 'test_step' step.", "")
+	cerr := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "test_step", "success", "Completed 'test_step' step.", "build output")
+
+	//assert
+	require.NoError(t, serr)
+	require.NoError(t, cerr)
+	require.Len(t, *requests, 2)
+	require.Equal(t, githubTestRequest{
+		Method: "POST",
+		Path:   "/repos/AnalogJ/capsulecd/check-runs",
+		Body: map[string]interface{}{
+			"name":        "CapsuleCD / test_step",
+			"head_branch": "feature",
+			"head_sha":    "49f5bfbf4610f0c2a54d33945521051ba92b2eac",
+			"details_url": "https://ci.example.com/builds/1",
+			"status":      "in_progress",
+			"output":      map[string]interface{}{"title": "test_step: pending", "summary": "Started 'test_step' step."},
+		},
+	}, withoutCheckRunTimestamps(t, (*requests)[0], "started_at"), "should create an in progress check run")
+	require.Equal(t, githubTestRequest{
+		Method: "PATCH",
+		Path:   "/repos/AnalogJ/capsulecd/check-runs/4",
+		Body: map[string]interface{}{
+			"name":        "CapsuleCD / test_step",
+			"details_url": "https://ci.example.com/builds/1",
+			"status":      "completed",
+			"conclusion":  "success",
+			"output":      map[string]interface{}{"title": "test_step: success", "summary": "Completed 'test_step' step."},
+		},
+	}, withoutCheckRunTimestamps(t, (*requests)[1], "completed_at"), "should complete the check run, without the log output")
+}
+
+func TestScmGithub_NotifyStep_Checks_Failure(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	githubScm, requests, closeServer := githubChecksTestScm(t, mockCtrl)
+	defer closeServer()
+
+	//test
+	serr := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "test_step", "pending", "Started 'test_step' step.", "")
+	ferr := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "test_step", "failure", "Error: 'tests failed'", "--- FAIL: TestExample\n```\nFAIL")
+
+	//assert
+	require.NoError(t, serr)
+	require.NoError(t, ferr)
+	require.Len(t, *requests, 2)
+	require.Equal(t, githubTestRequest{
+		Method: "PATCH",
+		Path:   "/repos/AnalogJ/capsulecd/check-runs/4",
+		Body: map[string]interface{}{
+			"name":        "CapsuleCD / test_step",
+			"details_url": "https://ci.example.com/builds/1",
+			"status":      "completed",
+			"conclusion":  "failure",
+			"output": map[string]interface{}{
+				"title":   "test_step: failure",
+				"summary": "Error: 'tests failed'",
+				"text":    "### Log output (last lines)\n\n```\n--- FAIL: TestExample\n` ` `\nFAIL\n```\n",
+			},
+		},
+	}, withoutCheckRunTimestamps(t, (*requests)[1], "completed_at"), "should fail the check run, with the (escaped) log tail")
+}
+
+func TestScmGithub_NotifyStep_Checks_CompletedWithoutStart(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	githubScm, requests, closeServer := githubChecksTestScm(t, mockCtrl)
+	defer closeServer()
+
+	//test
+	err := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "release_mode_step", "success", "Skipped release: 'no changes'", "")
+
+	//assert
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	require.Equal(t, githubTestRequest{
+		Method: "POST",
+		Path:   "/repos/AnalogJ/capsulecd/check-runs",
+		Body: map[string]interface{}{
+			"name":        "CapsuleCD / release_mode_step",
+			"head_branch": "feature",
+			"head_sha":    "49f5bfbf4610f0c2a54d33945521051ba92b2eac",
+			"details_url": "https://ci.example.com/builds/1",
+			"status":      "completed",
+			"conclusion":  "success",
+			"output":      map[string]interface{}{"title": "release_mode_step: success", "summary": "Skipped release: 'no changes'"},
+		},
+	}, withoutCheckRunTimestamps(t, (*requests)[0], "started_at", "completed_at"), "should create a completed check run")
+}
+
+func TestScmGithub_NotifyStep_ChecksDisabled(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetBool("scm_github_enable_checks").Return(false)
+	mockConfig.EXPECT().GetString("scm_notify_target_url").Return("https://ci.example.com/builds/1")
+	mockConfig.EXPECT().GetString("scm_notify_source").Return("CapsuleCD")
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{}, func(req githubTestRequest) interface{} {
+		return map[string]interface{}{}
+	})
+	defer closeServer()
+
+	//test
+	err := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "test_step", "failure", "Error: 'tests failed'", "FAIL")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []githubTestRequest{{
+		Method: "POST",
+		Path:   "/repos/AnalogJ/capsulecd/statuses/49f5bfbf4610f0c2a54d33945521051ba92b2eac",
+		Body: map[string]interface{}{
+			"state":       "failure",
+			"target_url":  "https://ci.example.com/builds/1",
+			"description": "Error: 'tests failed'",
+			"context":     "CapsuleCD",
+		},
+	}}, *requests, "should fall back to a commit status")
+}
+
+func TestScmGithub_Summarize_UpdatesExistingComment(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetBool("scm_disable_pull_request_comment").Return(false)
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	mockConfig.EXPECT().GetInt("scm_pull_request").Return(12)
+	githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req githubTestRequest) interface{} {
+		if req.Method == "GET" {
+			return []map[string]interface{}{
+				{"id": 7, "body": "LGTM"},
+				{"id": 8, "body": summaryCommentMarker + "\n### :x: Release failed\n"},
+			}
+		}
+		return map[string]interface{}{"id": 8}
+	})
+	defer closeServer()
+
+	//test
+	err := githubScm.Summarize("test_step", errors.New("tests failed"))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []githubTestRequest{
+		{Method: "GET", Path: "/repos/AnalogJ/capsulecd/issues/12/comments"},
+		{Method: "PATCH", Path: "/repos/AnalogJ/capsulecd/issues/comments/8", Body: map[string]interface{}{
+			"body": summaryComment(githubScm.PipelineData, "test_step", errors.New("tests failed")),
+		}},
+	}, *requests, "should update the previous summary comment, instead of creating a new comment")
+}
+
+func TestScmGithub_Summarize_CreatesComment(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetBool("scm_disable_pull_request_comment").Return(false)
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	mockConfig.EXPECT().GetInt("scm_pull_request").Return(12)
+	githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req githubTestRequest) interface{} {
+		if req.Method == "GET" {
+			return []map[string]interface{}{{"id": 7, "body": "LGTM"}}
+		}
+		return map[string]interface{}{"id": 9}
+	})
+	defer closeServer()
+
+	//test
+	err := githubScm.Summarize("test_step", errors.New("tests failed"))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []githubTestRequest{
+		{Method: "GET", Path: "/repos/AnalogJ/capsulecd/issues/12/comments"},
+		{Method: "POST", Path: "/repos/AnalogJ/capsulecd/issues/12/comments", Body: map[string]interface{}{
+			"body": summaryComment(githubScm.PipelineData, "test_step", errors.New("tests failed")),
+		}},
+	}, *requests, "should create a summary comment")
+}
+
+func TestScmGithub_Notify_TruncatesDescription(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetString("scm_notify_target_url").Return("https://ci.example.com/builds/1")
+	mockConfig.EXPECT().GetString("scm_notify_source").Return("CapsuleCD")
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{}, func(req githubTestRequest) interface{} {
+		return map[string]interface{}{}
+	})
+	defer closeServer()
+	message := "Skipped release: " + strings.Repeat("a very long reason, ", 10)
+
+	//test
+	err := githubScm.Notify("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "success", message)
+
+	//assert
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	description := (*requests)[0].Body["description"].(string)
+	require.Len(t, description, 140, "Github rejects descriptions longer than 140 characters")
+	require.Equal(t, message[:137]+"...", description)
+}
+
+func TestTruncateStatusDescription(t *testing.T) {
+	t.Parallel()
+
+	//test & assert
+	require.Equal(t, "Completed 'test_step' step.", truncateStatusDescription("Completed 'test_step' step."))
+	require.Equal(t, strings.Repeat("a", 140), truncateStatusDescription(strings.Repeat("a", 140)))
+	require.Equal(t, strings.Repeat("é", 137)+"...", truncateStatusDescription(strings.Repeat("é", 141)), "should not split multi-byte characters")
+}
+
+func TestScmGithub_isAuthorized_NotFound(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_authorization_users").Return([]string{})
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	mockConfig.EXPECT().GetStringSlice("scm_authorization_teams").Return([]string{"AnalogJ/maintainers"})
+	githubScm, requests, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req githubTestRequest) interface{} {
+		if req.Path == "/orgs/AnalogJ/teams" {
+			return []map[string]interface{}{{"id": 1, "slug": "maintainers"}}
+		}
+		return githubTestError{StatusCode: http.StatusNotFound, Message: "Not Found"}
+	})
+	defer closeServer()
+
+	//test
+	authorized, err := githubScm.isAuthorized("contributor")
+
+	//assert
+	require.NoError(t, err, "404s mean the user is not a collaborator or team member")
+	require.False(t, authorized)
+	require.Equal(t, []githubTestRequest{
+		{Method: "GET", Path: "/repos/AnalogJ/capsulecd/collaborators/contributor/permission"},
+		{Method: "GET", Path: "/orgs/AnalogJ/teams"},
+		{Method: "GET", Path: "/teams/1/memberships/contributor"},
+	}, *requests)
+}
+
+func TestScmGithub_isAuthorized_PermissionLevelServerError(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetStringSlice("scm_authorization_users").Return([]string{})
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	githubScm, _, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req githubTestRequest) interface{} {
+		return githubTestError{StatusCode: http.StatusInternalServerError, Message: "Server Error"}
+	})
+	defer closeServer()
+
+	//test
+	authorized, err := githubScm.isAuthorized("contributor")
+
+	//assert
+	require.Error(t, err, "should return the error, so it can be retried")
+	require.True(t, queue.IsTransient(err))
+	require.False(t, authorized)
+}
+
+func TestScmGithub_isTeamMember_ServerError(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	githubScm, _, closeServer := githubTestScm(t, mockConfig, &pipeline.Data{IsPullRequest: true}, func(req githubTestRequest) interface{} {
+		if req.Path == "/orgs/AnalogJ/teams" {
+			return []map[string]interface{}{{"id": 1, "slug": "maintainers"}}
+		}
+		return githubTestError{StatusCode: http.StatusInternalServerError, Message: "Server Error"}
+	})
+	defer closeServer()
+
+	//test
+	isMember, err := githubScm.isTeamMember(context.Background(), "AnalogJ/maintainers", "contributor")
+
+	//assert
+	require.Error(t, err, "only a 404 means the user is not a team member")
+	require.True(t, queue.IsTransient(err))
+	require.False(t, isMember)
+}
@@ -1,6 +1,7 @@
 package scm_test
 
 import (
+	"errors"
 	"github.com/analogj/capsulecd/pkg/scm"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -14,6 +15,7 @@ import (
 	"golang.org/x/oauth2"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 )
@@ -139,9 +141,12 @@ func TestScmGithub_RetrievePayload_PullRequest(t *testing.T) {
 	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
 	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
 	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(false)
 	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/cookbook_analogj_test")
 	mockConfig.EXPECT().GetInt("scm_pull_request").Return(12)
 	mockConfig.EXPECT().IsSet("scm_pull_request").Return(true)
+	mockConfig.EXPECT().GetString("scm_sender").Return("")
+	mockConfig.EXPECT().GetBool("scm_enable_authorization").Return(false)
 	pipelineData := new(pipeline.Data)
 	client := githubVcrSetup(t)
 
@@ -166,6 +171,7 @@ func TestScmGithub_RetrievePayload_PullRequest_InvalidState(t *testing.T) {
 	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
 	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
 	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(false)
 	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/cookbook_analogj_test")
 	mockConfig.EXPECT().GetInt("scm_pull_request").Return(11)
 	mockConfig.EXPECT().IsSet("scm_pull_request").Return(true)
@@ -193,12 +199,14 @@ func TestScmGithub_RetrievePayload_Push(t *testing.T) {
 	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
 	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
 	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(false)
 	mockConfig.EXPECT().IsSet("scm_pull_request").Return(false)
 	mockConfig.EXPECT().GetString("scm_sha").Return("0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c")
 	mockConfig.EXPECT().GetString("scm_branch").Return("master")
 	mockConfig.EXPECT().GetString("scm_clone_url").Return("https://github.com/analogj/capsulecd.git")
 	mockConfig.EXPECT().GetString("scm_repo_name").Return("capsulecd")
 	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	mockConfig.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
 	pipelineData := new(pipeline.Data)
 	client := githubVcrSetup(t)
 
@@ -218,6 +226,134 @@ func TestScmGithub_RetrievePayload_Push(t *testing.T) {
 	require.False(t, pipelineData.IsPullRequest)
 }
 
+func TestScmGithub_RetrievePayload_WebhookPullRequest(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
+	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
+	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(true)
+	mockConfig.EXPECT().GetString("scm_payload_file").Return(path.Join("testdata", "webhooks", "github_pull_request.json"))
+	mockConfig.EXPECT().IsSet("scm_pull_request").Return(false)
+	mockConfig.EXPECT().Set("scm_pull_request", 12)
+	mockConfig.EXPECT().IsSet("scm_repo_full_name").Return(true)
+	mockConfig.EXPECT().IsSet("scm_sender").Return(false)
+	mockConfig.EXPECT().Set("scm_sender", "AnalogJ")
+	mockConfig.EXPECT().GetString("scm_sender").Return("AnalogJ")
+	mockConfig.EXPECT().GetBool("scm_enable_authorization").Return(false)
+	pipelineData := new(pipeline.Data)
+
+	//test
+	githubScm, err := scm.Create("github", pipelineData, mockConfig, &http.Client{})
+	require.NoError(t, err)
+	payload, perr := githubScm.RetrievePayload()
+	require.NoError(t, perr)
+
+	//assert
+	require.True(t, pipelineData.IsPullRequest)
+	require.Equal(t, "12", payload.PullRequestNumber)
+	require.Equal(t, "octocat", payload.Author)
+	require.Equal(t, "AnalogJ", payload.Sender)
+	require.Equal(t, "Adds a keybindings config file. [minor]", payload.Body)
+	require.Equal(t, []string{"enhancement", "release:minor"}, payload.Labels)
+	require.Equal(t, "f3d573aacc59f2a6e2318dd140f3091c16b4b8fe", payload.Head.Sha)
+	require.Equal(t, "feature/key-bindings", payload.Head.Ref)
+	require.Equal(t, "https://github.com/octocat/cookbook_analogj_test.git", payload.Head.Repo.CloneUrl)
+	require.Equal(t, "master", payload.Base.Ref)
+	require.Equal(t, "AnalogJ/cookbook_analogj_test", payload.Base.Repo.FullName)
+}
+
+func TestScmGithub_RetrievePayload_WebhookPush(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
+	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
+	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(true)
+	mockConfig.EXPECT().GetString("scm_payload_file").Return(path.Join("testdata", "webhooks", "github_push.json"))
+	mockConfig.EXPECT().IsSet("scm_repo_full_name").Return(false)
+	mockConfig.EXPECT().Set("scm_repo_full_name", "AnalogJ/capsulecd")
+	mockConfig.EXPECT().IsSet("scm_sender").Return(true)
+	mockConfig.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
+	pipelineData := new(pipeline.Data)
+
+	//test
+	githubScm, err := scm.Create("github", pipelineData, mockConfig, &http.Client{})
+	require.NoError(t, err)
+	payload, perr := githubScm.RetrievePayload()
+	require.NoError(t, perr)
+
+	//assert
+	require.False(t, pipelineData.IsPullRequest)
+	require.Equal(t, "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c", payload.Head.Sha)
+	require.Equal(t, "master", payload.Head.Ref)
+	require.Equal(t, "https://github.com/AnalogJ/capsulecd.git", payload.Head.Repo.CloneUrl)
+	require.Equal(t, "capsulecd", payload.Head.Repo.Name)
+	require.Equal(t, "AnalogJ/capsulecd", payload.Head.Repo.FullName)
+
+	defaultBranch, derr := githubScm.DefaultBranch()
+	require.NoError(t, derr)
+	require.Equal(t, "master", defaultBranch, "should use the default branch from the webhook payload, without calling the API")
+}
+
+func TestScmGithub_DefaultBranch(t *testing.T) {
+	//setup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/repos/AnalogJ/capsulecd", r.URL.Path)
+		w.Write([]byte(`{"full_name": "AnalogJ/capsulecd", "default_branch": "main"}`))
+	}))
+	defer server.Close()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
+	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(true)
+	mockConfig.EXPECT().GetString("scm_github_api_endpoint").Return(server.URL + "/")
+	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	pipelineData := new(pipeline.Data)
+
+	//test
+	githubScm, err := scm.Create("github", pipelineData, mockConfig, &http.Client{})
+	require.NoError(t, err)
+	defaultBranch, derr := githubScm.DefaultBranch()
+
+	//assert
+	require.NoError(t, derr)
+	require.Equal(t, "main", defaultBranch)
+}
+
+func TestScmGithub_RetrievePayload_WebhookInvalid(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
+	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
+	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(true)
+	mockConfig.EXPECT().GetString("scm_payload_file").Return(path.Join("testdata", "webhooks", "bitbucket_push.json"))
+	pipelineData := new(pipeline.Data)
+
+	//test
+	githubScm, err := scm.Create("github", pipelineData, mockConfig, &http.Client{})
+	require.NoError(t, err)
+	payload, perr := githubScm.RetrievePayload()
+
+	//assert
+	require.Error(t, perr, "should return an error when the webhook is not a Github pull_request or push event")
+	require.Nil(t, payload)
+}
+
 func TestScmGithub_CheckoutPushPayload(t *testing.T) {
 	//setup
 	mockCtrl := gomock.NewController(t)
@@ -230,12 +366,16 @@ func TestScmGithub_CheckoutPushPayload(t *testing.T) {
 	mockConfig.EXPECT().GetString("scm_github_access_token_type").Return("user") //set the Access Token Type
 	// (so that git doesnt fail on placeholder token)
 	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(false)
+	mockConfig.EXPECT().GetString("scm_git_transport").Return("").MinTimes(1)
+	mockConfig.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
 	mockConfig.EXPECT().IsSet("scm_pull_request").Return(false)
 	mockConfig.EXPECT().GetString("scm_sha").Return("0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c")
 	mockConfig.EXPECT().GetString("scm_branch").Return("master")
 	mockConfig.EXPECT().GetString("scm_clone_url").Return("https://github.com/analogj/capsulecd.git")
 	mockConfig.EXPECT().GetString("scm_repo_name").Return("capsulecd")
 	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/capsulecd")
+	mockConfig.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Return(nil)
 	pipelineData := new(pipeline.Data)
 	client := githubVcrSetup(t)
 
@@ -289,9 +429,16 @@ func TestScmGithub_CheckoutPullRequestPayload(t *testing.T) {
 	mockConfig.EXPECT().GetString("scm_github_access_token").Return("")
 	mockConfig.EXPECT().GetString("scm_github_access_token_type").Return("user")
 	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().IsSet("scm_payload_file").Return(false)
+	mockConfig.EXPECT().GetString("scm_git_transport").Return("").MinTimes(1)
+	mockConfig.EXPECT().GetString("engine_tag_template").Return("v{{.Version}}").MinTimes(1)
 	mockConfig.EXPECT().GetString("scm_repo_full_name").Return("AnalogJ/cookbook_analogj_test").MinTimes(1)
 	mockConfig.EXPECT().GetInt("scm_pull_request").Return(12)
 	mockConfig.EXPECT().IsSet("scm_pull_request").Return(true)
+	mockConfig.EXPECT().GetString("scm_sender").Return("")
+	mockConfig.EXPECT().GetBool("scm_enable_authorization").Return(false)
+	mockConfig.EXPECT().GetBool("scm_require_signed_commits").Return(false)
+	mockConfig.EXPECT().GetString("scm_merge_strategy").Return("merge")
 	mockConfig.EXPECT().GetString("scm_notify_source").Return("CapsuleCD")
 	mockConfig.EXPECT().GetString("scm_notify_target_url").Return("https://www.capsulecd.com")
 	pipelineData := new(pipeline.Data)
@@ -516,3 +663,48 @@ func TestScmGithub_Notify(t *testing.T) {
 	pperr := githubScm.Notify("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "success", "test message")
 	require.NoError(t, pperr)
 }
+
+func TestScmGithub_NotifyStep_SuccessWithoutChecks(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
+	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
+	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().GetBool("scm_github_enable_checks").Return(false)
+	pipelineData := new(pipeline.Data)
+	client := githubVcrSetup(t)
+
+	//test
+	githubScm, err := scm.Create("github", pipelineData, mockConfig, client)
+	require.NoError(t, err)
+	pperr := githubScm.NotifyStep("49f5bfbf4610f0c2a54d33945521051ba92b2eac", "test_step", "success", "test message", "")
+
+	//assert
+	require.NoError(t, pperr, "step success should not update the commit status when checks are disabled")
+}
+
+func TestScmGithub_Summarize_Disabled(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().SetDefault(gomock.Any(), gomock.Any()).MinTimes(1)
+	mockConfig.EXPECT().IsSet("scm_github_access_token").Return(true)
+	mockConfig.EXPECT().IsSet("scm_github_api_endpoint").Return(false)
+	mockConfig.EXPECT().IsSet("scm_git_parent_path").Return(false)
+	mockConfig.EXPECT().GetBool("scm_disable_pull_request_comment").Return(true)
+	pipelineData := new(pipeline.Data)
+	pipelineData.IsPullRequest = true
+	client := githubVcrSetup(t)
+
+	//test
+	githubScm, err := scm.Create("github", pipelineData, mockConfig, client)
+	require.NoError(t, err)
+	serr := githubScm.Summarize("test_step", errors.New("test error"))
+
+	//assert
+	require.NoError(t, serr, "should not post a pull request comment when disabled")
+}
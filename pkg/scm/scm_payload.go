@@ -8,5 +8,9 @@ type Payload struct {
 
 	//Pull Request specific fields
 	Title             string
+	Body              string
+	Labels            []string
 	PullRequestNumber string
+	Author            string //user that opened the pull request
+	Sender            string //user that triggered this build (eg. by pushing to, or re-running the pull request)
 }
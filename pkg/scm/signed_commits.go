@@ -0,0 +1,86 @@
+package scm
+
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"log"
+	"strings"
+)
+
+// check that every pull request commit (base..head) is signed by an allowed GPG or SSH key (scm_require_signed_commits).
+// The pull request head commits must already be fetched into the local repository.
+func verifyPullRequestSignatures(config config.Interface, pipelineData *pipeline.Data) error {
+	verifier, err := signatureVerifier(config, pipelineData)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Verifying pull request commit signatures (%.8s..%.8s)", pipelineData.GitBaseInfo.Sha, pipelineData.GitHeadInfo.Sha)
+	unverifiedShas, err := utils.GitUnverifiedCommits(pipelineData.GitLocalPath, pipelineData.GitBaseInfo.Sha, pipelineData.GitHeadInfo.Sha, verifier)
+	if err != nil {
+		return err
+	} else if len(unverifiedShas) > 0 {
+		return errors.ScmUnverifiedCommitsError(fmt.Sprintf("The following pull request commits are unsigned, or not signed by an allowed key: %s", strings.Join(unverifiedShas, ", ")))
+	}
+	return nil
+}
+
+// check that the pull request head that was actually merged (fetched again after the signatures were verified) only
+// contains verified commits. Otherwise commits pushed to the pull request after it was verified would be released.
+func verifyMergedPullRequestHead(pipelineData *pipeline.Data, mergedHeadRef string) error {
+	unverifiedCommits, err := utils.GitCommitsBetween(pipelineData.GitLocalPath, pipelineData.GitHeadInfo.Sha, mergedHeadRef)
+	if err != nil {
+		return err
+	} else if len(unverifiedCommits) > 0 {
+		unverifiedShas := []string{}
+		for _, commit := range unverifiedCommits {
+			unverifiedShas = append(unverifiedShas, fmt.Sprintf("%.8s", commit.Sha))
+		}
+		return errors.ScmUnverifiedCommitsError(fmt.Sprintf("The pull request changed after its commit signatures were verified (%.8s), the following commits were not verified: %s", pipelineData.GitHeadInfo.Sha, strings.Join(unverifiedShas, ", ")))
+	}
+	return nil
+}
+
+// build the verifier from the allowed keys in the config, and the allowed key files in the repository.
+// Key files are read from the pull request base, so that a pull request cannot allow its own keys.
+func signatureVerifier(config config.Interface, pipelineData *pipeline.Data) (*utils.GitSignatureVerifier, error) {
+	verifier := utils.NewGitSignatureVerifier()
+
+	if config.IsSet("scm_signed_commits_gpg_keyring") {
+		keyring, err := config.GetBase64Decoded("scm_signed_commits_gpg_keyring")
+		if err != nil {
+			return nil, err
+		} else if err := verifier.AddGpgKeyring([]byte(keyring)); err != nil {
+			return nil, err
+		}
+	}
+	if config.IsSet("scm_signed_commits_ssh_allowed_signers") {
+		allowedSigners, err := config.GetBase64Decoded("scm_signed_commits_ssh_allowed_signers")
+		if err != nil {
+			return nil, err
+		} else if err := verifier.AddSshAllowedSigners([]byte(allowedSigners)); err != nil {
+			return nil, err
+		}
+	}
+
+	if keyringPath := config.GetString("scm_signed_commits_gpg_keyring_path"); keyringPath != "" {
+		keyring, err := utils.GitFileContent(pipelineData.GitLocalPath, pipelineData.GitBaseInfo.Sha, keyringPath)
+		if err != nil {
+			return nil, err
+		} else if err := verifier.AddGpgKeyring(keyring); err != nil {
+			return nil, err
+		}
+	}
+	if allowedSignersPath := config.GetString("scm_signed_commits_ssh_allowed_signers_path"); allowedSignersPath != "" {
+		allowedSigners, err := utils.GitFileContent(pipelineData.GitLocalPath, pipelineData.GitBaseInfo.Sha, allowedSignersPath)
+		if err != nil {
+			return nil, err
+		} else if err := verifier.AddSshAllowedSigners(allowedSigners); err != nil {
+			return nil, err
+		}
+	}
+	return verifier, nil
+}
@@ -0,0 +1,137 @@
+package scm
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSignatureVerifier_ConfigKeys(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().IsSet("scm_signed_commits_gpg_keyring").Return(false)
+	mockConfig.EXPECT().IsSet("scm_signed_commits_ssh_allowed_signers").Return(true)
+	mockConfig.EXPECT().GetBase64Decoded("scm_signed_commits_ssh_allowed_signers").Return(
+		"# release managers\n"+
+			"jason@example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIJQHlHVYPsvTBBr2dj6lnt9cxXcUBlrYJBbz7ZHz1yhT jason@example.com\n",
+		nil,
+	)
+	mockConfig.EXPECT().GetString("scm_signed_commits_gpg_keyring_path").Return("")
+	mockConfig.EXPECT().GetString("scm_signed_commits_ssh_allowed_signers_path").Return("")
+
+	//test
+	verifier, err := signatureVerifier(mockConfig, &pipeline.Data{})
+
+	//assert
+	require.NoError(t, err)
+	require.NotNil(t, verifier)
+}
+
+func TestSignatureVerifier_InvalidAllowedSigners(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().IsSet("scm_signed_commits_gpg_keyring").Return(false)
+	mockConfig.EXPECT().IsSet("scm_signed_commits_ssh_allowed_signers").Return(true)
+	mockConfig.EXPECT().GetBase64Decoded("scm_signed_commits_ssh_allowed_signers").Return("jason@example.com not-a-key", nil)
+
+	//test
+	_, err := signatureVerifier(mockConfig, &pipeline.Data{})
+
+	//assert
+	require.Error(t, err)
+}
+
+// create an origin repository with a pull request branch (feature), and a local clone of it. Returns the parent
+// directory, the origin path & the pipeline data for the clone, with the (verified) pull request head.
+func mergedHeadTestRepo(t *testing.T) (string, string, *pipeline.Data) {
+	parentPath, err := ioutil.TempDir("", "merged_head")
+	require.NoError(t, err)
+	originPath := path.Join(parentPath, "origin")
+	require.NoError(t, os.MkdirAll(originPath, 0755))
+	releaseBranchTestGit(t, originPath, "init", "-q")
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "-b", "master")
+	releaseBranchTestGit(t, originPath, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	baseSha := releaseBranchTestGit(t, originPath, "rev-parse", "HEAD")
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "-b", "feature")
+	require.NoError(t, ioutil.WriteFile(path.Join(originPath, "feature.txt"), []byte("verified\n"), 0644))
+	releaseBranchTestGit(t, originPath, "add", "-A")
+	releaseBranchTestGit(t, originPath, "commit", "-q", "-m", "feat: verified commit")
+	headSha := releaseBranchTestGit(t, originPath, "rev-parse", "HEAD")
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "master")
+
+	localPath, cerr := utils.GitClone(parentPath, "local", originPath)
+	require.NoError(t, cerr)
+	return parentPath, originPath, &pipeline.Data{
+		GitLocalPath:   localPath,
+		GitLocalBranch: "pr_12",
+		GitBaseInfo:    &pipeline.ScmCommitInfo{Sha: baseSha, Ref: "master"},
+		GitHeadInfo:    &pipeline.ScmCommitInfo{Sha: headSha, Ref: "feature"},
+	}
+}
+
+func TestVerifyMergedPullRequestHead(t *testing.T) {
+	//setup
+	parentPath, originPath, pipelineData := mergedHeadTestRepo(t)
+	defer os.RemoveAll(parentPath)
+	require.NoError(t, utils.GitMergeRemoteBranch(pipelineData.GitLocalPath, pipelineData.GitLocalBranch, "master", originPath, "feature", "squash", "feat: verified commit (#12)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")))
+
+	//test
+	err := verifyMergedPullRequestHead(pipelineData, utils.GitMergeRemoteBranchRef("feature"))
+
+	//assert
+	require.NoError(t, err, "the verified head was merged")
+}
+
+func TestVerifyMergedPullRequestHead_HeadMoved(t *testing.T) {
+	//setup
+	parentPath, originPath, pipelineData := mergedHeadTestRepo(t)
+	defer os.RemoveAll(parentPath)
+	// a commit is pushed to the pull request after its signatures were verified, but before it's merged.
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "feature")
+	releaseBranchTestGit(t, originPath, "commit", "-q", "--allow-empty", "-m", "unsigned commit")
+	unverifiedSha := releaseBranchTestGit(t, originPath, "rev-parse", "HEAD")
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "master")
+	require.NoError(t, utils.GitMergeRemoteBranch(pipelineData.GitLocalPath, pipelineData.GitLocalBranch, "master", originPath, "feature", "squash", "feat: verified commit (#12)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")))
+
+	//test
+	err := verifyMergedPullRequestHead(pipelineData, utils.GitMergeRemoteBranchRef("feature"))
+
+	//assert
+	require.Error(t, err)
+	require.IsType(t, errors.ScmUnverifiedCommitsError(""), err)
+	require.Contains(t, err.Error(), unverifiedSha[:8])
+}
+
+func TestVerifyMergedPullRequestHead_MergeCommit(t *testing.T) {
+	//setup
+	parentPath, originPath, pipelineData := mergedHeadTestRepo(t)
+	defer os.RemoveAll(parentPath)
+	// Github creates the pull request merge commit (refs/pull/12/merge) from the current head, which moved after verification.
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "feature")
+	releaseBranchTestGit(t, originPath, "commit", "-q", "--allow-empty", "-m", "unsigned commit")
+	unverifiedSha := releaseBranchTestGit(t, originPath, "rev-parse", "HEAD")
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "--detach", "master")
+	releaseBranchTestGit(t, originPath, "merge", "-q", "--no-ff", "-m", "Merge pull request #12", "feature")
+	releaseBranchTestGit(t, originPath, "update-ref", "refs/pull/12/merge", "HEAD")
+	releaseBranchTestGit(t, originPath, "checkout", "-q", "master")
+	require.NoError(t, utils.GitFetchPullRequest(pipelineData.GitLocalPath, "12", pipelineData.GitLocalBranch, "refs/pull/%s/merge", ""))
+
+	//test
+	err := verifyMergedPullRequestHead(pipelineData, "refs/remotes/origin/pr/12/merge^2")
+
+	//assert
+	require.Error(t, err)
+	require.IsType(t, errors.ScmUnverifiedCommitsError(""), err)
+	require.Contains(t, err.Error(), unverifiedSha[:8])
+}
@@ -0,0 +1,91 @@
+package scm
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"strings"
+)
+
+// hidden marker used to find (and update in place) the summary comment previously posted to a pull request.
+const summaryCommentMarker = "<!-- capsulecd:summary -->"
+
+// generate the markdown body for the pull request summary comment.
+// If failedStep is empty, the comment will describe the release, otherwise it will describe the failure.
+func summaryComment(pipelineData *pipeline.Data, failedStep string, failure error) string {
+	var buf bytes.Buffer
+	buf.WriteString(summaryCommentMarker + "\n")
+
+	if failedStep != "" {
+		buf.WriteString("### :x: Release failed\n\n")
+		buf.WriteString(fmt.Sprintf("The `%s` step failed", failedStep))
+		if failure != nil {
+			buf.WriteString(fmt.Sprintf(":\n\n```\n%s\n```\n", escapeCodeFence(failure.Error())))
+		} else {
+			buf.WriteString(".\n")
+		}
+		return buf.String()
+	}
+
+	tag := pipelineData.ReleaseTag
+	if pipelineData.ReleasePullRequest {
+		buf.WriteString(fmt.Sprintf("### :package: Release pull request opened for %s\n\n", tag))
+		buf.WriteString(fmt.Sprintf("%s will be tagged, released and distributed when the [release pull request](%s) is merged.\n", tag, pipelineData.ReleaseUrl))
+		if pipelineData.ReleaseBumpType != "" {
+			buf.WriteString(fmt.Sprintf("\nThis is a %s version bump (from %s).\n", pipelineData.ReleaseBumpType, pipelineData.ReleaseBumpReason))
+		}
+		return buf.String()
+	}
+	if pipelineData.ReleasePrerelease {
+		buf.WriteString(fmt.Sprintf("### :rocket: Released %s (pre-release)\n\n", tag))
+	} else {
+		buf.WriteString(fmt.Sprintf("### :rocket: Released %s\n\n", tag))
+	}
+	buf.WriteString("| | |\n|---|---|\n")
+	buf.WriteString(fmt.Sprintf("| **Version** | `%s` |\n", pipelineData.ReleaseVersion))
+	buf.WriteString(fmt.Sprintf("| **Tag** | `%s` |\n", tag))
+	if pipelineData.ReleaseBumpType != "" && !pipelineData.ReleaseVersionCommitted {
+		buf.WriteString(fmt.Sprintf("| **Bump** | %s (from %s) |\n", pipelineData.ReleaseBumpType, pipelineData.ReleaseBumpReason))
+	}
+	if pipelineData.ReleaseUrl != "" {
+		buf.WriteString(fmt.Sprintf("| **Release** | [%s](%s) |\n", tag, pipelineData.ReleaseUrl))
+	}
+	if pipelineData.ReleaseRegistry != "" {
+		buf.WriteString(fmt.Sprintf("| **Registry** | %s |\n", pipelineData.ReleaseRegistry))
+	}
+
+	if len(pipelineData.ReleasePublishedAssets) > 0 {
+		buf.WriteString("\n#### Assets\n\n")
+		for _, asset := range pipelineData.ReleasePublishedAssets {
+			buf.WriteString(fmt.Sprintf("- [%s](%s)\n", asset.ArtifactName, asset.DownloadUrl))
+		}
+	}
+
+	if strings.TrimSpace(pipelineData.ReleaseChangelog) != "" {
+		buf.WriteString("\n#### Changelog\n\n")
+		buf.WriteString(strings.TrimSpace(pipelineData.ReleaseChangelog) + "\n")
+	}
+	return buf.String()
+}
+
+// generate the markdown body for the release pull request (scm_enable_release_pull_request)
+func releasePullRequestBody(pipelineData *pipeline.Data) string {
+	var buf bytes.Buffer
+	tag := pipelineData.ReleaseTag
+	buf.WriteString(fmt.Sprintf("This pull request was generated by CapsuleCD, and contains the version bump for %s.\n\n", tag))
+	buf.WriteString(fmt.Sprintf("When it is merged, %s will be tagged, released and distributed.\n", tag))
+	if pipelineData.ReleaseBumpType != "" {
+		buf.WriteString(fmt.Sprintf("\nThis is a %s version bump (from %s).\n", pipelineData.ReleaseBumpType, pipelineData.ReleaseBumpReason))
+	}
+
+	if strings.TrimSpace(pipelineData.ReleaseChangelog) != "" {
+		buf.WriteString("\n#### Changelog\n\n")
+		buf.WriteString(strings.TrimSpace(pipelineData.ReleaseChangelog) + "\n")
+	}
+	return buf.String()
+}
+
+// prevent error messages from closing the surrounding markdown code block.
+func escapeCodeFence(content string) string {
+	return strings.Replace(content, "```", "` ` `", -1)
+}
@@ -0,0 +1,147 @@
+package scm
+
+import (
+	"errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSummaryComment_Success(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pipelineData := &pipeline.Data{
+		ReleaseVersion:    "1.2.3",
+		ReleaseTag:        "v1.2.3",
+		ReleaseUrl:        "https://github.com/AnalogJ/capsulecd/releases/tag/v1.2.3",
+		ReleaseRegistry:   "https://registry.npmjs.org",
+		ReleaseChangelog:  "Timestamp |  SHA | Message | Author\n",
+		ReleaseBumpType:   "minor",
+		ReleaseBumpReason: "label `release:minor`",
+		ReleasePublishedAssets: []pipeline.ScmPublishedAsset{
+			{ArtifactName: "capsulecd-linux-amd64", DownloadUrl: "https://github.com/AnalogJ/capsulecd/releases/download/v1.2.3/capsulecd-linux-amd64"},
+		},
+	}
+
+	//test
+	comment := summaryComment(pipelineData, "", nil)
+
+	//assert
+	require.Contains(t, comment, summaryCommentMarker)
+	require.Contains(t, comment, "| **Version** | `1.2.3` |")
+	require.Contains(t, comment, "| **Tag** | `v1.2.3` |")
+	require.Contains(t, comment, "[v1.2.3](https://github.com/AnalogJ/capsulecd/releases/tag/v1.2.3)")
+	require.Contains(t, comment, "| **Registry** | https://registry.npmjs.org |")
+	require.Contains(t, comment, "| **Bump** | minor (from label `release:minor`) |")
+	require.Contains(t, comment, "- [capsulecd-linux-amd64](https://github.com/AnalogJ/capsulecd/releases/download/v1.2.3/capsulecd-linux-amd64)")
+	require.Contains(t, comment, "#### Changelog")
+}
+
+func TestSummaryComment_SuccessWithoutOptionalData(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pipelineData := &pipeline.Data{ReleaseVersion: "1.2.3", ReleaseTag: "v1.2.3"}
+
+	//test
+	comment := summaryComment(pipelineData, "", nil)
+
+	//assert
+	require.Contains(t, comment, "| **Tag** | `v1.2.3` |")
+	require.NotContains(t, comment, "**Release**")
+	require.NotContains(t, comment, "**Registry**")
+	require.NotContains(t, comment, "**Bump**")
+	require.NotContains(t, comment, "#### Assets")
+	require.NotContains(t, comment, "#### Changelog")
+}
+
+func TestSummaryComment_Prerelease(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pipelineData := &pipeline.Data{ReleaseVersion: "1.2.0-rc.1", ReleaseTag: "v1.2.0-rc.1", ReleasePrerelease: true}
+
+	//test
+	comment := summaryComment(pipelineData, "", nil)
+
+	//assert
+	require.Contains(t, comment, "### :rocket: Released v1.2.0-rc.1 (pre-release)")
+	require.Contains(t, comment, "| **Version** | `1.2.0-rc.1` |")
+}
+
+func TestSummaryComment_ReleasePullRequestBumpType(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pipelineData := &pipeline.Data{
+		ReleaseVersion:     "1.3.0",
+		ReleaseTag:         "v1.3.0",
+		ReleaseUrl:         "https://github.com/AnalogJ/capsulecd/pull/13",
+		ReleasePullRequest: true,
+		ReleaseBumpType:    "minor",
+		ReleaseBumpReason:  "conventional commit 49f5bfbf",
+	}
+
+	//test
+	comment := summaryComment(pipelineData, "", nil)
+
+	//assert
+	require.Contains(t, comment, "### :package: Release pull request opened for v1.3.0")
+	require.Contains(t, comment, "This is a minor version bump (from conventional commit 49f5bfbf).")
+}
+
+func TestSummaryComment_Failure(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pipelineData := &pipeline.Data{ReleaseVersion: "1.2.3", ReleaseTag: "v1.2.3"}
+
+	//test
+	comment := summaryComment(pipelineData, "test_step", errors.New("tests failed ```"))
+
+	//assert
+	require.Contains(t, comment, summaryCommentMarker)
+	require.Contains(t, comment, "The `test_step` step failed")
+	require.Contains(t, comment, "tests failed ` ` `")
+	require.NotContains(t, comment, "**Version**")
+}
+
+func TestSummaryComment_ReleasePullRequest(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pipelineData := &pipeline.Data{
+		ReleaseVersion:     "1.2.3",
+		ReleaseTag:         "v1.2.3",
+		ReleaseUrl:         "https://github.com/AnalogJ/capsulecd/pull/13",
+		ReleasePullRequest: true,
+	}
+
+	//test
+	comment := summaryComment(pipelineData, "", nil)
+
+	//assert
+	require.Contains(t, comment, summaryCommentMarker)
+	require.Contains(t, comment, "Release pull request opened for v1.2.3")
+	require.Contains(t, comment, "(https://github.com/AnalogJ/capsulecd/pull/13)")
+	require.NotContains(t, comment, "**Version**")
+}
+
+func TestReleasePullRequestBody(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pipelineData := &pipeline.Data{
+		ReleaseVersion:   "1.2.3",
+		ReleaseTag:       "v1.2.3",
+		ReleaseChangelog: "Timestamp |  SHA | Message | Author\n",
+	}
+
+	//test
+	body := releasePullRequestBody(pipelineData)
+
+	//assert
+	require.Contains(t, body, "version bump for v1.2.3")
+	require.Contains(t, body, "#### Changelog")
+}
@@ -0,0 +1,60 @@
+package scm
+
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"net/url"
+	"strings"
+)
+
+// ConfigureGitTransport configures the SSH key used by git remote operations when scm_git_transport is `ssh`.
+// It must be called before the repository is cloned.
+func ConfigureGitTransport(config config.Interface) error {
+	if config.GetString("scm_git_transport") != "ssh" {
+		return utils.GitConfigureSshAuth(nil)
+	}
+
+	privateKey, err := config.GetBase64Decoded("scm_git_ssh_private_key")
+	if err != nil {
+		return err
+	}
+	knownHosts, err := config.GetBase64Decoded("scm_git_ssh_known_hosts")
+	if err != nil {
+		return err
+	}
+	return utils.GitConfigureSshAuth(&utils.GitSshAuth{
+		Username:         config.GetString("scm_git_ssh_username"),
+		PrivateKey:       []byte(privateKey),
+		Passphrase:       config.GetString("scm_git_ssh_private_key_passphrase"),
+		KnownHosts:       []byte(knownHosts),
+		KnownHostsPolicy: config.GetString("scm_git_ssh_known_hosts_policy"),
+	})
+}
+
+// the remote used to clone, fetch & push the repository. When scm_git_transport is `ssh`, the https clone url is
+// converted to an ssh url (authenticated using the scm_git_ssh_private_key), otherwise the credentials are embedded in
+// the https clone url.
+func gitRemoteUrl(config config.Interface, cloneUrl string, username string, password string) (string, error) {
+	if config.GetString("scm_git_transport") == "ssh" {
+		return sshGitRemote(cloneUrl, config.GetString("scm_git_ssh_username"))
+	}
+	return authGitRemote(cloneUrl, username, password)
+}
+
+// convert an https clone url (eg. `https://github.com/AnalogJ/capsulecd.git`) to an ssh url
+// (eg. `ssh://git@github.com/AnalogJ/capsulecd.git`). SSH urls are returned unchanged.
+func sshGitRemote(cloneUrl string, username string) (string, error) {
+	if !strings.Contains(cloneUrl, "://") {
+		return cloneUrl, nil // scp-like syntax, eg. `git@github.com:AnalogJ/capsulecd.git`
+	}
+	u, err := url.Parse(cloneUrl)
+	if err != nil {
+		return "", err
+	} else if u.Scheme == "ssh" {
+		return cloneUrl, nil
+	} else if u.Scheme != "https" && u.Scheme != "http" {
+		return "", fmt.Errorf("Cannot convert %s clone url to an ssh url", u.Scheme)
+	}
+	return (&url.URL{Scheme: "ssh", User: url.User(username), Host: u.Hostname(), Path: u.Path}).String(), nil
+}
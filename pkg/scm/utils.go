@@ -1,6 +1,15 @@
 package scm
 
-import "net/url"
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/changelog"
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"log"
+	"net/url"
+)
 
 func authGitRemote(cloneUrl string, username string, password string) (string, error) {
 	if username != "" || password != "" {
@@ -15,3 +24,102 @@ func authGitRemote(cloneUrl string, username string, password string) (string, e
 		return cloneUrl, nil
 	}
 }
+
+// default NotifyStep implementation for scms that only support a single commit status per ref.
+// step "success" states are ignored, the commit status should only be marked successful once the pipeline completes.
+func notifyStepStatus(scmImpl Interface, ref string, state string, message string) error {
+	if state == "success" {
+		return nil
+	}
+	return scmImpl.Notify(ref, state, message)
+}
+
+// find the release branch rule (scm_release_branches) matching the branch, if any.
+// rules are only read from the CapsuleCD config, not the repository capsule.yml file, as they are required before the repo is cloned.
+func releaseBranchRule(config config.Interface, branch string) (*pipeline.ReleaseBranch, error) {
+	releaseBranches := []pipeline.ReleaseBranch{}
+	if err := config.UnmarshalKey("scm_release_branches", &releaseBranches); err != nil {
+		return nil, err
+	}
+	for ndx := range releaseBranches {
+		if releaseBranches[ndx].Matches(branch) {
+			return &releaseBranches[ndx], nil
+		}
+	}
+	return nil, nil
+}
+
+// NearestTagPattern limits nearest tag detection to the tags of the current release line (if any), or the tags generated
+// from the tag template (engine_tag_template), so that unrelated tags (eg. `deploy-prod`) are ignored.
+func NearestTagPattern(config config.Interface, pipelineData *pipeline.Data) (string, error) {
+	if pipelineData.ReleaseBranch != nil && pipelineData.ReleaseBranch.TagPattern != "" {
+		return pipelineData.ReleaseBranch.TagPattern, nil
+	}
+	return utils.ReleaseTagPattern(config.GetString("engine_tag_template"), pipelineData)
+}
+
+// generate the changelog for the release (scm_changelog_* options), linking commits & pull requests to the scm.
+// pullRequestLabels is used to group entries by pull request label, and may be nil if the scm does not support labels.
+// Failures are logged, as the release should still be created without a changelog.
+func releaseChangelog(pipelineData *pipeline.Data, config config.Interface, scmName string, pullRequestLabels func(number string) ([]string, error)) string {
+	options, err := changelog.OptionsFromConfig(config)
+	if err != nil {
+		log.Printf("Could not read the changelog configuration: %s", err)
+		return ""
+	}
+	options.PullRequestLabels = pullRequestLabels
+	options.Links = changelog.RepositoryLinks(scmName, pipelineData)
+
+	releaseBody, err := changelog.Release(config, pipelineData, options)
+	if err != nil {
+		log.Printf("Could not generate the release changelog: %s", err)
+		return ""
+	}
+	return releaseBody
+}
+
+// generate the commit message used when merging a pull request.
+// squash commits use the pull request title & number (eg. `Add feature (#12)`), similar to Github.
+func mergeCommitMessage(strategy string, payload *Payload) string {
+	if strategy == utils.GitMergeStrategySquash {
+		return fmt.Sprintf("%s (#%s)", payload.Title, payload.PullRequestNumber)
+	}
+	return fmt.Sprintf("Merge pull request #%s from %s\n\n%s", payload.PullRequestNumber, payload.Head.Ref, payload.Title)
+}
+
+// the remote branch that the release commit should be pushed to.
+// pull requests are pushed to their base branch, pushes (eg. a merged release pull request) are pushed back to the same branch.
+func releaseTargetBranch(pipelineData *pipeline.Data) string {
+	if pipelineData.GitBaseInfo != nil {
+		return pipelineData.GitBaseInfo.Ref
+	}
+	return pipelineData.GitLocalBranch
+}
+
+// push the local branch (the pushed default branch, with the version bump commit) to the release pull request branch.
+// the release branch is force pushed, replacing the version bump commit of any previous (unmerged) release pull request,
+// so it must not contain any other commits that are missing from the local branch, as they would be lost.
+func pushReleaseBranch(pipelineData *pipeline.Data, config config.Interface, releaseBranch string) error {
+	remoteSha, _, ferr := utils.GitFetchRemoteRef(
+		pipelineData.GitLocalPath,
+		fmt.Sprintf("refs/heads/%s", releaseBranch),
+		fmt.Sprintf("refs/remotes/origin/%s", releaseBranch),
+	)
+	if ferr != nil {
+		return ferr
+	}
+
+	if remoteSha != "" {
+		commits, cerr := utils.GitCommitsBetween(pipelineData.GitLocalPath, "HEAD", remoteSha)
+		if cerr != nil {
+			return cerr
+		}
+		for _, commit := range commits {
+			if !utils.IsVersionBumpCommit(commit.Message, config.GetString("engine_version_bump_msg")) {
+				return errors.ScmReleaseBranchConflict(fmt.Sprintf("The release pull request branch (%s) contains a commit (%s) that is not in %s. Please remove it from the release branch (or merge it into %s), so the release pull request can be updated", releaseBranch, commit.Sha, pipelineData.GitLocalBranch, pipelineData.GitLocalBranch))
+			}
+		}
+	}
+
+	return utils.GitPushBranch(pipelineData.GitLocalPath, pipelineData.GitLocalBranch, releaseBranch, true)
+}
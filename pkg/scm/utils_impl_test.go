@@ -0,0 +1,204 @@
+package scm
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestMergeCommitMessage_Squash(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	payload := &Payload{
+		Title:             "Add support for custom key bindings",
+		PullRequestNumber: "12",
+		Head:              &pipeline.ScmCommitInfo{Ref: "feature/key-bindings"},
+	}
+
+	//test
+	message := mergeCommitMessage("squash", payload)
+
+	//assert
+	require.Equal(t, "Add support for custom key bindings (#12)", message)
+}
+
+func TestMergeCommitMessage_Merge(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	payload := &Payload{
+		Title:             "Add support for custom key bindings",
+		PullRequestNumber: "12",
+		Head:              &pipeline.ScmCommitInfo{Ref: "feature/key-bindings"},
+	}
+
+	//test
+	message := mergeCommitMessage("merge", payload)
+
+	//assert
+	require.Equal(t, "Merge pull request #12 from feature/key-bindings\n\nAdd support for custom key bindings", message)
+}
+
+func TestReleaseTargetBranch(t *testing.T) {
+	t.Parallel()
+
+	//test & assert
+	require.Equal(t, "master", releaseTargetBranch(&pipeline.Data{
+		GitLocalBranch: "pr_12",
+		GitBaseInfo:    &pipeline.ScmCommitInfo{Ref: "master"},
+	}), "pull requests should release to the base branch")
+	require.Equal(t, "develop", releaseTargetBranch(&pipeline.Data{GitLocalBranch: "develop"}), "pushes should release to the local branch")
+}
+
+// run a git command in the test repository, returning the trimmed output.
+func releaseBranchTestGit(t *testing.T, repoPath string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=CapsuleCD", "GIT_AUTHOR_EMAIL=CapsuleCD@users.noreply.github.com",
+		"GIT_COMMITTER_NAME=CapsuleCD", "GIT_COMMITTER_EMAIL=CapsuleCD@users.noreply.github.com",
+	)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+// create a bare origin repository, and a local clone with a version bump commit on master (ready to be pushed to the
+// release branch). Returns the parent directory, the origin path & the pipeline data for the clone.
+func releaseBranchTestRepo(t *testing.T) (string, string, *pipeline.Data) {
+	parentPath, err := ioutil.TempDir("", "release_branch")
+	require.NoError(t, err)
+	originPath := path.Join(parentPath, "origin.git")
+	localPath := path.Join(parentPath, "local")
+	releaseBranchTestGit(t, parentPath, "init", "-q", "--bare", originPath)
+	releaseBranchTestGit(t, parentPath, "clone", "-q", originPath, localPath)
+	releaseBranchTestGit(t, localPath, "checkout", "-q", "-b", "master")
+	releaseBranchTestGit(t, localPath, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	releaseBranchTestGit(t, localPath, "push", "-q", "origin", "master")
+	return parentPath, originPath, &pipeline.Data{GitLocalPath: localPath, GitLocalBranch: "master"}
+}
+
+func releaseBranchTestConfig(mockCtrl *gomock.Controller) *mock_config.MockInterface {
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetString("engine_version_bump_msg").Return("Automated packaging of release by CapsuleCD").AnyTimes()
+	return mockConfig
+}
+
+func TestPushReleaseBranch(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	parentPath, originPath, pipelineData := releaseBranchTestRepo(t)
+	defer os.RemoveAll(parentPath)
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "commit", "-q", "--allow-empty", "-m", "(v1.0.0) Automated packaging of release by CapsuleCD")
+
+	//test
+	err := pushReleaseBranch(pipelineData, releaseBranchTestConfig(mockCtrl), "capsulecd/release")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t,
+		releaseBranchTestGit(t, pipelineData.GitLocalPath, "rev-parse", "HEAD"),
+		releaseBranchTestGit(t, originPath, "rev-parse", "capsulecd/release"),
+		"should create the release branch")
+}
+
+func TestPushReleaseBranch_PreviousVersionBump(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	parentPath, originPath, pipelineData := releaseBranchTestRepo(t)
+	defer os.RemoveAll(parentPath)
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "checkout", "-q", "-b", "capsulecd/release")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "commit", "-q", "--allow-empty", "-m", "(v1.0.0) Automated packaging of release by CapsuleCD")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "push", "-q", "origin", "capsulecd/release")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "checkout", "-q", "master")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "commit", "-q", "--allow-empty", "-m", "Add a feature")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "commit", "-q", "--allow-empty", "-m", "(v1.1.0) Automated packaging of release by CapsuleCD")
+
+	//test
+	err := pushReleaseBranch(pipelineData, releaseBranchTestConfig(mockCtrl), "capsulecd/release")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t,
+		releaseBranchTestGit(t, pipelineData.GitLocalPath, "rev-parse", "HEAD"),
+		releaseBranchTestGit(t, originPath, "rev-parse", "capsulecd/release"),
+		"should replace the version bump commit of the previous release pull request")
+}
+
+func TestPushReleaseBranch_Conflict(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	parentPath, originPath, pipelineData := releaseBranchTestRepo(t)
+	defer os.RemoveAll(parentPath)
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "checkout", "-q", "-b", "capsulecd/release")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "commit", "-q", "--allow-empty", "-m", "(v1.0.0) Automated packaging of release by CapsuleCD")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "commit", "-q", "--allow-empty", "-m", "Update the changelog")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "push", "-q", "origin", "capsulecd/release")
+	releaseSha := releaseBranchTestGit(t, pipelineData.GitLocalPath, "rev-parse", "HEAD")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "checkout", "-q", "master")
+	releaseBranchTestGit(t, pipelineData.GitLocalPath, "commit", "-q", "--allow-empty", "-m", "(v1.0.0) Automated packaging of release by CapsuleCD")
+
+	//test
+	err := pushReleaseBranch(pipelineData, releaseBranchTestConfig(mockCtrl), "capsulecd/release")
+
+	//assert
+	require.IsType(t, errors.ScmReleaseBranchConflict(""), err)
+	require.Equal(t, releaseSha, releaseBranchTestGit(t, originPath, "rev-parse", "capsulecd/release"), "should not overwrite commits pushed to the release branch")
+}
+
+func TestReleaseBranchRule(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().UnmarshalKey("scm_release_branches", gomock.Any()).Times(2).Return(nil).Do(func(key string, rawVal interface{}) {
+		*(rawVal.(*[]pipeline.ReleaseBranch)) = []pipeline.ReleaseBranch{
+			{Branch: "release/*", BumpTypes: []string{"patch"}, TagPattern: "v1.*"},
+		}
+	})
+
+	//test
+	releaseBranch, err := releaseBranchRule(mockConfig, "release/1.x")
+	require.NoError(t, err)
+	missingBranch, merr := releaseBranchRule(mockConfig, "feature/test")
+	require.NoError(t, merr)
+
+	//assert
+	require.NotNil(t, releaseBranch)
+	require.Equal(t, "v1.*", releaseBranch.TagPattern)
+	require.Nil(t, missingBranch)
+}
+
+func TestNearestTagPattern(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConfig := mock_config.NewMockInterface(mockCtrl)
+	mockConfig.EXPECT().GetString("engine_tag_template").Return("{{.Name}}@{{.Version}}")
+	pipelineData := &pipeline.Data{
+		GitHeadInfo: &pipeline.ScmCommitInfo{Repo: &pipeline.ScmRepoInfo{Name: "capsulecd"}},
+	}
+
+	//test
+	templatePattern, terr := NearestTagPattern(mockConfig, pipelineData)
+	pipelineData.ReleaseBranch = &pipeline.ReleaseBranch{Branch: "release/*", TagPattern: "capsulecd@1.*"}
+	branchPattern, berr := NearestTagPattern(mockConfig, pipelineData)
+
+	//assert
+	require.NoError(t, terr)
+	require.Equal(t, "capsulecd@*", templatePattern, "should only match tags generated from the tag template")
+	require.NoError(t, berr)
+	require.Equal(t, "capsulecd@1.*", branchPattern, "release branch tag pattern should take precedence")
+}
@@ -0,0 +1,31 @@
+package scm
+
+import (
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// read the raw webhook event body (scm_payload_file), `-` will read the event from stdin.
+func readWebhookPayload(payloadFile string) ([]byte, error) {
+	var body []byte
+	var err error
+	if payloadFile == "-" {
+		body, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		body, err = ioutil.ReadFile(payloadFile)
+	}
+	if err != nil {
+		return nil, errors.ScmPayloadFormatError(fmt.Sprintf("Could not read webhook payload: %s", err))
+	}
+	return body, nil
+}
+
+// populate config values from the webhook payload, without overriding values that were explicitly specified.
+func setDefaultConfig(config config.Interface, key string, value interface{}) {
+	if !config.IsSet(key) {
+		config.Set(key, value)
+	}
+}
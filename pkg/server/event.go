@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// the webhook event attributes used to filter events & identify jobs.
+type webhookEvent struct {
+	Scm          string
+	Event        string // eg. `pull_request`, `push`, `pullrequest:created`, `repo:push`
+	Action       string // Github only, eg. `opened`, `labeled`
+	Labels       []string
+	Branch       string // base branch for pull requests, pushed branch for pushes.
+	RepoFullName string
+}
+
+type EventRule struct { //mapstructure is used to deserialize by Config.
+	Scm      string   `mapstructure:"scm"`      // `github` or `bitbucket`, matches all scms if empty.
+	Event    string   `mapstructure:"event"`    // webhook event type, eg. `pull_request` or `repo:push`
+	Actions  []string `mapstructure:"actions"`  // Github event actions, eg. `opened`, `labeled`. matches all actions if empty.
+	Labels   []string `mapstructure:"labels"`   // pull request must have at least one of these labels. ignored if empty.
+	Branches []string `mapstructure:"branches"` // branch name or glob patterns, eg. `release/*`. matches all branches if empty.
+}
+
+// only pull requests labeled `release` are processed when no event rules are configured.
+var defaultEventRules = []EventRule{
+	{Scm: "github", Event: "pull_request", Actions: []string{"labeled"}, Labels: []string{"release"}},
+}
+
+func (r *EventRule) Matches(event *webhookEvent) bool {
+	if r.Scm != "" && r.Scm != event.Scm {
+		return false
+	}
+	if r.Event != event.Event {
+		return false
+	}
+	if len(r.Actions) > 0 && !containsString(r.Actions, event.Action) {
+		return false
+	}
+	if len(r.Labels) > 0 && !containsAnyString(r.Labels, event.Labels) {
+		return false
+	}
+	if len(r.Branches) > 0 {
+		matched := false
+		for _, pattern := range r.Branches {
+			if ok, err := path.Match(pattern, event.Branch); err == nil && ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// extract the event attributes from a Github or Bitbucket webhook request.
+func parseWebhookEvent(scmName string, header http.Header, body []byte) (*webhookEvent, error) {
+	event := &webhookEvent{Scm: scmName}
+
+	if scmName == "github" {
+		event.Event = header.Get("X-GitHub-Event")
+		githubEvent := struct {
+			Action      string `json:"action"`
+			Ref         string `json:"ref"`
+			PullRequest *struct {
+				Labels []struct {
+					Name string `json:"name"`
+				} `json:"labels"`
+				Base struct {
+					Ref string `json:"ref"`
+				} `json:"base"`
+			} `json:"pull_request"`
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+		}{}
+		if err := json.Unmarshal(body, &githubEvent); err != nil {
+			return nil, errors.ScmPayloadFormatError(fmt.Sprintf("Could not parse Github webhook payload: %s", err))
+		}
+
+		event.Action = githubEvent.Action
+		event.RepoFullName = githubEvent.Repository.FullName
+		if githubEvent.PullRequest != nil {
+			event.Branch = githubEvent.PullRequest.Base.Ref
+			for _, label := range githubEvent.PullRequest.Labels {
+				event.Labels = append(event.Labels, label.Name)
+			}
+		} else {
+			event.Branch = strings.TrimPrefix(githubEvent.Ref, "refs/heads/")
+		}
+	} else {
+		event.Event = header.Get("X-Event-Key")
+		bitbucketEvent := struct {
+			PullRequest *struct {
+				Destination struct {
+					Branch struct {
+						Name string `json:"name"`
+					} `json:"branch"`
+				} `json:"destination"`
+			} `json:"pullrequest"`
+			Push struct {
+				Changes []struct {
+					New *struct {
+						Name string `json:"name"`
+					} `json:"new"`
+				} `json:"changes"`
+			} `json:"push"`
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+		}{}
+		if err := json.Unmarshal(body, &bitbucketEvent); err != nil {
+			return nil, errors.ScmPayloadFormatError(fmt.Sprintf("Could not parse Bitbucket webhook payload: %s", err))
+		}
+
+		event.RepoFullName = bitbucketEvent.Repository.FullName
+		if bitbucketEvent.PullRequest != nil {
+			event.Branch = bitbucketEvent.PullRequest.Destination.Branch.Name
+		} else if changes := bitbucketEvent.Push.Changes; len(changes) > 0 && changes[len(changes)-1].New != nil {
+			event.Branch = changes[len(changes)-1].New.Name
+		}
+	}
+
+	if event.Event == "" {
+		return nil, errors.ScmPayloadUnsupported("Webhook request is missing the event type header")
+	}
+	return event, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyString(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
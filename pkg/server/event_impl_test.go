@@ -0,0 +1,78 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestEventRule_Matches(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	rule := EventRule{Scm: "bitbucket", Event: "repo:push", Branches: []string{"release/*"}}
+
+	//test & assert
+	require.True(t, rule.Matches(&webhookEvent{Scm: "bitbucket", Event: "repo:push", Branch: "release/1.x"}))
+	require.False(t, rule.Matches(&webhookEvent{Scm: "bitbucket", Event: "repo:push", Branch: "master"}))
+	require.False(t, rule.Matches(&webhookEvent{Scm: "github", Event: "repo:push", Branch: "release/1.x"}))
+}
+
+func TestEventRule_Matches_Labels(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	rule := defaultEventRules[0]
+
+	//test & assert
+	require.True(t, rule.Matches(&webhookEvent{Scm: "github", Event: "pull_request", Action: "labeled", Labels: []string{"bug", "release"}}))
+	require.False(t, rule.Matches(&webhookEvent{Scm: "github", Event: "pull_request", Action: "labeled", Labels: []string{"bug"}}))
+	require.False(t, rule.Matches(&webhookEvent{Scm: "github", Event: "pull_request", Action: "opened", Labels: []string{"release"}}))
+}
+
+func TestParseWebhookEvent_BitbucketPush(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	header := http.Header{}
+	header.Set("X-Event-Key", "repo:push")
+	body := []byte(`{"repository": {"full_name": "sparktree/gem_analogj_test"}, "push": {"changes": [{"new": {"type": "branch", "name": "master"}}]}}`)
+
+	//test
+	event, err := parseWebhookEvent("bitbucket", header, body)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "repo:push", event.Event)
+	require.Equal(t, "master", event.Branch)
+	require.Equal(t, "sparktree/gem_analogj_test", event.RepoFullName)
+}
+
+func TestParseWebhookEvent_MissingEventHeader(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, err := parseWebhookEvent("github", http.Header{}, []byte(`{}`))
+
+	//assert
+	require.Error(t, err)
+}
+
+func TestValidSignature(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("{}"))
+	header := http.Header{}
+	header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	//test & assert
+	require.True(t, validSignature(header, "bitbucket", "secret", []byte("{}")))
+	require.False(t, validSignature(header, "bitbucket", "", []byte("{}")), "should never be valid without a secret")
+	require.False(t, validSignature(header, "bitbucket", "secret", []byte("tampered")))
+	require.False(t, validSignature(http.Header{}, "github", "secret", []byte("{}")))
+}
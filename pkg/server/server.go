@@ -0,0 +1,301 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"github.com/analogj/capsulecd/pkg"
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/queue"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maximum size of a webhook request body (Github limits payloads to 25MB)
+const maxPayloadSize = 25 << 20
+
+// Server receives Github/Bitbucket webhooks, and runs a CapsuleCD pipeline for each event matching the event rules
+// (server_event_rules). Jobs are stored in a persistent queue (server_workspace_path/queue.json), and are run one at a
+// time: the pipeline log, command output, environment & git configuration (backend, SSH key) are process-wide, so
+// concurrent jobs would interfere with each other.
+type Server struct {
+	Config config.Interface
+
+	// creates the isolated configuration used by each job pipeline.
+	JobConfig func() (config.Interface, error)
+	// runs the pipeline for a job, defaults to Pipeline.Start
+	Runner func(jobConfig config.Interface) error
+
+	queue *queue.Queue
+}
+
+func New(serverConfig config.Interface, jobConfig func() (config.Interface, error)) (*Server, error) {
+	if !serverConfig.IsSet("server_workspace_path") {
+		dirPath, err := ioutil.TempDir("", "capsulecd-server")
+		if err != nil {
+			return nil, err
+		}
+		serverConfig.Set("server_workspace_path", dirPath)
+	}
+
+	s := &Server{
+		Config:    serverConfig,
+		JobConfig: jobConfig,
+		Runner: func(jobConfig config.Interface) error {
+			pipeline := pkg.Pipeline{}
+			return pipeline.Start(jobConfig)
+		},
+	}
+
+	jobQueue, err := queue.New(queue.Options{
+		Workers:     1,
+		MaxQueued:   serverConfig.GetInt("server_queue_size"),
+		MaxAttempts: serverConfig.GetInt("server_job_max_attempts"),
+		RetryDelay:  time.Duration(serverConfig.GetInt("server_job_retry_delay")) * time.Second,
+		History:     serverConfig.GetInt("server_job_history"),
+		StatePath:   filepath.Join(serverConfig.GetString("server_workspace_path"), "queue.json"),
+		Finished:    s.finished,
+	}, s.run)
+	if err != nil {
+		return nil, err
+	}
+	s.queue = jobQueue
+	s.queue.Start()
+	return s, nil
+}
+
+func (s *Server) ListenAndServe() error {
+	log.Printf("Listening for webhooks on %s", s.Config.GetString("server_address"))
+	return http.ListenAndServe(s.Config.GetString("server_address"), s.Handler())
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhooks/github", s.webhookHandler("github"))
+	mux.HandleFunc("/webhooks/bitbucket", s.webhookHandler("bitbucket"))
+	mux.HandleFunc("/api/jobs", s.authenticateApi(s.jobsHandler))
+	mux.HandleFunc("/api/jobs/", s.authenticateApi(s.jobHandler))
+	return mux
+}
+
+// the job API exposes the job logs (which may include sensitive pipeline output), so requests must be authenticated
+// with the server_api_token (`Authorization: Bearer <token>`). The API is disabled if the token is not set.
+func (s *Server) authenticateApi(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := s.Config.GetString("server_api_token")
+		if token == "" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "The job API is disabled, server_api_token is not set"})
+			return
+		} else if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid API token"})
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) webhookHandler(scmName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Webhooks must be sent using POST"})
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Could not read webhook payload: %s", err)})
+			return
+		}
+
+		if !validSignature(r.Header, scmName, s.Config.GetString(fmt.Sprintf("server_%s_webhook_secret", scmName)), body) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid webhook signature"})
+			return
+		}
+
+		event, err := parseWebhookEvent(scmName, r.Header, body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		rule, err := s.matchEventRule(event)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		} else if rule == nil {
+			writeJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Ignoring %s event, no matching event rules", event.Event)})
+			return
+		}
+
+		job, err := s.enqueue(event, body)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job)
+	}
+}
+
+// GET /api/jobs
+func (s *Server) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.queue.List())
+}
+
+// GET /api/jobs/:id and GET /api/jobs/:id/logs
+func (s *Server) jobHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/")
+
+	job, found := s.queue.Get(parts[0])
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Job %s could not be found", parts[0])})
+		return
+	}
+
+	if len(parts) == 1 || parts[1] == "" {
+		writeJSON(w, http.StatusOK, job)
+	} else if parts[1] == "logs" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeFile(w, r, s.jobLogPath(job))
+	} else {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+	}
+}
+
+func (s *Server) matchEventRule(event *webhookEvent) (*EventRule, error) {
+	eventRules := defaultEventRules
+	if s.Config.IsSet("server_event_rules") {
+		eventRules = []EventRule{}
+		if err := s.Config.UnmarshalKey("server_event_rules", &eventRules); err != nil {
+			return nil, err
+		}
+	}
+
+	for ndx := range eventRules {
+		if eventRules[ndx].Matches(event) {
+			return &eventRules[ndx], nil
+		}
+	}
+	return nil, nil
+}
+
+// store the webhook payload in an isolated workspace, and add the job to the queue.
+func (s *Server) enqueue(event *webhookEvent, body []byte) (queue.Job, error) {
+	job := queue.Job{
+		Id:           queue.NewJobId(),
+		RepoFullName: event.RepoFullName,
+		Data: map[string]string{
+			"scm":   event.Scm,
+			"event": event.Event,
+		},
+	}
+	workspacePath := s.jobWorkspacePath(job)
+
+	if err := os.MkdirAll(workspacePath, 0700); err != nil {
+		return queue.Job{}, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(workspacePath, "payload.json"), body, 0600); err != nil {
+		return queue.Job{}, err
+	}
+
+	queuedJob, err := s.queue.Enqueue(job)
+	if err != nil {
+		os.RemoveAll(workspacePath)
+	}
+	return queuedJob, err
+}
+
+// isolated workspace containing the webhook payload and the cloned repository, removed when the job has finished.
+func (s *Server) jobWorkspacePath(job queue.Job) string {
+	return filepath.Join(s.Config.GetString("server_workspace_path"), job.Id)
+}
+
+// the job log is stored outside the job workspace, and kept until the job is removed from the job history.
+func (s *Server) jobLogPath(job queue.Job) string {
+	return filepath.Join(s.Config.GetString("server_workspace_path"), "logs", job.Id+".log")
+}
+
+// run the pipeline for a job (called by the queue worker).
+func (s *Server) run(job queue.Job) error {
+	workspacePath := s.jobWorkspacePath(job)
+
+	// the log is appended to, so that it includes the output of previous attempts.
+	if err := os.MkdirAll(filepath.Dir(s.jobLogPath(job)), 0700); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(s.jobLogPath(job), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	// capture the pipeline log & command output for this job. The pipeline also modifies the process environment (eg.
+	// the golang engine updates GOPATH & PATH) and configures the git SSH key, which must not leak into the next job.
+	environ := os.Environ()
+	log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+	utils.SetCmdLogOutput(io.MultiWriter(os.Stdout, logFile))
+	defer func() {
+		log.SetOutput(os.Stderr)
+		utils.SetCmdLogOutput(os.Stdout)
+		restoreEnvironment(environ)
+		utils.GitConfigureSshAuth(nil)
+	}()
+
+	// the repository is cloned again on every attempt.
+	if err := os.RemoveAll(filepath.Join(workspacePath, "src")); err != nil {
+		return err
+	}
+
+	jobConfig, err := s.JobConfig()
+	if err != nil {
+		return err
+	}
+	jobConfig.Set("scm", job.Data["scm"])
+	jobConfig.Set("scm_payload_file", filepath.Join(workspacePath, "payload.json"))
+	jobConfig.Set("scm_git_parent_path", filepath.Join(workspacePath, "src"))
+
+	log.Printf("Starting job %s, attempt %d (%s %s event for %s)", job.Id, job.Attempts, job.Data["scm"], job.Data["event"], job.RepoFullName)
+	return s.Runner(jobConfig)
+}
+
+// remove the job workspace once the job has finished (it is only needed to retry the job), along with the logs of jobs
+// that are no longer in the job history (server_job_history).
+func (s *Server) finished(job queue.Job) {
+	if err := os.RemoveAll(s.jobWorkspacePath(job)); err != nil {
+		log.Printf("Could not remove the workspace for job %s: %s", job.Id, err)
+	}
+
+	history := map[string]bool{}
+	for _, historyJob := range s.queue.List() {
+		history[historyJob.Id] = true
+	}
+	logPaths, _ := filepath.Glob(filepath.Join(s.Config.GetString("server_workspace_path"), "logs", "*.log"))
+	for _, logPath := range logPaths {
+		if !history[strings.TrimSuffix(filepath.Base(logPath), ".log")] {
+			os.Remove(logPath)
+		}
+	}
+}
+
+// replace the process environment with a snapshot taken by os.Environ()
+func restoreEnvironment(environ []string) {
+	os.Clearenv()
+	for _, envVar := range environ {
+		parts := strings.SplitN(envVar, "=", 2)
+		if len(parts) == 2 {
+			os.Setenv(parts[0], parts[1])
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,215 @@
+package server_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/queue"
+	"github.com/analogj/capsulecd/pkg/server"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "test-secret"
+const testApiToken = "test-token"
+
+func serverSetup(t *testing.T, runner func(jobConfig config.Interface) error) (*server.Server, string) {
+	workspacePath, err := ioutil.TempDir("", "capsulecd-server-test")
+	require.NoError(t, err)
+
+	serverConfig, err := config.Create()
+	require.NoError(t, err)
+	serverConfig.Set("server_workspace_path", workspacePath)
+	serverConfig.Set("server_github_webhook_secret", testWebhookSecret)
+	serverConfig.Set("server_api_token", testApiToken)
+
+	webhookServer, err := server.New(serverConfig, config.Create)
+	require.NoError(t, err)
+	webhookServer.Runner = runner
+	return webhookServer, workspacePath
+}
+
+func apiRequest(path string) *http.Request {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+testApiToken)
+	return req
+}
+
+func githubWebhookRequest(event string, body []byte, secret string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req := httptest.NewRequest("POST", "/webhooks/github", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+const labeledPullRequest = `{
+  "action": "labeled",
+  "pull_request": {
+    "labels": [{"name": "release"}],
+    "base": {"ref": "master"}
+  },
+  "repository": {"full_name": "AnalogJ/capsulecd"}
+}`
+
+func TestServer_Webhook_InvalidSignature(t *testing.T) {
+	//setup
+	webhookServer, workspacePath := serverSetup(t, nil)
+	defer os.RemoveAll(workspacePath)
+	resp := httptest.NewRecorder()
+
+	//test
+	webhookServer.Handler().ServeHTTP(resp, githubWebhookRequest("pull_request", []byte(labeledPullRequest), "wrong-secret"))
+
+	//assert
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestServer_Webhook_IgnoredEvent(t *testing.T) {
+	//setup
+	webhookServer, workspacePath := serverSetup(t, nil)
+	defer os.RemoveAll(workspacePath)
+	resp := httptest.NewRecorder()
+	body := []byte(`{"action": "opened", "pull_request": {"labels": [], "base": {"ref": "master"}}}`)
+
+	//test
+	webhookServer.Handler().ServeHTTP(resp, githubWebhookRequest("pull_request", body, testWebhookSecret))
+
+	//assert
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), "no matching event rules")
+}
+
+func TestServer_Webhook_RunsJob(t *testing.T) {
+	//setup
+	var jobConfig config.Interface
+	webhookServer, workspacePath := serverSetup(t, func(config config.Interface) error {
+		jobConfig = config
+		log.Print("running test pipeline")
+		return nil
+	})
+	defer os.RemoveAll(workspacePath)
+	resp := httptest.NewRecorder()
+
+	//test
+	webhookServer.Handler().ServeHTTP(resp, githubWebhookRequest("pull_request", []byte(labeledPullRequest), testWebhookSecret))
+
+	//assert
+	require.Equal(t, http.StatusAccepted, resp.Code)
+	job := queue.Job{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &job))
+	require.Equal(t, "AnalogJ/capsulecd", job.RepoFullName)
+
+	var jobStatus queue.Job
+	for i := 0; i < 50; i++ {
+		statusResp := httptest.NewRecorder()
+		webhookServer.Handler().ServeHTTP(statusResp, apiRequest("/api/jobs/"+job.Id))
+		require.Equal(t, http.StatusOK, statusResp.Code)
+		require.NoError(t, json.Unmarshal(statusResp.Body.Bytes(), &jobStatus))
+		if jobStatus.Status == queue.JobStatusSuccess {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, queue.JobStatusSuccess, jobStatus.Status)
+	require.Equal(t, "github", jobConfig.GetString("scm"))
+	require.Equal(t, filepath.Join(workspacePath, job.Id, "payload.json"), jobConfig.GetString("scm_payload_file"))
+
+	// the workspace is removed once the job has finished.
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(filepath.Join(workspacePath, job.Id)); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, serr := os.Stat(filepath.Join(workspacePath, job.Id))
+	require.True(t, os.IsNotExist(serr), "should remove the job workspace")
+
+	logResp := httptest.NewRecorder()
+	webhookServer.Handler().ServeHTTP(logResp, apiRequest("/api/jobs/"+job.Id+"/logs"))
+	require.Contains(t, logResp.Body.String(), "running test pipeline")
+}
+
+func TestServer_Jobs_NotFound(t *testing.T) {
+	//setup
+	webhookServer, workspacePath := serverSetup(t, nil)
+	defer os.RemoveAll(workspacePath)
+	resp := httptest.NewRecorder()
+
+	//test
+	webhookServer.Handler().ServeHTTP(resp, apiRequest("/api/jobs/missing"))
+
+	//assert
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestServer_Jobs_Unauthorized(t *testing.T) {
+	//setup
+	webhookServer, workspacePath := serverSetup(t, nil)
+	defer os.RemoveAll(workspacePath)
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/jobs", nil)
+	req.Header.Set("Authorization", "Bearer wrong-token")
+
+	//test
+	webhookServer.Handler().ServeHTTP(resp, req)
+
+	//assert
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestServer_Jobs_Disabled(t *testing.T) {
+	//setup
+	webhookServer, workspacePath := serverSetup(t, nil)
+	defer os.RemoveAll(workspacePath)
+	webhookServer.Config.Set("server_api_token", "")
+	resp := httptest.NewRecorder()
+
+	//test
+	webhookServer.Handler().ServeHTTP(resp, httptest.NewRequest("GET", "/api/jobs", nil))
+
+	//assert
+	require.Equal(t, http.StatusForbidden, resp.Code, "the job API should be disabled if server_api_token is not set")
+}
+
+func TestServer_Run_RestoresEnvironment(t *testing.T) {
+	//setup
+	webhookServer, workspacePath := serverSetup(t, func(config config.Interface) error {
+		os.Setenv("CAPSULE_SERVER_TEST_GOPATH", "/tmp/job")
+		return nil
+	})
+	defer os.RemoveAll(workspacePath)
+	resp := httptest.NewRecorder()
+
+	//test
+	webhookServer.Handler().ServeHTTP(resp, githubWebhookRequest("pull_request", []byte(labeledPullRequest), testWebhookSecret))
+
+	//assert
+	require.Equal(t, http.StatusAccepted, resp.Code)
+	job := queue.Job{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &job))
+	for i := 0; i < 50; i++ {
+		jobResp := httptest.NewRecorder()
+		webhookServer.Handler().ServeHTTP(jobResp, apiRequest("/api/jobs/"+job.Id))
+		require.NoError(t, json.Unmarshal(jobResp.Body.Bytes(), &job))
+		if job.Status == queue.JobStatusSuccess {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, queue.JobStatusSuccess, job.Status)
+	_, found := os.LookupEnv("CAPSULE_SERVER_TEST_GOPATH")
+	require.False(t, found, "environment changes made by the job should not leak into the next job")
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// validate the HMAC-SHA256 signature of the webhook body.
+// Github sends the signature in the `X-Hub-Signature-256` header, Bitbucket sends it in the `X-Hub-Signature` header.
+// Both use the format `sha256=<hex digest>`
+func validSignature(header http.Header, scmName string, secret string, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+
+	var signature string
+	if scmName == "github" {
+		signature = header.Get("X-Hub-Signature-256")
+	} else {
+		signature = header.Get("X-Hub-Signature")
+	}
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	actual, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(actual, mac.Sum(nil))
+}
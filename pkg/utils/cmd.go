@@ -4,6 +4,7 @@ import (
 	stderrors "errors"
 	"fmt"
 	"github.com/kvz/logstreamer"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -18,6 +19,15 @@ func BashCmdExec(cmd string, workingDir string, environ []string, logPrefix stri
 	return CmdExec("sh", []string{"-c", cmd}, workingDir, environ, logPrefix)
 }
 
+// destination for the output of commands executed via CmdExec
+var cmdLogOutput io.Writer = os.Stdout
+
+// SetCmdLogOutput changes the destination for the output of commands executed via CmdExec (eg. to capture the log of
+// a single pipeline run)
+func SetCmdLogOutput(output io.Writer) {
+	cmdLogOutput = output
+}
+
 func CmdExec(cmdName string, cmdArgs []string, workingDir string, environ []string, logPrefix string) error {
 	if logPrefix == "" {
 		logPrefix = " >> "
@@ -26,7 +36,7 @@ func CmdExec(cmdName string, cmdArgs []string, workingDir string, environ []stri
 	}
 
 	// Create a logger (your app probably already has one)
-	logger := log.New(os.Stdout, logPrefix, log.Ldate|log.Ltime)
+	logger := log.New(cmdLogOutput, logPrefix, log.Ldate|log.Ltime)
 
 	// Setup a streamer that we'll pipe cmd.Stdout to
 	logStreamerOut := logstreamer.NewLogstreamer(logger, "stdout", false)
@@ -37,8 +47,9 @@ func CmdExec(cmdName string, cmdArgs []string, workingDir string, environ []stri
 	defer logStreamerErr.Close()
 
 	cmd := exec.Command(cmdName, cmdArgs...)
-	cmd.Stdout = logStreamerOut
-	cmd.Stderr = logStreamerErr
+	// tee the output into the command output tail, so that it can be included in failure notifications.
+	cmd.Stdout = io.MultiWriter(logStreamerOut, cmdOutputTail)
+	cmd.Stderr = io.MultiWriter(logStreamerErr, cmdOutputTail)
 	if environ != nil {
 		cmd.Env = environ
 	}
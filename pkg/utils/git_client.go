@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"time"
+)
+
+// Supported git backends (scm_git_backend)
+const (
+	GitBackendLibgit2 = "libgit2" // cgo bindings for libgit2 (git2go), only available if CapsuleCD was built with libgit2
+	GitBackendGoGit   = "go-git"  // pure Go implementation (go-git)
+)
+
+// GitIdentity is the author, committer or tagger of a git object, independent of the git backend.
+type GitIdentity struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// GitClient is implemented by each git backend, and covers every git operation used to clone, merge & release a
+// repository. The Git* functions use the configured backend (GitConfigureBackend).
+type GitClient interface {
+	// Clone a git repo into a local directory. Returns the absolute path of the local repository.
+	Clone(parentPath string, repositoryName string, gitRemote string) (string, error)
+
+	// Fetch a pull request reference (eg. `refs/pull/12/merge`) from origin, and checkout into localBranchName.
+	FetchPullRequest(repoPath string, pullRequestNumber string, localBranchName string, srcPatternTmpl string, destPatternTmpl string) error
+
+	// Merge the remoteBranchName (fetched from remoteUrl) into localBranchName (created from baseBranchName) using
+	// one of the GitMergeStrategy* strategies. The remote branch is fetched into GitMergeRemoteBranchRef.
+	MergeRemoteBranch(repoPath string, localBranchName string, baseBranchName string, remoteUrl string, remoteBranchName string, strategy string, message string, signature *GitIdentity) error
+
+	// Checkout a local branch tracking the origin branch.
+	Checkout(repoPath string, branchName string) error
+
+	// Add all modified files to index, and create a commit signed by the signer (unsigned if the signer is nil).
+	Commit(repoPath string, message string, signature *GitIdentity, signer GitSigner) error
+
+	// Create an annotated tag for the current HEAD commit, signed by the signer (unsigned if the signer is nil).
+	// Returns the tagged commit sha.
+	Tag(repoPath string, version string, message string, signature *GitIdentity, signer GitSigner) (string, error)
+
+	// Push the local branch to the remote branch, along with the tag.
+	Push(repoPath string, localBranch string, remoteBranch string, tagName string) error
+
+	// Get the nearest tag on branch, only considering tags that match the glob pattern (if specified).
+	// basically `git describe --tags --abbrev=0 --match <pattern>`
+	FindNearestMatchingTagName(repoPath string, pattern string) (string, error)
+
+	// Generate a markdown table of the commits between baseSha and headSha.
+	GenerateChangelog(repoPath string, baseSha string, headSha string) (string, error)
+
+	// Get the commits reachable from headRef but not from baseRef (ie. `git log baseRef..headRef`), newest first.
+	// If baseRef is empty, all commits reachable from headRef are returned.
+	CommitsBetween(repoPath string, baseRef string, headRef string) ([]*pipeline.GitCommit, error)
+
+	// Get the commit sha & date of a lightweight or annotated tag.
+	GetTagDetails(repoPath string, tagName string) (*pipeline.GitTagDetails, error)
+
+	// Push a local branch to a (possibly different) remote branch, without pushing tags.
+	// if force is true, the remote branch will be overwritten.
+	PushBranch(repoPath string, localBranch string, remoteBranch string, force bool) error
+
+	// Create a commit with an empty tree, and store it in a local reference (outside of refs/heads).
+	// The commit will have a single parent if parentSha is specified. Returns the commit sha.
+	CreateMetadataCommit(repoPath string, refName string, parentSha string, message string, signature *GitIdentity) (string, error)
+
+	// Fetch a reference from the remote repository (origin) into a local reference, and return the referenced commit
+	// sha & message. An empty sha is returned if the remote reference does not exist.
+	FetchRemoteRef(repoPath string, remoteRefName string, localRefName string) (string, string, error)
+
+	// Fetch a reference from a remote url (eg. a pull request head repository) into a local reference.
+	FetchRemoteUrlRef(repoPath string, remoteUrl string, remoteRefName string, localRefName string) error
+
+	// Fetch all tags from the remote repository (origin), including tags created after the repository was cloned.
+	FetchTags(repoPath string) error
+
+	// Push a local reference to the remote repository (origin), without pushing branches or tags.
+	// The push is rejected if the remote reference cannot be fast-forwarded, unless force is true.
+	// If localRefName is empty, the remote reference is deleted.
+	PushRef(repoPath string, localRefName string, remoteRefName string, force bool) error
+
+	// Check if a tag already exists in the local repository.
+	TagExists(repoPath string, tagName string) (bool, error)
+
+	// Get the message of the commit currently checked out.
+	HeadCommitMessage(repoPath string) (string, error)
+
+	// Reset the current branch to the specified commit, discarding any changes (and commits) after it.
+	// basically `git reset --hard <sha>`
+	ResetToCommit(repoPath string, sha string) error
+
+	// Read the raw content of a commit object (headers & message), eg. to verify the commit signature.
+	CommitObject(repoPath string, sha string) ([]byte, error)
+
+	// Read the content of a file at the specified ref (eg. a commit sha), without checking it out.
+	FileContent(repoPath string, ref string, filePath string) ([]byte, error)
+}
+
+// the backend used by the Git* functions, until another backend is configured.
+var gitClient GitClient = defaultGitClient()
+
+// NewGitClient creates a client for the specified backend. If empty, libgit2 is used if CapsuleCD was built with
+// libgit2, otherwise go-git.
+func NewGitClient(backend string) (GitClient, error) {
+	switch backend {
+	case "":
+		return defaultGitClient(), nil
+	case GitBackendLibgit2:
+		return newLibgit2GitClient()
+	case GitBackendGoGit:
+		return new(goGitClient), nil
+	default:
+		return nil, fmt.Errorf("Unknown git backend: %s", backend)
+	}
+}
+
+func defaultGitClient() GitClient {
+	if client, err := newLibgit2GitClient(); err == nil {
+		return client
+	}
+	return new(goGitClient)
+}
+
+// GitConfigureBackend sets the backend used by the Git* functions. Like the SSH auth, the backend applies to the whole
+// process, it's configured when the pipeline starts.
+func GitConfigureBackend(backend string) error {
+	client, err := NewGitClient(backend)
+	if err != nil {
+		return err
+	}
+	GitSetClient(client)
+	return nil
+}
+
+// GitSetClient replaces the client used by the Git* functions, eg. with a mock.
+func GitSetClient(client GitClient) {
+	gitClient = client
+}
+
+// Clone a git repo into a local directory.
+// Credentials need to be specified by embedding in gitRemote url, or by configuring SSH auth (GitConfigureSshAuth).
+func GitClone(parentPath string, repositoryName string, gitRemote string) (string, error) {
+	return gitClient.Clone(parentPath, repositoryName, gitRemote)
+}
+
+func GitFetchPullRequest(repoPath string, pullRequestNumber string, localBranchName string, srcPatternTmpl string, destPatternTmpl string) error {
+	return gitClient.FetchPullRequest(repoPath, pullRequestNumber, localBranchName, srcPatternTmpl, destPatternTmpl)
+}
+
+func GitMergeRemoteBranch(repoPath string, localBranchName string, baseBranchName string, remoteUrl string, remoteBranchName string, strategy string, message string, signature *GitIdentity) error {
+	return gitClient.MergeRemoteBranch(repoPath, localBranchName, baseBranchName, remoteUrl, remoteBranchName, strategy, message, signature)
+}
+
+// The local reference that the remote branch merged by GitMergeRemoteBranch is fetched into.
+func GitMergeRemoteBranchRef(remoteBranchName string) string {
+	return fmt.Sprintf("refs/remotes/pr_origin/%s", remoteBranchName)
+}
+
+func GitCheckout(repoPath string, branchName string) error {
+	return gitClient.Checkout(repoPath, branchName)
+}
+
+//Add all modified files to index, and commit.
+func GitCommit(repoPath string, message string, signature *GitIdentity) error {
+	return gitClient.Commit(repoPath, message, signature, nil)
+}
+
+//Add all modified files to index, and create a commit signed by the signer (unsigned if the signer is nil).
+func GitSignedCommit(repoPath string, message string, signature *GitIdentity, signer GitSigner) error {
+	return gitClient.Commit(repoPath, message, signature, signer)
+}
+
+func GitTag(repoPath string, version string, message string, signature *GitIdentity) (string, error) {
+	return gitClient.Tag(repoPath, version, message, signature, nil)
+}
+
+// Create an annotated tag for the current HEAD commit, signed by the signer (unsigned if the signer is nil).
+// Returns the tagged commit sha.
+func GitSignedTag(repoPath string, version string, message string, signature *GitIdentity, signer GitSigner) (string, error) {
+	return gitClient.Tag(repoPath, version, message, signature, signer)
+}
+
+func GitPush(repoPath string, localBranch string, remoteBranch string, tagName string) error {
+	return gitClient.Push(repoPath, localBranch, remoteBranch, tagName)
+}
+
+// Get the nearest tag on branch.
+// tag must be nearest, ie. sorted by their distance from the HEAD of the branch, not the date or tagname.
+// basically `git describe --tags --abbrev=0`
+func GitFindNearestTagName(repoPath string) (string, error) {
+	return gitClient.FindNearestMatchingTagName(repoPath, "")
+}
+
+// Get the nearest tag on branch, only considering tags that match the glob pattern (if specified).
+// basically `git describe --tags --abbrev=0 --match <pattern>`
+func GitFindNearestMatchingTagName(repoPath string, pattern string) (string, error) {
+	return gitClient.FindNearestMatchingTagName(repoPath, pattern)
+}
+
+func GitGenerateChangelog(repoPath string, baseSha string, headSha string) (string, error) {
+	return gitClient.GenerateChangelog(repoPath, baseSha, headSha)
+}
+
+// Get the commits reachable from headRef but not from baseRef (ie. `git log baseRef..headRef`), newest first.
+// If baseRef is empty, all commits reachable from headRef are returned.
+func GitCommitsBetween(repoPath string, baseRef string, headRef string) ([]*pipeline.GitCommit, error) {
+	return gitClient.CommitsBetween(repoPath, baseRef, headRef)
+}
+
+func GitGetTagDetails(repoPath string, tagName string) (*pipeline.GitTagDetails, error) {
+	return gitClient.GetTagDetails(repoPath, tagName)
+}
+
+// Push a local branch to a (possibly different) remote branch, without pushing tags.
+// if force is true, the remote branch will be overwritten.
+func GitPushBranch(repoPath string, localBranch string, remoteBranch string, force bool) error {
+	return gitClient.PushBranch(repoPath, localBranch, remoteBranch, force)
+}
+
+// Create a commit with an empty tree, and store it in a local reference (outside of refs/heads).
+// Used to store metadata (eg. the release lock lease) in the remote repository. The commit will have a single parent if
+// parentSha is specified.
+func GitCreateMetadataCommit(repoPath string, refName string, parentSha string, message string, signature *GitIdentity) (string, error) {
+	return gitClient.CreateMetadataCommit(repoPath, refName, parentSha, message, signature)
+}
+
+// Fetch a reference from the remote repository (origin) into a local reference, and return the referenced commit sha &
+// message. An empty sha is returned if the remote reference does not exist.
+func GitFetchRemoteRef(repoPath string, remoteRefName string, localRefName string) (string, string, error) {
+	return gitClient.FetchRemoteRef(repoPath, remoteRefName, localRefName)
+}
+
+// Fetch a reference from a remote url (eg. a pull request head repository) into a local reference.
+func GitFetchRemoteUrlRef(repoPath string, remoteUrl string, remoteRefName string, localRefName string) error {
+	return gitClient.FetchRemoteUrlRef(repoPath, remoteUrl, remoteRefName, localRefName)
+}
+
+// Fetch all tags from the remote repository (origin), including tags created after the repository was cloned.
+func GitFetchTags(repoPath string) error {
+	return gitClient.FetchTags(repoPath)
+}
+
+// Push a local reference to the remote repository (origin), without pushing branches or tags.
+// The push is rejected if the remote reference cannot be fast-forwarded, unless force is true.
+// If localRefName is empty, the remote reference is deleted.
+func GitPushRef(repoPath string, localRefName string, remoteRefName string, force bool) error {
+	return gitClient.PushRef(repoPath, localRefName, remoteRefName, force)
+}
+
+// Check if a tag already exists in the local repository.
+func GitTagExists(repoPath string, tagName string) (bool, error) {
+	return gitClient.TagExists(repoPath, tagName)
+}
+
+// Get the message of the commit currently checked out.
+func GitHeadCommitMessage(repoPath string) (string, error) {
+	return gitClient.HeadCommitMessage(repoPath)
+}
+
+// Reset the current branch to the specified commit, discarding any changes (and commits) after it.
+// basically `git reset --hard <sha>`
+func GitResetToCommit(repoPath string, sha string) error {
+	return gitClient.ResetToCommit(repoPath, sha)
+}
+
+// Read the content of a file at the specified ref (eg. a commit sha), without checking it out.
+func GitFileContent(repoPath string, ref string, filePath string) ([]byte, error) {
+	return gitClient.FileContent(repoPath, ref, filePath)
+}
+
+func GitSignature(authorName string, authorEmail string) *GitIdentity {
+	return &GitIdentity{
+		Name:  authorName,
+		Email: authorEmail,
+		When:  time.Now(),
+	}
+}
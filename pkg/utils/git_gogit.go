@@ -0,0 +1,903 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/go-git/go-git/v5"
+	gogitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gogitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// pure Go git backend, does not require cgo or libgit2.
+type goGitClient struct{}
+
+// the number of tagged commits considered by FindNearestMatchingTagName, the `git describe` default.
+const goGitDescribeMaxCandidates = 10
+
+func (c *goGitClient) Clone(parentPath string, repositoryName string, gitRemote string) (string, error) {
+	absPath, _ := filepath.Abs(path.Join(parentPath, repositoryName))
+
+	if !FileExists(absPath) {
+		os.MkdirAll(absPath, os.ModePerm)
+	} else {
+		return "", errors.ScmFilesystemError(fmt.Sprintf("The local repository path already exists, this should never happen. %s", absPath))
+	}
+
+	auth, aerr := goGitAuth(gitRemote)
+	if aerr != nil {
+		return absPath, aerr
+	}
+	_, err := git.PlainClone(absPath, false, &git.CloneOptions{URL: gitRemote, Auth: auth})
+	return absPath, err
+}
+
+func (c *goGitClient) FetchPullRequest(repoPath string, pullRequestNumber string, localBranchName string, srcPatternTmpl string, destPatternTmpl string) error {
+
+	//defaults for Templates if they are not specified.
+	if len(srcPatternTmpl) == 0 {
+		srcPatternTmpl = "refs/pull/%s/merge" //this default template is for Github
+	}
+
+	if len(destPatternTmpl) == 0 {
+		destPatternTmpl = "refs/remotes/origin/pr/%s/merge"
+	}
+
+	//populate the templates
+	srcPattern := fmt.Sprintf(srcPatternTmpl, pullRequestNumber)
+	destPattern := fmt.Sprintf(destPatternTmpl, pullRequestNumber)
+	refspec := fmt.Sprintf("+%s:%s", srcPattern, destPattern)
+
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	// fetch the pull request merge and head references into this repo.
+	if ferr := goGitFetch(repo, "origin", refspec); ferr != nil {
+		log.Print("Failed to fetch PR reference from remote")
+		return ferr
+	}
+
+	// Get a reference to the PR merge branch in this repo
+	prRef, err := repo.Reference(plumbing.ReferenceName(destPattern), true)
+	if err != nil {
+		log.Print("Failed to find PR reference locally: " + destPattern)
+		return err
+	}
+
+	// No local branch, lets create one
+	if berr := goGitEnsureBranch(repo, localBranchName, prRef.Hash()); berr != nil {
+		log.Print("Failed to create local branch: " + localBranchName)
+		return berr
+	}
+	return goGitCheckoutBranch(repo, localBranchName)
+}
+
+func (c *goGitClient) MergeRemoteBranch(repoPath string, localBranchName string, baseBranchName string, remoteUrl string, remoteBranchName string, strategy string, message string, signature *GitIdentity) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	// Lookup commmit for base branch
+	baseBranch, err := repo.Reference(plumbing.NewBranchReferenceName(baseBranchName), true)
+	if err != nil {
+		log.Print("Failed to find local base branch: " + baseBranchName)
+		return err
+	}
+
+	// Creating local pr branch from the base branch commit, if it doesn't exist.
+	if berr := goGitEnsureBranch(repo, localBranchName, baseBranch.Hash()); berr != nil {
+		log.Print("Failed to create local branch: " + localBranchName)
+		return berr
+	}
+	if cerr := goGitCheckoutBranch(repo, localBranchName); cerr != nil {
+		log.Print("Failed to checkout branch " + localBranchName)
+		return cerr
+	}
+
+	//add a new remote for the PR head, and fetch the commits for the remoteBranchName
+	prRemoteAlias := "pr_origin"
+	if _, rerr := repo.CreateRemote(&gogitconfig.RemoteConfig{Name: prRemoteAlias, URLs: []string{remoteUrl}}); rerr != nil {
+		return rerr
+	}
+	remoteRefName := plumbing.NewRemoteReferenceName(prRemoteAlias, remoteBranchName)
+	if ferr := goGitFetch(repo, prRemoteAlias, fmt.Sprintf("+refs/heads/%s:%s", remoteBranchName, remoteRefName)); ferr != nil {
+		return ferr
+	}
+	remoteBranch, err := repo.Reference(remoteRefName, true)
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		log.Print("Failed get head ")
+		return err
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	remoteCommit, err := repo.CommitObject(remoteBranch.Hash())
+	if err != nil {
+		return err
+	}
+
+	// Do merge analysis
+	upToDate, err := remoteCommit.IsAncestor(headCommit)
+	if err != nil {
+		return err
+	} else if upToDate {
+		log.Print("Found nothing to merge. This should not happen for valid PR's")
+		return errors.ScmMergeNothingToMergeError("Found nothing to merge. This should not happen for valid PR's")
+	}
+	fastForward, err := headCommit.IsAncestor(remoteCommit)
+	if err != nil {
+		return err
+	}
+
+	switch strategy {
+	case "", GitMergeStrategyMerge, GitMergeStrategySquash:
+		// like libgit2, a merge (or squash) commit is created, even if the base branch could be fast-forwarded.
+		treeId, merr := goGitMergeCommits(repo, headCommit, remoteCommit)
+		if merr != nil {
+			return merr
+		}
+		parentHashes := []plumbing.Hash{headCommit.Hash}
+		if strategy != GitMergeStrategySquash {
+			parentHashes = append(parentHashes, remoteCommit.Hash)
+		}
+		return goGitCommitTree(repo, treeId, parentHashes, message, signature)
+	case GitMergeStrategyRebase:
+		if fastForward {
+			return goGitResetHard(repo, remoteCommit.Hash)
+		}
+		return goGitRebaseCommits(repo, headCommit, remoteCommit, signature)
+	case GitMergeStrategyFastForwardOnly:
+		if fastForward {
+			return goGitResetHard(repo, remoteCommit.Hash)
+		}
+		return errors.ScmMergeStrategyError(fmt.Sprintf("Cannot fast-forward %s to %s. Please rebase the pull request onto %s", baseBranchName, remoteBranchName, baseBranchName))
+	default:
+		return errors.ScmMergeStrategyError(fmt.Sprintf("Unsupported merge strategy: %s", strategy))
+	}
+}
+
+func (c *goGitClient) Checkout(repoPath string, branchName string) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	//Getting the reference for the remote branch
+	remoteBranch, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branchName), true)
+	if err != nil {
+		log.Print("Failed to find remote branch: " + branchName)
+		return err
+	}
+
+	// No local branch, lets create one
+	if _, lerr := repo.Reference(plumbing.NewBranchReferenceName(branchName), false); lerr != nil {
+		if berr := goGitEnsureBranch(repo, branchName, remoteBranch.Hash()); berr != nil {
+			log.Print("Failed to create local branch: " + branchName)
+			return berr
+		}
+
+		// Setting upstream to origin branch
+		if uerr := repo.CreateBranch(&gogitconfig.Branch{Name: branchName, Remote: "origin", Merge: plumbing.NewBranchReferenceName(branchName)}); uerr != nil {
+			log.Print("Failed to create upstream to origin/" + branchName)
+			return uerr
+		}
+	}
+	return goGitCheckoutBranch(repo, branchName)
+}
+
+func (c *goGitClient) Commit(repoPath string, message string, signature *GitIdentity, signer GitSigner) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+	worktree, werr := repo.Worktree()
+	if werr != nil {
+		return werr
+	}
+
+	if aerr := worktree.AddWithOptions(&git.AddOptions{All: true}); aerr != nil {
+		return aerr
+	}
+	commitId, cerr := worktree.Commit(message, &git.CommitOptions{Author: goGitSignature(signature), Committer: goGitSignature(signature)})
+	if cerr != nil || signer == nil {
+		return cerr
+	}
+
+	// go-git can only sign commits with GPG keys, so the unsigned commit is replaced with a signed commit object.
+	commit, lerr := repo.CommitObject(commitId)
+	if lerr != nil {
+		return lerr
+	}
+	parentShas := []string{}
+	for _, parentHash := range commit.ParentHashes {
+		parentShas = append(parentShas, parentHash.String())
+	}
+	commitContent, serr := gitSignedCommitObject(commit.TreeHash.String(), parentShas, signature, message, signer)
+	if serr != nil {
+		return serr
+	}
+	signedCommitId, werr := goGitWriteObject(repo, plumbing.CommitObject, commitContent)
+	if werr != nil {
+		return werr
+	}
+	head, herr := repo.Head()
+	if herr != nil {
+		return herr
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), signedCommitId))
+}
+
+func (c *goGitClient) Tag(repoPath string, version string, message string, signature *GitIdentity, signer GitSigner) (string, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+	commitHead, herr := repo.Head()
+	if herr != nil {
+		return "", herr
+	}
+
+	tagMessage := fmt.Sprintf("(%s) %s", version, message)
+	if signer != nil {
+		if _, terr := repo.Tag(version); terr == nil {
+			return "", git.ErrTagExists
+		}
+		tagContent, serr := gitSignedTagObject(commitHead.Hash().String(), version, signature, tagMessage, signer)
+		if serr != nil {
+			return "", serr
+		}
+		tagId, werr := goGitWriteObject(repo, plumbing.TagObject, tagContent)
+		if werr != nil {
+			return "", werr
+		}
+		if rerr := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(version), tagId)); rerr != nil {
+			return "", rerr
+		}
+		return commitHead.Hash().String(), nil
+	}
+
+	if _, terr := repo.CreateTag(version, commitHead.Hash(), &git.CreateTagOptions{Tagger: goGitSignature(signature), Message: tagMessage}); terr != nil {
+		return "", terr
+	}
+	return commitHead.Hash().String(), nil
+}
+
+func (c *goGitClient) Push(repoPath string, localBranch string, remoteBranch string, tagName string) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+	return goGitPush(repo,
+		fmt.Sprintf("refs/heads/%s:refs/heads/%s", localBranch, remoteBranch),
+		fmt.Sprintf("refs/tags/%s:refs/tags/%s", tagName, tagName),
+	)
+}
+
+// Push a local branch to a (possibly different) remote branch, without pushing tags.
+// if force is true, the remote branch will be overwritten.
+func (c *goGitClient) PushBranch(repoPath string, localBranch string, remoteBranch string, force bool) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	refspec := fmt.Sprintf("refs/heads/%s:refs/heads/%s", localBranch, remoteBranch)
+	if force {
+		refspec = "+" + refspec
+	}
+	return goGitPush(repo, refspec)
+}
+
+// Create a commit with an empty tree, and store it in a local reference (outside of refs/heads).
+// Used to store metadata (eg. the release lock lease) in the remote repository. The commit will have a single parent if
+// parentSha is specified.
+func (c *goGitClient) CreateMetadataCommit(repoPath string, refName string, parentSha string, message string, signature *GitIdentity) (string, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	treeId, terr := goGitWriteTree(repo, map[string]object.TreeEntry{})
+	if terr != nil {
+		return "", terr
+	}
+
+	parentHashes := []plumbing.Hash{}
+	if parentSha != "" {
+		parent, perr := repo.CommitObject(plumbing.NewHash(parentSha))
+		if perr != nil {
+			return "", perr
+		}
+		parentHashes = append(parentHashes, parent.Hash)
+	}
+
+	commitId, cerr := goGitWriteCommit(repo, &object.Commit{
+		Author:       *goGitSignature(signature),
+		Committer:    *goGitSignature(signature),
+		Message:      message,
+		TreeHash:     treeId,
+		ParentHashes: parentHashes,
+	})
+	if cerr != nil {
+		return "", cerr
+	}
+	if rerr := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(refName), commitId)); rerr != nil {
+		return "", rerr
+	}
+	return commitId.String(), nil
+}
+
+// Fetch a reference from the remote repository (origin) into a local reference, and return the referenced commit sha &
+// message. An empty sha is returned if the remote reference does not exist.
+func (c *goGitClient) FetchRemoteRef(repoPath string, remoteRefName string, localRefName string) (string, string, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return "", "", oerr
+	}
+	remote, lerr := repo.Remote("origin")
+	if lerr != nil {
+		return "", "", lerr
+	}
+	auth, aerr := goGitAuth(remote.Config().URLs[0])
+	if aerr != nil {
+		return "", "", aerr
+	}
+
+	remoteRefs, rerr := remote.List(&git.ListOptions{Auth: auth})
+	if rerr == transport.ErrEmptyRemoteRepository {
+		return "", "", nil
+	} else if rerr != nil {
+		return "", "", rerr
+	}
+	var remoteRef *plumbing.Reference
+	for _, ref := range remoteRefs {
+		if ref.Name().String() == remoteRefName {
+			remoteRef = ref
+		}
+	}
+	if remoteRef == nil {
+		return "", "", nil
+	}
+
+	if ferr := goGitFetchRemote(remote, fmt.Sprintf("+%s:%s", remoteRefName, localRefName)); ferr != nil {
+		return "", "", ferr
+	}
+	commit, cerr := repo.CommitObject(remoteRef.Hash())
+	if cerr != nil {
+		return "", "", cerr
+	}
+	return commit.Hash.String(), commit.Message, nil
+}
+
+// Fetch a reference from a remote url (eg. a pull request head repository) into a local reference.
+func (c *goGitClient) FetchRemoteUrlRef(repoPath string, remoteUrl string, remoteRefName string, localRefName string) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	// the remote is not stored in the repository config, like an anonymous libgit2 remote.
+	remote := git.NewRemote(repo.Storer, &gogitconfig.RemoteConfig{Name: "anonymous", URLs: []string{remoteUrl}})
+	return goGitFetchRemote(remote, fmt.Sprintf("+%s:%s", remoteRefName, localRefName))
+}
+
+// Fetch all tags from the remote repository (origin), including tags created after the repository was cloned.
+func (c *goGitClient) FetchTags(repoPath string) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+	remote, lerr := repo.Remote("origin")
+	if lerr != nil {
+		return lerr
+	}
+	return goGitFetchRemote(remote, "+refs/tags/*:refs/tags/*")
+}
+
+// Push a local reference to the remote repository (origin), without pushing branches or tags.
+// The push is rejected if the remote reference cannot be fast-forwarded, unless force is true.
+// If localRefName is empty, the remote reference is deleted.
+func (c *goGitClient) PushRef(repoPath string, localRefName string, remoteRefName string, force bool) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	refspec := fmt.Sprintf("%s:%s", localRefName, remoteRefName)
+	if force && localRefName != "" {
+		refspec = "+" + refspec
+	}
+	return goGitPush(repo, refspec)
+}
+
+// Check if a tag already exists in the local repository.
+func (c *goGitClient) TagExists(repoPath string, tagName string) (bool, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return false, oerr
+	}
+
+	_, lerr := repo.Reference(plumbing.NewTagReferenceName(tagName), false)
+	if lerr == plumbing.ErrReferenceNotFound {
+		return false, nil
+	} else if lerr != nil {
+		return false, lerr
+	}
+	return true, nil
+}
+
+// Get the message of the commit currently checked out.
+func (c *goGitClient) HeadCommitMessage(repoPath string) (string, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	head, herr := repo.Head()
+	if herr != nil {
+		return "", herr
+	}
+	commit, lerr := repo.CommitObject(head.Hash())
+	if lerr != nil {
+		return "", lerr
+	}
+	return commit.Message, nil
+}
+
+// Reset the current branch to the specified commit, discarding any changes (and commits) after it.
+// basically `git reset --hard <sha>`
+func (c *goGitClient) ResetToCommit(repoPath string, sha string) error {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	commit, lerr := repo.CommitObject(plumbing.NewHash(sha))
+	if lerr != nil {
+		return lerr
+	}
+	return goGitResetHard(repo, commit.Hash)
+}
+
+// Read the raw content of a commit object (headers & message), eg. to verify the commit signature.
+func (c *goGitClient) CommitObject(repoPath string, sha string) ([]byte, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+
+	commitObj, lerr := repo.Storer.EncodedObject(plumbing.CommitObject, plumbing.NewHash(sha))
+	if lerr != nil {
+		return nil, lerr
+	}
+	reader, rerr := commitObj.Reader()
+	if rerr != nil {
+		return nil, rerr
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// Read the content of a file at the specified ref (eg. a commit sha), without checking it out.
+func (c *goGitClient) FileContent(repoPath string, ref string, filePath string) ([]byte, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+
+	commitId, rerr := repo.ResolveRevision(plumbing.Revision(ref))
+	if rerr != nil {
+		return nil, rerr
+	}
+	commit, lerr := repo.CommitObject(*commitId)
+	if lerr != nil {
+		return nil, lerr
+	}
+	file, ferr := commit.File(filePath)
+	if ferr != nil {
+		return nil, ferr
+	}
+	content, cerr := file.Contents()
+	if cerr != nil {
+		return nil, cerr
+	}
+	return []byte(content), nil
+}
+
+// go-git does not implement `git describe`, so the same algorithm is used: the candidates are the first tagged commits
+// found when walking the commits from HEAD (newest first), and the nearest tag is the candidate with the fewest commits
+// between it and HEAD (the commits reachable from HEAD, but not from the tagged commit). Ties are won by the candidate
+// found first. Annotated tags are preferred over lightweight tags for the same commit.
+func (c *goGitClient) FindNearestMatchingTagName(repoPath string, pattern string) (string, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	type tagCandidate struct {
+		name      string
+		annotated bool
+	}
+	candidates := map[plumbing.Hash]tagCandidate{}
+	tagRefs, terr := repo.Tags()
+	if terr != nil {
+		return "", terr
+	}
+	if ierr := tagRefs.ForEach(func(tagRef *plumbing.Reference) error {
+		tagName := tagRef.Name().Short()
+		if pattern != "" {
+			if matched, merr := path.Match(pattern, tagName); merr != nil || !matched {
+				return merr
+			}
+		}
+		candidate := tagCandidate{name: tagName}
+		commitId := tagRef.Hash()
+		if tag, lerr := repo.TagObject(tagRef.Hash()); lerr == nil {
+			commit, cerr := tag.Commit()
+			if cerr != nil {
+				return nil //annotated tags that do not point to a commit can't describe a commit.
+			}
+			candidate.annotated = true
+			commitId = commit.Hash
+		}
+		if existing, found := candidates[commitId]; !found || (candidate.annotated && !existing.annotated) {
+			candidates[commitId] = candidate
+		}
+		return nil
+	}); ierr != nil {
+		return "", ierr
+	}
+
+	head, herr := repo.Head()
+	if herr != nil {
+		return "", herr
+	}
+	headCommit, lerr := repo.CommitObject(head.Hash())
+	if lerr != nil {
+		return "", lerr
+	}
+
+	headAncestors := 0
+	found := []plumbing.Hash{}
+	werr := object.NewCommitIterCTime(headCommit, nil, nil).ForEach(func(commit *object.Commit) error {
+		headAncestors++
+		if _, isCandidate := candidates[commit.Hash]; isCandidate && len(found) < goGitDescribeMaxCandidates {
+			found = append(found, commit.Hash)
+		}
+		return nil
+	})
+	if werr != nil {
+		return "", werr
+	} else if len(found) == 0 {
+		return "", fmt.Errorf("No tags can describe '%s'", head.Hash().String())
+	}
+
+	nearestTag, nearestDepth := "", -1
+	for _, commitId := range found {
+		commit, cerr := repo.CommitObject(commitId)
+		if cerr != nil {
+			return "", cerr
+		}
+		tagAncestors := 0
+		if ierr := object.NewCommitPreorderIter(commit, nil, nil).ForEach(func(*object.Commit) error {
+			tagAncestors++
+			return nil
+		}); ierr != nil {
+			return "", ierr
+		}
+
+		if depth := headAncestors - tagAncestors; nearestDepth < 0 || depth < nearestDepth {
+			nearestTag, nearestDepth = candidates[commitId].name, depth
+		}
+	}
+	return nearestTag, nil
+}
+
+func (c *goGitClient) GenerateChangelog(repoPath string, baseSha string, headSha string) (string, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	markdown := StripIndent(`Timestamp |  SHA | Message | Author
+	------------- | ------------- | ------------- | -------------
+	`)
+
+	werr := goGitWalkRange(repo, baseSha, headSha, func(commit *object.Commit) error {
+		markdown += fmt.Sprintf("%s | %.8s | %s | %s\n", //TODO: this should have a link for the SHA.
+			commit.Author.When.UTC().Format("2006-01-02T15:04Z"),
+			commit.Hash.String(),
+			cleanCommitMessage(commit.Message),
+			commit.Author.Name,
+		)
+		return nil
+	})
+	if werr != nil {
+		return "", werr
+	}
+	return markdown, nil
+}
+
+func (c *goGitClient) CommitsBetween(repoPath string, baseRef string, headRef string) ([]*pipeline.GitCommit, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+
+	commits := []*pipeline.GitCommit{}
+	werr := goGitWalkRange(repo, baseRef, headRef, func(commit *object.Commit) error {
+		commits = append(commits, &pipeline.GitCommit{
+			Sha:         commit.Hash.String(),
+			Message:     commit.Message,
+			AuthorName:  commit.Author.Name,
+			AuthorEmail: commit.Author.Email,
+			Date:        commit.Author.When,
+			ParentCount: commit.NumParents(),
+		})
+		return nil
+	})
+	if werr != nil {
+		return nil, werr
+	}
+	return commits, nil
+}
+
+func (c *goGitClient) GetTagDetails(repoPath string, tagName string) (*pipeline.GitTagDetails, error) {
+	repo, oerr := git.PlainOpen(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+
+	tagRef, rerr := repo.Tag(tagName)
+	if rerr != nil {
+		return nil, rerr
+	}
+	tag, lerr := repo.TagObject(tagRef.Hash()) //assume its an annotated tag.
+
+	var currentTag *pipeline.GitTagDetails
+	if lerr != nil {
+		//this is a lightweight tag, not an annotated tag.
+		commitRef, cerr := repo.CommitObject(tagRef.Hash())
+		if cerr != nil {
+			return nil, cerr
+		}
+
+		log.Printf("Light-weight tag (%s) Commit ID: %s, DATE: %s", tagName, commitRef.Hash.String(), commitRef.Author.When.String())
+
+		currentTag = &pipeline.GitTagDetails{
+			TagShortName: tagName,
+			CommitSha:    commitRef.Hash.String(),
+			CommitDate:   commitRef.Author.When,
+		}
+
+	} else {
+
+		log.Printf("Annotated tag (%s) Tag ID: %s, Commit ID: %s, DATE: %s", tagName, tag.Hash.String(), tag.Target.String(), tag.Tagger.When.String())
+
+		currentTag = &pipeline.GitTagDetails{
+			TagShortName: tagName,
+			CommitSha:    tag.Target.String(),
+			CommitDate:   tag.Tagger.When,
+		}
+	}
+	return currentTag, nil
+}
+
+//private methods
+
+func goGitSignature(signature *GitIdentity) *object.Signature {
+	return &object.Signature{
+		Name:  signature.Name,
+		Email: signature.Email,
+		When:  signature.When,
+	}
+}
+
+// fetch the refspec from the remote. An up-to-date reference is not an error.
+func goGitFetch(repo *git.Repository, remoteName string, refspec string) error {
+	remote, lerr := repo.Remote(remoteName)
+	if lerr != nil {
+		return lerr
+	}
+	return goGitFetchRemote(remote, refspec)
+}
+
+func goGitFetchRemote(remote *git.Remote, refspec string) error {
+	auth, aerr := goGitAuth(remote.Config().URLs[0])
+	if aerr != nil {
+		return aerr
+	}
+
+	ferr := remote.Fetch(&git.FetchOptions{RefSpecs: []gogitconfig.RefSpec{gogitconfig.RefSpec(refspec)}, Auth: auth})
+	if ferr == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return ferr
+}
+
+// push the refspecs to origin. Up-to-date references are not an error.
+func goGitPush(repo *git.Repository, refspecs ...string) error {
+	remote, lerr := repo.Remote("origin")
+	if lerr != nil {
+		return lerr
+	}
+	auth, aerr := goGitAuth(remote.Config().URLs[0])
+	if aerr != nil {
+		return aerr
+	}
+
+	pushRefSpecs := []gogitconfig.RefSpec{}
+	for _, refspec := range refspecs {
+		pushRefSpecs = append(pushRefSpecs, gogitconfig.RefSpec(refspec))
+	}
+	perr := remote.Push(&git.PushOptions{RefSpecs: pushRefSpecs, Auth: auth})
+	if perr == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return perr
+}
+
+// create a local branch pointing at the commit, unless it already exists.
+func goGitEnsureBranch(repo *git.Repository, branchName string, commitId plumbing.Hash) error {
+	branchRefName := plumbing.NewBranchReferenceName(branchName)
+	if _, err := repo.Reference(branchRefName, false); err == nil {
+		return nil
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(branchRefName, commitId))
+}
+
+// checkout the local branch, and point HEAD at it.
+func goGitCheckoutBranch(repo *git.Repository, branchName string) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branchName)})
+}
+
+// point the current branch (and HEAD) at the commit, discarding any changes. basically `git reset --hard <sha>`
+func goGitResetHard(repo *git.Repository, commitId plumbing.Hash) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Reset(&git.ResetOptions{Commit: commitId, Mode: git.HardReset})
+}
+
+// create a commit for the tree on the current branch.
+func goGitCommitTree(repo *git.Repository, treeId plumbing.Hash, parentHashes []plumbing.Hash, message string, signature *GitIdentity) error {
+	commitId, err := goGitWriteCommit(repo, &object.Commit{
+		Author:       *goGitSignature(signature),
+		Committer:    *goGitSignature(signature),
+		Message:      message,
+		TreeHash:     treeId,
+		ParentHashes: parentHashes,
+	})
+	if err != nil {
+		return err
+	}
+	return goGitResetHard(repo, commitId)
+}
+
+// write the commit to the object database, without updating any references.
+func goGitWriteCommit(repo *git.Repository, commit *object.Commit) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+// write a raw git object (eg. a signed commit) to the object database.
+func goGitWriteObject(repo *git.Repository, objectType plumbing.ObjectType, content []byte) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(objectType)
+	obj.SetSize(int64(len(content)))
+	writer, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := writer.Write(content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+// walk the commits reachable from headRef but not from baseRef (all commits if baseRef is empty), newest first.
+func goGitWalkRange(repo *git.Repository, baseRef string, headRef string, walkFn func(commit *object.Commit) error) error {
+	headId, rerr := repo.ResolveRevision(plumbing.Revision(headRef))
+	if rerr != nil {
+		return rerr
+	}
+	headCommit, lerr := repo.CommitObject(*headId)
+	if lerr != nil {
+		return lerr
+	}
+
+	hidden := map[plumbing.Hash]bool{}
+	if baseRef != "" {
+		baseId, berr := repo.ResolveRevision(plumbing.Revision(baseRef))
+		if berr != nil {
+			return berr
+		}
+		baseCommit, lerr := repo.CommitObject(*baseId)
+		if lerr != nil {
+			return lerr
+		}
+		if herr := object.NewCommitPreorderIter(baseCommit, nil, nil).ForEach(func(commit *object.Commit) error {
+			hidden[commit.Hash] = true
+			return nil
+		}); herr != nil {
+			return herr
+		}
+	}
+	return object.NewCommitIterCTime(headCommit, hidden, nil).ForEach(walkFn)
+}
+
+// authenticate SSH remotes with the configured SSH auth (GitConfigureSshAuth). HTTPS remotes use the credentials
+// embedded in the remote url.
+func goGitAuth(remoteUrl string) (transport.AuthMethod, error) {
+	if gitSshAuth == nil {
+		return nil, nil
+	}
+	endpoint, err := transport.NewEndpoint(remoteUrl)
+	if err != nil {
+		return nil, err
+	} else if endpoint.Protocol != "ssh" {
+		return nil, nil
+	}
+
+	username := endpoint.User
+	if username == "" {
+		username = gitSshAuth.Username
+	}
+	publicKeys, err := gogitssh.NewPublicKeys(username, gitSshAuth.PrivateKey, gitSshAuth.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	publicKeys.HostKeyCallback = goGitHostKeyCallback
+	return publicKeys, nil
+}
+
+func goGitHostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = host
+	}
+
+	if gitSshAuth.KnownHostsPolicy == GitKnownHostsPolicyInsecure {
+		log.Printf("WARNING: accepting the host key for %s without verification (%s)", hostname, ssh.FingerprintSHA256(key))
+		return nil
+	}
+
+	matchesHostkey := func(publicKey ssh.PublicKey) bool {
+		return bytes.Equal(publicKey.Marshal(), key.Marshal())
+	}
+	if gitKnownHostsContains(gitKnownHosts(), hostname, matchesHostkey) {
+		return nil
+	}
+	log.Printf("The host key for %s is not a known host, add it to the known hosts (eg. `ssh-keyscan %s`)", hostname, hostname)
+	return fmt.Errorf("The host key for %s is not a known host", hostname)
+}
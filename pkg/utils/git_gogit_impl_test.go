@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGoGitMergeText(t *testing.T) {
+	t.Parallel()
+
+	base := "line 1\nline 2\nline 3\nline 4\nline 5\n"
+	testCases := []struct {
+		name   string
+		ours   string
+		theirs string
+		merged string
+	}{
+		{"unchanged", base, base, base},
+		{"one side", "line 1\nline 2 changed\nline 3\nline 4\nline 5\n", base, "line 1\nline 2 changed\nline 3\nline 4\nline 5\n"},
+		{"both sides", "line 1 ours\nline 2\nline 3\nline 4\nline 5\n", "line 1\nline 2\nline 3\nline 4\nline 5 theirs\n", "line 1 ours\nline 2\nline 3\nline 4\nline 5 theirs\n"},
+		{"identical changes", "line 1\nline 2\nline 3 both\nline 4\nline 5\n", "line 1\nline 2\nline 3 both\nline 4\nline 5\n", "line 1\nline 2\nline 3 both\nline 4\nline 5\n"},
+		{"insert & delete", "line 0\nline 1\nline 2\nline 3\nline 4\nline 5\n", "line 1\nline 2\nline 3\nline 4\n", "line 0\nline 1\nline 2\nline 3\nline 4\n"},
+		{"no trailing newline", "line 1 ours\nline 2\nline 3\nline 4\nline 5\n", "line 1\nline 2\nline 3\nline 4\nline 5", "line 1 ours\nline 2\nline 3\nline 4\nline 5"},
+	}
+
+	for _, testCase := range testCases {
+		//test
+		merged, ok := goGitMergeText(base, testCase.ours, testCase.theirs)
+
+		//assert
+		require.True(t, ok, testCase.name)
+		require.Equal(t, testCase.merged, merged, testCase.name)
+	}
+}
+
+func TestGoGitMergeText_Conflict(t *testing.T) {
+	t.Parallel()
+
+	base := "line 1\nline 2\nline 3\nline 4\nline 5\n"
+	testCases := []struct {
+		name   string
+		ours   string
+		theirs string
+	}{
+		{"same line", "line 1\nline 2\nline 3 ours\nline 4\nline 5\n", "line 1\nline 2\nline 3 theirs\nline 4\nline 5\n"},
+		{"adjacent lines", "line 1\nline 2\nline 3 ours\nline 4\nline 5\n", "line 1\nline 2\nline 3\nline 4 theirs\nline 5\n"},
+		{"change & delete", "line 1\nline 2\nline 3 ours\nline 4\nline 5\n", "line 1\nline 2\nline 4\nline 5\n"},
+		{"insertions", "line 1\nline 2\nours\nline 3\nline 4\nline 5\n", "line 1\nline 2\ntheirs\nline 3\nline 4\nline 5\n"},
+	}
+
+	for _, testCase := range testCases {
+		//test
+		merged, ok := goGitMergeText(base, testCase.ours, testCase.theirs)
+
+		//assert
+		require.False(t, ok, testCase.name)
+		require.Empty(t, merged, testCase.name)
+	}
+}
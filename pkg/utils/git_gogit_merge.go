@@ -0,0 +1,457 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"io"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+// go-git does not implement merges, so a three-way merge is implemented here: each file changed on only one side is
+// taken from that side, and files changed on both sides are merged line by line (basically `git merge-file`).
+// Conflicting changes fail the merge, like libgit2 with MergeTreeFailOnConflict.
+
+// merge the theirs commit into the ours commit, and return the merged tree.
+func goGitMergeCommits(repo *git.Repository, ours *object.Commit, theirs *object.Commit) (plumbing.Hash, error) {
+	// if there are multiple merge bases (criss-cross merges), the first is used instead of a virtual merge base.
+	mergeBases, err := ours.MergeBase(theirs)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	var baseTree *object.Tree
+	if len(mergeBases) > 0 {
+		if baseTree, err = mergeBases[0].Tree(); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	oursTree, err := ours.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	theirsTree, err := theirs.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	treeId, conflicts, err := goGitMergeTrees(repo, baseTree, oursTree, theirsTree)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	} else if len(conflicts) > 0 {
+		log.Printf("Conflicts encountered. Please resolve them. %v", conflicts)
+		return plumbing.ZeroHash, errors.ScmMergeConflictError("Merge resulted in conflicts. Please solve the conflicts before merging.")
+	}
+	return treeId, nil
+}
+
+// replay the theirs commits (that are not already in ours) on top of ours, similar to `git rebase`.
+// Original commit authors & messages are retained. Merge commits cannot be replayed, so an error is returned (before
+// any commits are applied) if the theirs commits include a merge commit.
+func goGitRebaseCommits(repo *git.Repository, ours *object.Commit, theirs *object.Commit, signature *GitIdentity) error {
+	hidden := map[plumbing.Hash]bool{}
+	if err := object.NewCommitPreorderIter(ours, nil, nil).ForEach(func(commit *object.Commit) error {
+		hidden[commit.Hash] = true
+		return nil
+	}); err != nil {
+		return err
+	}
+	commits, err := goGitTopologicalCommits(theirs, hidden)
+	if err != nil {
+		return err
+	}
+
+	for _, commit := range commits {
+		if commit.NumParents() > 1 {
+			return gitRebaseMergeCommitError(commit.Hash.String())
+		}
+	}
+
+	current := ours
+	for _, commit := range commits {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return err
+		}
+		baseTree, err := parent.Tree()
+		if err != nil {
+			return err
+		}
+		currentTree, err := current.Tree()
+		if err != nil {
+			return err
+		}
+		commitTree, err := commit.Tree()
+		if err != nil {
+			return err
+		}
+
+		treeId, conflicts, err := goGitMergeTrees(repo, baseTree, currentTree, commitTree)
+		if err != nil {
+			return err
+		} else if len(conflicts) > 0 {
+			log.Printf("Conflicts encountered. Please resolve them. %v", conflicts)
+			return errors.ScmMergeConflictError(fmt.Sprintf("Rebase resulted in conflicts while applying %s. Please rebase the pull request manually.", commit.Hash.String()))
+		}
+
+		commitId, err := goGitWriteCommit(repo, &object.Commit{
+			Author:       commit.Author,
+			Committer:    *goGitSignature(signature),
+			Message:      commit.Message,
+			TreeHash:     treeId,
+			ParentHashes: []plumbing.Hash{current.Hash},
+		})
+		if err != nil {
+			return err
+		}
+		if current, err = repo.CommitObject(commitId); err != nil {
+			return err
+		}
+	}
+	return goGitResetHard(repo, current.Hash)
+}
+
+// the commits reachable from head (but not hidden), parents before children.
+func goGitTopologicalCommits(head *object.Commit, hidden map[plumbing.Hash]bool) ([]*object.Commit, error) {
+	sorted := []*object.Commit{}
+	visited := map[plumbing.Hash]bool{}
+	var visit func(commit *object.Commit) error
+	visit = func(commit *object.Commit) error {
+		if hidden[commit.Hash] || visited[commit.Hash] {
+			return nil
+		}
+		visited[commit.Hash] = true
+		if err := commit.Parents().ForEach(visit); err != nil {
+			return err
+		}
+		sorted = append(sorted, commit)
+		return nil
+	}
+	return sorted, visit(head)
+}
+
+// three-way merge of the trees (the base tree may be nil if there is no merge base). Returns the merged tree, or the
+// paths that could not be merged.
+func goGitMergeTrees(repo *git.Repository, base *object.Tree, ours *object.Tree, theirs *object.Tree) (plumbing.Hash, []string, error) {
+	baseEntries, err := goGitTreeEntries(base)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	oursEntries, err := goGitTreeEntries(ours)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	theirsEntries, err := goGitTreeEntries(theirs)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+
+	paths := map[string]bool{}
+	for _, entries := range []map[string]object.TreeEntry{baseEntries, oursEntries, theirsEntries} {
+		for filePath := range entries {
+			paths[filePath] = true
+		}
+	}
+
+	merged := map[string]object.TreeEntry{}
+	conflicts := []string{}
+	for filePath := range paths {
+		baseEntry, inBase := baseEntries[filePath]
+		oursEntry, inOurs := oursEntries[filePath]
+		theirsEntry, inTheirs := theirsEntries[filePath]
+
+		switch {
+		case goGitSameEntry(oursEntry, inOurs, theirsEntry, inTheirs):
+			if inOurs {
+				merged[filePath] = oursEntry
+			}
+		case goGitSameEntry(baseEntry, inBase, oursEntry, inOurs):
+			// only changed (or deleted) in theirs
+			if inTheirs {
+				merged[filePath] = theirsEntry
+			}
+		case goGitSameEntry(baseEntry, inBase, theirsEntry, inTheirs):
+			// only changed (or deleted) in ours
+			if inOurs {
+				merged[filePath] = oursEntry
+			}
+		default:
+			// changed on both sides, only the content of regular files can be merged.
+			entry, ok, merr := goGitMergeFile(repo, baseEntry, inBase, oursEntry, inOurs, theirsEntry, inTheirs)
+			if merr != nil {
+				return plumbing.ZeroHash, nil, merr
+			} else if !ok {
+				conflicts = append(conflicts, filePath)
+				continue
+			}
+			merged[filePath] = entry
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return plumbing.ZeroHash, conflicts, nil
+	}
+
+	treeId, err := goGitWriteTree(repo, merged)
+	if _, isConflict := err.(errors.ScmMergeConflictError); isConflict {
+		return plumbing.ZeroHash, []string{err.Error()}, nil
+	}
+	return treeId, nil, err
+}
+
+func goGitSameEntry(entry object.TreeEntry, exists bool, other object.TreeEntry, otherExists bool) bool {
+	if !exists || !otherExists {
+		return exists == otherExists
+	}
+	return entry.Hash == other.Hash && entry.Mode == other.Mode
+}
+
+// merge the content of a file changed on both sides. Returns false if the file cannot be merged (eg. deleted on one
+// side, binary files or conflicting changes).
+func goGitMergeFile(repo *git.Repository, base object.TreeEntry, inBase bool, ours object.TreeEntry, inOurs bool, theirs object.TreeEntry, inTheirs bool) (object.TreeEntry, bool, error) {
+	isRegularFile := func(entry object.TreeEntry) bool {
+		return entry.Mode == filemode.Regular || entry.Mode == filemode.Executable
+	}
+	if !inOurs || !inTheirs || !isRegularFile(ours) || !isRegularFile(theirs) {
+		return object.TreeEntry{}, false, nil
+	}
+
+	mode := ours.Mode
+	if ours.Mode != theirs.Mode {
+		if inBase && base.Mode == ours.Mode {
+			mode = theirs.Mode
+		} else if !inBase || base.Mode != theirs.Mode {
+			return object.TreeEntry{}, false, nil
+		}
+	}
+
+	baseContent := []byte{}
+	if inBase && isRegularFile(base) {
+		content, err := goGitBlobContent(repo, base.Hash)
+		if err != nil {
+			return object.TreeEntry{}, false, err
+		}
+		baseContent = content
+	}
+	oursContent, err := goGitBlobContent(repo, ours.Hash)
+	if err != nil {
+		return object.TreeEntry{}, false, err
+	}
+	theirsContent, err := goGitBlobContent(repo, theirs.Hash)
+	if err != nil {
+		return object.TreeEntry{}, false, err
+	}
+	for _, content := range [][]byte{baseContent, oursContent, theirsContent} {
+		if bytes.IndexByte(content, 0) >= 0 {
+			return object.TreeEntry{}, false, nil //binary files are not merged.
+		}
+	}
+
+	mergedContent, ok := goGitMergeText(string(baseContent), string(oursContent), string(theirsContent))
+	if !ok {
+		return object.TreeEntry{}, false, nil
+	}
+	blobId, err := goGitWriteObject(repo, plumbing.BlobObject, []byte(mergedContent))
+	if err != nil {
+		return object.TreeEntry{}, false, err
+	}
+	return object.TreeEntry{Name: ours.Name, Mode: mode, Hash: blobId}, true, nil
+}
+
+// a change replacing the base lines [start, end) with lines.
+type goGitHunk struct {
+	start int
+	end   int
+	lines []string
+}
+
+// merge the changes from base to ours & theirs. Overlapping (or adjacent) changes conflict, unless they are identical.
+func goGitMergeText(base string, ours string, theirs string) (string, bool) {
+	baseLines := goGitSplitLines(base)
+	oursHunks := goGitDiffHunks(base, ours)
+	theirsHunks := goGitDiffHunks(base, theirs)
+
+	var merged strings.Builder
+	position, o, t := 0, 0, 0
+	for o < len(oursHunks) || t < len(theirsHunks) {
+		// group the overlapping hunks from both sides, starting with the first hunk.
+		var oursGroup, theirsGroup []goGitHunk
+		var start, end int
+		if t >= len(theirsHunks) || (o < len(oursHunks) && oursHunks[o].start <= theirsHunks[t].start) {
+			start, end = oursHunks[o].start, oursHunks[o].end
+			oursGroup = append(oursGroup, oursHunks[o])
+			o++
+		} else {
+			start, end = theirsHunks[t].start, theirsHunks[t].end
+			theirsGroup = append(theirsGroup, theirsHunks[t])
+			t++
+		}
+		for {
+			if o < len(oursHunks) && oursHunks[o].start <= end {
+				oursGroup = append(oursGroup, oursHunks[o])
+				if oursHunks[o].end > end {
+					end = oursHunks[o].end
+				}
+				o++
+			} else if t < len(theirsHunks) && theirsHunks[t].start <= end {
+				theirsGroup = append(theirsGroup, theirsHunks[t])
+				if theirsHunks[t].end > end {
+					end = theirsHunks[t].end
+				}
+				t++
+			} else {
+				break
+			}
+		}
+
+		merged.WriteString(strings.Join(baseLines[position:start], ""))
+		oursText := goGitApplyHunks(baseLines, oursGroup, start, end)
+		theirsText := goGitApplyHunks(baseLines, theirsGroup, start, end)
+		if len(oursGroup) > 0 && len(theirsGroup) > 0 && oursText != theirsText {
+			return "", false
+		} else if len(oursGroup) > 0 {
+			merged.WriteString(oursText)
+		} else {
+			merged.WriteString(theirsText)
+		}
+		position = end
+	}
+	merged.WriteString(strings.Join(baseLines[position:], ""))
+	return merged.String(), true
+}
+
+// the line changes from base to other.
+func goGitDiffHunks(base string, other string) []goGitHunk {
+	hunks := []goGitHunk{}
+	position, current := 0, -1
+	for _, change := range diff.Do(base, other) {
+		lines := goGitSplitLines(change.Text)
+		if change.Type == diffmatchpatch.DiffEqual {
+			position += len(lines)
+			current = -1
+			continue
+		}
+		if current < 0 {
+			hunks = append(hunks, goGitHunk{start: position, end: position})
+			current = len(hunks) - 1
+		}
+		if change.Type == diffmatchpatch.DiffDelete {
+			position += len(lines)
+			hunks[current].end = position
+		} else {
+			hunks[current].lines = append(hunks[current].lines, lines...)
+		}
+	}
+	return hunks
+}
+
+// the content of the base lines [start, end) after applying the hunks.
+func goGitApplyHunks(baseLines []string, hunks []goGitHunk, start int, end int) string {
+	var content strings.Builder
+	position := start
+	for _, hunk := range hunks {
+		content.WriteString(strings.Join(baseLines[position:hunk.start], ""))
+		content.WriteString(strings.Join(hunk.lines, ""))
+		position = hunk.end
+	}
+	content.WriteString(strings.Join(baseLines[position:end], ""))
+	return content.String()
+}
+
+// split the text into lines, keeping the line endings.
+func goGitSplitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// the files (blobs, symlinks & submodules) in the tree, by path.
+func goGitTreeEntries(tree *object.Tree) (map[string]object.TreeEntry, error) {
+	entries := map[string]object.TreeEntry{}
+	if tree == nil {
+		return entries, nil
+	}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		filePath, entry, err := walker.Next()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if entry.Mode != filemode.Dir {
+			entries[filePath] = entry
+		}
+	}
+}
+
+func goGitBlobContent(repo *git.Repository, blobId plumbing.Hash) ([]byte, error) {
+	blob, err := repo.BlobObject(blobId)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// write the tree (and subtrees) for the files, by path.
+func goGitWriteTree(repo *git.Repository, entries map[string]object.TreeEntry) (plumbing.Hash, error) {
+	files := map[string]object.TreeEntry{}
+	dirs := map[string]map[string]object.TreeEntry{}
+	for filePath, entry := range entries {
+		parts := strings.SplitN(filePath, "/", 2)
+		if len(parts) == 1 {
+			files[parts[0]] = entry
+			continue
+		}
+		if dirs[parts[0]] == nil {
+			dirs[parts[0]] = map[string]object.TreeEntry{}
+		}
+		dirs[parts[0]][parts[1]] = entry
+	}
+
+	tree := &object.Tree{}
+	for name, entry := range files {
+		if _, isDir := dirs[name]; isDir {
+			return plumbing.ZeroHash, errors.ScmMergeConflictError(fmt.Sprintf("%s is both a file and a directory", name))
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: entry.Mode, Hash: entry.Hash})
+	}
+	for name, dirEntries := range dirs {
+		subtreeId, err := goGitWriteTree(repo, dirEntries)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: subtreeId})
+	}
+
+	// git sorts tree entries by name, as if directory names end with `/`
+	sortName := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return sortName(tree.Entries[i]) < sortName(tree.Entries[j]) })
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
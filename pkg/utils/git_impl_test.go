@@ -1,7 +1,274 @@
 package utils
 
-import "testing"
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+)
 
 func TestGit_cleanCommitMessage(t *testing.T) {
 
 }
+
+// create a repository with a single commit using the git cli, so signed objects can be verified with `git verify-*`
+func gitCliTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dirPath, err := ioutil.TempDir("", "signing")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path.Join(dirPath, "README.md"), []byte("hello\n"), 0644))
+	gitCli(t, dirPath, nil, "", "init")
+	gitCli(t, dirPath, nil, "", "add", "README.md")
+	gitCli(t, dirPath, nil, "", "-c", "user.name=CapsuleCD", "-c", "user.email=CapsuleCD@users.noreply.github.com", "commit", "-m", "initial commit")
+	return dirPath
+}
+
+func gitCli(t *testing.T, dirPath string, env []string, stdin string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dirPath
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+// throwaway GPG key, returns the armored private key and a GNUPGHOME containing the public key.
+func gpgTestKey(t *testing.T) ([]byte, string) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	entity, err := openpgp.NewEntity("CapsuleCD", "test", "CapsuleCD@users.noreply.github.com", nil)
+	require.NoError(t, err)
+
+	var privateKey bytes.Buffer
+	privateWriter, err := armor.Encode(&privateKey, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(privateWriter, nil))
+	require.NoError(t, privateWriter.Close())
+
+	var publicKey bytes.Buffer
+	publicWriter, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(publicWriter))
+	require.NoError(t, publicWriter.Close())
+
+	gnupgHome, err := ioutil.TempDir("", "gnupg")
+	require.NoError(t, err)
+	importCmd := exec.Command("gpg", "--homedir", gnupgHome, "--batch", "--import")
+	importCmd.Stdin = &publicKey
+	output, err := importCmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return privateKey.Bytes(), gnupgHome
+}
+
+// throwaway SSH key, returns the PEM private key and an allowed signers file for the public key.
+func sshTestKey(t *testing.T) ([]byte, string) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	publicKey, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	allowedSigners, err := ioutil.TempFile("", "allowed_signers")
+	require.NoError(t, err)
+	defer allowedSigners.Close()
+	_, err = allowedSigners.WriteString("CapsuleCD@users.noreply.github.com " + string(ssh.MarshalAuthorizedKey(publicKey)))
+	require.NoError(t, err)
+	return privateKey, allowedSigners.Name()
+}
+
+func TestGitSignedTagObject_Gpg(t *testing.T) {
+	//setup
+	repoPath := gitCliTestRepo(t)
+	defer os.RemoveAll(repoPath)
+	privateKey, gnupgHome := gpgTestKey(t)
+	defer os.RemoveAll(gnupgHome)
+	signer, err := NewGitSigner(GitSigningFormatGpg, privateKey, "")
+	require.NoError(t, err)
+
+	//test
+	tagContent, serr := gitSignedTagObject(gitCli(t, repoPath, nil, "", "rev-parse", "HEAD"), "v1.0.0", GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"), "(v1.0.0) Automated packaging of release by CapsuleCD", signer)
+	require.NoError(t, serr)
+
+	//assert
+	require.Contains(t, string(tagContent), "-----BEGIN PGP SIGNATURE-----")
+	tagSha := gitCli(t, repoPath, nil, string(tagContent), "hash-object", "-t", "tag", "-w", "--stdin")
+	gitCli(t, repoPath, nil, "", "update-ref", "refs/tags/v1.0.0", tagSha)
+	gitCli(t, repoPath, []string{"GNUPGHOME=" + gnupgHome}, "", "verify-tag", "v1.0.0")
+}
+
+func TestGitSignedTagObject_Ssh(t *testing.T) {
+	//setup
+	repoPath := gitCliTestRepo(t)
+	defer os.RemoveAll(repoPath)
+	privateKey, allowedSignersPath := sshTestKey(t)
+	defer os.Remove(allowedSignersPath)
+	signer, err := NewGitSigner(GitSigningFormatSsh, privateKey, "")
+	require.NoError(t, err)
+
+	//test
+	tagContent, serr := gitSignedTagObject(gitCli(t, repoPath, nil, "", "rev-parse", "HEAD"), "v1.0.0", GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"), "(v1.0.0) Automated packaging of release by CapsuleCD", signer)
+	require.NoError(t, serr)
+
+	//assert
+	require.Contains(t, string(tagContent), "-----BEGIN SSH SIGNATURE-----")
+	tagSha := gitCli(t, repoPath, nil, string(tagContent), "hash-object", "-t", "tag", "-w", "--stdin")
+	gitCli(t, repoPath, nil, "", "update-ref", "refs/tags/v1.0.0", tagSha)
+	gitCli(t, repoPath, nil, "", "-c", "gpg.format=ssh", "-c", "gpg.ssh.allowedSignersFile="+allowedSignersPath, "verify-tag", "v1.0.0")
+}
+
+func TestGitSignedCommitObject_Gpg(t *testing.T) {
+	//setup
+	repoPath := gitCliTestRepo(t)
+	defer os.RemoveAll(repoPath)
+	privateKey, gnupgHome := gpgTestKey(t)
+	defer os.RemoveAll(gnupgHome)
+	signer, err := NewGitSigner(GitSigningFormatGpg, privateKey, "")
+	require.NoError(t, err)
+
+	//test
+	commitContent, serr := gitSignedCommitObject(
+		gitCli(t, repoPath, nil, "", "rev-parse", "HEAD^{tree}"),
+		[]string{gitCli(t, repoPath, nil, "", "rev-parse", "HEAD")},
+		GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"),
+		"(v1.0.0) Automated packaging of release by CapsuleCD",
+		signer,
+	)
+	require.NoError(t, serr)
+
+	//assert
+	commitSha := gitCli(t, repoPath, nil, string(commitContent), "hash-object", "-t", "commit", "-w", "--stdin")
+	gitCli(t, repoPath, []string{"GNUPGHOME=" + gnupgHome}, "", "verify-commit", commitSha)
+}
+
+func TestNewGitSigner_InvalidKey(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, gerr := NewGitSigner(GitSigningFormatGpg, []byte("not a key"), "")
+	_, serr := NewGitSigner(GitSigningFormatSsh, []byte("not a key"), "")
+	_, ferr := NewGitSigner("x509", []byte("not a key"), "")
+
+	//assert
+	require.Error(t, gerr)
+	require.Error(t, serr)
+	require.Error(t, ferr, "should raise an error for unknown signing formats")
+}
+
+func TestGitSignatureVerifier_Gpg(t *testing.T) {
+	//setup
+	privateKey, gnupgHome := gpgTestKey(t)
+	defer os.RemoveAll(gnupgHome)
+	signer, err := NewGitSigner(GitSigningFormatGpg, privateKey, "")
+	require.NoError(t, err)
+	publicKey, err := exec.Command("gpg", "--homedir", gnupgHome, "--export", "--armor").Output()
+	require.NoError(t, err)
+	otherPrivateKey, otherGnupgHome := gpgTestKey(t)
+	defer os.RemoveAll(otherGnupgHome)
+	otherSigner, err := NewGitSigner(GitSigningFormatGpg, otherPrivateKey, "")
+	require.NoError(t, err)
+
+	verifier := NewGitSignatureVerifier()
+	require.NoError(t, verifier.AddGpgKeyring(publicKey))
+	signature, serr := signer.Sign([]byte("payload"))
+	require.NoError(t, serr)
+	otherSignature, oerr := otherSigner.Sign([]byte("payload"))
+	require.NoError(t, oerr)
+
+	//test & assert
+	require.NoError(t, verifier.Verify([]byte("payload"), signature))
+	require.Error(t, verifier.Verify([]byte("modified payload"), signature), "should fail if the payload was modified")
+	require.Error(t, verifier.Verify([]byte("payload"), otherSignature), "should fail if signed by an unknown key")
+	require.Error(t, verifier.Verify([]byte("payload"), ""), "should fail if unsigned")
+}
+
+// commits signed by the git cli (`git commit -S`) can be verified.
+func TestGitSplitSignedCommit_GitCliSshSignature(t *testing.T) {
+	//setup
+	repoPath := gitCliTestRepo(t)
+	defer os.RemoveAll(repoPath)
+	privateKey, allowedSignersPath := sshTestKey(t)
+	defer os.Remove(allowedSignersPath)
+	allowedSigners, err := ioutil.ReadFile(allowedSignersPath)
+	require.NoError(t, err)
+	privateKeyPath := path.Join(repoPath, ".git", "signing_key")
+	require.NoError(t, ioutil.WriteFile(privateKeyPath, privateKey, 0600))
+	require.NoError(t, ioutil.WriteFile(privateKeyPath+".pub", []byte(strings.SplitN(string(allowedSigners), " ", 2)[1]), 0644))
+	require.NoError(t, ioutil.WriteFile(path.Join(repoPath, "README.md"), []byte("signed\n"), 0644))
+	gitCli(t, repoPath, nil, "", "-c", "user.name=CapsuleCD", "-c", "user.email=CapsuleCD@users.noreply.github.com", "-c", "gpg.format=ssh", "-c", "user.signingkey="+privateKeyPath, "commit", "-S", "-a", "-m", "signed commit\n\nwith a body")
+	signedCommit := gitCli(t, repoPath, nil, "", "cat-file", "commit", "HEAD") + "\n"
+	unsignedCommit := gitCli(t, repoPath, nil, "", "cat-file", "commit", "HEAD^") + "\n"
+	verifier := NewGitSignatureVerifier()
+	require.NoError(t, verifier.AddSshAllowedSigners(allowedSigners))
+
+	//test
+	payload, signature := gitSplitSignedCommit([]byte(signedCommit))
+	unsignedPayload, unsignedSignature := gitSplitSignedCommit([]byte(unsignedCommit))
+
+	//assert
+	require.NotContains(t, string(payload), "gpgsig")
+	require.Contains(t, signature, "-----BEGIN SSH SIGNATURE-----")
+	require.NoError(t, verifier.Verify(payload, signature))
+	require.Equal(t, unsignedCommit, string(unsignedPayload))
+	require.Empty(t, unsignedSignature)
+	require.Error(t, verifier.Verify(unsignedPayload, unsignedSignature))
+}
+
+func TestGitSignatureVerifier_SignedCommitObject(t *testing.T) {
+	//setup
+	privateKey, allowedSignersPath := sshTestKey(t)
+	defer os.Remove(allowedSignersPath)
+	signer, err := NewGitSigner(GitSigningFormatSsh, privateKey, "")
+	require.NoError(t, err)
+	allowedSigners, err := ioutil.ReadFile(allowedSignersPath)
+	require.NoError(t, err)
+	verifier := NewGitSignatureVerifier()
+	require.NoError(t, verifier.AddSshAllowedSigners(allowedSigners))
+	commitContent, cerr := gitSignedCommitObject("4b825dc642cb6eb9a060e54bf8d69288fbee4904", []string{}, GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"), "message", signer)
+	require.NoError(t, cerr)
+
+	//test
+	payload, signature := gitSplitSignedCommit(commitContent)
+
+	//assert
+	require.NoError(t, verifier.Verify(payload, signature))
+	require.Error(t, NewGitSignatureVerifier().Verify(payload, signature), "should fail if the key is not allowed")
+}
+
+func TestGitSignatureVerifier_MalformedSshSignature(t *testing.T) {
+	//setup
+	armorSignature := func(blob []byte) string {
+		return "-----BEGIN SSH SIGNATURE-----\n" + base64.StdEncoding.EncodeToString(blob) + "\n-----END SSH SIGNATURE-----\n"
+	}
+	preamble := append([]byte("SSHSIG"), 0, 0, 0, 1)
+	oversized := append(append([]byte{}, preamble...), 0xff, 0xff, 0xff, 0xff, 's', 's', 'h')
+	truncated := append(append([]byte{}, preamble...), 0, 0, 0, 11, 's', 's', 'h')
+	verifier := NewGitSignatureVerifier()
+
+	//test
+	oerr := verifier.Verify([]byte("payload"), armorSignature(oversized))
+	terr := verifier.Verify([]byte("payload"), armorSignature(truncated))
+
+	//assert
+	require.EqualError(t, oerr, "invalid SSH signature: field length 4294967295 exceeds the remaining 3 bytes", "should not allocate a field larger than the signature")
+	require.EqualError(t, terr, "invalid SSH signature: field length 11 exceeds the remaining 3 bytes")
+	require.Error(t, verifier.Verify([]byte("payload"), armorSignature(preamble)), "should fail if the signature has no fields")
+}
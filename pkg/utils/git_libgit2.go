@@ -0,0 +1,1135 @@
+// +build cgo,!nolibgit2
+
+package utils
+
+import (
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"fmt"
+	git2go "gopkg.in/libgit2/git2go.v25"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// libgit2 backend, using the git2go cgo bindings. Excluded from builds without cgo, or with the `nolibgit2` build tag
+// (see git_nolibgit2.go).
+type libgit2GitClient struct{}
+
+func newLibgit2GitClient() (GitClient, error) {
+	return new(libgit2GitClient), nil
+}
+
+// Clone a git repo into a local directory.
+// Credentials need to be specified by embedding in gitRemote url.
+// TODO: this pattern may not work on Bitbucket/GitLab
+func (c *libgit2GitClient) Clone(parentPath string, repositoryName string, gitRemote string) (string, error) {
+	absPath, _ := filepath.Abs(path.Join(parentPath, repositoryName))
+
+	if !FileExists(absPath) {
+		os.MkdirAll(absPath, os.ModePerm)
+	} else {
+		return "", errors.ScmFilesystemError(fmt.Sprintf("The local repository path already exists, this should never happen. %s", absPath))
+	}
+
+	_, err := git2go.Clone(gitRemote, absPath, &git2go.CloneOptions{
+		FetchOptions: &git2go.FetchOptions{RemoteCallbacks: gitRemoteCallbacks()},
+	})
+	return absPath, err
+}
+
+// https://stackoverflow.com/questions/13638235/git-checkout-remote-reference
+// https://gist.github.com/danielfbm/ba4ae91efa96bb4771351bdbd2c8b06f
+// https://github.com/libgit2/git2go/issues/126
+// https://www.atlassian.com/git/articles/pull-request-proficiency-fetching-abilities-unlocked
+// https://www.atlassian.com/blog/archives/how-to-fetch-pull-requests
+// https://stackoverflow.com/questions/48806891/bitbucket-does-not-update-refspec-for-pr-causing-jenkins-to-build-old-commits
+func (c *libgit2GitClient) FetchPullRequest(repoPath string, pullRequestNumber string, localBranchName string, srcPatternTmpl string, destPatternTmpl string) error {
+
+	//defaults for Templates if they are not specified.
+	if len(srcPatternTmpl) == 0 {
+		srcPatternTmpl = "refs/pull/%s/merge" //this default template is for Github
+	}
+
+	if len(destPatternTmpl) == 0 {
+		destPatternTmpl = "refs/remotes/origin/pr/%s/merge"
+	}
+
+	//populate the templates
+	srcPattern := fmt.Sprintf(srcPatternTmpl, pullRequestNumber)
+	destPattern := fmt.Sprintf(destPatternTmpl, pullRequestNumber)
+	refspec := fmt.Sprintf("+%s:%s", srcPattern, destPattern)
+
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	checkoutOpts := &git2go.CheckoutOpts{
+		Strategy: git2go.CheckoutSafe | git2go.CheckoutRecreateMissing | git2go.CheckoutAllowConflicts | git2go.CheckoutUseTheirs,
+	}
+
+	remote, lerr := repo.Remotes.Lookup("origin")
+	if lerr != nil {
+		log.Print("Failed to lookup origin remote")
+		return lerr
+	}
+	time.Sleep(time.Second)
+
+	// fetch the pull request merge and head references into this repo.
+	ferr := remote.Fetch([]string{refspec}, &git2go.FetchOptions{RemoteCallbacks: gitRemoteCallbacks()}, "")
+	if ferr != nil {
+		log.Print("Failed to fetch PR reference from remote")
+		return ferr
+	}
+
+	// Get a reference to the PR merge branch in this repo
+	prRef, err := repo.References.Lookup(destPattern)
+	if err != nil {
+		log.Print("Failed to find PR reference locally: " + destPattern)
+		return err
+	}
+
+	// Lookup commmit for PR branch
+	prCommit, err := repo.LookupCommit(prRef.Target())
+	if err != nil {
+		log.Print(fmt.Sprintf("Failed to find PR head commit: %s", prRef.Target()))
+		return err
+	}
+	defer prCommit.Free()
+
+	prLocalBranch, err := repo.LookupBranch(localBranchName, git2go.BranchLocal)
+	// No local branch, lets create one
+	if prLocalBranch == nil || err != nil {
+		// Creating local branch
+		prLocalBranch, err = repo.CreateBranch(localBranchName, prCommit, false)
+		if err != nil {
+			log.Print("Failed to create local branch: " + localBranchName)
+			return err
+		}
+	}
+	if prLocalBranch == nil {
+		return errors.ScmFilesystemError("Error while locating/creating local branch")
+	}
+	defer prLocalBranch.Free()
+
+	// Getting the tree for the branch
+	localCommit, err := repo.LookupCommit(prLocalBranch.Target())
+	if err != nil {
+		log.Print("Failed to lookup for commit in local branch " + localBranchName)
+		return err
+	}
+	//defer localCommit.Free()
+
+	tree, err := repo.LookupTree(localCommit.TreeId())
+	if err != nil {
+		log.Print("Failed to lookup for tree " + localBranchName)
+		return err
+	}
+	//defer tree.Free()
+
+	// Checkout the tree
+	err = repo.CheckoutTree(tree, checkoutOpts)
+	if err != nil {
+		log.Print("Failed to checkout tree " + localBranchName)
+		return err
+	}
+	// Setting the Head to point to our branch
+	return repo.SetHead("refs/heads/" + localBranchName)
+}
+
+// https://github.com/welaw/welaw/blob/100be9cf9a4c6d26f8126678c05072ff725202dd/pkg/easyrepo/merge.go#L11
+// https://gist.github.com/danielfbm/37b0ca88b745503557b2b3f16865d8c3
+// https://gist.github.com/danielfbm/ba4ae91efa96bb4771351bdbd2c8b06f
+// https://github.com/Devying/git2go-example/blob/master/fetch1.go
+//https://github.com/jandre/passward/blob/e37bce388cf6417d7123c802add1937574c2b30e/passward/git.go#L186-L206
+// https://github.com/electricbookworks/electric-book-gui/blob/4d9ad588dbdf7a94345ef10a1bb6944bc2a2f69a/src/go/src/ebw/git/RepoConflict.go
+//
+// Merge the remoteBranchName (fetched from remoteUrl) into localBranchName (created from baseBranchName) using one of
+// the GitMergeStrategy* strategies. message is used for merge and squash commits.
+func (c *libgit2GitClient) MergeRemoteBranch(repoPath string, localBranchName string, baseBranchName string, remoteUrl string, remoteBranchName string, strategy string, message string, signature *GitIdentity) error {
+
+	checkoutOpts := &git2go.CheckoutOpts{
+		Strategy: git2go.CheckoutSafe | git2go.CheckoutRecreateMissing | git2go.CheckoutAllowConflicts | git2go.CheckoutUseTheirs,
+	}
+
+	//get current checked out repository.
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	// Lookup commmit for base branch
+	baseBranch, err := repo.LookupBranch(baseBranchName, git2go.BranchLocal)
+	if err != nil {
+		log.Print("Failed to find local base branch: " + baseBranchName)
+		return err
+	}
+
+	baseCommit, err := repo.LookupCommit(baseBranch.Target())
+	if err != nil {
+		log.Print(fmt.Sprintf("Failed to find head commit for base branch: %s", baseBranchName))
+		return err
+	}
+	defer baseCommit.Free()
+
+	// Check if there's a local branch with the pr_* name already.
+	prLocalBranch, err := repo.LookupBranch(localBranchName, git2go.BranchLocal)
+	// No local branch, lets create one
+	if prLocalBranch == nil || err != nil {
+		// Creating local pr branch from the base branch commit.
+		prLocalBranch, err = repo.CreateBranch(localBranchName, baseCommit, false)
+		if err != nil {
+			log.Print("Failed to create local branch: " + localBranchName)
+			return err
+		}
+	}
+
+	// Getting the tree for the branch
+	prLocalCommit, err := repo.LookupCommit(prLocalBranch.Target())
+	if err != nil {
+		log.Print("Failed to lookup for commit in local branch " + localBranchName)
+		return err
+	}
+	//defer localCommit.Free()
+
+	tree, err := repo.LookupTree(prLocalCommit.TreeId())
+	if err != nil {
+		log.Print("Failed to lookup for tree " + localBranchName)
+		return err
+	}
+	//defer tree.Free()
+
+	// Checkout the tree
+	err = repo.CheckoutTree(tree, checkoutOpts)
+	if err != nil {
+		log.Print("Failed to checkout tree " + localBranchName)
+		return err
+	}
+	// Setting the Head to point to our branch
+	herr := repo.SetHead("refs/heads/" + localBranchName)
+	if herr != nil {
+		return herr
+	}
+
+	//add a new remote for the PR head.
+	prRemoteAlias := "pr_origin"
+	prRemote, rerr := repo.Remotes.Create(prRemoteAlias, remoteUrl)
+	if rerr != nil {
+		return rerr
+	}
+
+	//fetch the commits for the remoteBranchName
+	rferr := prRemote.Fetch([]string{"refs/heads/" + remoteBranchName}, &git2go.FetchOptions{RemoteCallbacks: gitRemoteCallbacks()}, "")
+	if rferr != nil {
+		return rferr
+	}
+
+	remoteBranch, errRef := repo.References.Lookup(fmt.Sprintf("refs/remotes/%s/%s", prRemoteAlias, remoteBranchName))
+	if errRef != nil {
+		return errRef
+	}
+	remoteBranchID := remoteBranch.Target()
+
+	//Assuming we are already checkout as the destination branch
+	remotePrAnnCommit, err := repo.AnnotatedCommitFromRef(remoteBranch)
+	if err != nil {
+		log.Print("Failed get annotated commit from remote ")
+		return err
+	}
+	defer remotePrAnnCommit.Free()
+
+	//Getting repo HEAD
+	head, err := repo.Head()
+	if err != nil {
+		log.Print("Failed get head ")
+		return err
+	}
+
+	// Do merge analysis
+	mergeHeads := make([]*git2go.AnnotatedCommit, 1)
+	mergeHeads[0] = remotePrAnnCommit
+	analysis, _, err := repo.MergeAnalysis(mergeHeads)
+	if err != nil {
+		return err
+	}
+
+	if analysis&git2go.MergeAnalysisNone != 0 || analysis&git2go.MergeAnalysisUpToDate != 0 {
+		log.Print("Found nothing to merge. This should not happen for valid PR's")
+		return errors.ScmMergeNothingToMergeError("Found nothing to merge. This should not happen for valid PR's")
+	}
+
+	switch strategy {
+	case "", GitMergeStrategyMerge, GitMergeStrategySquash:
+		if analysis&git2go.MergeAnalysisNormal != 0 {
+			// Just merge changes
+			return gitMergeCommit(repo, mergeHeads, head, remoteBranchID, strategy == GitMergeStrategySquash, message, signature)
+		} else if analysis&git2go.MergeAnalysisFastForward != 0 {
+			return gitFastForward(repo, prLocalBranch, head, remoteBranchID)
+		}
+	case GitMergeStrategyRebase:
+		if analysis&git2go.MergeAnalysisFastForward != 0 {
+			return gitFastForward(repo, prLocalBranch, head, remoteBranchID)
+		} else if analysis&git2go.MergeAnalysisNormal != 0 {
+			return gitRebaseCommits(repo, head, remoteBranchID, signature)
+		}
+	case GitMergeStrategyFastForwardOnly:
+		if analysis&git2go.MergeAnalysisFastForward != 0 {
+			return gitFastForward(repo, prLocalBranch, head, remoteBranchID)
+		}
+		return errors.ScmMergeStrategyError(fmt.Sprintf("Cannot fast-forward %s to %s. Please rebase the pull request onto %s", baseBranchName, remoteBranchName, baseBranchName))
+	default:
+		return errors.ScmMergeStrategyError(fmt.Sprintf("Unsupported merge strategy: %s", strategy))
+	}
+
+	log.Printf("Unexpected merge analysis result %d", analysis)
+	return errors.ScmMergeAnalysisUnknownError(fmt.Sprintf("Unexpected merge analysis result: %d", analysis))
+}
+
+func (c *libgit2GitClient) Checkout(repoPath string, branchName string) error {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	checkoutOpts := &git2go.CheckoutOpts{
+		Strategy: git2go.CheckoutSafe | git2go.CheckoutRecreateMissing | git2go.CheckoutAllowConflicts | git2go.CheckoutUseTheirs,
+	}
+	//Getting the reference for the remote branch
+	// remoteBranch, err := repo.References.Lookup("refs/remotes/origin/" + branchName)
+	remoteBranch, err := repo.LookupBranch("origin/"+branchName, git2go.BranchRemote)
+	if err != nil {
+		log.Print("Failed to find remote branch: " + branchName)
+		return err
+	}
+	//defer remoteBranch.Free()
+
+	// Lookup for commit from remote branch
+	commit, err := repo.LookupCommit(remoteBranch.Target())
+	if err != nil {
+		log.Print("Failed to find remote branch commit: " + branchName)
+		return err
+	}
+	//defer commit.Free()
+
+	localBranch, err := repo.LookupBranch(branchName, git2go.BranchLocal)
+	// No local branch, lets create one
+	if localBranch == nil || err != nil {
+		// Creating local branch
+		localBranch, err = repo.CreateBranch(branchName, commit, false)
+		if err != nil {
+			log.Print("Failed to create local branch: " + branchName)
+			return err
+		}
+
+		// Setting upstream to origin branch
+		err = localBranch.SetUpstream("origin/" + branchName)
+		if err != nil {
+			log.Print("Failed to create upstream to origin/" + branchName)
+			return err
+		}
+	}
+	if localBranch == nil {
+		return errors.ScmFilesystemError("Error while locating/creating local branch")
+	}
+	//defer localBranch.Free()
+
+	// Getting the tree for the branch
+	localCommit, err := repo.LookupCommit(localBranch.Target())
+	if err != nil {
+		log.Print("Failed to lookup for commit in local branch " + branchName)
+		return err
+	}
+	//defer localCommit.Free()
+
+	tree, err := repo.LookupTree(localCommit.TreeId())
+	if err != nil {
+		log.Print("Failed to lookup for tree " + branchName)
+		return err
+	}
+	//defer tree.Free()
+
+	// Checkout the tree
+	err = repo.CheckoutTree(tree, checkoutOpts)
+	if err != nil {
+		log.Print("Failed to checkout tree " + branchName)
+		return err
+	}
+	// Setting the Head to point to our branch
+	return repo.SetHead("refs/heads/" + branchName)
+}
+
+//Add all modified files to index, and create a commit signed by the signer (unsigned if the signer is nil).
+func (c *libgit2GitClient) Commit(repoPath string, message string, signature *GitIdentity, signer GitSigner) error {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	//get repo index.
+	idx, ierr := repo.Index()
+	if ierr != nil {
+		return ierr
+	}
+	aerr := idx.AddAll([]string{}, git2go.IndexAddDefault, nil)
+	if aerr != nil {
+		return aerr
+	}
+	treeId, wterr := idx.WriteTree()
+	if wterr != nil {
+		return wterr
+	}
+	werr := idx.Write()
+	if werr != nil {
+		return werr
+	}
+
+	tree, lerr := repo.LookupTree(treeId)
+	if lerr != nil {
+		return lerr
+	}
+
+	currentBranch, berr := repo.Head()
+	if berr != nil {
+		return berr
+	}
+
+	commitTarget, terr := repo.LookupCommit(currentBranch.Target())
+	if terr != nil {
+		return terr
+	}
+
+	if signer == nil {
+		_, cerr := repo.CreateCommit("HEAD", libgit2Signature(signature), libgit2Signature(signature), message, tree, commitTarget)
+		//if(cerr != nil){return cerr}
+
+		return cerr
+	}
+
+	// libgit2 cannot sign commits, so the signed commit object is written directly to the object database.
+	commitContent, serr := gitSignedCommitObject(tree.Id().String(), []string{commitTarget.Id().String()}, signature, message, signer)
+	if serr != nil {
+		return serr
+	}
+	odb, derr := repo.Odb()
+	if derr != nil {
+		return derr
+	}
+	commitId, werr := odb.Write(commitContent, git2go.ObjectCommit)
+	if werr != nil {
+		return werr
+	}
+	_, rerr := currentBranch.SetTarget(commitId, message)
+	return rerr
+}
+
+// Create an annotated tag for the current HEAD commit, signed by the signer (unsigned if the signer is nil).
+// Returns the tagged commit sha.
+func (c *libgit2GitClient) Tag(repoPath string, version string, message string, signature *GitIdentity, signer GitSigner) (string, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+	commitHead, herr := repo.Head()
+	if herr != nil {
+		return "", herr
+	}
+
+	commit, lerr := repo.LookupCommit(commitHead.Target())
+	if lerr != nil {
+		return "", lerr
+	}
+
+	tagMessage := fmt.Sprintf("(%s) %s", version, message)
+	if signer != nil {
+		// libgit2 cannot sign tags, so the signed tag object is written directly to the object database.
+		tagContent, serr := gitSignedTagObject(commit.Id().String(), version, signature, tagMessage, signer)
+		if serr != nil {
+			return "", serr
+		}
+		odb, derr := repo.Odb()
+		if derr != nil {
+			return "", derr
+		}
+		tagId, werr := odb.Write(tagContent, git2go.ObjectTag)
+		if werr != nil {
+			return "", werr
+		}
+		if _, rerr := repo.References.Create("refs/tags/"+version, tagId, false, tagMessage); rerr != nil {
+			return "", rerr
+		}
+		return commit.Id().String(), nil
+	}
+
+	//tagId, terr := repo.Tags.CreateLightweight(version, commit, false)
+	tagId, terr := repo.Tags.Create(version, commit, libgit2Signature(signature), tagMessage)
+	if terr != nil {
+		return "", terr
+	}
+
+	tagObj, terr := repo.LookupTag(tagId)
+	if terr != nil {
+		return "", terr
+	}
+	return tagObj.TargetId().String(), terr
+}
+
+func (c *libgit2GitClient) Push(repoPath string, localBranch string, remoteBranch string, tagName string) error {
+	//- https://gist.github.com/danielfbm/37b0ca88b745503557b2b3f16865d8c3
+	//- https://stackoverflow.com/questions/37026399/git2go-after-createcommit-all-files-appear-like-being-added-for-deletion
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	// Push
+	remote, lerr := repo.Remotes.Lookup("origin")
+	if lerr != nil {
+		return lerr
+	}
+	//remote.ConnectPush(gitRemoteCallbacks(), &git.ProxyOptions{}, []string{})
+
+	//err = remote.Push([]string{"refs/heads/master"}, nil, signature, message)
+	return remote.Push([]string{
+		fmt.Sprintf("refs/heads/%s:refs/heads/%s", localBranch, remoteBranch),
+		fmt.Sprintf("refs/tags/%s:refs/tags/%s", tagName, tagName),
+	}, &git2go.PushOptions{RemoteCallbacks: gitRemoteCallbacks()})
+}
+
+// Push a local branch to a (possibly different) remote branch, without pushing tags.
+// if force is true, the remote branch will be overwritten.
+func (c *libgit2GitClient) PushBranch(repoPath string, localBranch string, remoteBranch string, force bool) error {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	remote, lerr := repo.Remotes.Lookup("origin")
+	if lerr != nil {
+		return lerr
+	}
+
+	refspec := fmt.Sprintf("refs/heads/%s:refs/heads/%s", localBranch, remoteBranch)
+	if force {
+		refspec = "+" + refspec
+	}
+	return libgit2Push(remote, refspec)
+}
+
+// Create a commit with an empty tree, and store it in a local reference (outside of refs/heads).
+// Used to store metadata (eg. the release lock lease) in the remote repository. The commit will have a single parent if
+// parentSha is specified.
+func (c *libgit2GitClient) CreateMetadataCommit(repoPath string, refName string, parentSha string, message string, signature *GitIdentity) (string, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	treeBuilder, berr := repo.TreeBuilder()
+	if berr != nil {
+		return "", berr
+	}
+	defer treeBuilder.Free()
+	treeId, werr := treeBuilder.Write()
+	if werr != nil {
+		return "", werr
+	}
+	tree, lerr := repo.LookupTree(treeId)
+	if lerr != nil {
+		return "", lerr
+	}
+
+	parents := []*git2go.Commit{}
+	if parentSha != "" {
+		parentId, ierr := git2go.NewOid(parentSha)
+		if ierr != nil {
+			return "", ierr
+		}
+		parent, perr := repo.LookupCommit(parentId)
+		if perr != nil {
+			return "", perr
+		}
+		parents = append(parents, parent)
+	}
+
+	commitId, cerr := repo.CreateCommit("", libgit2Signature(signature), libgit2Signature(signature), message, tree, parents...)
+	if cerr != nil {
+		return "", cerr
+	}
+	if _, rerr := repo.References.Create(refName, commitId, true, message); rerr != nil {
+		return "", rerr
+	}
+	return commitId.String(), nil
+}
+
+// Fetch a reference from the remote repository (origin) into a local reference, and return the referenced commit sha &
+// message. An empty sha is returned if the remote reference does not exist.
+func (c *libgit2GitClient) FetchRemoteRef(repoPath string, remoteRefName string, localRefName string) (string, string, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return "", "", oerr
+	}
+
+	remote, lerr := repo.Remotes.Lookup("origin")
+	if lerr != nil {
+		return "", "", lerr
+	}
+
+	remoteCallbacks := gitRemoteCallbacks()
+	if cerr := remote.ConnectFetch(&remoteCallbacks, &git2go.ProxyOptions{}, []string{}); cerr != nil {
+		return "", "", cerr
+	}
+	remoteHeads, rerr := remote.Ls(remoteRefName)
+	remote.Disconnect()
+	if rerr != nil {
+		return "", "", rerr
+	} else if len(remoteHeads) == 0 {
+		return "", "", nil
+	}
+
+	if ferr := remote.Fetch([]string{fmt.Sprintf("+%s:%s", remoteRefName, localRefName)}, &git2go.FetchOptions{RemoteCallbacks: gitRemoteCallbacks()}, ""); ferr != nil {
+		return "", "", ferr
+	}
+	commit, cerr := repo.LookupCommit(remoteHeads[0].Id)
+	if cerr != nil {
+		return "", "", cerr
+	}
+	return commit.Id().String(), commit.Message(), nil
+}
+
+// Push a local reference to the remote repository (origin), without pushing branches or tags.
+// The push is rejected if the remote reference cannot be fast-forwarded, unless force is true.
+// If localRefName is empty, the remote reference is deleted.
+func (c *libgit2GitClient) PushRef(repoPath string, localRefName string, remoteRefName string, force bool) error {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	remote, lerr := repo.Remotes.Lookup("origin")
+	if lerr != nil {
+		return lerr
+	}
+
+	refspec := fmt.Sprintf("%s:%s", localRefName, remoteRefName)
+	if force {
+		refspec = "+" + refspec
+	}
+	return libgit2Push(remote, refspec)
+}
+
+// Check if a tag already exists in the local repository.
+func (c *libgit2GitClient) TagExists(repoPath string, tagName string) (bool, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return false, oerr
+	}
+
+	_, lerr := repo.References.Lookup(fmt.Sprintf("refs/tags/%s", tagName))
+	if lerr != nil {
+		if git2go.IsErrorCode(lerr, git2go.ErrNotFound) {
+			return false, nil
+		}
+		return false, lerr
+	}
+	return true, nil
+}
+
+// Get the message of the commit currently checked out.
+func (c *libgit2GitClient) HeadCommitMessage(repoPath string) (string, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	head, herr := repo.Head()
+	if herr != nil {
+		return "", herr
+	}
+
+	commit, lerr := repo.LookupCommit(head.Target())
+	if lerr != nil {
+		return "", lerr
+	}
+	return commit.Message(), nil
+}
+
+// Reset the current branch to the specified commit, discarding any changes (and commits) after it.
+// basically `git reset --hard <sha>`
+func (c *libgit2GitClient) ResetToCommit(repoPath string, sha string) error {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	commitId, ierr := git2go.NewOid(sha)
+	if ierr != nil {
+		return ierr
+	}
+
+	commit, lerr := repo.LookupCommit(commitId)
+	if lerr != nil {
+		return lerr
+	}
+
+	return repo.ResetToCommit(commit, git2go.ResetHard, &git2go.CheckoutOpts{
+		Strategy: git2go.CheckoutForce,
+	})
+}
+
+// Get the nearest tag on branch, only considering tags that match the glob pattern (if specified).
+// basically `git describe --tags --abbrev=0 --match <pattern>`
+func (c *libgit2GitClient) FindNearestMatchingTagName(repoPath string, pattern string) (string, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	descOptions, derr := git2go.DefaultDescribeOptions()
+	if derr != nil {
+		return "", derr
+	}
+	descOptions.Strategy = git2go.DescribeTags
+	descOptions.Pattern = pattern
+
+	formatOptions, ferr := git2go.DefaultDescribeFormatOptions()
+	if ferr != nil {
+		return "", ferr
+	}
+	formatOptions.AbbreviatedSize = 0
+
+	descr, derr := repo.DescribeWorkdir(&descOptions)
+	if derr != nil {
+		return "", derr
+	}
+
+	nearestTag, ferr := descr.Format(&formatOptions)
+	if ferr != nil {
+		return "", ferr
+	}
+
+	return nearestTag, nil
+}
+
+func (c *libgit2GitClient) GenerateChangelog(repoPath string, baseSha string, headSha string) (string, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return "", oerr
+	}
+
+	markdown := StripIndent(`Timestamp |  SHA | Message | Author
+	------------- | ------------- | ------------- | -------------
+	`)
+
+	revWalk, werr := repo.Walk()
+	if werr != nil {
+		return "", werr
+	}
+
+	rerr := revWalk.PushRange(fmt.Sprintf("%s..%s", baseSha, headSha))
+	if rerr != nil {
+		return "", rerr
+	}
+
+	revWalk.Iterate(func(commit *git2go.Commit) bool {
+		markdown += fmt.Sprintf("%s | %.8s | %s | %s\n", //TODO: this should have a link for the SHA.
+			commit.Author().When.UTC().Format("2006-01-02T15:04Z"),
+			commit.Id().String(),
+			cleanCommitMessage(commit.Message()),
+			commit.Author().Name,
+		)
+		return true
+	})
+	//for {
+	//	err := revWalk.Next()
+	//	if err != nil {
+	//		break
+	//	}
+	//
+	//	log.Info(gi.String())
+	//}
+
+	return markdown, nil
+}
+
+// Get the commits reachable from headRef but not from baseRef (ie. `git log baseRef..headRef`), newest first.
+// If baseRef is empty, all commits reachable from headRef are returned.
+func (c *libgit2GitClient) CommitsBetween(repoPath string, baseRef string, headRef string) ([]*pipeline.GitCommit, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+
+	revWalk, werr := repo.Walk()
+	if werr != nil {
+		return nil, werr
+	}
+	defer revWalk.Free()
+
+	var rerr error
+	if baseRef == "" {
+		headObj, perr := repo.RevparseSingle(headRef)
+		if perr != nil {
+			return nil, perr
+		}
+		rerr = revWalk.Push(headObj.Id())
+	} else {
+		rerr = revWalk.PushRange(fmt.Sprintf("%s..%s", baseRef, headRef))
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	commits := []*pipeline.GitCommit{}
+	ierr := revWalk.Iterate(func(commit *git2go.Commit) bool {
+		commits = append(commits, &pipeline.GitCommit{
+			Sha:         commit.Id().String(),
+			Message:     commit.Message(),
+			AuthorName:  commit.Author().Name,
+			AuthorEmail: commit.Author().Email,
+			Date:        commit.Author().When,
+			ParentCount: int(commit.ParentCount()),
+		})
+		return true
+	})
+	if ierr != nil {
+		return nil, ierr
+	}
+	return commits, nil
+}
+
+// Read the raw content of a commit object (headers & message), eg. to verify the commit signature.
+func (c *libgit2GitClient) CommitObject(repoPath string, sha string) ([]byte, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+	odb, derr := repo.Odb()
+	if derr != nil {
+		return nil, derr
+	}
+	commitId, ierr := git2go.NewOid(sha)
+	if ierr != nil {
+		return nil, ierr
+	}
+	commitObj, rerr := odb.Read(commitId)
+	if rerr != nil {
+		return nil, rerr
+	}
+	defer commitObj.Free()
+	return append([]byte{}, commitObj.Data()...), nil
+}
+
+// Fetch a reference from a remote url (eg. a pull request head repository) into a local reference.
+func (c *libgit2GitClient) FetchRemoteUrlRef(repoPath string, remoteUrl string, remoteRefName string, localRefName string) error {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	remote, rerr := repo.Remotes.CreateAnonymous(remoteUrl)
+	if rerr != nil {
+		return rerr
+	}
+	defer remote.Free()
+
+	return remote.Fetch([]string{fmt.Sprintf("+%s:%s", remoteRefName, localRefName)}, &git2go.FetchOptions{RemoteCallbacks: gitRemoteCallbacks()}, "")
+}
+
+// Fetch all tags from the remote repository (origin), including tags created after the repository was cloned.
+func (c *libgit2GitClient) FetchTags(repoPath string) error {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return oerr
+	}
+
+	remote, lerr := repo.Remotes.Lookup("origin")
+	if lerr != nil {
+		return lerr
+	}
+	return remote.Fetch([]string{"+refs/tags/*:refs/tags/*"}, &git2go.FetchOptions{RemoteCallbacks: gitRemoteCallbacks()}, "")
+}
+
+// Read the content of a file at the specified ref (eg. a commit sha), without checking it out.
+func (c *libgit2GitClient) FileContent(repoPath string, ref string, filePath string) ([]byte, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+
+	fileObj, rerr := repo.RevparseSingle(fmt.Sprintf("%s:%s", ref, filePath))
+	if rerr != nil {
+		return nil, rerr
+	}
+	blob, lerr := repo.LookupBlob(fileObj.Id())
+	if lerr != nil {
+		return nil, lerr
+	}
+	return blob.Contents(), nil
+}
+
+func (c *libgit2GitClient) GetTagDetails(repoPath string, tagName string) (*pipeline.GitTagDetails, error) {
+	repo, oerr := git2go.OpenRepository(repoPath)
+	if oerr != nil {
+		return nil, oerr
+	}
+
+	id, aerr := repo.References.Dwim(tagName)
+	if aerr != nil {
+		return nil, aerr
+	}
+	tag, lerr := repo.LookupTag(id.Target()) //assume its an annotated tag.
+
+	var currentTag *pipeline.GitTagDetails
+	if lerr != nil {
+		//this is a lightweight tag, not an annotated tag.
+		commitRef, rerr := repo.LookupCommit(id.Target())
+		if rerr != nil {
+			return nil, rerr
+		}
+
+		author := commitRef.Author()
+
+		log.Printf("Light-weight tag (%s) Commit ID: %s, DATE: %s", tagName, commitRef.Id().String(), author.When.String())
+
+		currentTag = &pipeline.GitTagDetails{
+			TagShortName: tagName,
+			CommitSha:    commitRef.Id().String(),
+			CommitDate:   author.When,
+		}
+
+	} else {
+
+		log.Printf("Annotated tag (%s) Tag ID: %s, Commit ID: %s, DATE: %s", tagName, tag.Id().String(), tag.TargetId().String(), tag.Tagger().When.String())
+
+		currentTag = &pipeline.GitTagDetails{
+			TagShortName: tagName,
+			CommitSha:    tag.TargetId().String(),
+			CommitDate:   tag.Tagger().When,
+		}
+	}
+	return currentTag, nil
+
+}
+
+//private methods
+
+// convert the backend-neutral identity to a libgit2 signature.
+func libgit2Signature(identity *GitIdentity) *git2go.Signature {
+	return &git2go.Signature{
+		Name:  identity.Name,
+		Email: identity.Email,
+		When:  identity.When,
+	}
+}
+
+// push the refspecs to the remote. References rejected by the remote (eg. non-fast-forward updates) are reported using a
+// callback, not an error.
+func libgit2Push(remote *git2go.Remote, refspecs ...string) error {
+	rejected := []string{}
+	pushOptions := &git2go.PushOptions{RemoteCallbacks: gitRemoteCallbacks()}
+	pushOptions.RemoteCallbacks.PushUpdateReferenceCallback = func(refname, status string) git2go.ErrorCode {
+		if status != "" {
+			rejected = append(rejected, fmt.Sprintf("%s (%s)", refname, status))
+		}
+		return git2go.ErrOk
+	}
+	if perr := remote.Push(refspecs, pushOptions); perr != nil {
+		return perr
+	} else if len(rejected) > 0 {
+		return fmt.Errorf("Push was rejected by the remote: %s", strings.Join(rejected, ", "))
+	}
+	return nil
+}
+
+// merge the remote commit into the current HEAD, and create a merge commit.
+// if squash is true, the merged tree will be committed with the HEAD commit as its only parent.
+func gitMergeCommit(repo *git2go.Repository, mergeHeads []*git2go.AnnotatedCommit, head *git2go.Reference, remoteBranchID *git2go.Oid, squash bool, message string, signature *GitIdentity) error {
+	//Options for merge
+	mergeOpts, err := git2go.DefaultMergeOptions()
+	if err != nil {
+		return err
+	}
+	mergeOpts.FileFavor = git2go.MergeFileFavorNormal
+	mergeOpts.TreeFlags = git2go.MergeTreeFailOnConflict
+
+	//Options for checkout
+	mergeCheckoutOpts := &git2go.CheckoutOpts{
+		Strategy: git2go.CheckoutSafe | git2go.CheckoutRecreateMissing | git2go.CheckoutUseTheirs,
+	}
+
+	//Merge action
+	if err = repo.Merge(mergeHeads, &mergeOpts, mergeCheckoutOpts); err != nil {
+		log.Print("Failed to merge heads")
+		// Check for conflicts
+		index, err := repo.Index()
+		if err != nil {
+			log.Print("Failed to get repo index")
+			return err
+		}
+		if index.HasConflicts() {
+			log.Printf("Conflicts encountered. Please resolve them. %v", err)
+			return errors.ScmMergeConflictError("Merge resulted in conflicts. Please solve the conflicts before merging.")
+		}
+		return err
+	}
+
+	//Getting repo Index
+	index, err := repo.Index()
+	if err != nil {
+		log.Print("Failed to get repo index")
+		return err
+	}
+	defer index.Free()
+
+	//Checking for conflicts
+	if index.HasConflicts() {
+		return errors.ScmMergeConflictError("Merge resulted in conflicts. Please solve the conflicts before merging.")
+	}
+
+	// Make the merge commit
+
+	// Get Write Tree
+	treeId, err := index.WriteTree()
+	if err != nil {
+		return err
+	}
+
+	tree, err := repo.LookupTree(treeId)
+	if err != nil {
+		return err
+	}
+
+	localCommit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return err
+	}
+
+	if squash {
+		_, err = repo.CreateCommit("HEAD", libgit2Signature(signature), libgit2Signature(signature), message, tree, localCommit)
+	} else {
+		remoteCommit, rerr := repo.LookupCommit(remoteBranchID)
+		if rerr != nil {
+			return rerr
+		}
+		_, err = repo.CreateCommit("HEAD", libgit2Signature(signature), libgit2Signature(signature), message, tree, localCommit, remoteCommit)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Clean up
+	return repo.StateCleanup()
+}
+
+// point the local branch (and HEAD) at the remote commit.
+func gitFastForward(repo *git2go.Repository, localBranch *git2go.Branch, head *git2go.Reference, remoteBranchID *git2go.Oid) error {
+	remoteCommit, err := repo.LookupCommit(remoteBranchID)
+	if err != nil {
+		return err
+	}
+
+	remoteTree, err := remoteCommit.Tree()
+	if err != nil {
+		return err
+	}
+
+	// Checkout
+	if err := repo.CheckoutTree(remoteTree, &git2go.CheckoutOpts{Strategy: git2go.CheckoutSafe | git2go.CheckoutRecreateMissing}); err != nil {
+		return err
+	}
+
+	// Point branch to the object
+	if _, err := localBranch.SetTarget(remoteBranchID, ""); err != nil {
+		return err
+	}
+	if _, err := head.SetTarget(remoteBranchID, ""); err != nil {
+		return err
+	}
+	return nil
+}
+
+// replay the remote commits (that are not already in HEAD) on top of HEAD, similar to `git rebase`.
+// Original commit authors & messages are retained. Merge commits cannot be replayed, so an error is returned (before
+// any commits are applied) if the remote commits include a merge commit.
+func gitRebaseCommits(repo *git2go.Repository, head *git2go.Reference, remoteBranchID *git2go.Oid, signature *GitIdentity) error {
+	mergeBaseID, err := repo.MergeBase(head.Target(), remoteBranchID)
+	if err != nil {
+		return err
+	}
+
+	revWalk, err := repo.Walk()
+	if err != nil {
+		return err
+	}
+	defer revWalk.Free()
+	revWalk.Sorting(git2go.SortTopological | git2go.SortReverse)
+	if err := revWalk.Push(remoteBranchID); err != nil {
+		return err
+	}
+	if err := revWalk.Hide(mergeBaseID); err != nil {
+		return err
+	}
+
+	commits := []*git2go.Commit{}
+	if err := revWalk.Iterate(func(commit *git2go.Commit) bool {
+		commits = append(commits, commit)
+		return true
+	}); err != nil {
+		return err
+	}
+
+	for _, commit := range commits {
+		if commit.ParentCount() > 1 {
+			return gitRebaseMergeCommitError(commit.Id().String())
+		}
+	}
+
+	cherrypickOpts, err := git2go.DefaultCherrypickOptions()
+	if err != nil {
+		return err
+	}
+
+	for _, commit := range commits {
+		if err := repo.Cherrypick(commit, cherrypickOpts); err != nil {
+			return err
+		}
+
+		index, err := repo.Index()
+		if err != nil {
+			return err
+		}
+		if index.HasConflicts() {
+			return errors.ScmMergeConflictError(fmt.Sprintf("Rebase resulted in conflicts while applying %s. Please rebase the pull request manually.", commit.Id().String()))
+		}
+
+		treeId, err := index.WriteTree()
+		if err != nil {
+			return err
+		}
+		tree, err := repo.LookupTree(treeId)
+		if err != nil {
+			return err
+		}
+
+		currentHead, err := repo.Head()
+		if err != nil {
+			return err
+		}
+		parentCommit, err := repo.LookupCommit(currentHead.Target())
+		if err != nil {
+			return err
+		}
+
+		if _, err := repo.CreateCommit("HEAD", commit.Author(), libgit2Signature(signature), commit.Message(), tree, parentCommit); err != nil {
+			return err
+		}
+		if err := repo.StateCleanup(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+// +build cgo,!nolibgit2
+
+package utils_test
+
+import "github.com/analogj/capsulecd/pkg/utils"
+
+// built with libgit2, so it's the default backend.
+const defaultGitBackend = utils.GitBackendLibgit2
@@ -0,0 +1,11 @@
+// +build !cgo nolibgit2
+
+package utils
+
+import "fmt"
+
+// CapsuleCD was built without libgit2 (without cgo, or with the `nolibgit2` build tag), so only the go-git backend
+// is available.
+func newLibgit2GitClient() (GitClient, error) {
+	return nil, fmt.Errorf("CapsuleCD was built without libgit2, use the %s git backend instead", GitBackendGoGit)
+}
@@ -0,0 +1,8 @@
+// +build !cgo nolibgit2
+
+package utils_test
+
+import "github.com/analogj/capsulecd/pkg/utils"
+
+// built without libgit2, so go-git is the default backend.
+const defaultGitBackend = utils.GitBackendGoGit
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+const (
+	GitSigningFormatGpg = "gpg"
+	GitSigningFormatSsh = "ssh"
+)
+
+// GitSigner creates the armored, detached signature embedded in signed git commits & tags.
+type GitSigner interface {
+	Sign(payload []byte) (string, error)
+}
+
+// NewGitSigner creates a signer for an armored GPG private key, or an SSH private key (PEM/OpenSSH format).
+// The passphrase is only required if the private key is encrypted.
+func NewGitSigner(format string, privateKey []byte, passphrase string) (GitSigner, error) {
+	switch format {
+	case GitSigningFormatGpg, "":
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(privateKey))
+		if err != nil {
+			return nil, err
+		} else if len(entities) == 0 || entities[0].PrivateKey == nil {
+			return nil, fmt.Errorf("GPG signing key does not contain a private key")
+		}
+		entity := entities[0]
+		if entity.PrivateKey.Encrypted {
+			if err := entity.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+				return nil, err
+			}
+		}
+		for _, subkey := range entity.Subkeys {
+			if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+				if err := subkey.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return &gpgGitSigner{entity: entity}, nil
+	case GitSigningFormatSsh:
+		var signer ssh.Signer
+		var err error
+		if passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(privateKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &sshGitSigner{signer: signer}, nil
+	default:
+		return nil, fmt.Errorf("Unknown git signing format: %s", format)
+	}
+}
+
+type gpgGitSigner struct {
+	entity *openpgp.Entity
+}
+
+func (s *gpgGitSigner) Sign(payload []byte) (string, error) {
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, s.entity, bytes.NewReader(payload), nil); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(signature.String()) + "\n", nil
+}
+
+// SSH signatures use the `sshsig` format (https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig)
+// with the `git` namespace, the same as `ssh-keygen -Y sign -n git`
+type sshGitSigner struct {
+	signer ssh.Signer
+}
+
+func (s *sshGitSigner) Sign(payload []byte) (string, error) {
+	const namespace = "git"
+	const hashAlgorithm = "sha512"
+	hash := sha512.Sum512(payload)
+
+	var signedData bytes.Buffer
+	signedData.WriteString("SSHSIG")
+	writeSshString(&signedData, []byte(namespace))
+	writeSshString(&signedData, []byte{})
+	writeSshString(&signedData, []byte(hashAlgorithm))
+	writeSshString(&signedData, hash[:])
+
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// ssh-rsa (sha1) signatures are rejected by ssh-keygen
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signedData.Bytes(), ssh.SigAlgoRSASHA2512)
+	} else {
+		signature, err = s.signer.Sign(rand.Reader, signedData.Bytes())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var blob bytes.Buffer
+	blob.WriteString("SSHSIG")
+	binary.Write(&blob, binary.BigEndian, uint32(1))
+	writeSshString(&blob, s.signer.PublicKey().Marshal())
+	writeSshString(&blob, []byte(namespace))
+	writeSshString(&blob, []byte{})
+	writeSshString(&blob, []byte(hashAlgorithm))
+	writeSshString(&blob, ssh.Marshal(signature))
+
+	encoded := base64.StdEncoding.EncodeToString(blob.Bytes())
+	armored := []string{"-----BEGIN SSH SIGNATURE-----"}
+	for len(encoded) > 70 {
+		armored = append(armored, encoded[:70])
+		encoded = encoded[70:]
+	}
+	armored = append(armored, encoded, "-----END SSH SIGNATURE-----")
+	return strings.Join(armored, "\n") + "\n", nil
+}
+
+func writeSshString(buf *bytes.Buffer, data []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+}
+
+// format a signature the way it's stored in git objects, eg. `CapsuleCD <CapsuleCD@users.noreply.github.com> 1514808000 +0000`
+func gitObjectSignature(signature *GitIdentity) string {
+	_, offset := signature.When.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("%s <%s> %d %s%02d%02d", signature.Name, signature.Email, signature.When.Unix(), sign, offset/3600, (offset%3600)/60)
+}
+
+// generate the content of a signed commit object. The signature is stored in the `gpgsig` header (for both GPG & SSH
+// signatures), and is created from the commit content without the header.
+func gitSignedCommitObject(treeSha string, parentShas []string, signature *GitIdentity, message string, signer GitSigner) ([]byte, error) {
+	var header bytes.Buffer
+	header.WriteString(fmt.Sprintf("tree %s\n", treeSha))
+	for _, parentSha := range parentShas {
+		header.WriteString(fmt.Sprintf("parent %s\n", parentSha))
+	}
+	header.WriteString(fmt.Sprintf("author %s\n", gitObjectSignature(signature)))
+	header.WriteString(fmt.Sprintf("committer %s\n", gitObjectSignature(signature)))
+	body := "\n" + strings.TrimRight(message, "\n") + "\n"
+
+	commitSignature, err := signer.Sign([]byte(header.String() + body))
+	if err != nil {
+		return nil, err
+	}
+	header.WriteString("gpgsig " + strings.Replace(strings.TrimRight(commitSignature, "\n"), "\n", "\n ", -1) + "\n")
+	return []byte(header.String() + body), nil
+}
+
+// generate the content of a signed (annotated) tag object. The signature is appended to the tag message.
+func gitSignedTagObject(targetSha string, tagName string, signature *GitIdentity, message string, signer GitSigner) ([]byte, error) {
+	payload := fmt.Sprintf("object %s\ntype commit\ntag %s\ntagger %s\n\n%s\n", targetSha, tagName, gitObjectSignature(signature), strings.TrimRight(message, "\n"))
+
+	tagSignature, err := signer.Sign([]byte(payload))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(payload + tagSignature), nil
+}
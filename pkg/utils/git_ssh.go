@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// the remote host key must be listed in the configured known hosts (or ~/.ssh/known_hosts)
+	GitKnownHostsPolicyStrict = "strict"
+	// any remote host key is accepted (the fingerprint is logged). Only use this for testing.
+	GitKnownHostsPolicyInsecure = "insecure"
+)
+
+// GitSshAuth configures the key used to authenticate with remotes using the SSH transport
+// (eg. `ssh://git@github.com/AnalogJ/capsulecd.git`), such as a deploy key.
+type GitSshAuth struct {
+	Username         string
+	PrivateKey       []byte
+	Passphrase       string
+	KnownHosts       []byte // known_hosts file content
+	KnownHostsPolicy string
+}
+
+// SSH authentication used by all remote operations (clone, fetch & push). HTTPS remotes continue to use the credentials
+// embedded in the remote url.
+var gitSshAuth *GitSshAuth
+var gitSshPublicKey string
+
+// GitConfigureSshAuth sets (or clears, if nil) the SSH key used for remote operations. The SSH auth applies to the whole
+// process, it's configured when the pipeline starts.
+func GitConfigureSshAuth(auth *GitSshAuth) error {
+	if auth == nil {
+		gitSshAuth, gitSshPublicKey = nil, ""
+		return nil
+	}
+
+	switch auth.KnownHostsPolicy {
+	case "":
+		auth.KnownHostsPolicy = GitKnownHostsPolicyStrict
+	case GitKnownHostsPolicyStrict, GitKnownHostsPolicyInsecure:
+	default:
+		return fmt.Errorf("Unknown known hosts policy: %s", auth.KnownHostsPolicy)
+	}
+
+	// libgit2 requires the public key, derive it from the private key.
+	var signer ssh.Signer
+	var err error
+	if auth.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(auth.PrivateKey, []byte(auth.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(auth.PrivateKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	gitSshAuth = auth
+	gitSshPublicKey = string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	return nil
+}
+
+// the configured known hosts, followed by the content of ~/.ssh/known_hosts (if it exists).
+func gitKnownHosts() []byte {
+	knownHosts := gitSshAuth.KnownHosts
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		if userKnownHosts, err := ioutil.ReadFile(path.Join(homeDir, ".ssh", "known_hosts")); err == nil {
+			knownHosts = append(append(append([]byte{}, knownHosts...), '\n'), userKnownHosts...)
+		}
+	}
+	return knownHosts
+}
+
+// check if the known_hosts content contains a matching key for the hostname. Hashed hostnames are supported, revoked
+// keys & certificate authorities are not.
+func gitKnownHostsContains(knownHosts []byte, hostname string, matchesKey func(publicKey ssh.PublicKey) bool) bool {
+	rest := knownHosts
+	for len(rest) > 0 {
+		marker, hosts, publicKey, _, next, err := ssh.ParseKnownHosts(rest)
+		if err != nil {
+			// ParseKnownHosts fails at the end of the content, or on an invalid line (which is skipped)
+			if lineEnd := bytes.IndexByte(rest, '\n'); lineEnd >= 0 && len(bytes.TrimSpace(rest)) > 0 {
+				rest = rest[lineEnd+1:]
+				continue
+			}
+			return false
+		}
+		rest = next
+		if marker != "" {
+			continue
+		}
+		for _, host := range hosts {
+			if gitKnownHostMatches(host, hostname) && matchesKey(publicKey) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match a known_hosts host entry, eg. `github.com`, `[github.com]:22` or a hashed `|1|salt|hash` entry.
+func gitKnownHostMatches(host string, hostname string) bool {
+	if strings.HasPrefix(host, "|1|") {
+		parts := strings.Split(host, "|")
+		if len(parts) != 4 {
+			return false
+		}
+		salt, serr := base64.StdEncoding.DecodeString(parts[2])
+		hash, herr := base64.StdEncoding.DecodeString(parts[3])
+		if serr != nil || herr != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(hostname))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+	if strings.HasPrefix(host, "[") {
+		host = strings.TrimPrefix(strings.SplitN(host, "]", 2)[0], "[")
+	}
+	return strings.EqualFold(host, hostname)
+}
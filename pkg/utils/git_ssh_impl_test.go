@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"strings"
+	"testing"
+)
+
+func testHostKey(t *testing.T) ssh.PublicKey {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	publicKey, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	return publicKey
+}
+
+func TestGitKnownHostsContains(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	githubKey := testHostKey(t)
+	enterpriseKey := testHostKey(t)
+	bitbucketKey := testHostKey(t)
+	unknownKey := testHostKey(t)
+
+	salt := []byte("0123456789abcdefghij")
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte("bitbucket.org"))
+	hashedHost := "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	knownHosts := strings.Join([]string{
+		"# known hosts",
+		"github.com,140.82.112.3 " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(githubKey))),
+		"this line is invalid",
+		"[github.example.com]:2222 " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(enterpriseKey))),
+		hashedHost + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(bitbucketKey))),
+		"@revoked github.com " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(unknownKey))),
+	}, "\n")
+	matches := func(expected ssh.PublicKey) func(publicKey ssh.PublicKey) bool {
+		return func(publicKey ssh.PublicKey) bool {
+			return string(publicKey.Marshal()) == string(expected.Marshal())
+		}
+	}
+
+	//test & assert
+	require.True(t, gitKnownHostsContains([]byte(knownHosts), "github.com", matches(githubKey)))
+	require.True(t, gitKnownHostsContains([]byte(knownHosts), "GitHub.com", matches(githubKey)), "hostnames are case-insensitive")
+	require.True(t, gitKnownHostsContains([]byte(knownHosts), "github.example.com", matches(enterpriseKey)))
+	require.True(t, gitKnownHostsContains([]byte(knownHosts), "bitbucket.org", matches(bitbucketKey)), "should match hashed hostnames")
+	require.False(t, gitKnownHostsContains([]byte(knownHosts), "github.com", matches(bitbucketKey)), "should not match another host's key")
+	require.False(t, gitKnownHostsContains([]byte(knownHosts), "github.com", matches(unknownKey)), "should not match revoked keys")
+	require.False(t, gitKnownHostsContains([]byte(knownHosts), "gitlab.com", matches(githubKey)))
+	require.False(t, gitKnownHostsContains([]byte{}, "github.com", matches(githubKey)))
+}
+
+func TestGitConfigureSshAuth(t *testing.T) {
+	//setup
+	privateKey, allowedSignersPath := sshTestKey(t)
+	defer GitConfigureSshAuth(nil)
+	_ = allowedSignersPath
+
+	//test & assert
+	require.Error(t, GitConfigureSshAuth(&GitSshAuth{PrivateKey: []byte("not a key")}))
+	require.Error(t, GitConfigureSshAuth(&GitSshAuth{PrivateKey: privateKey, KnownHostsPolicy: "trust-me"}), "should raise an error for unknown known hosts policies")
+	require.Nil(t, gitSshAuth, "should not use SSH auth if the configuration is invalid")
+
+	require.NoError(t, GitConfigureSshAuth(&GitSshAuth{Username: "git", PrivateKey: privateKey}))
+	require.Equal(t, GitKnownHostsPolicyStrict, gitSshAuth.KnownHostsPolicy, "should default to the strict known hosts policy")
+	require.True(t, strings.HasPrefix(gitSshPublicKey, "ssh-rsa "), "should derive the public key")
+	sshAuth, aerr := goGitAuth("ssh://git@github.com/AnalogJ/capsulecd.git")
+	require.NoError(t, aerr)
+	require.NotNil(t, sshAuth, "should authenticate SSH remotes")
+	httpsAuth, aerr := goGitAuth("https://github.com/AnalogJ/capsulecd.git")
+	require.NoError(t, aerr)
+	require.Nil(t, httpsAuth, "should not authenticate HTTPS remotes")
+
+	require.NoError(t, GitConfigureSshAuth(nil))
+	require.Nil(t, gitSshAuth)
+}
@@ -0,0 +1,63 @@
+// +build cgo,!nolibgit2
+
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"golang.org/x/crypto/ssh"
+	git2go "gopkg.in/libgit2/git2go.v25"
+	"log"
+)
+
+// callbacks used by every remote operation, to authenticate with the SSH key & verify the remote host key.
+func gitRemoteCallbacks() git2go.RemoteCallbacks {
+	if gitSshAuth == nil {
+		return git2go.RemoteCallbacks{}
+	}
+	return git2go.RemoteCallbacks{
+		CredentialsCallback:      gitCredentialsCallback,
+		CertificateCheckCallback: gitCertificateCheckCallback,
+	}
+}
+
+func gitCredentialsCallback(url string, usernameFromUrl string, allowedTypes git2go.CredType) (git2go.ErrorCode, *git2go.Cred) {
+	if allowedTypes&git2go.CredTypeSshKey == 0 {
+		return git2go.ErrPassthrough, nil
+	}
+	username := usernameFromUrl
+	if username == "" {
+		username = gitSshAuth.Username
+	}
+	ret, cred := git2go.NewCredSshKeyFromMemory(username, gitSshPublicKey, string(gitSshAuth.PrivateKey), gitSshAuth.Passphrase)
+	return git2go.ErrorCode(ret), &cred
+}
+
+func gitCertificateCheckCallback(cert *git2go.Certificate, valid bool, hostname string) git2go.ErrorCode {
+	if cert.Kind != git2go.CertificateHostkey {
+		// HTTPS certificates are validated by libgit2
+		if !valid {
+			return git2go.ErrCertificate
+		}
+		return git2go.ErrOk
+	}
+
+	hostkey := cert.Hostkey
+	matchesHostkey := func(publicKey ssh.PublicKey) bool {
+		if hostkey.Kind&git2go.HostkeySHA1 != 0 {
+			return sha1.Sum(publicKey.Marshal()) == hostkey.HashSHA1
+		}
+		return md5.Sum(publicKey.Marshal()) == hostkey.HashMD5
+	}
+
+	if gitSshAuth.KnownHostsPolicy == GitKnownHostsPolicyInsecure {
+		log.Printf("WARNING: accepting the host key for %s without verification (%x)", hostname, hostkey.HashSHA1)
+		return git2go.ErrOk
+	}
+
+	if gitKnownHostsContains(gitKnownHosts(), hostname, matchesHostkey) {
+		return git2go.ErrOk
+	}
+	log.Printf("The host key for %s is not a known host, add it to the known hosts (eg. `ssh-keyscan %s`)", hostname, hostname)
+	return git2go.ErrCertificate
+}
@@ -0,0 +1,25 @@
+// +build cgo,!nolibgit2
+
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGitRemoteCallbacks(t *testing.T) {
+	//setup
+	privateKey, _ := sshTestKey(t)
+	defer GitConfigureSshAuth(nil)
+
+	//test & assert
+	require.Error(t, GitConfigureSshAuth(&GitSshAuth{PrivateKey: []byte("not a key")}))
+	require.Nil(t, gitRemoteCallbacks().CredentialsCallback, "should not use SSH auth if the configuration is invalid")
+
+	require.NoError(t, GitConfigureSshAuth(&GitSshAuth{Username: "git", PrivateKey: privateKey}))
+	require.NotNil(t, gitRemoteCallbacks().CredentialsCallback)
+	require.NotNil(t, gitRemoteCallbacks().CertificateCheckCallback)
+
+	require.NoError(t, GitConfigureSshAuth(nil))
+	require.Nil(t, gitRemoteCallbacks().CredentialsCallback)
+}
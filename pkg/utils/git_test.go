@@ -1,268 +1,768 @@
 package utils_test
 
 import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/errors"
 	"github.com/analogj/capsulecd/pkg/utils"
 	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
+	"strings"
 	"testing"
 )
 
-func TestGitClone(t *testing.T) {
+// The git operations are tested against each backend.
+type GitTestSuite struct {
+	suite.Suite
+	Backend string
+	Git     utils.GitClient
+}
+
+func (suite *GitTestSuite) SetupTest() {
+	client, err := utils.NewGitClient(suite.Backend)
+	if err != nil && suite.Backend == utils.GitBackendLibgit2 && os.Getenv("CI") != "true" {
+		suite.T().Skip(err.Error()) //built without libgit2, CI must run the libgit2 suite
+	}
+	require.NoError(suite.T(), err)
+	suite.Git = client
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestGitLibgit2_TestSuite(t *testing.T) {
+	suite.Run(t, &GitTestSuite{Backend: utils.GitBackendLibgit2})
+}
+
+func TestGitGoGit_TestSuite(t *testing.T) {
+	suite.Run(t, &GitTestSuite{Backend: utils.GitBackendGoGit})
+}
+
+func TestNewGitClient(t *testing.T) {
 	t.Parallel()
 
+	//test
+	client, err := utils.NewGitClient("")
+	defaultClient, derr := utils.NewGitClient(defaultGitBackend)
+	unknownClient, uerr := utils.NewGitClient("svn")
+
+	//assert
+	require.NoError(t, err)
+	require.NoError(t, derr)
+	require.IsType(t, defaultClient, client, "should default to libgit2 when built with it, otherwise go-git")
+	require.Error(t, uerr, "should raise an error for unknown backends")
+	require.Nil(t, unknownClient)
+}
+
+// a local repository created with the git cli, used as the origin of the cloned repository, so that tests do not
+// depend on the network. Each commit is one minute newer than the previous commit.
+type gitFixture struct {
+	t    *testing.T
+	Path string
+	date int64
+}
+
+func newGitFixture(t *testing.T) *gitFixture {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dirPath, err := ioutil.TempDir("", "origin")
+	require.NoError(t, err)
+	fixture := &gitFixture{t: t, Path: dirPath, date: 1500000000}
+	fixture.Git("init")
+	fixture.Git("symbolic-ref", "HEAD", "refs/heads/master")
+	return fixture
+}
+
+func (f *gitFixture) Git(args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = f.Path
+	date := fmt.Sprintf("@%d +0000", f.date)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Jason Kulatunga", "GIT_AUTHOR_EMAIL=jason@thesparktree.com", "GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME=Jason Kulatunga", "GIT_COMMITTER_EMAIL=jason@thesparktree.com", "GIT_COMMITTER_DATE="+date,
+	)
+	output, err := cmd.CombinedOutput()
+	require.NoError(f.t, err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+// write the files (an empty content deletes the file), and commit them on the current branch. Returns the commit sha.
+func (f *gitFixture) Commit(message string, files map[string]string) string {
+	for filePath, content := range files {
+		if content == "" {
+			require.NoError(f.t, os.Remove(path.Join(f.Path, filePath)))
+			continue
+		}
+		require.NoError(f.t, os.MkdirAll(path.Dir(path.Join(f.Path, filePath)), 0755))
+		require.NoError(f.t, ioutil.WriteFile(path.Join(f.Path, filePath), []byte(content), 0644))
+	}
+	f.date += 60
+	f.Git("add", "-A")
+	f.Git("commit", "--allow-empty", "-m", message)
+	return f.Git("rev-parse", "HEAD")
+}
+
+// a diverged pull request: master & feature both changed the README (different lines) since the feature branch was
+// created. The origin is left on master.
+func divergedGitFixture(t *testing.T) *gitFixture {
+	fixture := newGitFixture(t)
+	fixture.Commit("initial commit", map[string]string{"README.md": "# Title\n\nline 1\nline 2\nline 3\n"})
+	fixture.Git("tag", "v1.0.0")
+	fixture.Git("checkout", "-b", "feature")
+	fixture.Commit("feature change", map[string]string{"README.md": "# Title\n\nline 1\nline 2\nfeature line 3\n", "feature.txt": "feature\n"})
+	fixture.Git("checkout", "master")
+	fixture.Commit("master change", map[string]string{"README.md": "# New Title\n\nline 1\nline 2\nline 3\n", "master.txt": "master\n"})
+	return fixture
+}
+
+func readTestFile(t *testing.T, filePath string) string {
+	content, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_Diverged() {
 	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	masterSha := fixture.Git("rev-parse", "master")
+	featureSha := fixture.Git("rev-parse", "feature")
 
 	//test
-	clonePath, cerr := utils.GitClone(dirPath, "test", "https://github.com/AnalogJ/test.git")
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategyMerge, "Merge feature into master", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
 
 	//assert
-	require.NoError(t, cerr)
-	require.NotEmpty(t, clonePath)
+	require.NoError(suite.T(), merr)
+	require.Equal(suite.T(), "# New Title\n\nline 1\nline 2\nfeature line 3\n", readTestFile(suite.T(), path.Join(clonePath, "README.md")), "should merge the changes to both sides of the file")
+	require.Equal(suite.T(), "feature\n", readTestFile(suite.T(), path.Join(clonePath, "feature.txt")))
+	require.Equal(suite.T(), "master\n", readTestFile(suite.T(), path.Join(clonePath, "master.txt")))
+	commits, lerr := suite.Git.CommitsBetween(clonePath, masterSha, "pr_1")
+	require.NoError(suite.T(), lerr)
+	require.Len(suite.T(), commits, 2)
+	require.True(suite.T(), commits[0].IsMerge())
+	require.Equal(suite.T(), "Merge feature into master", commits[0].Message)
+	require.Equal(suite.T(), featureSha, commits[1].Sha)
+	message, herr := suite.Git.HeadCommitMessage(clonePath)
+	require.NoError(suite.T(), herr)
+	require.Equal(suite.T(), "Merge feature into master", message, "should checkout the merged branch")
 }
 
-func TestGitClone_ExistingPath(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_Conflict() {
+	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	fixture.Git("checkout", "feature")
+	fixture.Commit("conflicting change", map[string]string{"README.md": "# Feature Title\n\nline 1\nline 2\nfeature line 3\n"})
+	fixture.Git("checkout", "master")
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+
+	//test
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategyMerge, "Merge feature into master", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
+
+	//assert
+	require.Error(suite.T(), merr)
+	require.IsType(suite.T(), errors.ScmMergeConflictError(""), merr, "should fail if both sides changed the same lines")
+}
 
+// add a merge commit to the feature branch of the fixture, merging a side branch containing side.txt
+func mergeCommitGitFixture(fixture *gitFixture) {
+	fixture.Git("checkout", "-b", "side", "feature")
+	fixture.Commit("side change", map[string]string{"side.txt": "side\n"})
+	fixture.Git("checkout", "feature")
+	fixture.Commit("another feature change", map[string]string{"feature.txt": "feature 2\n"})
+	fixture.Git("merge", "--no-ff", "side", "-m", "Merge branch 'side' into feature")
+	fixture.Git("checkout", "master")
+}
+
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_SquashDiverged() {
 	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
-	merr := os.MkdirAll(path.Join(dirPath, "test"), os.ModePerm)
-	require.NoError(t, merr)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	masterSha := fixture.Git("rev-parse", "master")
 
 	//test
-	clonePath, cerr := utils.GitClone(dirPath, "test", "https://github.com/AnalogJ/test.git")
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategySquash, "Add feature (#1)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
 
 	//assert
-	require.Error(t, cerr, "should raise an error if cloning to an existing path")
-	require.Empty(t, clonePath)
+	require.NoError(suite.T(), merr)
+	require.Equal(suite.T(), "# New Title\n\nline 1\nline 2\nfeature line 3\n", readTestFile(suite.T(), path.Join(clonePath, "README.md")))
+	require.Equal(suite.T(), "feature\n", readTestFile(suite.T(), path.Join(clonePath, "feature.txt")))
+	require.Equal(suite.T(), "master\n", readTestFile(suite.T(), path.Join(clonePath, "master.txt")))
+	commits, lerr := suite.Git.CommitsBetween(clonePath, masterSha, "pr_1")
+	require.NoError(suite.T(), lerr)
+	require.Len(suite.T(), commits, 1, "should squash the pull request into a single commit")
+	require.False(suite.T(), commits[0].IsMerge())
+	require.Equal(suite.T(), "Add feature (#1)", commits[0].Message)
+	require.Equal(suite.T(), "CapsuleCD", commits[0].AuthorName)
 }
 
-func TestGitClone_InvalidRemote(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_SquashMergeCommit() {
+	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	mergeCommitGitFixture(fixture)
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	masterSha := fixture.Git("rev-parse", "master")
+
+	//test
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategySquash, "Add feature (#1)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
 
+	//assert
+	require.NoError(suite.T(), merr)
+	require.Equal(suite.T(), "feature 2\n", readTestFile(suite.T(), path.Join(clonePath, "feature.txt")))
+	require.Equal(suite.T(), "side\n", readTestFile(suite.T(), path.Join(clonePath, "side.txt")), "should include the changes from the merged branch")
+	commits, lerr := suite.Git.CommitsBetween(clonePath, masterSha, "pr_1")
+	require.NoError(suite.T(), lerr)
+	require.Len(suite.T(), commits, 1)
+	require.False(suite.T(), commits[0].IsMerge())
+}
+
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_RebaseDiverged() {
 	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	fixture.Git("checkout", "feature")
+	fixture.Commit("another feature change", map[string]string{"feature.txt": "feature 2\n"})
+	fixture.Git("checkout", "master")
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	masterSha := fixture.Git("rev-parse", "master")
+	featureSha := fixture.Git("rev-parse", "feature")
 
 	//test
-	_, cerr := utils.GitClone(dirPath, "test", "this-is-an-invalid-url-remote")
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategyRebase, "Add feature (#1)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
 
 	//assert
-	require.Error(t, cerr, "should raise an error if invalid remote")
+	require.NoError(suite.T(), merr)
+	require.Equal(suite.T(), "# New Title\n\nline 1\nline 2\nfeature line 3\n", readTestFile(suite.T(), path.Join(clonePath, "README.md")))
+	require.Equal(suite.T(), "feature 2\n", readTestFile(suite.T(), path.Join(clonePath, "feature.txt")))
+	require.Equal(suite.T(), "master\n", readTestFile(suite.T(), path.Join(clonePath, "master.txt")))
+	commits, lerr := suite.Git.CommitsBetween(clonePath, masterSha, "pr_1")
+	require.NoError(suite.T(), lerr)
+	require.Len(suite.T(), commits, 2, "should replay each pull request commit")
+	require.Equal(suite.T(), "another feature change", strings.TrimSpace(commits[0].Message))
+	require.Equal(suite.T(), "feature change", strings.TrimSpace(commits[1].Message))
+	for _, commit := range commits {
+		require.False(suite.T(), commit.IsMerge())
+		require.NotEqual(suite.T(), featureSha, commit.Sha, "should create new commits on top of the base branch")
+		require.Equal(suite.T(), "Jason Kulatunga", commit.AuthorName, "should keep the original author")
+	}
 }
 
-func TestGitFetch(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_RebaseMergeCommit() {
+	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	mergeCommitGitFixture(fixture)
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	masterSha := fixture.Git("rev-parse", "master")
+
+	//test
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategyRebase, "Add feature (#1)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
+
+	//assert
+	require.Error(suite.T(), merr)
+	require.IsType(suite.T(), errors.ScmMergeStrategyError(""), merr, "should not drop merge commits (and their changes) while rebasing")
+	commits, lerr := suite.Git.CommitsBetween(clonePath, masterSha, "pr_1")
+	require.NoError(suite.T(), lerr)
+	require.Empty(suite.T(), commits, "should not apply any commits")
+}
 
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_FastForwardOnly() {
 	//setup
+	fixture := newGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	masterSha := fixture.Commit("initial commit", map[string]string{"README.md": "# Title\n"})
+	fixture.Git("checkout", "-b", "feature")
+	featureSha := fixture.Commit("feature change", map[string]string{"feature.txt": "feature\n"})
+	fixture.Git("checkout", "master")
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
 
 	//test
-	clonePath, cerr := utils.GitClone(dirPath, "cookbook_analogj_test", "https://github.com/AnalogJ/cookbook_analogj_test.git")
-	require.NoError(t, cerr)
-	ferr := utils.GitFetchPullRequest(clonePath, "12", "localBranchName", "refs/pull/%s/merge", "")
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategyFastForwardOnly, "Add feature (#1)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
 
 	//assert
-	require.NoError(t, ferr)
+	require.NoError(suite.T(), merr)
+	require.Equal(suite.T(), "feature\n", readTestFile(suite.T(), path.Join(clonePath, "feature.txt")))
+	commits, lerr := suite.Git.CommitsBetween(clonePath, masterSha, "pr_1")
+	require.NoError(suite.T(), lerr)
+	require.Len(suite.T(), commits, 1)
+	require.Equal(suite.T(), featureSha, commits[0].Sha, "should fast-forward to the pull request commit")
 }
 
-func TestGitFetch_InvalidDirectory(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitMergeRemoteBranch_FastForwardOnlyDiverged() {
+	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "merge_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
 
+	//test
+	merr := suite.Git.MergeRemoteBranch(clonePath, "pr_1", "master", fixture.Path, "feature", utils.GitMergeStrategyFastForwardOnly, "Add feature (#1)", utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com"))
+
+	//assert
+	require.Error(suite.T(), merr)
+	require.IsType(suite.T(), errors.ScmMergeStrategyError(""), merr, "should fail if the base branch has diverged")
+}
+
+/*
+
+The nearest tag is the tag with the fewest commits between it & HEAD, not the most recent tag:
+
+      v2.0.0
+c0--m1--m2--m3--M   master
+  \            /
+   f1----------+    feature
+   v1.5.0 (newer than m3)
+
+*/
+func (suite *GitTestSuite) TestGitFindNearestTagName_Ancestry() {
 	//setup
-	dirPath := path.Join("this", "path", "does", "not", "exist")
+	fixture := newGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	fixture.Commit("c0", map[string]string{"README.md": "c0\n"})
+	fixture.Git("branch", "feature")
+	fixture.Commit("m1", map[string]string{"master.txt": "m1\n"})
+	fixture.Commit("m2", map[string]string{"master.txt": "m2\n"})
+	fixture.Git("tag", "-a", "v2.0.0", "-m", "v2.0.0")
+	fixture.Commit("m3", map[string]string{"master.txt": "m3\n"})
+	fixture.Git("checkout", "feature")
+	fixture.Commit("f1", map[string]string{"feature.txt": "f1\n"})
+	fixture.Git("tag", "v1.5.0")
+	fixture.Git("checkout", "master")
+	fixture.date += 60
+	fixture.Git("merge", "--no-ff", "-m", "M", "feature")
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "tags_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
 
 	//test
-	ferr := utils.GitFetchPullRequest(dirPath, "12", "localBranchName", "refs/pull/%s/merge", "")
+	tag, ferr := suite.Git.FindNearestMatchingTagName(clonePath, "")
+	matchingTag, merr := suite.Git.FindNearestMatchingTagName(clonePath, "v1.*")
 
 	//assert
-	require.Error(t, ferr)
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), fixture.Git("describe", "--tags", "--abbrev=0"), tag, "should match git describe")
+	require.Equal(suite.T(), "v2.0.0", tag)
+	require.NoError(suite.T(), merr)
+	require.Equal(suite.T(), "v1.5.0", matchingTag)
 }
 
-func TestGitCheckout(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitFindNearestTagName_NoTags() {
+	//setup
+	fixture := newGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	fixture.Commit("initial commit", map[string]string{"README.md": "hello\n"})
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "tags_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+
+	//test
+	tag, ferr := suite.Git.FindNearestMatchingTagName(clonePath, "")
+
+	//assert
+	require.Error(suite.T(), ferr)
+	require.Empty(suite.T(), tag)
+}
 
+func (suite *GitTestSuite) TestGitRepositoryInfo() {
 	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "info_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	initialSha := fixture.Git("rev-parse", "v1.0.0")
+
+	//test & assert
+	exists, terr := suite.Git.TagExists(clonePath, "v1.0.0")
+	require.NoError(suite.T(), terr)
+	require.True(suite.T(), exists)
+	exists, terr = suite.Git.TagExists(clonePath, "v9.9.9")
+	require.NoError(suite.T(), terr)
+	require.False(suite.T(), exists)
+
+	content, ferr := suite.Git.FileContent(clonePath, initialSha, "README.md")
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), "# Title\n\nline 1\nline 2\nline 3\n", string(content), "should read the file without a checkout")
+	_, ferr = suite.Git.FileContent(clonePath, initialSha, "master.txt")
+	require.Error(suite.T(), ferr)
+
+	commitContent, oerr := suite.Git.CommitObject(clonePath, initialSha)
+	require.NoError(suite.T(), oerr)
+	require.Equal(suite.T(), fixture.Git("cat-file", "commit", initialSha), strings.TrimSpace(string(commitContent)))
+
+	require.NoError(suite.T(), suite.Git.ResetToCommit(clonePath, initialSha))
+	message, herr := suite.Git.HeadCommitMessage(clonePath)
+	require.NoError(suite.T(), herr)
+	require.Equal(suite.T(), "initial commit\n", message)
+	require.False(suite.T(), utils.FileExists(path.Join(clonePath, "master.txt")), "should reset the working directory")
+
+	require.NoError(suite.T(), suite.Git.FetchRemoteUrlRef(clonePath, fixture.Path, "refs/heads/feature", "refs/remotes/pr_head/feature"))
+	commits, lerr := suite.Git.CommitsBetween(clonePath, initialSha, "refs/remotes/pr_head/feature")
+	require.NoError(suite.T(), lerr)
+	require.Len(suite.T(), commits, 1)
+	require.Equal(suite.T(), fixture.Git("rev-parse", "feature"), commits[0].Sha)
+}
+
+func (suite *GitTestSuite) TestGitMetadataRef() {
+	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "metadata_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
+	const refName = "refs/capsulecd/metadata"
+
+	//test & assert
+	sha, message, ferr := suite.Git.FetchRemoteRef(clonePath, refName, "refs/remotes/origin/capsulecd/metadata")
+	require.NoError(suite.T(), ferr)
+	require.Empty(suite.T(), sha, "should not find a missing remote reference")
+	require.Empty(suite.T(), message)
+
+	firstSha, merr := suite.Git.CreateMetadataCommit(clonePath, refName, "", "first", signature)
+	require.NoError(suite.T(), merr)
+	require.NoError(suite.T(), suite.Git.PushRef(clonePath, refName, refName, false))
+	require.Equal(suite.T(), firstSha, fixture.Git("rev-parse", refName))
+	require.Equal(suite.T(), "4b825dc642cb6eb9a060e54bf8d69288fbee4904", fixture.Git("rev-parse", refName+"^{tree}"), "should have an empty tree")
+
+	secondSha, merr := suite.Git.CreateMetadataCommit(clonePath, refName, firstSha, "second", signature)
+	require.NoError(suite.T(), merr)
+	require.NoError(suite.T(), suite.Git.PushRef(clonePath, refName, refName, false), "should fast-forward the remote reference")
+
+	otherSha, merr := suite.Git.CreateMetadataCommit(clonePath, refName, "", "other", signature)
+	require.NoError(suite.T(), merr)
+	require.Error(suite.T(), suite.Git.PushRef(clonePath, refName, refName, false), "should reject non fast-forward updates")
+	require.Equal(suite.T(), secondSha, fixture.Git("rev-parse", refName))
+	require.NoError(suite.T(), suite.Git.PushRef(clonePath, refName, refName, true))
+	require.Equal(suite.T(), otherSha, fixture.Git("rev-parse", refName))
+
+	sha, message, ferr = suite.Git.FetchRemoteRef(clonePath, refName, "refs/remotes/origin/capsulecd/metadata")
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), otherSha, sha)
+	require.Equal(suite.T(), "other", strings.TrimSpace(message))
+
+	require.NoError(suite.T(), suite.Git.PushRef(clonePath, "", refName, false), "should delete the remote reference")
+	require.Empty(suite.T(), fixture.Git("for-each-ref", refName))
+}
+
+func (suite *GitTestSuite) TestGitPushBranch() {
+	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "push_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	require.NoError(suite.T(), suite.Git.Checkout(clonePath, "feature"))
+
+	//test & assert
+	require.NoError(suite.T(), suite.Git.PushBranch(clonePath, "feature", "release", false))
+	require.Equal(suite.T(), fixture.Git("rev-parse", "feature"), fixture.Git("rev-parse", "release"))
+	require.Error(suite.T(), suite.Git.PushBranch(clonePath, "master", "release", false), "should reject non fast-forward updates")
+	require.NoError(suite.T(), suite.Git.PushBranch(clonePath, "master", "release", true))
+	require.Equal(suite.T(), fixture.Git("rev-parse", "master"), fixture.Git("rev-parse", "release"))
+}
+
+func (suite *GitTestSuite) TestGitFetchTags() {
+	//setup
+	fixture := divergedGitFixture(suite.T())
+	defer deleteTestRepo(fixture.Path)
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "fetch_tags_test", fixture.Path)
+	require.NoError(suite.T(), cerr)
+	fixture.Git("tag", "-a", "v1.1.0", "-m", "v1.1.0", "master")
 
 	//test
-	ferr := utils.GitCheckout(clonePath, "do_not_delete_capsulecd_test_branch")
+	ferr := suite.Git.FetchTags(clonePath)
 
 	//assert
-	require.NoError(t, ferr)
+	require.NoError(suite.T(), ferr)
+	tagExists, terr := suite.Git.TagExists(clonePath, "v1.1.0")
+	require.NoError(suite.T(), terr)
+	require.True(suite.T(), tagExists, "should fetch tags created after the repository was cloned")
+	nearestTag, nerr := suite.Git.FindNearestMatchingTagName(clonePath, "")
+	require.NoError(suite.T(), nerr)
+	require.Equal(suite.T(), "v1.1.0", nearestTag)
 }
 
-func TestGitCheckout_InvalidDirectory(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitClone() {
+	//setup
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+
+	//test
+	clonePath, cerr := suite.Git.Clone(dirPath, "test", "https://github.com/AnalogJ/test.git")
+
+	//assert
+	require.NoError(suite.T(), cerr)
+	require.NotEmpty(suite.T(), clonePath)
+}
 
+func (suite *GitTestSuite) TestGitClone_ExistingPath() {
 	//setup
-	dirPath := path.Join("this", "path", "does", "not", "exist")
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	merr := os.MkdirAll(path.Join(dirPath, "test"), os.ModePerm)
+	require.NoError(suite.T(), merr)
+	defer deleteTestRepo(dirPath)
 
 	//test
-	ferr := utils.GitCheckout(dirPath, "localBranchName")
+	clonePath, cerr := suite.Git.Clone(dirPath, "test", "https://github.com/AnalogJ/test.git")
 
 	//assert
-	require.Error(t, ferr)
+	require.Error(suite.T(), cerr, "should raise an error if cloning to an existing path")
+	require.Empty(suite.T(), clonePath)
 }
 
-func TestGitCheckout_InvalidBranch(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitClone_InvalidRemote() {
+	//setup
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+
+	//test
+	_, cerr := suite.Git.Clone(dirPath, "test", "this-is-an-invalid-url-remote")
+
+	//assert
+	require.Error(suite.T(), cerr, "should raise an error if invalid remote")
+}
 
+func (suite *GitTestSuite) TestGitFetch() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
-	require.NoError(t, cerr)
 
 	//test
-	ferr := utils.GitCheckout(clonePath, "invalid_branch_test")
+	clonePath, cerr := suite.Git.Clone(dirPath, "cookbook_analogj_test", "https://github.com/AnalogJ/cookbook_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+	ferr := suite.Git.FetchPullRequest(clonePath, "12", "localBranchName", "refs/pull/%s/merge", "")
 
 	//assert
-	require.Error(t, ferr)
+	require.NoError(suite.T(), ferr)
 }
 
-func TestGitCommit(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitFetch_InvalidDirectory() {
+	//setup
+	dirPath := path.Join("this", "path", "does", "not", "exist")
+
+	//test
+	ferr := suite.Git.FetchPullRequest(dirPath, "12", "localBranchName", "refs/pull/%s/merge", "")
 
+	//assert
+	require.Error(suite.T(), ferr)
+}
+
+func (suite *GitTestSuite) TestGitCheckout() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "commit_to_npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
-	require.NoError(t, cerr)
-	ferr := utils.GitCheckout(clonePath, "do_not_delete_capsulecd_test_branch")
-	require.NoError(t, ferr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+
+	//test
+	ferr := suite.Git.Checkout(clonePath, "do_not_delete_capsulecd_test_branch")
+
+	//assert
+	require.NoError(suite.T(), ferr)
+}
+
+func (suite *GitTestSuite) TestGitCheckout_InvalidDirectory() {
+	//setup
+	dirPath := path.Join("this", "path", "does", "not", "exist")
+
+	//test
+	ferr := suite.Git.Checkout(dirPath, "localBranchName")
+
+	//assert
+	require.Error(suite.T(), ferr)
+}
+
+func (suite *GitTestSuite) TestGitCheckout_InvalidBranch() {
+	//setup
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+
+	//test
+	ferr := suite.Git.Checkout(clonePath, "invalid_branch_test")
+
+	//assert
+	require.Error(suite.T(), ferr)
+}
+
+func (suite *GitTestSuite) TestGitCommit() {
+	//setup
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "commit_to_npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+	ferr := suite.Git.Checkout(clonePath, "do_not_delete_capsulecd_test_branch")
+	require.NoError(suite.T(), ferr)
 	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
 
 	//test
 	d1 := []byte("hello\nworld\n")
 	werr := ioutil.WriteFile(clonePath+"/commit_testfile.txt", d1, 0644)
-	require.NoError(t, werr)
-	gcerr := utils.GitCommit(clonePath, "Added New File", signature)
+	require.NoError(suite.T(), werr)
+	gcerr := suite.Git.Commit(clonePath, "Added New File", signature, nil)
 
 	//assert
-	require.NoError(t, gcerr)
+	require.NoError(suite.T(), gcerr)
 }
 
-func TestGitCommit_InvalidDirectory(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitCommit_InvalidDirectory() {
 	//setup
 	dirPath := path.Join("this", "path", "does", "not", "exist")
 	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
 
 	//test
-	ferr := utils.GitCommit(dirPath, "message", signature)
+	ferr := suite.Git.Commit(dirPath, "message", signature, nil)
 
 	//assert
-	require.Error(t, ferr)
+	require.Error(suite.T(), ferr)
 }
 
-func TestGitTag(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitTag() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "add_tag_npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
-	require.NoError(t, cerr)
-	ferr := utils.GitCheckout(clonePath, "do_not_delete_capsulecd_test_branch")
-	require.NoError(t, ferr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "add_tag_npm_analogj_test", "https://github.com/AnalogJ/npm_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+	ferr := suite.Git.Checkout(clonePath, "do_not_delete_capsulecd_test_branch")
+	require.NoError(suite.T(), ferr)
 	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
 
 
 	//test
 	d1 := []byte("hello\nworld\n")
 	werr := ioutil.WriteFile(clonePath+"/tag_testfile.txt", d1, 0644)
-	require.NoError(t, werr)
-	gcerr := utils.GitCommit(clonePath, "Added New File", signature)
-	require.NoError(t, gcerr)
-	tid, terr := utils.GitTag(clonePath, "v9.9.9", "test git tag message", signature)
+	require.NoError(suite.T(), werr)
+	gcerr := suite.Git.Commit(clonePath, "Added New File", signature, nil)
+	require.NoError(suite.T(), gcerr)
+	tid, terr := suite.Git.Tag(clonePath, "v9.9.9", "test git tag message", signature, nil)
 
 	//assert
-	require.NoError(t, terr)
-	require.NotEmpty(t, tid)
+	require.NoError(suite.T(), terr)
+	require.NotEmpty(suite.T(), tid)
 }
 
-func TestGitTag_InvalidDirectory(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitTag_InvalidDirectory() {
 	//setup
 	dirPath := path.Join("this", "path", "does", "not", "exist")
 	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
 
 	//test
-	tag, ferr := utils.GitTag(dirPath, "version", "test git tag message", signature)
+	tag, ferr := suite.Git.Tag(dirPath, "version", "test git tag message", signature, nil)
 
 	//assert
-	require.Error(t, ferr)
-	require.Empty(t, tag)
+	require.Error(suite.T(), ferr)
+	require.Empty(suite.T(), tag)
 }
 
-func TestGitPush(t *testing.T) {
-	t.Skip() //Skipping because access_token not available during remote testing.
+func (suite *GitTestSuite) TestGitPush() {
+	suite.T().Skip() //Skipping because access_token not available during remote testing.
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
 
-	clonePath, cerr := utils.GitClone(dirPath, "push_npm_analogj_test", "https://access_token_here:@github.com/AnalogJ/npm_analogj_test.git")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "push_npm_analogj_test", "https://access_token_here:@github.com/AnalogJ/npm_analogj_test.git")
+	require.NoError(suite.T(), cerr)
 
-	ferr := utils.GitCheckout(clonePath, "do_not_delete_capsulecd_test_branch")
-	require.NoError(t, ferr)
+	ferr := suite.Git.Checkout(clonePath, "do_not_delete_capsulecd_test_branch")
+	require.NoError(suite.T(), ferr)
 	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
 
 	//create a new file
 	d1 := []byte("hello\nworld\n")
 	werr := ioutil.WriteFile(clonePath+"/push_testfile.txt", d1, 0644)
-	require.NoError(t, werr)
+	require.NoError(suite.T(), werr)
 
-	gcerr := utils.GitCommit(clonePath, "Added New File", signature)
-	require.NoError(t, gcerr)
+	gcerr := suite.Git.Commit(clonePath, "Added New File", signature, nil)
+	require.NoError(suite.T(), gcerr)
 
-	perr := utils.GitPush(clonePath, "do_not_delete_capsulecd_test_branch", "do_not_delete_capsulecd_test_branch", "v1.0.0")
-	require.NoError(t, perr)
+	perr := suite.Git.Push(clonePath, "do_not_delete_capsulecd_test_branch", "do_not_delete_capsulecd_test_branch", "v1.0.0")
+	require.NoError(suite.T(), perr)
 
 }
 
-func TestGitPush_PullRequest(t *testing.T) {
-	t.Skip() //Skipping because access_token not available during remote testing.
+func (suite *GitTestSuite) TestGitPush_PullRequest() {
+	suite.T().Skip() //Skipping because access_token not available during remote testing.
 
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "cookbook_analogj_test", "https://access_token_here:@github.com/AnalogJ/cookbook_analogj_test.git")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "cookbook_analogj_test", "https://access_token_here:@github.com/AnalogJ/cookbook_analogj_test.git")
+	require.NoError(suite.T(), cerr)
 	signature := utils.GitSignature("CapsuleCD", "CapsuleCD@users.noreply.github.com")
 
 	//test
-	ferr := utils.GitFetchPullRequest(clonePath, "13", "localBranchName", "refs/pull/%s/merge", "")
-	require.NoError(t, ferr)
+	ferr := suite.Git.FetchPullRequest(clonePath, "13", "localBranchName", "refs/pull/%s/merge", "")
+	require.NoError(suite.T(), ferr)
 	d1 := []byte("hello\nworld\n")
 	werr := ioutil.WriteFile(clonePath+"/push_testfile.txt", d1, 0644)
-	require.NoError(t, werr)
-	gcerr := utils.GitCommit(clonePath, "Added New File", signature)
-	require.NoError(t, gcerr)
-	perr := utils.GitPush(clonePath, "localBranchName", "master", "v1.0.0")
+	require.NoError(suite.T(), werr)
+	gcerr := suite.Git.Commit(clonePath, "Added New File", signature, nil)
+	require.NoError(suite.T(), gcerr)
+	perr := suite.Git.Push(clonePath, "localBranchName", "master", "v1.0.0")
 
 	//test
-	require.NoError(t, perr)
+	require.NoError(suite.T(), perr)
 
 }
 
@@ -300,84 +800,76 @@ func TestGitPush_PullRequest(t *testing.T) {
 //	require.Empty(t, tag)
 //}
 
-func TestGitFindNearestTagName_CheckoutMaster(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitFindNearestTagName_CheckoutMaster() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "tags_analogj_test", "https://github.com/AnalogJ/tags_analogj_test.git")
-	require.NoError(t, cerr)
-	cerr = utils.GitCheckout(clonePath, "master")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "tags_analogj_test", "https://github.com/AnalogJ/tags_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+	cerr = suite.Git.Checkout(clonePath, "master")
+	require.NoError(suite.T(), cerr)
 
 	//test
-	tag, ferr := utils.GitFindNearestTagName(clonePath)
+	tag, ferr := suite.Git.FindNearestMatchingTagName(clonePath, "")
 
 	//assert
-	require.NoError(t, ferr)
-	require.Equal(t, "v0.4.1", tag, "should actually be v0.4.1")
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), "v0.4.1", tag, "should actually be v0.4.1")
 
 }
 
-func TestGitFindNearestTagName_CheckoutBranch(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitFindNearestTagName_CheckoutBranch() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "tags_analogj_test", "https://github.com/AnalogJ/tags_analogj_test.git")
-	require.NoError(t, cerr)
-	cerr = utils.GitCheckout(clonePath, "do_not_merge_2")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "tags_analogj_test", "https://github.com/AnalogJ/tags_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+	cerr = suite.Git.Checkout(clonePath, "do_not_merge_2")
+	require.NoError(suite.T(), cerr)
 
 	//test
-	tag, ferr := utils.GitFindNearestTagName(clonePath)
+	tag, ferr := suite.Git.FindNearestMatchingTagName(clonePath, "")
 
 	//assert
-	require.NoError(t, ferr)
-	require.Equal(t, "v0.4.2-rc2", tag, "should actually be v0.4.2-rc2")
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), "v0.4.2-rc2", tag, "should actually be v0.4.2-rc2")
 }
 
-func TestGitFindNearestTagName_FetchPullRequest(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitFindNearestTagName_FetchPullRequest() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "tags_analogj_test2", "https://github.com/AnalogJ/tags_analogj_test2.git")
-	require.NoError(t, cerr)
-	cerr = utils.GitFetchPullRequest(clonePath, "1", "tagsAnalogJTest2_pr1", "refs/pull/%s/merge", "")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "tags_analogj_test2", "https://github.com/AnalogJ/tags_analogj_test2.git")
+	require.NoError(suite.T(), cerr)
+	cerr = suite.Git.FetchPullRequest(clonePath, "1", "tagsAnalogJTest2_pr1", "refs/pull/%s/merge", "")
+	require.NoError(suite.T(), cerr)
 
 	//test
-	tag, ferr := utils.GitFindNearestTagName(clonePath)
+	tag, ferr := suite.Git.FindNearestMatchingTagName(clonePath, "")
 
 	//assert
-	require.NoError(t, ferr)
-	require.Equal(t, "v2.0.0", tag, "should actually be v2.0.0") //this should be 1.0.0 because it happened before the pr opened.
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), "v2.0.0", tag, "should actually be v2.0.0") //this should be 1.0.0 because it happened before the pr opened.
 
 }
 
-func TestGitGenerateChangelog(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitGenerateChangelog() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "cookbook_analogj_test", "https://github.com/AnalogJ/cookbook_analogj_test.git")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "cookbook_analogj_test", "https://github.com/AnalogJ/cookbook_analogj_test.git")
+	require.NoError(suite.T(), cerr)
 
 	//test
-	changelog, ferr := utils.GitGenerateChangelog(clonePath, "43adaa328f74fd44abb33d33d8b149ab3780f209", "f3d573aacc59f2a6e2318dd140f3091c16b4b8fe")
+	changelog, ferr := suite.Git.GenerateChangelog(clonePath, "43adaa328f74fd44abb33d33d8b149ab3780f209", "f3d573aacc59f2a6e2318dd140f3091c16b4b8fe")
 
 	//assert
-	require.NoError(t, ferr)
-	require.Equal(t, utils.StripIndent(
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), utils.StripIndent(
 		`Timestamp |  SHA | Message | Author
 	------------- | ------------- | ------------- | -------------
 	2017-07-16T01:41Z | f3d573aa | Added New File | CapsuleCD
@@ -389,18 +881,49 @@ func TestGitGenerateChangelog(t *testing.T) {
 	`), changelog)
 }
 
-func TestGitGenerateChangelog_InvalidDirectory(t *testing.T) {
-	t.Parallel()
+func (suite *GitTestSuite) TestGitCommitsBetween() {
+	//setup
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(suite.T(), err)
+	defer deleteTestRepo(dirPath)
+	clonePath, cerr := suite.Git.Clone(dirPath, "cookbook_analogj_test", "https://github.com/AnalogJ/cookbook_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+
+	//test
+	commits, ferr := suite.Git.CommitsBetween(clonePath, "43adaa328f74fd44abb33d33d8b149ab3780f209", "f3d573aacc59f2a6e2318dd140f3091c16b4b8fe")
+
+	//assert
+	require.NoError(suite.T(), ferr)
+	require.Len(suite.T(), commits, 6)
+	require.Equal(suite.T(), "f3d573aacc59f2a6e2318dd140f3091c16b4b8fe", commits[0].Sha)
+	require.Equal(suite.T(), "Added New File", strings.TrimSpace(commits[0].Message))
+	require.Equal(suite.T(), "CapsuleCD", commits[0].AuthorName)
+	require.False(suite.T(), commits[0].IsMerge())
+	require.True(suite.T(), commits[1].IsMerge(), "should detect merge commits")
+}
 
+func (suite *GitTestSuite) TestGitCommitsBetween_InvalidDirectory() {
 	//setup
 	dirPath := path.Join("this", "path", "does", "not", "exist")
 
 	//test
-	changelog, ferr := utils.GitGenerateChangelog(dirPath, "basheSha", "headSha")
+	commits, ferr := suite.Git.CommitsBetween(dirPath, "baseSha", "headSha")
 
 	//assert
-	require.Error(t, ferr)
-	require.Empty(t, changelog)
+	require.Error(suite.T(), ferr)
+	require.Nil(suite.T(), commits)
+}
+
+func (suite *GitTestSuite) TestGitGenerateChangelog_InvalidDirectory() {
+	//setup
+	dirPath := path.Join("this", "path", "does", "not", "exist")
+
+	//test
+	changelog, ferr := suite.Git.GenerateChangelog(dirPath, "basheSha", "headSha")
+
+	//assert
+	require.Error(suite.T(), ferr)
+	require.Empty(suite.T(), changelog)
 }
 
 /*
@@ -416,26 +939,24 @@ t--o--o--o--o--t--o--o----+   	master
 
 */
 
-func TestGitGenerateChangelog_TagSincePROpened(t *testing.T) {
-	t.Parallel()
-
+func (suite *GitTestSuite) TestGitGenerateChangelog_TagSincePROpened() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "tags_analogj_test2", "https://github.com/AnalogJ/tags_analogj_test2.git")
-	require.NoError(t, cerr)
-	cerr = utils.GitFetchPullRequest(clonePath, "1", "tagsAnalogJTest2_pr1", "refs/pull/%s/merge", "")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "tags_analogj_test2", "https://github.com/AnalogJ/tags_analogj_test2.git")
+	require.NoError(suite.T(), cerr)
+	cerr = suite.Git.FetchPullRequest(clonePath, "1", "tagsAnalogJTest2_pr1", "refs/pull/%s/merge", "")
+	require.NoError(suite.T(), cerr)
 	//headSha, err := utils.GitHead(clonePath)
-	//require.NoError(t, err)
+	//require.NoError(suite.T(), err)
 
 	//test
-	changelog, ferr := utils.GitGenerateChangelog(clonePath, "v2.0.0", "tagsAnalogJTest2_pr1")
+	changelog, ferr := suite.Git.GenerateChangelog(clonePath, "v2.0.0", "tagsAnalogJTest2_pr1")
 
 	//assert
-	require.NoError(t, ferr)
-	require.Equal(t, utils.StripIndent(
+	require.NoError(suite.T(), ferr)
+	require.Equal(suite.T(), utils.StripIndent(
 		`Timestamp |  SHA | Message | Author
 		------------- | ------------- | ------------- | -------------
 		2017-07-24T21:08Z | 4abaa1bd | Merge 9ec0a955d2219f278e569ba5349dcc18a76044a4 into 04e64639394a31231b107ac923d594ea1e3cd257 | Jason Kulatunga
@@ -478,29 +999,29 @@ func TestGitGenerateGitIgnore_WithInvalidLanguage(t *testing.T) {
 	require.False(t, utils.FileExists(path.Join(dirPath, ".gitignore")), "should not generate gitignore")
 }
 
-func TestGitGetTagDetails(t *testing.T) {
+func (suite *GitTestSuite) TestGitGetTagDetails() {
 	//setup
 	dirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
+	require.NoError(suite.T(), err)
 	defer deleteTestRepo(dirPath)
-	clonePath, cerr := utils.GitClone(dirPath, "tags_analogj_test", "https://github.com/AnalogJ/tags_analogj_test.git")
-	require.NoError(t, cerr)
-	cerr = utils.GitFetchPullRequest(clonePath, "1", "tagsAnalogJTest_pr1", "refs/pull/%s/merge", "")
-	require.NoError(t, cerr)
+	clonePath, cerr := suite.Git.Clone(dirPath, "tags_analogj_test", "https://github.com/AnalogJ/tags_analogj_test.git")
+	require.NoError(suite.T(), cerr)
+	cerr = suite.Git.FetchPullRequest(clonePath, "1", "tagsAnalogJTest_pr1", "refs/pull/%s/merge", "")
+	require.NoError(suite.T(), cerr)
 
 	//test
-	tag1, err1 := utils.GitGetTagDetails(clonePath, "v0.4.1")
-	require.NoError(t, err1)
+	tag1, err1 := suite.Git.GetTagDetails(clonePath, "v0.4.1")
+	require.NoError(suite.T(), err1)
 
-	tag2, err2 := utils.GitGetTagDetails(clonePath, "0.4.0")
-	require.NoError(t, err2)
+	tag2, err2 := suite.Git.GetTagDetails(clonePath, "0.4.0")
+	require.NoError(suite.T(), err2)
 
 	//assert
-	require.Equal(t, "v0.4.1", tag1.TagShortName, "should have correct lightweight tag name")
-	require.Equal(t, "ff6fdb84a33b665cf41651eb51d1b86cbf5d3653", tag1.CommitSha, "should have correct lightweight tag sha")
+	require.Equal(suite.T(), "v0.4.1", tag1.TagShortName, "should have correct lightweight tag name")
+	require.Equal(suite.T(), "ff6fdb84a33b665cf41651eb51d1b86cbf5d3653", tag1.CommitSha, "should have correct lightweight tag sha")
 
-	require.Equal(t, "0.4.0", tag2.TagShortName, "should have correct annotated tag name")
-	require.Equal(t, "825711cfb2fb9d44615b415cf723a8590890402f", tag2.CommitSha, "should have correct annotated tag sha")
+	require.Equal(suite.T(), "0.4.0", tag2.TagShortName, "should have correct annotated tag name")
+	require.Equal(suite.T(), "825711cfb2fb9d44615b415cf723a8590890402f", tag2.CommitSha, "should have correct annotated tag sha")
 }
 
 func deleteTestRepo(testRepoDirectory string) {
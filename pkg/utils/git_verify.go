@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"log"
+	"strings"
+)
+
+// GitSignatureVerifier verifies the signatures of git commits & tags against the allowed GPG keyring and SSH allowed
+// signers.
+type GitSignatureVerifier struct {
+	gpgKeyring openpgp.EntityList
+	sshSigners []ssh.PublicKey
+}
+
+func NewGitSignatureVerifier() *GitSignatureVerifier {
+	return &GitSignatureVerifier{gpgKeyring: openpgp.EntityList{}, sshSigners: []ssh.PublicKey{}}
+}
+
+// AddGpgKeyring adds the public keys from one or more armored GPG key blocks (eg. `gpg --export --armor`) to the
+// allowed keyring.
+func (v *GitSignatureVerifier) AddGpgKeyring(keyring []byte) error {
+	reader := bytes.NewReader(keyring)
+	for {
+		block, err := armor.Decode(reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		entities, err := openpgp.ReadKeyRing(block.Body)
+		if err != nil {
+			return err
+		}
+		v.gpgKeyring = append(v.gpgKeyring, entities...)
+	}
+}
+
+// AddSshAllowedSigners adds the public keys from an SSH allowed signers file (see `ssh-keygen(1)`, ALLOWED SIGNERS),
+// eg. `jason@example.com ssh-ed25519 AAAA...`
+func (v *GitSignatureVerifier) AddSshAllowedSigners(allowedSigners []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(allowedSigners))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// skip the principals, the remainder is an authorized_keys style public key (with optional options).
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("Invalid allowed signers entry: %s", line)
+		}
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(fields[1]))
+		if err != nil {
+			return err
+		}
+		v.sshSigners = append(v.sshSigners, publicKey)
+	}
+	return scanner.Err()
+}
+
+// Verify checks that the armored signature (GPG or SSH) of the payload was created by one of the allowed keys.
+func (v *GitSignatureVerifier) Verify(payload []byte, signature string) error {
+	if signature == "" {
+		return fmt.Errorf("unsigned")
+	} else if strings.HasPrefix(signature, "-----BEGIN SSH SIGNATURE-----") {
+		return v.verifySsh(payload, signature)
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(v.gpgKeyring, bytes.NewReader(payload), strings.NewReader(signature)); err != nil {
+		return fmt.Errorf("invalid GPG signature: %s", err)
+	}
+	return nil
+}
+
+// verify an `sshsig` signature (https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig), see sshGitSigner
+func (v *GitSignatureVerifier) verifySsh(payload []byte, signature string) error {
+	encoded := strings.Replace(strings.Replace(signature, "-----BEGIN SSH SIGNATURE-----", "", 1), "-----END SSH SIGNATURE-----", "", 1)
+	blob, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return fmt.Errorf("invalid SSH signature: %s", err)
+	}
+
+	reader := bytes.NewReader(blob)
+	magic := make([]byte, 6)
+	var version uint32
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != "SSHSIG" {
+		return fmt.Errorf("invalid SSH signature: missing SSHSIG preamble")
+	} else if err := binary.Read(reader, binary.BigEndian, &version); err != nil || version != 1 {
+		return fmt.Errorf("invalid SSH signature: unsupported version")
+	}
+	fields := [][]byte{}
+	for i := 0; i < 5; i++ {
+		field, ferr := readSshString(reader)
+		if ferr != nil {
+			return fmt.Errorf("invalid SSH signature: %s", ferr)
+		}
+		fields = append(fields, field)
+	}
+	publicKeyBytes, namespace, reserved, hashAlgorithm, signatureBytes := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	if string(namespace) != "git" {
+		return fmt.Errorf("invalid SSH signature: unexpected namespace %s", namespace)
+	}
+	var hash []byte
+	switch string(hashAlgorithm) {
+	case "sha256":
+		sum := sha256.Sum256(payload)
+		hash = sum[:]
+	case "sha512":
+		sum := sha512.Sum512(payload)
+		hash = sum[:]
+	default:
+		return fmt.Errorf("invalid SSH signature: unsupported hash algorithm %s", hashAlgorithm)
+	}
+
+	publicKey, err := ssh.ParsePublicKey(publicKeyBytes)
+	if err != nil {
+		return fmt.Errorf("invalid SSH signature: %s", err)
+	}
+	allowed := false
+	for _, sshSigner := range v.sshSigners {
+		if bytes.Equal(sshSigner.Marshal(), publicKey.Marshal()) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("signed by unknown SSH key %s", ssh.FingerprintSHA256(publicKey))
+	}
+
+	sshSignature := new(ssh.Signature)
+	if err := ssh.Unmarshal(signatureBytes, sshSignature); err != nil {
+		return fmt.Errorf("invalid SSH signature: %s", err)
+	}
+
+	var signedData bytes.Buffer
+	signedData.WriteString("SSHSIG")
+	writeSshString(&signedData, namespace)
+	writeSshString(&signedData, reserved)
+	writeSshString(&signedData, hashAlgorithm)
+	writeSshString(&signedData, hash)
+	if err := publicKey.Verify(signedData.Bytes(), sshSignature); err != nil {
+		return fmt.Errorf("invalid SSH signature: %s", err)
+	}
+	return nil
+}
+
+// read a length-prefixed string from the signature blob. The length is attacker controlled, so it is checked against the
+// remaining bytes before allocating.
+func readSshString(reader *bytes.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if int64(length) > int64(reader.Len()) {
+		return nil, fmt.Errorf("field length %d exceeds the remaining %d bytes", length, reader.Len())
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Get the shas of the commits reachable from headRef but not from baseRef that are not signed by one of the keys allowed
+// by the verifier. The reason each commit failed verification is logged.
+func GitUnverifiedCommits(repoPath string, baseRef string, headRef string, verifier *GitSignatureVerifier) ([]string, error) {
+	commits, cerr := gitClient.CommitsBetween(repoPath, baseRef, headRef)
+	if cerr != nil {
+		return nil, cerr
+	}
+
+	unverifiedShas := []string{}
+	for _, commit := range commits {
+		commitContent, rerr := gitClient.CommitObject(repoPath, commit.Sha)
+		if rerr != nil {
+			return nil, rerr
+		}
+		payload, signature := gitSplitSignedCommit(commitContent)
+
+		if verr := verifier.Verify(payload, signature); verr != nil {
+			log.Printf("Commit %.8s signature could not be verified: %s", commit.Sha, verr)
+			unverifiedShas = append(unverifiedShas, commit.Sha)
+		}
+	}
+	return unverifiedShas, nil
+}
+
+// split a raw commit object into the signed payload (the commit without the `gpgsig` header) and the armored signature.
+// The signature is empty if the commit is unsigned.
+func gitSplitSignedCommit(content []byte) ([]byte, string) {
+	// the header ends at the first empty line, the message follows.
+	headerEnd := bytes.Index(content, []byte("\n\n")) + 1
+	if headerEnd <= 0 {
+		headerEnd = len(content)
+	}
+
+	var payload bytes.Buffer
+	var signature []string
+	inSignature := false
+	for _, line := range strings.SplitAfter(string(content[:headerEnd]), "\n") {
+		if strings.HasPrefix(line, "gpgsig ") {
+			inSignature = true
+			signature = append(signature, strings.TrimSuffix(strings.TrimPrefix(line, "gpgsig "), "\n"))
+		} else if inSignature && strings.HasPrefix(line, " ") {
+			signature = append(signature, strings.TrimSuffix(strings.TrimPrefix(line, " "), "\n"))
+		} else {
+			inSignature = false
+			payload.WriteString(line)
+		}
+	}
+	payload.Write(content[headerEnd:])
+
+	if len(signature) == 0 {
+		return payload.Bytes(), ""
+	}
+	return payload.Bytes(), strings.Join(signature, "\n") + "\n"
+}
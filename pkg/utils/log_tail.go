@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+)
+
+// LogTail is an io.Writer that only keeps the last N lines written to it.
+// It's used to store the output of the most recently executed commands, so that a failing step can report the tail of
+// its log (eg. in a Github check run) without having to buffer the entire build log.
+type LogTail struct {
+	maxLines int
+	lines    []string
+	partial  string
+	mutex    sync.Mutex
+}
+
+func NewLogTail(maxLines int) *LogTail {
+	return &LogTail{maxLines: maxLines}
+}
+
+func (t *LogTail) Write(p []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	content := t.partial + string(p)
+	lines := strings.Split(content, "\n")
+
+	//the last element is either empty (content ended with a newline) or an incomplete line.
+	t.partial = lines[len(lines)-1]
+	t.lines = append(t.lines, lines[:len(lines)-1]...)
+
+	if len(t.lines) > t.maxLines {
+		t.lines = t.lines[len(t.lines)-t.maxLines:]
+	}
+	return len(p), nil
+}
+
+func (t *LogTail) String() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lines := t.lines
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	if len(lines) > t.maxLines {
+		lines = lines[len(lines)-t.maxLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (t *LogTail) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.lines = nil
+	t.partial = ""
+}
+
+// stores the last 50 lines written to stdout/stderr by commands executed via CmdExec
+var cmdOutputTail = NewLogTail(50)
+
+// CmdOutputTail returns the last lines of output written by commands executed via CmdExec.
+func CmdOutputTail() string {
+	return cmdOutputTail.String()
+}
+
+// ResetCmdOutputTail clears the stored command output. Should be called at the start of every pipeline step.
+func ResetCmdOutputTail() {
+	cmdOutputTail.Reset()
+}
@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLogTail_Write(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	tail := utils.NewLogTail(3)
+
+	//test
+	tail.Write([]byte("line 1\nline 2\n"))
+	tail.Write([]byte("line 3\nline 4\n"))
+
+	//assert
+	require.Equal(t, "line 2\nline 3\nline 4", tail.String(), "should only keep the last 3 lines")
+}
+
+func TestLogTail_Write_PartialLines(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	tail := utils.NewLogTail(5)
+
+	//test
+	tail.Write([]byte("line "))
+	tail.Write([]byte("1\nline 2"))
+
+	//assert
+	require.Equal(t, "line 1\nline 2", tail.String(), "should join partial writes")
+}
+
+func TestLogTail_Reset(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	tail := utils.NewLogTail(5)
+	tail.Write([]byte("line 1\nline 2\n"))
+
+	//test
+	tail.Reset()
+
+	//assert
+	require.Empty(t, tail.String())
+}
+
+func TestCmdOutputTail(t *testing.T) {
+	//setup
+	utils.ResetCmdOutputTail()
+
+	//test
+	cerr := utils.BashCmdExec("echo 'hello from bash' && (>&2 echo 'test writing to stderr')", "", nil, "")
+
+	//assert
+	require.NoError(t, cerr)
+	require.Contains(t, utils.CmdOutputTail(), "hello from bash")
+	require.Contains(t, utils.CmdOutputTail(), "test writing to stderr")
+}
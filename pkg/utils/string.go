@@ -3,6 +3,7 @@ package utils
 import (
 	"strings"
 	"bytes"
+	"fmt"
 	"text/template"
 )
 
@@ -90,3 +91,30 @@ func LeftPad2Len(s string, padStr string, overallLen int) string {
 func StripIndent(multilineStr string) string {
 	return strings.Replace(multilineStr, "\t", "", -1)
 }
+
+// generate the version bump commit message, eg. `(v1.2.3) Automated packaging of release by CapsuleCD`
+// the tagName is generated from the tag template (engine_tag_template), eg. `(capsulecd@1.2.3) ...`
+func VersionBumpCommitMessage(tagName string, bumpMsg string) string {
+	return fmt.Sprintf("(%s) %s", tagName, bumpMsg)
+}
+
+// check if the commit message was generated by VersionBumpCommitMessage.
+// used to prevent CapsuleCD from releasing its own version bump commits when releasing on push, and to exclude them from changelogs.
+func IsVersionBumpCommit(message string, bumpMsg string) bool {
+	_, isBump := VersionBumpCommitTag(message, bumpMsg)
+	return isBump
+}
+
+// retrieve the tag name from a commit message generated by VersionBumpCommitMessage.
+// returns false if the commit message is not a version bump commit message.
+func VersionBumpCommitTag(message string, bumpMsg string) (string, bool) {
+	subject := strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+	if !strings.HasPrefix(subject, "(") {
+		return "", false
+	}
+	tagEnd := strings.Index(subject, ") ")
+	if tagEnd <= 0 || subject[tagEnd+2:] != strings.TrimSpace(bumpMsg) {
+		return "", false
+	}
+	return subject[1:tagEnd], true
+}
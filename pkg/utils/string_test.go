@@ -63,3 +63,33 @@ func TestRightPad(t *testing.T) {
 	require.Equal(t, "12345----------", utils.RightPad("12345", "-", 10))
 	require.Equal(t, "12345---", utils.RightPad("12345", "-", 3))
 }
+
+func TestIsVersionBumpCommit(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	bumpMsg := "Automated packaging of release by CapsuleCD"
+
+	//test & assert
+	require.True(t, utils.IsVersionBumpCommit(utils.VersionBumpCommitMessage("v1.2.3", bumpMsg), bumpMsg))
+	require.True(t, utils.IsVersionBumpCommit("(v1.2.3) Automated packaging of release by CapsuleCD\n", bumpMsg), "should ignore trailing newlines")
+	require.True(t, utils.IsVersionBumpCommit(utils.VersionBumpCommitMessage("capsulecd@1.2.3", bumpMsg), bumpMsg), "should match templated tag names")
+	require.False(t, utils.IsVersionBumpCommit("Merge pull request #12 from feature/key-bindings", bumpMsg))
+	require.False(t, utils.IsVersionBumpCommit("(v1.2.3) Fix typo", bumpMsg), "should only match the configured bump message")
+}
+
+func TestVersionBumpCommitTag(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	bumpMsg := "Automated packaging of release by CapsuleCD"
+
+	//test
+	tagName, isBump := utils.VersionBumpCommitTag("(capsulecd@1.2.3) Automated packaging of release by CapsuleCD\n", bumpMsg)
+	_, isFeatureBump := utils.VersionBumpCommitTag("(v1.2.3) Fix typo", bumpMsg)
+
+	//assert
+	require.True(t, isBump)
+	require.Equal(t, "capsulecd@1.2.3", tagName)
+	require.False(t, isFeatureBump, "should only match the configured bump message")
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"strings"
+)
+
+// the release tag template used when engine_tag_template is empty, eg. `v1.2.3`
+const DefaultTagTemplate = "v{{.Version}}"
+
+// data available to the release tag template (engine_tag_template)
+type tagTemplateData struct {
+	Name    string // repository name
+	Version string // release version, eg. `1.2.3`
+}
+
+// the name of the repository being released (the pull request base, or the pushed repository).
+func ReleaseRepoName(pipelineData *pipeline.Data) string {
+	releaseRepo := pipelineData.GitHeadInfo
+	if pipelineData.GitBaseInfo != nil {
+		releaseRepo = pipelineData.GitBaseInfo
+	}
+	if releaseRepo == nil || releaseRepo.Repo == nil {
+		return ""
+	}
+	return releaseRepo.Repo.Name
+}
+
+// generate the release tag name for the version from the tag template, eg. `v1.2.3`, `capsulecd@1.2.3` or `release-1.2.3`
+func ReleaseTagName(tagTemplate string, pipelineData *pipeline.Data, version string) (string, error) {
+	if tagTemplate == "" {
+		tagTemplate = DefaultTagTemplate
+	}
+	tagName, err := PopulateTemplate(tagTemplate, tagTemplateData{Name: ReleaseRepoName(pipelineData), Version: version})
+	if err != nil {
+		return "", fmt.Errorf("Invalid tag template (%s): %s", tagTemplate, err)
+	} else if tagName == "" || !strings.Contains(tagName, version) {
+		return "", fmt.Errorf("Invalid tag template (%s): the tag name must include the {{.Version}}", tagTemplate)
+	}
+	return tagName, nil
+}
+
+// generate a glob pattern matching the release tags created from the tag template (for any version), eg. `v*`.
+// used to ignore unrelated tags (eg. `deploy-prod`) when finding the nearest tag.
+func ReleaseTagPattern(tagTemplate string, pipelineData *pipeline.Data) (string, error) {
+	if tagTemplate == "" {
+		tagTemplate = DefaultTagTemplate
+	}
+	pattern, err := PopulateTemplate(tagTemplate, tagTemplateData{Name: escapeGlob(ReleaseRepoName(pipelineData)), Version: "*"})
+	if err != nil {
+		return "", fmt.Errorf("Invalid tag template (%s): %s", tagTemplate, err)
+	} else if !strings.Contains(pattern, "*") {
+		return "", fmt.Errorf("Invalid tag template (%s): the tag name must include the {{.Version}}", tagTemplate)
+	}
+	return pattern, nil
+}
+
+// escape the glob special characters, so the string is matched literally.
+func escapeGlob(str string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+	return replacer.Replace(str)
+}
+
+// extract the version from a release tag name generated from the tag template, eg. `capsulecd@1.2.3` -> `1.2.3`.
+// Returns an error if the tag does not match the tag template.
+func ReleaseTagVersion(tagTemplate string, pipelineData *pipeline.Data, tagName string) (string, error) {
+	if tagTemplate == "" {
+		tagTemplate = DefaultTagTemplate
+	}
+	const versionPlaceholder = "\x00"
+	rendered, err := PopulateTemplate(tagTemplate, tagTemplateData{Name: ReleaseRepoName(pipelineData), Version: versionPlaceholder})
+	if err != nil {
+		return "", fmt.Errorf("Invalid tag template (%s): %s", tagTemplate, err)
+	}
+	parts := strings.Split(rendered, versionPlaceholder)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("Invalid tag template (%s): the tag name must include the {{.Version}} once", tagTemplate)
+	}
+	prefix, suffix := parts[0], parts[1]
+	if len(tagName) <= len(prefix)+len(suffix) || !strings.HasPrefix(tagName, prefix) || !strings.HasSuffix(tagName, suffix) {
+		return "", fmt.Errorf("Tag (%s) does not match the tag template (%s)", tagName, tagTemplate)
+	}
+	return tagName[len(prefix) : len(tagName)-len(suffix)], nil
+}
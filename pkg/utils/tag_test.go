@@ -0,0 +1,97 @@
+package utils_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/pipeline"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"path"
+	"testing"
+)
+
+func tagTestPipelineData(repoName string) *pipeline.Data {
+	return &pipeline.Data{
+		GitHeadInfo: &pipeline.ScmCommitInfo{Repo: &pipeline.ScmRepoInfo{Name: "fork"}},
+		GitBaseInfo: &pipeline.ScmCommitInfo{Repo: &pipeline.ScmRepoInfo{Name: repoName}},
+	}
+}
+
+var releaseTagTests = []struct {
+	tagTemplate     string
+	expectedTag     string
+	expectedPattern string
+}{
+	{"", "v1.2.3", "v*"},
+	{"v{{.Version}}", "v1.2.3", "v*"},
+	{"{{.Name}}@{{.Version}}", "capsulecd@1.2.3", "capsulecd@*"},
+	{"release-{{.Version}}", "release-1.2.3", "release-*"},
+}
+
+func TestReleaseTagName(t *testing.T) {
+	t.Parallel()
+	for _, tt := range releaseTagTests {
+		//test
+		tagName, err := utils.ReleaseTagName(tt.tagTemplate, tagTestPipelineData("capsulecd"), "1.2.3")
+		pattern, perr := utils.ReleaseTagPattern(tt.tagTemplate, tagTestPipelineData("capsulecd"))
+
+		//assert
+		require.NoError(t, err)
+		require.NoError(t, perr)
+		require.Equal(t, tt.expectedTag, tagName, "should use the base repository name")
+		require.Equal(t, tt.expectedPattern, pattern)
+		matched, merr := path.Match(pattern, tagName)
+		require.NoError(t, merr)
+		require.True(t, matched, "pattern should match the generated tag")
+	}
+}
+
+func TestReleaseTagPattern_EscapesName(t *testing.T) {
+	t.Parallel()
+
+	//test
+	pattern, err := utils.ReleaseTagPattern("{{.Name}}@{{.Version}}", tagTestPipelineData("repo[1]"))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, `repo\[1\]@*`, pattern)
+	matched, merr := path.Match(pattern, "repo[1]@1.0.0")
+	require.NoError(t, merr)
+	require.True(t, matched)
+}
+
+func TestReleaseTagName_Invalid(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, serr := utils.ReleaseTagName("{{.Version", tagTestPipelineData("capsulecd"), "1.2.3")
+	_, verr := utils.ReleaseTagName("latest", tagTestPipelineData("capsulecd"), "1.2.3")
+	_, perr := utils.ReleaseTagPattern("latest", tagTestPipelineData("capsulecd"))
+
+	//assert
+	require.Error(t, serr, "should fail for invalid template syntax")
+	require.Error(t, verr, "should fail if the template does not include the version")
+	require.Error(t, perr, "should fail if the template does not include the version")
+}
+
+func TestReleaseTagVersion(t *testing.T) {
+	t.Parallel()
+	for _, tt := range releaseTagTests {
+		//test
+		version, err := utils.ReleaseTagVersion(tt.tagTemplate, tagTestPipelineData("capsulecd"), tt.expectedTag)
+
+		//assert
+		require.NoError(t, err)
+		require.Equal(t, "1.2.3", version, "should extract the version from %s", tt.expectedTag)
+	}
+}
+
+func TestReleaseTagVersion_Mismatch(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, perr := utils.ReleaseTagVersion("{{.Name}}@{{.Version}}", tagTestPipelineData("capsulecd"), "other@1.2.3")
+	_, eerr := utils.ReleaseTagVersion("v{{.Version}}", tagTestPipelineData("capsulecd"), "v")
+
+	//assert
+	require.Error(t, perr, "should fail if the tag was not generated from the template")
+	require.Error(t, eerr, "should fail if the tag does not include a version")
+}
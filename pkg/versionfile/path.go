@@ -0,0 +1,252 @@
+package versionfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// a parsed path segment, either a mapping key, a sequence index, or a wildcard (`*` or `[*]`)
+type pathSegment struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+// a scalar value location in the file content (byte offsets)
+type scalarSpan struct {
+	Start int
+	End   int
+	Style yaml.Style
+}
+
+// replace the scalar values matching the path. JSON is a subset of YAML, so both are parsed as YAML, which keeps track
+// of the location of each value. Only the matched values are replaced, the rest of the file (formatting, comments &
+// key order) is unchanged.
+func replacePath(content string, query string, replacement string, isJson bool) (string, int, error) {
+	segments, err := parsePath(query)
+	if err != nil {
+		return "", 0, err
+	}
+
+	parseContent := content
+	if isJson {
+		// tabs are valid JSON whitespace, but not valid YAML indentation. Replacing them does not change the locations.
+		parseContent = strings.Replace(content, "\t", " ", -1)
+	}
+
+	nodes := []*yaml.Node{}
+	decoder := yaml.NewDecoder(strings.NewReader(parseContent))
+	for {
+		var document yaml.Node
+		if derr := decoder.Decode(&document); derr == io.EOF {
+			break
+		} else if derr != nil {
+			return "", 0, fmt.Errorf("could not parse file: %s", derr)
+		}
+		if len(document.Content) > 0 {
+			nodes = append(nodes, findNodes(document.Content[0], segments)...)
+		}
+	}
+
+	lineOffsets := lineStartOffsets(content)
+	spans := []scalarSpan{}
+	seen := map[int]bool{}
+	for _, node := range nodes {
+		if node.Kind != yaml.ScalarNode {
+			return "", 0, fmt.Errorf("path (%s) must match scalar values, found a %s on line %d", query, nodeKindName(node.Kind), node.Line)
+		}
+		span, serr := scalarLocation(content, lineOffsets, node)
+		if serr != nil {
+			return "", 0, serr
+		}
+		// aliases refer to the same anchored value.
+		if !seen[span.Start] {
+			seen[span.Start] = true
+			spans = append(spans, span)
+		}
+	}
+
+	// replace from the end of the file, so that the offsets of the remaining values are unchanged.
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start > spans[j].Start })
+	for _, span := range spans {
+		content = content[:span.Start] + quoteScalar(replacement, span.Style) + content[span.End:]
+	}
+	return content, len(spans), nil
+}
+
+// parse a JSONPath/YAMLPath style query, eg. `$.version`, `.dependencies["my-lib"]`, `containers[0].image` or `items[*]`
+func parsePath(query string) ([]pathSegment, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid path (%s): %s", query, reason)
+	}
+
+	rest := strings.TrimPrefix(strings.TrimSpace(query), "$")
+	segments := []pathSegment{}
+	for first := true; rest != ""; first = false {
+		switch {
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, invalid("missing `]`")
+			}
+			selector := rest[1:end]
+			if strings.HasPrefix(selector, `"`) || strings.HasPrefix(selector, `'`) {
+				// quoted keys may contain `]`, find the closing quote instead.
+				closing := strings.Index(rest[2:], selector[:1]+"]")
+				if closing < 0 {
+					return nil, invalid("unterminated quoted key")
+				}
+				end = closing + 3
+				segments = append(segments, pathSegment{Key: rest[2 : closing+2]})
+			} else if selector == "*" {
+				segments = append(segments, pathSegment{Wildcard: true})
+			} else {
+				index, aerr := strconv.Atoi(selector)
+				if aerr != nil || index < 0 {
+					return nil, invalid(fmt.Sprintf("invalid index `%s`", selector))
+				}
+				segments = append(segments, pathSegment{Index: index, IsIndex: true})
+			}
+			rest = rest[end+1:]
+		case rest[0] == '.' || first:
+			rest = strings.TrimPrefix(rest, ".")
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, invalid("empty key")
+			}
+			key := rest[:end]
+			if key == "*" {
+				segments = append(segments, pathSegment{Wildcard: true})
+			} else {
+				segments = append(segments, pathSegment{Key: key})
+			}
+			rest = rest[end:]
+		default:
+			return nil, invalid(fmt.Sprintf("unexpected `%s`", rest))
+		}
+	}
+	return segments, nil
+}
+
+// find the nodes matching the path segments.
+func findNodes(node *yaml.Node, segments []pathSegment) []*yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if len(segments) == 0 {
+		return []*yaml.Node{node}
+	}
+
+	segment, rest := segments[0], segments[1:]
+	found := []*yaml.Node{}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if segment.Wildcard || (!segment.IsIndex && node.Content[i].Value == segment.Key) {
+				found = append(found, findNodes(node.Content[i+1], rest)...)
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if segment.Wildcard || (segment.IsIndex && segment.Index == i) {
+				found = append(found, findNodes(child, rest)...)
+			}
+		}
+	}
+	return found
+}
+
+// byte offset of the start of each line.
+func lineStartOffsets(content string) []int {
+	offsets := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// determine the location of the scalar value (including quotes) in the file content.
+func scalarLocation(content string, lineOffsets []int, node *yaml.Node) (scalarSpan, error) {
+	if node.Line < 1 || node.Line > len(lineOffsets) {
+		return scalarSpan{}, fmt.Errorf("could not locate the value on line %d", node.Line)
+	}
+	// the column is counted in characters, not bytes.
+	start := lineOffsets[node.Line-1]
+	for column := 1; column < node.Column && start < len(content); column++ {
+		_, size := utf8.DecodeRuneInString(content[start:])
+		start += size
+	}
+
+	span := scalarSpan{Start: start, Style: node.Style & (yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle | yaml.LiteralStyle | yaml.FoldedStyle)}
+	switch span.Style {
+	case yaml.DoubleQuotedStyle:
+		for i := start + 1; i < len(content); i++ {
+			if content[i] == '\\' {
+				i++
+			} else if content[i] == '"' {
+				span.End = i + 1
+				return span, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := start + 1; i < len(content); i++ {
+			if content[i] == '\'' {
+				if i+1 < len(content) && content[i+1] == '\'' {
+					i++
+					continue
+				}
+				span.End = i + 1
+				return span, nil
+			}
+		}
+	case yaml.LiteralStyle, yaml.FoldedStyle:
+		return scalarSpan{}, fmt.Errorf("block scalars are not supported (line %d)", node.Line)
+	default:
+		if strings.Contains(node.Value, "\n") || !strings.HasPrefix(content[start:], node.Value) {
+			return scalarSpan{}, fmt.Errorf("multi-line values are not supported (line %d)", node.Line)
+		}
+		span.End = start + len(node.Value)
+		return span, nil
+	}
+	return scalarSpan{}, fmt.Errorf("could not locate the value on line %d", node.Line)
+}
+
+// quote the replacement using the same style as the original value.
+func quoteScalar(value string, style yaml.Style) string {
+	switch style {
+	case yaml.DoubleQuotedStyle:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(value)
+		return strings.TrimSuffix(buf.String(), "\n")
+	case yaml.SingleQuotedStyle:
+		return "'" + strings.Replace(value, "'", "''", -1) + "'"
+	default:
+		return value
+	}
+}
+
+func nodeKindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "sequence"
+	default:
+		return "node"
+	}
+}
@@ -0,0 +1,179 @@
+package versionfile
+
+import (
+	"fmt"
+	"github.com/Masterminds/semver"
+	"github.com/analogj/capsulecd/pkg/errors"
+	"github.com/analogj/capsulecd/pkg/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// the replacement template used when a rule does not specify one.
+const DefaultTemplate = "{{.Version}}"
+
+// Rule describes where the version is stored in a file (engine_version_files), eg. a README badge, a Dockerfile label
+// or a Helm `Chart.yaml`. Exactly one of Regex, JsonPath or YamlPath must be specified.
+type Rule struct { //mapstructure is used to deserialize by Config.
+	Path     string `mapstructure:"path"`     // file path, relative to the repository root.
+	Regex    string `mapstructure:"regex"`    // the first capture group (or the whole match if there are none) is replaced.
+	JsonPath string `mapstructure:"jsonpath"` // eg. `$.version` or `$.dependencies["my-lib"]`
+	YamlPath string `mapstructure:"yamlpath"` // eg. `$.appVersion` or `$.spec.template.spec.containers[*].image`
+	Template string `mapstructure:"template"` // replacement value, defaults to `{{.Version}}`, eg. `myorg/app:{{.Version}}`
+	Matches  int    `mapstructure:"matches"`  // expected number of matches, defaults to 1. -1 allows any number (at least one).
+}
+
+// TemplateData is available to the replacement template.
+type TemplateData struct {
+	Name       string // repository name
+	Version    string // release version, eg. `1.2.0-rc.1`
+	Major      string // eg. `1`, empty if the version is not a semantic version.
+	Minor      string // eg. `2`
+	Patch      string // eg. `0`
+	Prerelease string // eg. `rc.1`, empty for releases.
+	Tag        string // release tag name, eg. `v1.2.0-rc.1`
+}
+
+// NewTemplateData populates the template data for the version.
+func NewTemplateData(name string, version string, tag string) TemplateData {
+	data := TemplateData{Name: name, Version: version, Tag: tag}
+	if v, err := semver.NewVersion(version); err == nil {
+		data.Major = fmt.Sprintf("%d", v.Major())
+		data.Minor = fmt.Sprintf("%d", v.Minor())
+		data.Patch = fmt.Sprintf("%d", v.Patch())
+		data.Prerelease = v.Prerelease()
+	}
+	return data
+}
+
+// Update applies the rules to the files in the repository, and returns the paths of the updated files.
+// All rules are validated before any file is written, so a failing rule does not leave the repository partially updated.
+func Update(repoPath string, rules []Rule, data TemplateData) ([]string, error) {
+	contents := map[string]string{}
+	updatedPaths := []string{}
+
+	for ndx := range rules {
+		rule := rules[ndx]
+		filePath, perr := rulePath(repoPath, rule)
+		if perr != nil {
+			return nil, perr
+		}
+
+		content, ok := contents[filePath]
+		if !ok {
+			fileContent, rerr := ioutil.ReadFile(filePath)
+			if rerr != nil {
+				return nil, errors.EngineVersionFileInvalid(fmt.Sprintf("Could not read version file (%s): %s", rule.Path, rerr))
+			}
+			content = string(fileContent)
+			updatedPaths = append(updatedPaths, rule.Path)
+		}
+
+		updatedContent, aerr := apply(content, rule, data)
+		if aerr != nil {
+			return nil, errors.EngineVersionFileInvalid(fmt.Sprintf("Could not update version file (%s): %s", rule.Path, aerr))
+		}
+		contents[filePath] = updatedContent
+	}
+
+	for filePath, content := range contents {
+		info, serr := os.Stat(filePath)
+		if serr != nil {
+			return nil, serr
+		}
+		if werr := ioutil.WriteFile(filePath, []byte(content), info.Mode()); werr != nil {
+			return nil, werr
+		}
+	}
+	return updatedPaths, nil
+}
+
+// the absolute path of the rule file, which must be inside the repository.
+func rulePath(repoPath string, rule Rule) (string, error) {
+	if rule.Path == "" {
+		return "", errors.EngineVersionFileInvalid("Version file rules require a path")
+	}
+	relPath := filepath.Clean(filepath.FromSlash(rule.Path))
+	if filepath.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", errors.EngineVersionFileInvalid(fmt.Sprintf("Version file (%s) must be inside the repository", rule.Path))
+	}
+	return filepath.Join(repoPath, relPath), nil
+}
+
+// apply a single rule to the file content, checking the expected number of matches.
+func apply(content string, rule Rule, data TemplateData) (string, error) {
+	tmpl := rule.Template
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	replacement, terr := utils.PopulateTemplate(tmpl, data)
+	if terr != nil {
+		return "", fmt.Errorf("invalid template (%s): %s", tmpl, terr)
+	}
+
+	var updatedContent string
+	var matches int
+	var err error
+	switch {
+	case rule.Regex != "" && rule.JsonPath == "" && rule.YamlPath == "":
+		updatedContent, matches, err = replaceRegex(content, rule.Regex, replacement)
+	case rule.JsonPath != "" && rule.Regex == "" && rule.YamlPath == "":
+		updatedContent, matches, err = replacePath(content, rule.JsonPath, replacement, true)
+	case rule.YamlPath != "" && rule.Regex == "" && rule.JsonPath == "":
+		updatedContent, matches, err = replacePath(content, rule.YamlPath, replacement, false)
+	default:
+		return "", fmt.Errorf("exactly one of regex, jsonpath or yamlpath must be specified")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	expected := rule.Matches
+	if expected == 0 {
+		expected = 1
+	}
+	if matches == 0 || (expected > 0 && matches != expected) {
+		return "", fmt.Errorf("expected %s, found %d", matchesDescription(expected), matches)
+	}
+	return updatedContent, nil
+}
+
+func matchesDescription(expected int) string {
+	if expected < 0 {
+		return "at least 1 match"
+	} else if expected == 1 {
+		return "exactly 1 match"
+	}
+	return fmt.Sprintf("exactly %d matches", expected)
+}
+
+// replace the first capture group of each match (or the whole match if the pattern has no capture groups).
+func replaceRegex(content string, pattern string, replacement string) (string, int, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid regex (%s): %s", pattern, err)
+	}
+
+	indexes := re.FindAllStringSubmatchIndex(content, -1)
+	var sb strings.Builder
+	last, matches := 0, 0
+	for _, loc := range indexes {
+		start, end := loc[0], loc[1]
+		if re.NumSubexp() > 0 {
+			start, end = loc[2], loc[3]
+			if start < 0 {
+				// the capture group did not participate in the match.
+				continue
+			}
+		}
+		sb.WriteString(content[last:start])
+		sb.WriteString(replacement)
+		last = end
+		matches++
+	}
+	sb.WriteString(content[last:])
+	return sb.String(), matches, nil
+}
@@ -0,0 +1,183 @@
+package versionfile_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/versionfile"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	repoPath, err := ioutil.TempDir("", "versionfile")
+	require.NoError(t, err)
+	for filePath, content := range files {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(repoPath, filePath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(repoPath, filePath), []byte(content), 0644))
+	}
+	return repoPath
+}
+
+func readTestFile(t *testing.T, repoPath string, filePath string) string {
+	content, err := ioutil.ReadFile(path.Join(repoPath, filePath))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestNewTemplateData(t *testing.T) {
+	t.Parallel()
+
+	//test
+	data := versionfile.NewTemplateData("capsulecd", "1.2.0-rc.1", "v1.2.0-rc.1")
+
+	//assert
+	require.Equal(t, versionfile.TemplateData{Name: "capsulecd", Version: "1.2.0-rc.1", Major: "1", Minor: "2", Patch: "0", Prerelease: "rc.1", Tag: "v1.2.0-rc.1"}, data)
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	repoPath := writeTestFiles(t, map[string]string{
+		"README.md":  "# CapsuleCD\nInstall v1.1.0:\n\n    go get github.com/analogj/capsulecd@v1.1.0\n",
+		"Dockerfile": "FROM alpine\nLABEL org.opencontainers.image.version=\"1.1.0\"\n",
+		"package.json": `{
+	"name": "capsulecd",
+	"version": "1.1.0",
+	"dependencies": {
+		"@capsulecd/core": "^1.1.0"
+	}
+}
+`,
+		"chart/Chart.yaml": "apiVersion: v2\nname: capsulecd # the chart name\nversion: 1.1.0\nappVersion: '1.1.0'\n",
+	})
+	defer os.RemoveAll(repoPath)
+	rules := []versionfile.Rule{
+		{Path: "README.md", Regex: `capsulecd@(v[0-9.]+)`, Template: "{{.Tag}}"},
+		{Path: "README.md", Regex: `Install v[0-9.]+`, Template: "Install {{.Tag}}"},
+		{Path: "Dockerfile", Regex: `image\.version="([^"]+)"`},
+		{Path: "package.json", JsonPath: "$.version"},
+		{Path: "package.json", JsonPath: `$.dependencies["@capsulecd/core"]`, Template: "^{{.Version}}"},
+		{Path: "chart/Chart.yaml", YamlPath: ".version"},
+		{Path: "chart/Chart.yaml", YamlPath: "appVersion"},
+	}
+
+	//test
+	updatedPaths, err := versionfile.Update(repoPath, rules, versionfile.NewTemplateData("capsulecd", "1.2.0", "v1.2.0"))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []string{"README.md", "Dockerfile", "package.json", "chart/Chart.yaml"}, updatedPaths)
+	require.Equal(t, "# CapsuleCD\nInstall v1.2.0:\n\n    go get github.com/analogj/capsulecd@v1.2.0\n", readTestFile(t, repoPath, "README.md"))
+	require.Equal(t, "FROM alpine\nLABEL org.opencontainers.image.version=\"1.2.0\"\n", readTestFile(t, repoPath, "Dockerfile"))
+	require.Equal(t, `{
+	"name": "capsulecd",
+	"version": "1.2.0",
+	"dependencies": {
+		"@capsulecd/core": "^1.2.0"
+	}
+}
+`, readTestFile(t, repoPath, "package.json"), "should keep the formatting & key order")
+	require.Equal(t, "apiVersion: v2\nname: capsulecd # the chart name\nversion: 1.2.0\nappVersion: '1.2.0'\n", readTestFile(t, repoPath, "chart/Chart.yaml"), "should keep comments & quoting")
+}
+
+func TestUpdate_Matches(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	manifest := "image: capsulecd:1.1.0\n---\ncontainers:\n  - image: capsulecd:1.1.0\n  - image: capsulecd:1.1.0\n"
+	repoPath := writeTestFiles(t, map[string]string{"deploy.yaml": manifest})
+	defer os.RemoveAll(repoPath)
+	data := versionfile.NewTemplateData("capsulecd", "1.2.0", "v1.2.0")
+
+	//test
+	_, defaultErr := versionfile.Update(repoPath, []versionfile.Rule{{Path: "deploy.yaml", Regex: `capsulecd:([0-9.]+)`}}, data)
+	_, countErr := versionfile.Update(repoPath, []versionfile.Rule{{Path: "deploy.yaml", YamlPath: "containers[*].image", Matches: 3}}, data)
+	unchanged := readTestFile(t, repoPath, "deploy.yaml")
+	_, countUpdateErr := versionfile.Update(repoPath, []versionfile.Rule{{Path: "deploy.yaml", YamlPath: "$.containers[*].image", Template: "capsulecd:{{.Version}}", Matches: 2}}, data)
+	_, anyUpdateErr := versionfile.Update(repoPath, []versionfile.Rule{{Path: "deploy.yaml", Regex: `capsulecd:1\.1\.0`, Template: "capsulecd:{{.Version}}", Matches: -1}}, data)
+
+	//assert
+	require.Error(t, defaultErr, "should require exactly 1 match by default")
+	require.Error(t, countErr, "should require the configured number of matches")
+	require.Equal(t, manifest, unchanged, "should not update files if a rule fails")
+	require.NoError(t, countUpdateErr)
+	require.NoError(t, anyUpdateErr)
+	require.Equal(t, "image: capsulecd:1.2.0\n---\ncontainers:\n  - image: capsulecd:1.2.0\n  - image: capsulecd:1.2.0\n", readTestFile(t, repoPath, "deploy.yaml"))
+}
+
+func TestUpdate_PartialFailure(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	repoPath := writeTestFiles(t, map[string]string{"VERSION": "1.1.0", "README.md": "no version here"})
+	defer os.RemoveAll(repoPath)
+	rules := []versionfile.Rule{
+		{Path: "VERSION", Regex: `.+`},
+		{Path: "README.md", Regex: `v[0-9.]+`},
+	}
+
+	//test
+	_, err := versionfile.Update(repoPath, rules, versionfile.NewTemplateData("capsulecd", "1.2.0", "v1.2.0"))
+
+	//assert
+	require.Error(t, err)
+	require.Equal(t, "1.1.0", readTestFile(t, repoPath, "VERSION"), "should not update any files if a rule fails")
+}
+
+func TestUpdate_Invalid(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	repoPath := writeTestFiles(t, map[string]string{
+		"package.json": `{"version": "1.1.0", "files": ["index.js"]}`,
+		"values.yaml":  "description: |\n  version 1.1.0\n",
+	})
+	defer os.RemoveAll(repoPath)
+	data := versionfile.NewTemplateData("capsulecd", "1.2.0", "v1.2.0")
+
+	invalidRules := []versionfile.Rule{
+		{Path: "", Regex: `.+`},
+		{Path: "../package.json", JsonPath: "$.version"},
+		{Path: "missing.json", JsonPath: "$.version"},
+		{Path: "package.json"},
+		{Path: "package.json", JsonPath: "$.version", Regex: `.+`},
+		{Path: "package.json", Regex: `(`},
+		{Path: "package.json", JsonPath: "$.version["},
+		{Path: "package.json", JsonPath: "$.version", Template: "{{.Version"},
+		{Path: "package.json", JsonPath: "$.files"},
+		{Path: "package.json", JsonPath: "$.missing"},
+		{Path: "values.yaml", YamlPath: "$.description"},
+	}
+
+	for _, rule := range invalidRules {
+		//test
+		_, err := versionfile.Update(repoPath, []versionfile.Rule{rule}, data)
+
+		//assert
+		require.Error(t, err, "rule should be invalid: %v", rule)
+	}
+	require.Equal(t, `{"version": "1.1.0", "files": ["index.js"]}`, readTestFile(t, repoPath, "package.json"))
+}
+
+func TestUpdate_QuotedValues(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	repoPath := writeTestFiles(t, map[string]string{
+		"values.yaml": "name: \"café\"\ntag: \"1.1.0\"\nlabel: 'it''s 1.1.0'\n",
+	})
+	defer os.RemoveAll(repoPath)
+	rules := []versionfile.Rule{
+		{Path: "values.yaml", YamlPath: "tag", Template: `"{{.Version}}"`},
+		{Path: "values.yaml", YamlPath: "label", Template: "it's {{.Version}}"},
+	}
+
+	//test
+	_, err := versionfile.Update(repoPath, rules, versionfile.NewTemplateData("capsulecd", "1.2.0", "v1.2.0"))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "name: \"café\"\ntag: \"\\\"1.2.0\\\"\"\nlabel: 'it''s 1.2.0'\n", readTestFile(t, repoPath, "values.yaml"), "should escape the value using the original quoting style")
+}
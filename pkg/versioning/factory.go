@@ -0,0 +1,39 @@
+package versioning
+
+import (
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/config"
+	"github.com/analogj/capsulecd/pkg/errors"
+)
+
+func Create(schemeType string, config config.Interface) (Interface, error) {
+
+	var scheme Interface
+
+	switch schemeType {
+	case "semver", "":
+		scheme = new(versioningSemver)
+	case "calver":
+		scheme = new(versioningCalver)
+	default:
+		return nil, errors.EngineUnspecifiedError(fmt.Sprintf("Unknown Version Scheme: %s", schemeType))
+	}
+
+	if err := scheme.Init(config); err != nil {
+		return nil, err
+	}
+	return scheme, nil
+}
+
+// Check that the scheme only generates valid semver (`major.minor.patch`) versions, for engines whose package managers
+// reject other versions (eg. npm, chef).
+func ValidateSemverCompatible(schemeType string, config config.Interface) error {
+	scheme, err := Create(schemeType, config)
+	if err != nil {
+		return err
+	}
+	if calver, ok := scheme.(*versioningCalver); ok {
+		return calver.semverCompatible()
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package versioning_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/versioning"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCreate_Semver(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+
+	//test
+	scheme, err := versioning.Create("semver", fakeConfig)
+	defaultScheme, derr := versioning.Create("", fakeConfig)
+
+	//assert
+	require.NoError(t, err)
+	require.NotNil(t, scheme)
+	require.NoError(t, derr)
+	require.NotNil(t, defaultScheme, "should default to semver")
+}
+
+func TestCreate_Calver(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("YYYY.MM.MICRO")
+
+	//test
+	scheme, err := versioning.Create("calver", fakeConfig)
+
+	//assert
+	require.NoError(t, err)
+	require.NotNil(t, scheme)
+}
+
+func TestCreate_Calver_InvalidFormat(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("YYYY.MM")
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("YYYY.QQ.MICRO")
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("MICRO")
+
+	//test
+	_, merr := versioning.Create("calver", fakeConfig)
+	_, serr := versioning.Create("calver", fakeConfig)
+	_, oerr := versioning.Create("calver", fakeConfig)
+
+	//assert
+	require.Error(t, merr, "should require the MICRO segment")
+	require.Error(t, serr, "should fail for unknown segments")
+	require.Error(t, oerr, "should require a date segment")
+}
+
+func TestCreate_Invalid(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+
+	//test
+	scheme, err := versioning.Create("romver", fakeConfig)
+
+	//assert
+	require.Error(t, err, "should return an error")
+	require.Nil(t, scheme)
+}
+
+func TestValidateSemverCompatible(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("YYYY.MM.MICRO")
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("YYYY.0M.MICRO")
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return("YY.MM.DD.MICRO")
+
+	//test & assert
+	require.NoError(t, versioning.ValidateSemverCompatible("semver", fakeConfig))
+	require.NoError(t, versioning.ValidateSemverCompatible("calver", fakeConfig))
+	require.EqualError(t, versioning.ValidateSemverCompatible("calver", fakeConfig), "Calver format (YYYY.0M.MICRO) must not have zero padded segments (0M) to generate semver versions")
+	require.EqualError(t, versioning.ValidateSemverCompatible("calver", fakeConfig), "Calver format (YY.MM.DD.MICRO) must have 3 segments to generate semver versions")
+	require.Error(t, versioning.ValidateSemverCompatible("romver", fakeConfig))
+}
@@ -0,0 +1,21 @@
+package versioning
+
+import (
+	"github.com/analogj/capsulecd/pkg/config"
+)
+
+// Create mock using:
+// mockgen -source=pkg/versioning/interface.go -destination=pkg/versioning/mock/mock_versioning.go
+type Interface interface {
+	Init(config config.Interface) error
+
+	// Determine the version released after the currentVersion.
+	// bumpType is the version bump type determined from the pull request or engine_version_bump_type (eg. major, minor,
+	// patch), schemes that are not bump based (eg. calver) may ignore it.
+	// Returns an error if the currentVersion is invalid for the scheme, or the bump type is not supported.
+	NextVersion(currentVersion string, bumpType string) (string, error)
+
+	// Compare the versions, returns -1, 0 or 1 if a is lower, equal or higher than b.
+	// Returns an error if either version is invalid for the scheme.
+	Compare(a string, b string) (int, error)
+}
@@ -0,0 +1,149 @@
+package versioning
+
+import (
+	stderrors "errors"
+	"fmt"
+	"github.com/analogj/capsulecd/pkg/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the calver date segments, rendered from the release date (UTC).
+var calverDateSegments = map[string]func(t time.Time) string{
+	"YYYY": func(t time.Time) string { return strconv.Itoa(t.Year()) },
+	"YY":   func(t time.Time) string { return strconv.Itoa(t.Year() - 2000) },
+	"0Y":   func(t time.Time) string { return fmt.Sprintf("%02d", t.Year()-2000) },
+	"MM":   func(t time.Time) string { return strconv.Itoa(int(t.Month())) },
+	"0M":   func(t time.Time) string { return fmt.Sprintf("%02d", int(t.Month())) },
+	"WW":   func(t time.Time) string { _, week := t.ISOWeek(); return strconv.Itoa(week) },
+	"0W":   func(t time.Time) string { _, week := t.ISOWeek(); return fmt.Sprintf("%02d", week) },
+	"DD":   func(t time.Time) string { return strconv.Itoa(t.Day()) },
+	"0D":   func(t time.Time) string { return fmt.Sprintf("%02d", t.Day()) },
+}
+
+// the release counter segment, reset when the date segments change.
+const calverMicroSegment = "MICRO"
+
+// Calendar Versioning (https://calver.org), eg. `2021.6.3` for the `YYYY.MM.MICRO` format (engine_calver_format).
+// The version bump type is ignored, the micro counter is incremented for each release within the same period, and
+// rolls over to 0 when the period changes (eg. `2021.5.3` -> `2021.6.0`).
+type versioningCalver struct {
+	Format []string         // format segments, eg. `YYYY`, `MM`, `MICRO`
+	Now    func() time.Time // release date, overridden by tests
+}
+
+func (c *versioningCalver) Init(config config.Interface) error {
+	format := config.GetString("engine_calver_format")
+	c.Format = strings.Split(format, ".")
+	c.Now = time.Now
+
+	// the micro counter is required, otherwise releases within the same period would have the same version.
+	if len(c.Format) < 2 || c.Format[len(c.Format)-1] != calverMicroSegment {
+		return fmt.Errorf("Invalid calver format (%s), must end with the `MICRO` segment, eg. `YYYY.MM.MICRO`", format)
+	}
+	for _, segment := range c.Format[:len(c.Format)-1] {
+		if _, ok := calverDateSegments[segment]; !ok {
+			return fmt.Errorf("Invalid calver format (%s), unknown segment `%s`", format, segment)
+		}
+	}
+	return nil
+}
+
+// calver versions are only valid semver versions if the format has 3 segments, without zero padding (eg. `2024.03.1`).
+func (c *versioningCalver) semverCompatible() error {
+	format := strings.Join(c.Format, ".")
+	if len(c.Format) != 3 {
+		return fmt.Errorf("Calver format (%s) must have 3 segments to generate semver versions", format)
+	}
+	for _, segment := range c.Format {
+		if strings.HasPrefix(segment, "0") {
+			return fmt.Errorf("Calver format (%s) must not have zero padded segments (%s) to generate semver versions", format, segment)
+		}
+	}
+	return nil
+}
+
+func (c *versioningCalver) NextVersion(currentVersion string, bumpType string) (string, error) {
+	switch bumpType {
+	case "major", "minor", "patch":
+	case "prerelease", "release":
+		return "", fmt.Errorf("Version bump type (%s) is not supported by the calver version scheme", bumpType)
+	default:
+		return "", stderrors.New("Unknown version bump interval")
+	}
+
+	currentSegments, perr := c.parse(currentVersion)
+	if perr != nil {
+		return "", perr
+	}
+
+	// render the date segments for the release date
+	now := c.Now().UTC()
+	nextSegments := []string{}
+	for _, segment := range c.Format[:len(c.Format)-1] {
+		nextSegments = append(nextSegments, calverDateSegments[segment](now))
+	}
+
+	micro := 0
+	switch compareCalverSegments(currentSegments[:len(currentSegments)-1], nextSegments) {
+	case 0:
+		// another release within the same period
+		currentMicro, _ := strconv.Atoi(currentSegments[len(currentSegments)-1])
+		micro = currentMicro + 1
+	case 1:
+		return "", fmt.Errorf("Version (%s) is newer than the release date (%s)", currentVersion, strings.Join(nextSegments, "."))
+	}
+	return strings.Join(append(nextSegments, strconv.Itoa(micro)), "."), nil
+}
+
+func (c *versioningCalver) Compare(a string, b string) (int, error) {
+	aSegments, aerr := c.parse(a)
+	if aerr != nil {
+		return 0, aerr
+	}
+	bSegments, berr := c.parse(b)
+	if berr != nil {
+		return 0, berr
+	}
+	return compareCalverSegments(aSegments, bSegments), nil
+}
+
+// split the version into its segments, validating them against the format.
+func (c *versioningCalver) parse(version string) ([]string, error) {
+	formatErr := fmt.Errorf("Version (%s) does not match the calver format (%s)", version, strings.Join(c.Format, "."))
+	segments := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(segments) != len(c.Format) {
+		return nil, formatErr
+	}
+	for ndx, segment := range segments {
+		if _, err := strconv.ParseUint(segment, 10, 64); err != nil {
+			return nil, formatErr
+		}
+		switch c.Format[ndx] {
+		case "YYYY":
+			if len(segment) != 4 {
+				return nil, formatErr
+			}
+		case "0Y", "0M", "0W", "0D":
+			if len(segment) != 2 {
+				return nil, formatErr
+			}
+		}
+	}
+	return segments, nil
+}
+
+// compare the numeric segments, returns -1, 0 or 1 if a is older, the same or newer than b.
+func compareCalverSegments(a []string, b []string) int {
+	for ndx := range a {
+		aVal, _ := strconv.Atoi(a[ndx])
+		bVal, _ := strconv.Atoi(b[ndx])
+		if aVal < bVal {
+			return -1
+		} else if aVal > bVal {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+package versioning
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func calverTestScheme(t *testing.T, format string, now time.Time) *versioningCalver {
+	mockCtrl := gomock.NewController(t)
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_calver_format").Return(format)
+	scheme := new(versioningCalver)
+	require.NoError(t, scheme.Init(fakeConfig))
+	scheme.Now = func() time.Time { return now }
+	return scheme
+}
+
+var calverTests = []struct {
+	format         string
+	currentVersion string
+	expected       string
+}{
+	{"YYYY.MM.MICRO", "2021.6.0", "2021.6.1"},
+	{"YYYY.MM.MICRO", "2021.6.9", "2021.6.10"},
+	{"YYYY.MM.MICRO", "v2021.6.2", "2021.6.3"},
+	{"YYYY.MM.MICRO", "2021.5.3", "2021.6.0"},
+	{"YYYY.MM.MICRO", "2020.12.7", "2021.6.0"},
+	{"YYYY.0M.MICRO", "2021.05.3", "2021.06.0"},
+	{"YY.0M.0D.MICRO", "21.06.15.1", "21.06.15.2"},
+	{"YY.0M.0D.MICRO", "21.06.14.1", "21.06.15.0"},
+	{"0Y.0W.MICRO", "21.24.0", "21.24.1"},
+}
+
+func TestCalver_NextVersion(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, time.June, 15, 10, 0, 0, 0, time.UTC)
+
+	for _, tt := range calverTests {
+		//setup
+		scheme := calverTestScheme(t, tt.format, now)
+
+		//test
+		nextVersion, err := scheme.NextVersion(tt.currentVersion, "patch")
+
+		//assert
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, nextVersion, "%s release after %s", tt.format, tt.currentVersion)
+	}
+}
+
+func TestCalver_NextVersion_IgnoresBumpType(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	scheme := calverTestScheme(t, "YYYY.MM.MICRO", time.Date(2021, time.June, 15, 10, 0, 0, 0, time.UTC))
+
+	//test
+	majorVersion, merr := scheme.NextVersion("2021.6.1", "major")
+	minorVersion, nerr := scheme.NextVersion("2021.6.1", "minor")
+
+	//assert
+	require.NoError(t, merr)
+	require.Equal(t, "2021.6.2", majorVersion)
+	require.NoError(t, nerr)
+	require.Equal(t, "2021.6.2", minorVersion)
+}
+
+func TestCalver_NextVersion_MonthRollover(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	scheme := calverTestScheme(t, "YYYY.MM.MICRO", time.Date(2021, time.June, 30, 23, 0, 0, 0, time.UTC))
+
+	//test
+	juneVersion, jerr := scheme.NextVersion("2021.6.4", "patch")
+	scheme.Now = func() time.Time { return time.Date(2021, time.July, 1, 1, 0, 0, 0, time.UTC) }
+	julyVersion, kerr := scheme.NextVersion(juneVersion, "patch")
+	julyVersion2, lerr := scheme.NextVersion(julyVersion, "patch")
+
+	//assert
+	require.NoError(t, jerr)
+	require.Equal(t, "2021.6.5", juneVersion)
+	require.NoError(t, kerr)
+	require.Equal(t, "2021.7.0", julyVersion, "should reset the micro counter when the month changes")
+	require.NoError(t, lerr)
+	require.Equal(t, "2021.7.1", julyVersion2)
+}
+
+func TestCalver_NextVersion_Invalid(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	scheme := calverTestScheme(t, "YYYY.0M.MICRO", time.Date(2021, time.June, 15, 10, 0, 0, 0, time.UTC))
+
+	//test
+	_, verr := scheme.NextVersion("abcde", "patch")
+	_, serr := scheme.NextVersion("1.2.3", "patch")
+	_, perr := scheme.NextVersion("2021.6.3", "patch")
+	_, cerr := scheme.NextVersion("2021.06.3.1", "patch")
+	_, ferr := scheme.NextVersion("2021.07.0", "patch")
+	_, berr := scheme.NextVersion("2021.06.3", "micro")
+	_, rerr := scheme.NextVersion("2021.06.3", "prerelease")
+
+	//assert
+	require.Error(t, verr, "should return an error if unparsable version")
+	require.Error(t, serr, "should return an error if the version is not a calendar version")
+	require.Error(t, perr, "should validate zero padded segments")
+	require.Error(t, cerr, "should validate the number of segments")
+	require.Error(t, ferr, "should return an error if the version is newer than the release date")
+	require.Error(t, berr, "should return an error if unknown bump type")
+	require.Error(t, rerr, "should not support pre-releases")
+}
+
+func TestCalver_Compare(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	scheme := calverTestScheme(t, "YYYY.MM.MICRO", time.Date(2021, time.June, 15, 10, 0, 0, 0, time.UTC))
+
+	//test
+	greater, gerr := scheme.Compare("2021.10.0", "2021.9.4")
+	equal, eerr := scheme.Compare("2021.6.1", "v2021.6.1")
+	_, verr := scheme.Compare("1.2.3", "2021.6.1")
+
+	//assert
+	require.NoError(t, gerr)
+	require.Equal(t, 1, greater, "should compare segments numerically")
+	require.NoError(t, eerr)
+	require.Equal(t, 0, equal)
+	require.Error(t, verr)
+}
@@ -0,0 +1,109 @@
+package versioning
+
+import (
+	stderrors "errors"
+	"fmt"
+	"github.com/Masterminds/semver"
+	"github.com/analogj/capsulecd/pkg/config"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pre-release identifiers (engine_version_prerelease_id), eg. `alpha`, `beta` or `rc`
+var prereleaseIdPattern = regexp.MustCompile(`^[0-9A-Za-z-]*[A-Za-z-][0-9A-Za-z-]*$`)
+
+// Semantic Versioning (https://semver.org), `major.minor.patch[-prerelease]`
+type versioningSemver struct {
+	Config config.Interface
+}
+
+func (s *versioningSemver) Init(config config.Interface) error {
+	s.Config = config
+	return nil
+}
+
+// pre-release & build metadata are dropped, unless a pre-release is bumped. A pre-release is promoted by the bump
+// type it was created for, eg. `1.2.0-rc.2` is promoted to `1.2.0` by a minor (or patch) bump.
+func (s *versioningSemver) NextVersion(currentVersion string, bumpType string) (string, error) {
+	v, nerr := semver.NewVersion(currentVersion)
+	if nerr != nil {
+		return "", nerr
+	}
+
+	isPrerelease := v.Prerelease() != ""
+	switch bumpType {
+	case "major":
+		if isPrerelease && v.Minor() == 0 && v.Patch() == 0 {
+			return fmt.Sprintf("%d.%d.%d", v.Major(), 0, 0), nil
+		}
+		return fmt.Sprintf("%d.%d.%d", v.Major()+1, 0, 0), nil
+	case "minor":
+		if isPrerelease && v.Patch() == 0 {
+			return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), 0), nil
+		}
+		return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor()+1, 0), nil
+	case "patch":
+		if isPrerelease {
+			return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch()), nil
+		}
+		return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch()+1), nil
+	case "prerelease":
+		return nextPrerelease(v, s.Config.GetString("engine_version_prerelease_id"))
+	case "release":
+		if !isPrerelease {
+			return "", fmt.Errorf("Cannot promote %s to a release, it is not a pre-release version", currentVersion)
+		}
+		return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch()), nil
+	default:
+		return "", stderrors.New("Unknown version bump interval")
+	}
+}
+
+// versions are compared using semver precedence, eg. `1.2.0-rc.1` < `1.2.0`
+func (s *versioningSemver) Compare(a string, b string) (int, error) {
+	aVersion, aerr := semver.NewVersion(a)
+	if aerr != nil {
+		return 0, aerr
+	}
+	bVersion, berr := semver.NewVersion(b)
+	if berr != nil {
+		return 0, berr
+	}
+	return aVersion.Compare(bVersion), nil
+}
+
+// determine the next pre-release version for the identifier (eg. `rc`), following semver precedence:
+// `1.2.3` -> `1.2.4-rc.1`, `1.2.0-rc.1` -> `1.2.0-rc.2`, `1.2.0-beta.3` -> `1.2.0-rc.1`
+// switching to an identifier with lower precedence (eg. `rc` -> `alpha`) is not allowed, the pre-release must be
+// promoted first.
+func nextPrerelease(v *semver.Version, prereleaseId string) (string, error) {
+	if !prereleaseIdPattern.MatchString(prereleaseId) {
+		return "", fmt.Errorf("Invalid pre-release identifier (%s), must be alphanumeric, eg. `alpha`, `beta` or `rc`", prereleaseId)
+	}
+
+	if v.Prerelease() == "" {
+		return fmt.Sprintf("%d.%d.%d-%s.1", v.Major(), v.Minor(), v.Patch()+1, prereleaseId), nil
+	}
+
+	nextVersion := fmt.Sprintf("%d.%d.%d-%s.1", v.Major(), v.Minor(), v.Patch(), prereleaseId)
+	identifiers := strings.Split(v.Prerelease(), ".")
+	if identifiers[0] == prereleaseId {
+		// increment the pre-release number, eg. `rc.1` -> `rc.2` (or `rc` -> `rc.1`)
+		last := len(identifiers) - 1
+		if number, err := strconv.Atoi(identifiers[last]); last > 0 && err == nil {
+			identifiers[last] = strconv.Itoa(number + 1)
+		} else {
+			identifiers = append(identifiers, "1")
+		}
+		nextVersion = fmt.Sprintf("%d.%d.%d-%s", v.Major(), v.Minor(), v.Patch(), strings.Join(identifiers, "."))
+	}
+
+	next, nerr := semver.NewVersion(nextVersion)
+	if nerr != nil {
+		return "", nerr
+	} else if !next.GreaterThan(v) {
+		return "", fmt.Errorf("Pre-release version %s would have a lower precedence than the current version %s", nextVersion, v.Original())
+	}
+	return nextVersion, nil
+}
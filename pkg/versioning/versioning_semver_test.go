@@ -0,0 +1,91 @@
+package versioning_test
+
+import (
+	"github.com/analogj/capsulecd/pkg/config/mock"
+	"github.com/analogj/capsulecd/pkg/versioning"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var semverTests = []struct {
+	currentVersion string
+	bumpType       string
+	expected       string
+}{
+	{"1.2.2", "patch", "1.2.3"},
+	{"v1.2.3", "patch", "1.2.4"},
+	{"1.2.2", "minor", "1.3.0"},
+	{"1.2.2", "major", "2.0.0"},
+	{"1.2.2+build.5", "patch", "1.2.3"},
+	{"1.2.3", "prerelease", "1.2.4-rc.1"},
+	{"1.2.0-rc.1", "prerelease", "1.2.0-rc.2"},
+	{"1.2.0-beta.3", "prerelease", "1.2.0-rc.1"},
+	{"1.2.0-rc.2", "release", "1.2.0"},
+	{"1.2.1-rc.1", "patch", "1.2.1"},
+	{"1.3.0-rc.1", "minor", "1.3.0"},
+	{"1.3.1-rc.1", "minor", "1.4.0"},
+	{"2.0.0-rc.1", "major", "2.0.0"},
+}
+
+func TestSemver_NextVersion(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_prerelease_id").AnyTimes().Return("rc")
+	scheme, err := versioning.Create("semver", fakeConfig)
+	require.NoError(t, err)
+
+	for _, tt := range semverTests {
+		//test
+		nextVersion, nerr := scheme.NextVersion(tt.currentVersion, tt.bumpType)
+
+		//assert
+		require.NoError(t, nerr)
+		require.Equal(t, tt.expected, nextVersion, "%s bump of %s", tt.bumpType, tt.currentVersion)
+	}
+}
+
+func TestSemver_NextVersion_Invalid(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	fakeConfig.EXPECT().GetString("engine_version_prerelease_id").Return("alpha")
+	scheme, err := versioning.Create("semver", fakeConfig)
+	require.NoError(t, err)
+
+	//test
+	_, verr := scheme.NextVersion("abcde", "patch")
+	_, berr := scheme.NextVersion("1.2.3", "micro")
+	_, rerr := scheme.NextVersion("1.2.3", "release")
+	_, perr := scheme.NextVersion("1.2.0-rc.1", "prerelease")
+
+	//assert
+	require.Error(t, verr, "should return an error if unparsable version")
+	require.Error(t, berr, "should return an error if unknown bump type")
+	require.Error(t, rerr, "should not promote a release version")
+	require.Error(t, perr, "should not allow a lower precedence pre-release")
+}
+
+func TestSemver_Compare(t *testing.T) {
+	//setup
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	fakeConfig := mock_config.NewMockInterface(mockCtrl)
+	scheme, err := versioning.Create("semver", fakeConfig)
+	require.NoError(t, err)
+
+	//test
+	greater, gerr := scheme.Compare("2.0.0", "1.9.9")
+	prerelease, perr := scheme.Compare("2.0.0-rc.1", "2.0.0")
+	_, verr := scheme.Compare("latest", "2.0.0")
+
+	//assert
+	require.NoError(t, gerr)
+	require.Equal(t, 1, greater)
+	require.NoError(t, perr)
+	require.Equal(t, -1, prerelease, "pre-releases should have a lower precedence")
+	require.Error(t, verr)
+}